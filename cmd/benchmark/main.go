@@ -0,0 +1,85 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "os"
+    "time"
+
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/benchmark"
+)
+
+// benchmark measures Schedule, Filter and Score latency, throughput and
+// allocations on a synthetic cluster and fails when the design targets
+// are missed.
+var (
+    spines         int
+    leavesPerSpine int
+    nodesPerLeaf   int
+    gpusPerNode    int
+    concurrency    int
+    iterations     int
+    maxP99         time.Duration
+    minThroughput  float64
+    outputFile     string
+)
+
+func main() {
+    klog.InitFlags(nil)
+    flag.Parse()
+
+    config := benchmark.DefaultConfig()
+    config.Fixture = benchmark.FixtureConfig{
+        Spines:         spines,
+        LeavesPerSpine: leavesPerSpine,
+        NodesPerLeaf:   nodesPerLeaf,
+        GPUsPerNode:    gpusPerNode,
+    }
+    config.Concurrency = concurrency
+    config.Iterations = iterations
+
+    klog.Infof("Benchmarking on %d nodes with %d concurrent callers", config.Fixture.Nodes(), concurrency)
+    results, err := benchmark.Run(context.Background(), config)
+    if err != nil {
+        klog.Fatalf("Benchmark failed: %v", err)
+    }
+    benchmark.WriteTable(os.Stdout, results)
+
+    if outputFile != "" {
+        data, err := json.MarshalIndent(results, "", "  ")
+        if err != nil {
+            klog.Fatalf("Error encoding results: %v", err)
+        }
+        if err := os.WriteFile(outputFile, data, 0644); err != nil {
+            klog.Fatalf("Error writing results: %v", err)
+        }
+    }
+
+    violations := benchmark.Check(results, benchmark.Targets{
+        MaxP99:                maxP99,
+        MinScheduleThroughput: minThroughput,
+    })
+    for _, v := range violations {
+        klog.Errorf("Target missed: %s", v)
+    }
+    if len(violations) > 0 {
+        os.Exit(1)
+    }
+}
+
+func init() {
+    defaults := benchmark.DefaultConfig()
+    targets := benchmark.DesignTargets()
+    flag.IntVar(&spines, "spines", defaults.Fixture.Spines, "Number of spine switches")
+    flag.IntVar(&leavesPerSpine, "leaves-per-spine", defaults.Fixture.LeavesPerSpine, "Leaf switches per spine")
+    flag.IntVar(&nodesPerLeaf, "nodes-per-leaf", defaults.Fixture.NodesPerLeaf, "Nodes per leaf switch")
+    flag.IntVar(&gpusPerNode, "gpus-per-node", defaults.Fixture.GPUsPerNode, "GPUs per node")
+    flag.IntVar(&concurrency, "concurrency", defaults.Concurrency, "Goroutines calling the scheduler at once")
+    flag.IntVar(&iterations, "iterations", defaults.Iterations, "Calls per goroutine for each operation and strategy")
+    flag.DurationVar(&maxP99, "max-p99", targets.MaxP99, "Fail when any p99 latency exceeds this")
+    flag.Float64Var(&minThroughput, "min-throughput", targets.MinScheduleThroughput, "Fail when Schedule handles fewer decisions per second")
+    flag.StringVar(&outputFile, "output", "", "Write the results as JSON to this file")
+}
@@ -1,27 +1,27 @@
-go
 // +build !generate
 package main
 
 import (
     "flag"
     "os"
+    "os/signal"
+    "syscall"
     "time"
 
+    kubeinformers "k8s.io/client-go/informers"
     "k8s.io/client-go/kubernetes"
     "k8s.io/client-go/tools/clientcmd"
     "k8s.io/klog/v2"
-    "github.com/iamakanshab/topology-aware-scheduler/pkg/apis/topology/v1alpha1"
-    clientset "github.com/iamakanshab/topology-aware-gpu-scheduler/pkg/generated/clientset/versioned"
-    informers "github.com/iamakanshab/topology-aware-gpu-scheduler/pkg/generated/informers/externalversions"
-    listers "github.com/iamakanshab/topology-aware-gpu-scheduler/pkg/generated/listers/topology/v1alpha1"
-    "github.com/iamakanshab/topology-aware-scheduler/pkg/controller"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/controller"
     "github.com/prometheus/client_golang/prometheus/promhttp"
     "net/http"
 )
 
 var (
-    masterURL  string
-    kubeconfig string
+    masterURL      string
+    kubeconfig     string
+    topologySource string
+    labelInterval  time.Duration
 )
 
 func main() {
@@ -38,19 +38,25 @@ func main() {
         klog.Fatalf("Error building kubernetes clientset: %s", err.Error())
     }
 
-    topologyClient, err := clientset.NewForConfig(cfg)
-    if err != nil {
-        klog.Fatalf("Error building topology clientset: %s", err.Error())
-    }
+    stopCh := make(chan struct{})
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+    go func() {
+        <-signals
+        close(stopCh)
+    }()
 
-    topologyInformerFactory := informers.NewSharedInformerFactory(topologyClient, time.Second*30)
+    kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Second*30)
 
-    controller := controller.NewController(
-        kubeClient,
-        topologyClient,
-        topologyInformerFactory.Topology().V1alpha1().TopologySchedulerConfigs(),
-        topologyInformerFactory.Topology().V1alpha1().DomainConfigs(),
-    )
+    // Label nodes with their place in the canonical topology
+    if topologySource != "" {
+        labeler := controller.NewNodeLabeler(kubeClient, kubeInformerFactory.Core().V1().Nodes(), topologySource, labelInterval)
+        go func() {
+            if err := labeler.Run(2, stopCh); err != nil {
+                klog.Fatalf("Error running node labeler: %s", err.Error())
+            }
+        }()
+    }
 
     // Start metrics server
     go func() {
@@ -60,14 +66,15 @@ func main() {
 
     // Notice that there is no need to run Start methods in a separate goroutine.
     // Start() is non-blocking and runs the informer collection in the background.
-    topologyInformerFactory.Start(stopCh)
+    // DomainConfigs are reconciled by the scheduler, which owns the topology.
+    kubeInformerFactory.Start(stopCh)
 
-    if err = controller.Run(2, stopCh); err != nil {
-        klog.Fatalf("Error running controller: %s", err.Error())
-    }
+    <-stopCh
 }
 
 func init() {
     flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+    flag.StringVar(&topologySource, "topology-source", "", "Topology document file or URL, e.g. http://scheduler:8080/topology/export, to label nodes from")
+    flag.DurationVar(&labelInterval, "label-interval", time.Minute, "Interval between reloads of the topology for node labels")
     flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
 }
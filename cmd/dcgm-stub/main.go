@@ -0,0 +1,40 @@
+package main
+
+import (
+    "flag"
+    "net/http"
+    "os"
+
+    "k8s.io/klog/v2"
+)
+
+// dcgm-stub serves a static dcgm-exporter metrics file so GPU health
+// handling can be exercised without real GPUs.
+var (
+    metricsFile string
+    listenAddr  string
+)
+
+func main() {
+    klog.InitFlags(nil)
+    flag.Parse()
+
+    http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+        // Re-read on every request so faults can be injected by editing the file
+        data, err := os.ReadFile(metricsFile)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+        w.Write(data)
+    })
+
+    klog.Infof("Serving %s on %s/metrics", metricsFile, listenAddr)
+    klog.Fatal(http.ListenAndServe(listenAddr, nil))
+}
+
+func init() {
+    flag.StringVar(&metricsFile, "metrics-file", "dcgm-metrics.txt", "File with dcgm-exporter output to serve")
+    flag.StringVar(&listenAddr, "listen", ":9400", "Address to listen on")
+}
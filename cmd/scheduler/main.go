@@ -9,6 +9,8 @@ import (
     "os"
     "time"
 
+    v1 "k8s.io/api/core/v1"
+    kubeinformers "k8s.io/client-go/informers"
     "k8s.io/client-go/kubernetes"
     "k8s.io/client-go/tools/clientcmd"
     "k8s.io/client-go/tools/leaderelection"
@@ -16,10 +18,18 @@ import (
     "k8s.io/klog/v2"
     "k8s.io/kubernetes/pkg/scheduler/apis/config"
     metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/labels"
     "github.com/prometheus/client_golang/prometheus/promhttp"
 
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/discovery"
     "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/extension"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/history"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/simulator"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/utils/topology"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/controller"
     clientset "github.com/yourusername/topology-aware-gpu-scheduler/pkg/generated/clientset/versioned"
+    topologyinformers "github.com/yourusername/topology-aware-gpu-scheduler/pkg/generated/informers/externalversions"
 )
 
 var (
@@ -29,6 +39,29 @@ var (
     leaderElect         bool
     lockObjectName      string
     lockObjectNamespace string
+    dcgmEndpoint        string
+    dcgmInterval        time.Duration
+    historyDB           string
+    configFile          string
+    topologyFile        string
+    ibFabric            string
+    slurmTopology       string
+    topologyHistory     string
+    topologyHistoryRetain int
+    lldpDiscovery       bool
+    lldpInterfaces      string
+    lldpSpinePattern    string
+    lldpSwitches        string
+    lldpInterval        time.Duration
+    domainConfigs       bool
+    domainStatusInterval time.Duration
+    noExecuteInterval   time.Duration
+    schedulerConfigKey  string
+    tuneWeights         bool
+    tunerFrozen         bool
+    externalScorer      string
+    externalTimeout     time.Duration
+    externalWeight      float64
     version            string // Added for version info
     buildDate          string // Added for build date
 )
@@ -57,16 +90,255 @@ func main() {
         klog.Fatalf("Error building topology clientset: %v", err)
     }
 
+    // Load the scheduler configuration and check that every node maps to a
+    // leaf under the node label schema
+    schedulerConfig, err := algorithm.LoadSchedulerConfig(configFile)
+    if err != nil {
+        klog.Fatalf("Error loading scheduler config: %v", err)
+    }
+    reportUnmatchedNodes(kubeClient, schedulerConfig.Spec.NodeLabels)
+
     // Create scheduler cache and topology cache
     nodeCache := algorithm.NewNodeCache()
     topologyCache := algorithm.NewTopologyCache(nodeCache)
+    topologyCache.SetLabelSchema(schedulerConfig.Spec.NodeLabels)
     
-    // Create the scheduler
+    // Create the scheduler with the weights and constraints of the
+    // configuration file
     scheduler := algorithm.NewTopologyScheduler(topologyCache)
+    if err := scheduler.SetConfig(&schedulerConfig.Spec); err != nil {
+        klog.Fatalf("Error applying scheduler config: %v", err)
+    }
+
+    // Open the historical performance store
+    if historyDB != "" {
+        store, err := history.Open(historyDB)
+        if err != nil {
+            klog.Fatalf("Error opening history store: %v", err)
+        }
+        defer store.Close()
+        scheduler.SetHistoryStore(store)
+    }
+
+    // Optionally tune the scoring weights from observed job throughput
+    if tuneWeights {
+        tunerConfig := algorithm.DefaultTunerConfig()
+        tunerConfig.Frozen = tunerFrozen
+        if err := scheduler.EnableTuner(tunerConfig); err != nil {
+            klog.Fatalf("Error enabling weight tuner: %v", err)
+        }
+    }
+
+    // Blend in scores from an external scoring service
+    if externalScorer != "" {
+        client, err := extension.NewClient(externalScorer, externalTimeout)
+        if err != nil {
+            klog.Fatalf("Error connecting to external scorer: %v", err)
+        }
+        defer client.Close()
+        scheduler.SetExternalScorer(client, externalWeight)
+    }
+
+    // Track per-GPU health from the DCGM exporter
+    if dcgmEndpoint != "" {
+        scraper := topology.NewDCGMScraper(dcgmEndpoint, 5*time.Second)
+        go topologyCache.SyncGPUHealth(context.Background(), scraper, dcgmInterval)
+    }
+
+    // Load the declared topology from version control
+    if topologyFile != "" {
+        doc, err := discovery.LoadTopologyFile(topologyFile)
+        if err != nil {
+            klog.Fatalf("Error loading topology file: %v", err)
+        }
+        nodeList, err := kubeClient.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+        if err != nil {
+            klog.Fatalf("Error listing nodes: %v", err)
+        }
+        nodes := make([]*v1.Node, 0, len(nodeList.Items))
+        for i := range nodeList.Items {
+            nodes = append(nodes, &nodeList.Items[i])
+        }
+        if _, err := doc.Populate(topologyCache, nodes); err != nil {
+            klog.Fatalf("Error populating topology from file: %v", err)
+        }
+    }
+
+    // Load the InfiniBand fabric with its link bandwidths
+    if ibFabric != "" {
+        source := discovery.NewIBSource(topologyCache)
+        source.SetLabelSchema(schedulerConfig.Spec.NodeLabels)
+        if _, err := source.ImportFile(context.Background(), kubeClient, ibFabric); err != nil {
+            klog.Fatalf("Error importing InfiniBand fabric: %v", err)
+        }
+    }
+
+    // Share the switch tree with the Slurm partitions of the fleet
+    if slurmTopology != "" {
+        source := discovery.NewSlurmSource(topologyCache)
+        source.SetLabelSchema(schedulerConfig.Spec.NodeLabels)
+        if _, err := source.ImportFile(context.Background(), kubeClient, slurmTopology); err != nil {
+            klog.Fatalf("Error importing Slurm topology: %v", err)
+        }
+    }
+
+    // Record topology changes from here on, once the startup imports have
+    // rebuilt the topology
+    var changeLog *history.ChangeLog
+    if topologyHistory != "" {
+        changeLog, err = history.OpenChangeLog(topologyHistory, topologyHistoryRetain)
+        if err != nil {
+            klog.Fatalf("Error opening topology history: %v", err)
+        }
+        defer changeLog.Close()
+        if err := topologyCache.SetChangeLog(changeLog); err != nil {
+            klog.Fatalf("Error recording topology history: %v", err)
+        }
+    }
+
+    // Derive leaf membership and spine connectivity from LLDP neighbours
+    if lldpDiscovery {
+        source, err := discovery.NewLLDPSource(topologyCache, discovery.LLDPConfig{
+            InterfacePattern: lldpInterfaces,
+            SpinePattern:     lldpSpinePattern,
+        })
+        if err != nil {
+            klog.Fatalf("Error creating LLDP discovery: %v", err)
+        }
+        source.SetLabelSchema(schedulerConfig.Spec.NodeLabels)
+        if lldpSwitches != "" {
+            switches, err := discovery.LoadSwitchNeighbors(lldpSwitches)
+            if err != nil {
+                klog.Fatalf("Error loading switch neighbors: %v", err)
+            }
+            source.SetSwitchNeighbors(switches)
+        }
+        go source.Run(context.Background(), kubeClient, lldpInterval)
+    }
+
+    // Move nodes whose topology labels change, together with the jobs of
+    // the pods running on them
+    topologyCache.SetPodSource(func(nodeName string) ([]*v1.Pod, error) {
+        pods, err := kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{
+            FieldSelector: "spec.nodeName=" + nodeName,
+        })
+        if err != nil {
+            return nil, err
+        }
+        onNode := make([]*v1.Pod, 0, len(pods.Items))
+        for i := range pods.Items {
+            onNode = append(onNode, &pods.Items[i])
+        }
+        return onNode, nil
+    })
+    informerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+    informerFactory.Core().V1().Nodes().Informer().AddEventHandler(topologyCache.NodeEventHandler())
+
+    // Reconcile DomainConfig objects into the topology. Only the leader
+    // runs the controller, as the status reports the GPUs it has placed.
+    var domainController *controller.Controller
+    topologyInformerFactory := topologyinformers.NewSharedInformerFactory(topologyClient, 0)
+    if domainConfigs {
+        domainController = controller.NewController(
+            topologyClient,
+            topologyInformerFactory.Topology().V1alpha1().DomainConfigs(),
+            informerFactory.Core().V1().Nodes(),
+            topologyCache,
+            domainStatusInterval,
+        )
+        domainController.SetLabelSchema(schedulerConfig.Spec.NodeLabels)
+    }
+
+    // Move pods out of domains with NoExecute taints, whichever source set
+    // the taints
+    recovery := algorithm.NewRecoveryManager(algorithm.NewDomainManager(), scheduler)
+    podLister := informerFactory.Core().V1().Pods().Lister()
+    podsOnNodes := func(nodes []string) ([]*v1.Pod, error) {
+        pods, err := podLister.List(labels.Everything())
+        if err != nil {
+            return nil, err
+        }
+        onNodes := make(map[string]bool, len(nodes))
+        for _, node := range nodes {
+            onNodes[node] = true
+        }
+        var inDomain []*v1.Pod
+        for _, pod := range pods {
+            if onNodes[pod.Spec.NodeName] {
+                inDomain = append(inDomain, pod)
+            }
+        }
+        return inDomain, nil
+    }
+
+    // Swap in weights and constraints from the SchedulerConfig object
+    // without a restart. Every replica follows it; only the leader writes
+    // its status.
+    var configController *controller.SchedulerConfigController
+    if schedulerConfigKey != "" {
+        configController, err = controller.NewSchedulerConfigController(
+            topologyClient,
+            topologyInformerFactory.Topology().V1alpha1().SchedulerConfigs(),
+            scheduler,
+            &schedulerConfig.Spec,
+            schedulerConfigKey,
+        )
+        if err != nil {
+            klog.Fatalf("Error creating SchedulerConfig controller: %v", err)
+        }
+    }
+
+    informerStopCh := make(chan struct{})
+    defer close(informerStopCh)
+    informerFactory.Start(informerStopCh)
+    topologyInformerFactory.Start(informerStopCh)
+    if configController != nil {
+        go func() {
+            if err := configController.Run(informerStopCh); err != nil {
+                klog.Errorf("Error running SchedulerConfig controller: %v", err)
+            }
+        }()
+    }
+    lead := func() {
+        if configController != nil {
+            configController.WriteStatus()
+        }
+        if domainController != nil {
+            go func() {
+                if err := domainController.Run(informerStopCh); err != nil {
+                    klog.Errorf("Error running DomainConfig controller: %v", err)
+                }
+            }()
+        }
+        go recovery.RunNoExecute(informerStopCh, noExecuteInterval, podsOnNodes)
+        runScheduler(scheduler, kubeClient)
+    }
+
+    // Imports that would break the topology have already been refused, so
+    // an invalid topology here is not used for scheduling
+    report := topologyCache.Validate()
+    for _, issue := range report.Warnings {
+        klog.Warningf("Topology %s", issue)
+    }
+    if !report.Valid() {
+        klog.Fatalf("Refusing to start with an invalid topology: %v", &algorithm.ValidationError{Report: report})
+    }
 
     // Start metrics server
     go func() {
         http.Handle("/metrics", promhttp.Handler())
+        http.Handle("/stragglers/report", scheduler.StragglerDetector())
+        http.Handle("/history/report", scheduler.HistoryHandler())
+        http.Handle("/topology/snapshot", simulator.SnapshotHandler(topologyCache))
+        http.Handle("/topology/export", discovery.ExportHandler(topologyCache))
+        http.Handle("/topology/validate", topologyCache.ValidationHandler())
+        if changeLog != nil {
+            http.Handle("/topology/history", changeLog.Handler())
+            http.Handle("/topology/history/diff", changeLog.Handler())
+        }
+        if tuner := scheduler.Tuner(); tuner != nil {
+            http.Handle("/tuner", tuner)
+        }
         klog.Fatal(http.ListenAndServe(":8080", nil))
     }()
 
@@ -90,7 +362,7 @@ func main() {
             RetryPeriod:    2 * time.Second,
             Callbacks: leaderelection.LeaderCallbacks{
                 OnStartedLeading: func(ctx context.Context) {
-                    runScheduler(scheduler, kubeClient)
+                    lead()
                 },
                 OnStoppedLeading: func() {
                     klog.Info("Leader lost")
@@ -102,7 +374,7 @@ func main() {
             },
         })
     } else {
-        runScheduler(scheduler, kubeClient)
+        lead()
     }
 }
 
@@ -122,6 +394,26 @@ func runScheduler(scheduler *algorithm.TopologyScheduler, client kubernetes.Inte
     <-stopCh
 }
 
+// reportUnmatchedNodes logs the nodes the label schema cannot place. They
+// are left out of the topology until their labels are fixed.
+func reportUnmatchedNodes(client kubernetes.Interface, schema *algorithm.NodeLabelSchema) {
+    nodeList, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+    if err != nil {
+        klog.Warningf("Error listing nodes to check the label schema: %v", err)
+        return
+    }
+
+    nodes := make([]*v1.Node, 0, len(nodeList.Items))
+    for i := range nodeList.Items {
+        nodes = append(nodes, &nodeList.Items[i])
+    }
+    unmatched := schema.UnmatchedNodes(nodes)
+    for _, name := range unmatched {
+        klog.Warningf("Node %s matches no leaf in the node label schema", name)
+    }
+    klog.Infof("Node label schema places %d of %d nodes", len(nodes)-len(unmatched), len(nodes))
+}
+
 func getHostname() string {
     hostname, err := os.Hostname()
     if err != nil {
@@ -137,4 +429,27 @@ func init() {
     flag.BoolVar(&leaderElect, "leader-elect", true, "Enable leader election")
     flag.StringVar(&lockObjectName, "lock-object-name", "topology-scheduler", "Name of lock object")
     flag.StringVar(&lockObjectNamespace, "lock-object-namespace", "kube-system", "Namespace of lock object")
+    flag.StringVar(&dcgmEndpoint, "dcgm-endpoint", "", "URL of a DCGM exporter metrics endpoint for GPU health, disabled when empty")
+    flag.StringVar(&configFile, "config", "/app/config/config.yaml", "Path of the scheduler configuration file")
+    flag.StringVar(&historyDB, "history-db", "/var/lib/topology-scheduler/history.db", "Path of the historical performance database, disabled when empty")
+    flag.BoolVar(&tuneWeights, "tune-weights", false, "Tune scoring weights online from job throughput and queue wait")
+    flag.BoolVar(&tunerFrozen, "tuner-frozen", false, "Start the weight tuner frozen")
+    flag.StringVar(&externalScorer, "external-scorer", "", "Address of an external gRPC scoring service, disabled when empty")
+    flag.DurationVar(&externalTimeout, "external-scorer-timeout", 100*time.Millisecond, "Deadline for external scoring calls")
+    flag.Float64Var(&externalWeight, "external-scorer-weight", 0.3, "Share of the final score given to the external scorer")
+    flag.StringVar(&topologyFile, "topology-file", "", "Versioned topology document (YAML or JSON) to load at startup")
+    flag.StringVar(&ibFabric, "ib-fabric", "", "ibnetdiscover or iblinkinfo dump to load the topology from")
+    flag.StringVar(&topologyHistory, "topology-history", "", "bbolt file recording the topology change history, disabled when empty")
+    flag.IntVar(&topologyHistoryRetain, "topology-history-retain", 10000, "Topology changes kept before older ones are compacted, all when 0")
+    flag.StringVar(&slurmTopology, "slurm-topology", "", "Slurm topology.conf to load the switch tree from")
+    flag.BoolVar(&lldpDiscovery, "lldp-discovery", false, "Discover leaf membership and spine connectivity from LLDP neighbour data")
+    flag.StringVar(&lldpInterfaces, "lldp-interfaces", "", "Regular expression selecting the fabric interfaces, all when empty")
+    flag.StringVar(&lldpSpinePattern, "lldp-spine-pattern", "", "Regular expression selecting spine switches among leaf switch neighbours")
+    flag.StringVar(&lldpSwitches, "lldp-switches", "", "YAML or JSON file with the LLDP tables of the leaf switches")
+    flag.DurationVar(&lldpInterval, "lldp-interval", 5*time.Minute, "Interval between LLDP discovery runs")
+    flag.BoolVar(&domainConfigs, "domain-configs", false, "Build the topology from DomainConfig objects and report their status")
+    flag.StringVar(&schedulerConfigKey, "scheduler-config", "kube-system/topology-scheduler-config", "namespace/name of the SchedulerConfig object to follow, disabled when empty")
+    flag.DurationVar(&domainStatusInterval, "domain-status-interval", 30*time.Second, "Interval between DomainConfig status refreshes")
+    flag.DurationVar(&noExecuteInterval, "no-execute-interval", 10*time.Second, "Interval between checks for pods to move out of domains with NoExecute taints")
+    flag.DurationVar(&dcgmInterval, "dcgm-interval", 15*time.Second, "Interval between GPU health scrapes")
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "net"
+    "time"
+
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/extension"
+)
+
+// scoring-stub is a minimal external scorer for local testing. It prefers
+// candidates whose domain can hold the whole job with the least leftover
+// capacity, and can be told to sleep to exercise the scheduler's timeout.
+var (
+    listenAddr string
+    delay      = flag.Duration("delay", 0, "Artificial delay before answering")
+)
+
+type stubScorer struct{}
+
+func (stubScorer) Score(ctx context.Context, req *extension.ScoreRequest) (*extension.ScoreResponse, error) {
+    if *delay > 0 {
+        select {
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        case <-time.After(*delay):
+        }
+    }
+
+    resp := &extension.ScoreResponse{ModelVersion: "stub"}
+    for _, c := range req.Candidates {
+        free := c.DomainTotalGPUs - c.DomainUsedGPUs
+        score := 0.0
+        if free >= req.Pod.TotalGPUs && free > 0 {
+            score = 1 - float64(free-req.Pod.TotalGPUs)/float64(c.DomainTotalGPUs)
+        }
+        resp.Scores = append(resp.Scores, extension.CandidateScore{Node: c.Node, Score: score})
+    }
+    return resp, nil
+}
+
+func main() {
+    klog.InitFlags(nil)
+    flag.Parse()
+
+    lis, err := net.Listen("tcp", listenAddr)
+    if err != nil {
+        klog.Fatalf("Error listening on %s: %v", listenAddr, err)
+    }
+
+    server := extension.NewServer()
+    extension.RegisterScorerServer(server, stubScorer{})
+
+    klog.Infof("Stub scorer listening on %s", listenAddr)
+    klog.Fatal(server.Serve(lis))
+}
+
+func init() {
+    flag.StringVar(&listenAddr, "listen", ":9500", "Address to listen on")
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "os"
+    "time"
+
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/simulator"
+)
+
+// simulator replays a job trace through the scheduler on a synthetic
+// cluster and reports utilization, queueing and placement quality.
+var (
+    topologyFile   string
+    traceFile      string
+    traceFormat    string
+    maxJobs        int
+    exportTrace    string
+    reportFile     string
+    samplesFile    string
+    sampleInterval time.Duration
+    leafPenalty    float64
+    spinePenalty   float64
+    tuneWeights    bool
+    chaosFile      string
+    recoveryTarget time.Duration
+    topologyURL    string
+    whatIfFile     string
+    traceWindow    time.Duration
+)
+
+func main() {
+    klog.InitFlags(nil)
+    flag.Parse()
+
+    spec, err := loadTopology()
+    if err != nil {
+        klog.Fatalf("Error loading topology: %v", err)
+    }
+    jobs, err := simulator.ImportTrace(simulator.TraceFormat(traceFormat), traceFile)
+    if err != nil {
+        klog.Fatalf("Error loading trace: %v", err)
+    }
+    if maxJobs > 0 && len(jobs) > maxJobs {
+        jobs = jobs[:maxJobs]
+    }
+
+    // Save the converted trace so it can be edited or replayed directly
+    if exportTrace != "" {
+        f, err := os.Create(exportTrace)
+        if err != nil {
+            klog.Fatalf("Error creating trace file: %v", err)
+        }
+        if err := simulator.WriteTrace(f, jobs); err != nil {
+            klog.Fatalf("Error writing trace: %v", err)
+        }
+        f.Close()
+    }
+
+    config := simulator.DefaultConfig()
+    config.SampleInterval = sampleInterval
+    config.LeafPenalty = leafPenalty
+    config.SpinePenalty = spinePenalty
+
+    // Compare the current topology against a planned change
+    if whatIfFile != "" {
+        runWhatIf(spec, simulator.RecentJobs(jobs, traceWindow), config)
+        return
+    }
+
+    sim, err := simulator.New(spec, config)
+    if err != nil {
+        klog.Fatalf("Error creating simulator: %v", err)
+    }
+    if tuneWeights {
+        if err := sim.Scheduler().EnableTuner(algorithm.DefaultTunerConfig()); err != nil {
+            klog.Fatalf("Error enabling weight tuner: %v", err)
+        }
+    }
+
+    if chaosFile != "" {
+        script, err := simulator.LoadChaosScript(chaosFile)
+        if err != nil {
+            klog.Fatalf("Error loading chaos script: %v", err)
+        }
+        if err := sim.Inject(script.Events); err != nil {
+            klog.Fatalf("Invalid chaos script: %v", err)
+        }
+    }
+
+    klog.Infof("Replaying %d jobs on %d GPUs", len(jobs), spec.TotalGPUs())
+    report, err := sim.Run(context.Background(), jobs)
+    if err != nil {
+        klog.Fatalf("Simulation failed: %v", err)
+    }
+    report.WriteSummary(os.Stdout)
+
+    if reportFile != "" {
+        data, err := json.MarshalIndent(report, "", "  ")
+        if err != nil {
+            klog.Fatalf("Error encoding report: %v", err)
+        }
+        if err := os.WriteFile(reportFile, data, 0644); err != nil {
+            klog.Fatalf("Error writing report: %v", err)
+        }
+    }
+
+    if samplesFile != "" {
+        f, err := os.Create(samplesFile)
+        if err != nil {
+            klog.Fatalf("Error creating samples file: %v", err)
+        }
+        defer f.Close()
+        if err := report.WriteSamplesCSV(f); err != nil {
+            klog.Fatalf("Error writing samples: %v", err)
+        }
+    }
+
+    if violations := report.CheckRecovery(recoveryTarget); len(violations) > 0 {
+        for _, v := range violations {
+            klog.Errorf("Recovery target missed: %s", v)
+        }
+        os.Exit(1)
+    }
+}
+
+// loadTopology reads the topology from a running scheduler when a URL is
+// given and from the topology file otherwise
+func loadTopology() (*simulator.TopologySpec, error) {
+    if topologyURL != "" {
+        return simulator.FetchTopologySpec(topologyURL)
+    }
+    return simulator.LoadTopologySpec(topologyFile)
+}
+
+func runWhatIf(spec *simulator.TopologySpec, jobs []simulator.Job, config simulator.Config) {
+    delta, err := simulator.LoadTopologyDelta(whatIfFile)
+    if err != nil {
+        klog.Fatalf("Error loading topology delta: %v", err)
+    }
+
+    klog.Infof("Replaying %d jobs on the current and modified topology", len(jobs))
+    report, err := simulator.WhatIf(context.Background(), spec, delta, jobs, config)
+    if err != nil {
+        klog.Fatalf("What-if simulation failed: %v", err)
+    }
+    report.WriteSummary(os.Stdout)
+
+    if reportFile != "" {
+        data, err := json.MarshalIndent(report, "", "  ")
+        if err != nil {
+            klog.Fatalf("Error encoding report: %v", err)
+        }
+        if err := os.WriteFile(reportFile, data, 0644); err != nil {
+            klog.Fatalf("Error writing report: %v", err)
+        }
+    }
+}
+
+func init() {
+    flag.StringVar(&topologyFile, "topology", "topology.yaml", "YAML or JSON description of the simulated cluster")
+    flag.StringVar(&traceFile, "trace", "trace.csv", "Job arrival trace")
+    flag.StringVar(&traceFormat, "trace-format", "csv", "Format of the trace: csv, philly, pai or helios")
+    flag.IntVar(&maxJobs, "max-jobs", 0, "Replay only the first jobs of the trace, all when zero")
+    flag.StringVar(&exportTrace, "export-trace", "", "Write the trace in the simulator CSV format to this file")
+    flag.StringVar(&reportFile, "report", "", "Write the full JSON report to this file")
+    flag.StringVar(&samplesFile, "samples", "", "Write the utilization time series as CSV to this file")
+    flag.DurationVar(&sampleInterval, "sample-interval", time.Minute, "Simulated time between time series samples")
+    flag.Float64Var(&leafPenalty, "leaf-penalty", 0.02, "Slowdown per additional leaf a job spans")
+    flag.Float64Var(&spinePenalty, "spine-penalty", 0.1, "Slowdown per additional spine a job spans")
+    flag.BoolVar(&tuneWeights, "tune-weights", false, "Enable the online weight tuner during the replay")
+    flag.StringVar(&chaosFile, "chaos", "", "YAML or JSON script of failures to inject during the replay")
+    flag.DurationVar(&recoveryTarget, "recovery-target", 30*time.Second, "Fail when recovering from an injected failure takes longer")
+    flag.StringVar(&topologyURL, "topology-url", "", "Fetch the live topology from a scheduler, e.g. http://scheduler:8080/topology/snapshot")
+    flag.StringVar(&whatIfFile, "what-if", "", "YAML or JSON topology delta to compare against the current topology")
+    flag.DurationVar(&traceWindow, "trace-window", 0, "Replay only trace jobs that arrived within this window before the last arrival, all when zero")
+}
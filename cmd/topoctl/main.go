@@ -0,0 +1,175 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/discovery"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// topoctl checks topology definitions before they reach the scheduler.
+//
+//  topoctl validate cluster-topology.yaml
+//  topoctl validate --format=slurm topology.conf
+//  topoctl validate --server=http://scheduler:8080
+func main() {
+    klog.InitFlags(nil)
+    flag.Usage = usage
+    flag.Parse()
+
+    if flag.NArg() < 1 {
+        usage()
+        os.Exit(2)
+    }
+    switch flag.Arg(0) {
+    case "validate":
+        os.Exit(validate(flag.Args()[1:]))
+    default:
+        fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+        usage()
+        os.Exit(2)
+    }
+}
+
+func usage() {
+    fmt.Fprintln(os.Stderr, "Usage: topoctl validate [--format=topology|slurm] FILE")
+    fmt.Fprintln(os.Stderr, "       topoctl validate --server=URL")
+}
+
+// validate prints the validation report of a topology file or of the live
+// topology of a scheduler and returns 1 when it has errors
+func validate(args []string) int {
+    fs := flag.NewFlagSet("validate", flag.ExitOnError)
+    format := fs.String("format", "", "Format of the file: topology or slurm, from the extension when empty")
+    server := fs.String("server", "", "Validate the live topology of the scheduler at this URL")
+    fs.Parse(args)
+
+    var report *algorithm.ValidationReport
+    var err error
+    switch {
+    case *server != "":
+        report, err = fetchReport(*server)
+    case fs.NArg() == 1:
+        report, err = validateFile(fs.Arg(0), *format)
+    default:
+        usage()
+        return 2
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%v\n", err)
+        return 1
+    }
+
+    for _, issue := range report.Errors {
+        fmt.Println(issue)
+    }
+    for _, issue := range report.Warnings {
+        fmt.Println(issue)
+    }
+    fmt.Printf("%d errors, %d warnings\n", len(report.Errors), len(report.Warnings))
+    if !report.Valid() {
+        return 1
+    }
+    return 0
+}
+
+// validateFile loads the file into empty caches. Nodes are taken from the
+// file itself, so label checks are left to the scheduler.
+func validateFile(path, format string) (*algorithm.ValidationReport, error) {
+    if format == "" {
+        format = "topology"
+        if filepath.Ext(path) == ".conf" {
+            format = "slurm"
+        }
+    }
+
+    topologyCache := algorithm.NewTopologyCache(algorithm.NewNodeCache())
+    var discovered *discovery.DiscoveredTopology
+    var err error
+    switch format {
+    case "topology":
+        doc, loadErr := discovery.LoadTopologyFile(path)
+        if loadErr != nil {
+            return nil, loadErr
+        }
+        discovered, err = doc.Populate(topologyCache, doc.Nodes())
+    case "slurm":
+        topology, loadErr := discovery.LoadSlurmTopology(path)
+        if loadErr != nil {
+            return nil, loadErr
+        }
+        discovered, err = discovery.NewSlurmSource(topologyCache).Import(topology, slurmNodes(topology))
+    default:
+        return nil, fmt.Errorf("unknown format %q", format)
+    }
+
+    var invalid *algorithm.ValidationError
+    if errors.As(err, &invalid) {
+        return invalid.Report, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    report := topologyCache.Validate()
+    for _, conflict := range discovered.Conflicts {
+        report.Warnings = append(report.Warnings, algorithm.TopologyIssue{
+            Severity: algorithm.SeverityWarning,
+            Check:    string(conflict.Kind),
+            Node:     conflict.Node,
+            Message:  conflict.String(),
+        })
+    }
+    return report, nil
+}
+
+// slurmNodes makes a ready node for every node the switches list
+func slurmNodes(topology *discovery.SlurmTopology) []*v1.Node {
+    seen := make(map[string]bool)
+    var nodes []*v1.Node
+    for _, sw := range topology.Switches {
+        for _, name := range sw.Nodes {
+            if seen[name] {
+                continue
+            }
+            seen[name] = true
+            nodes = append(nodes, &v1.Node{
+                ObjectMeta: metav1.ObjectMeta{Name: name},
+                Status: v1.NodeStatus{
+                    Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+                },
+            })
+        }
+    }
+    return nodes
+}
+
+func fetchReport(server string) (*algorithm.ValidationReport, error) {
+    client := &http.Client{Timeout: 10 * time.Second}
+    resp, err := client.Get(strings.TrimSuffix(server, "/") + "/topology/validate")
+    if err != nil {
+        return nil, fmt.Errorf("failed to fetch validation report: %v", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("failed to fetch validation report: %s", resp.Status)
+    }
+    report := &algorithm.ValidationReport{}
+    if err := json.NewDecoder(resp.Body).Decode(report); err != nil {
+        return nil, fmt.Errorf("invalid validation report: %v", err)
+    }
+    return report, nil
+}
@@ -3,7 +3,7 @@
 // +k8s:conversion-gen=false
 // +k8s:openapi-gen=true
 // +k8s:generator-name=client
-// +groupName=topology.scheduler.k8s.io
+// +groupName=topology.scheduler
 
 // Package v1alpha1 contains the v1alpha1 version of the topology scheduler API.
 package v1alpha1
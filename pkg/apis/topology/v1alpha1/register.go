@@ -7,7 +7,7 @@ import (
 )
 
 const (
-    GroupName = "topology.scheduler.k8s.io"
+    GroupName = "topology.scheduler"
     Version   = "v1alpha1"
 )
 
@@ -39,6 +39,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
         SchemeGroupVersion,
         &TopologyScheduler{},
         &TopologySchedulerList{},
+        &DomainConfig{},
+        &DomainConfigList{},
+        &SchedulerConfig{},
+        &SchedulerConfigList{},
     )
 
     metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
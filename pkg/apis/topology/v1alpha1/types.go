@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+    corev1 "k8s.io/api/core/v1"
     metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -41,3 +42,152 @@ type TopologySchedulerList struct {
     metav1.ListMeta `json:"metadata"`
     Items []TopologyScheduler `json:"items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+
+// DomainConfig declares one domain of the network topology. The scheduler
+// adds it to its topology and reports the domain's capacity in the status.
+type DomainConfig struct {
+    metav1.TypeMeta   `json:",inline"`
+    metav1.ObjectMeta `json:"metadata,omitempty"`
+    Spec   DomainConfigSpec   `json:"spec"`
+    Status DomainConfigStatus `json:"status,omitempty"`
+}
+
+// DomainConfigSpec is the spec for a DomainConfig resource
+type DomainConfigSpec struct {
+    // Level is leaf or spine
+    Level string `json:"level"`
+    // Parent names the spine DomainConfig above a leaf
+    Parent string `json:"parent,omitempty"`
+    // NodeSelector selects the nodes of a leaf
+    NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+    // BandwidthGbps is the bandwidth of the leaf's uplink to its parent
+    BandwidthGbps int32 `json:"bandwidthGbps,omitempty"`
+    // Latency is the latency of the leaf's uplink to its parent
+    Latency *metav1.Duration `json:"latency,omitempty"`
+    // Limits caps what jobs may use of the domain
+    Limits DomainLimits `json:"limits,omitempty"`
+    // Taints keep pods without a matching domain toleration out of the
+    // domain and the leaves below it. NoExecute also moves running pods.
+    Taints []corev1.Taint `json:"taints,omitempty"`
+}
+
+// DomainLimits caps the capacity of a domain
+type DomainLimits struct {
+    // MaxGPUs caps the GPUs that can be placed in a leaf, 0 for no cap
+    MaxGPUs int32 `json:"maxGPUs,omitempty"`
+}
+
+const (
+    // DomainConfigReady is true once the domain is part of the topology
+    DomainConfigReady = "Ready"
+
+    DomainHealthy   = "Healthy"
+    DomainDegraded  = "Degraded"
+    DomainUnhealthy = "Unhealthy"
+)
+
+// DomainConfigStatus is the status for a DomainConfig resource. The
+// counts of a spine add up the leaves below it.
+type DomainConfigStatus struct {
+    ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+    Nodes              int32 `json:"nodes"`
+    GPUsTotal          int32 `json:"gpusTotal"`
+    GPUsUsed           int32 `json:"gpusUsed"`
+    // Health is Healthy when all GPUs are healthy, Unhealthy when none
+    // are and Degraded otherwise
+    Health     string             `json:"health,omitempty"`
+    Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DomainConfigList is a list of DomainConfig resources
+type DomainConfigList struct {
+    metav1.TypeMeta `json:",inline"`
+    metav1.ListMeta `json:"metadata"`
+    Items []DomainConfig `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Namespaced
+// +kubebuilder:subresource:status
+
+// SchedulerConfig holds the settings the running scheduler picks up
+// without a restart
+type SchedulerConfig struct {
+    metav1.TypeMeta   `json:",inline"`
+    metav1.ObjectMeta `json:"metadata,omitempty"`
+    Spec   SchedulerConfigSpec   `json:"spec"`
+    Status SchedulerConfigStatus `json:"status,omitempty"`
+}
+
+// SchedulerConfigSpec is the spec for a SchedulerConfig resource. Unset
+// parts take the values of the scheduler's configuration file.
+type SchedulerConfigSpec struct {
+    ScoringWeights      *ScoringWeights      `json:"scoringWeights,omitempty"`
+    TopologyConstraints *TopologyConstraints `json:"topologyConstraints,omitempty"`
+    // CommProfiles scales the scoring weights for the jobs of each
+    // communication profile, keyed by profile name
+    CommProfiles map[string]CommProfileWeights `json:"commProfiles,omitempty"`
+}
+
+// ScoringWeights weighs the parts of the domain score. They are normalized
+// to sum to 1.
+type ScoringWeights struct {
+    ResourceAvailability  float64 `json:"resourceAvailability"`
+    TopologyAlignment     float64 `json:"topologyAlignment"`
+    DomainUtilization     float64 `json:"domainUtilization"`
+    HistoricalPerformance float64 `json:"historicalPerformance"`
+}
+
+// CommProfileWeights multiplies the scoring weights for the jobs of one
+// communication profile. Unset factors keep the built-in ones.
+type CommProfileWeights struct {
+    ResourceAvailability  *float64 `json:"resourceAvailability,omitempty"`
+    TopologyAlignment     *float64 `json:"topologyAlignment,omitempty"`
+    DomainUtilization     *float64 `json:"domainUtilization,omitempty"`
+    HistoricalPerformance *float64 `json:"historicalPerformance,omitempty"`
+}
+
+// TopologyConstraints caps the share of a leaf one job can take. Tenant
+// caps all jobs of one namespace together. Zero means no cap.
+type TopologyConstraints struct {
+    MaxNodesPerLeaf int32       `json:"maxNodesPerLeaf,omitempty"`
+    MaxGPUsPerLeaf  int32       `json:"maxGPUsPerLeaf,omitempty"`
+    Tenant          *LeafLimits `json:"tenant,omitempty"`
+}
+
+// LeafLimits bounds the nodes and GPUs held in a single leaf
+type LeafLimits struct {
+    MaxNodesPerLeaf int32 `json:"maxNodesPerLeaf,omitempty"`
+    MaxGPUsPerLeaf  int32 `json:"maxGPUsPerLeaf,omitempty"`
+}
+
+const (
+    // SchedulerConfigAccepted is true when the scheduler runs with the spec
+    SchedulerConfigAccepted = "Accepted"
+    // SchedulerConfigInvalid is true when the spec was rejected
+    SchedulerConfigInvalid = "Invalid"
+)
+
+// SchedulerConfigStatus is the status for a SchedulerConfig resource
+type SchedulerConfigStatus struct {
+    ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+    Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SchedulerConfigList is a list of SchedulerConfig resources
+type SchedulerConfigList struct {
+    metav1.TypeMeta `json:",inline"`
+    metav1.ListMeta `json:"metadata"`
+    Items []SchedulerConfig `json:"items"`
+}
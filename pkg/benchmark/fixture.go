@@ -0,0 +1,138 @@
+package benchmark
+
+import (
+    "context"
+    "fmt"
+
+    "k8s.io/kubernetes/pkg/scheduler/framework"
+    frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+    topologyplugin "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/plugins/topology"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/simulator"
+)
+
+// FixtureConfig sizes a synthetic leaf/spine cluster
+type FixtureConfig struct {
+    Spines         int
+    LeavesPerSpine int
+    NodesPerLeaf   int
+    GPUsPerNode    int
+}
+
+// DefaultFixtureConfig is a 1024 node cluster, the size the design targets
+func DefaultFixtureConfig() FixtureConfig {
+    return FixtureConfig{
+        Spines:         4,
+        LeavesPerSpine: 8,
+        NodesPerLeaf:   32,
+        GPUsPerNode:    8,
+    }
+}
+
+func (c FixtureConfig) Nodes() int {
+    return c.Spines * c.LeavesPerSpine * c.NodesPerLeaf
+}
+
+// Spec returns the topology description of the fixture
+func (c FixtureConfig) Spec() *simulator.TopologySpec {
+    spec := &simulator.TopologySpec{}
+    for s := 0; s < c.Spines; s++ {
+        spine := simulator.SpineSpec{Name: fmt.Sprintf("spine-%d", s)}
+        for l := 0; l < c.LeavesPerSpine; l++ {
+            spine.Leaves = append(spine.Leaves, simulator.LeafSpec{
+                Name:        fmt.Sprintf("spine-%d-leaf-%d", s, l),
+                Nodes:       c.NodesPerLeaf,
+                GPUsPerNode: c.GPUsPerNode,
+            })
+        }
+        spec.Spines = append(spec.Spines, spine)
+    }
+    return spec
+}
+
+// Fixture is a populated topology with a scheduler and plugin on top
+type Fixture struct {
+    Config    FixtureConfig
+    Cache     *algorithm.TopologyCache
+    NodeCache *algorithm.NodeCache
+    Scheduler *algorithm.TopologyScheduler
+    Plugin    *topologyplugin.TopologySchedulerPlugin
+    Nodes     []*framework.NodeInfo
+}
+
+func NewFixture(config FixtureConfig) (*Fixture, error) {
+    spec := config.Spec()
+    if err := spec.Validate(); err != nil {
+        return nil, fmt.Errorf("invalid fixture: %v", err)
+    }
+
+    cache, nodeCache, err := simulator.BuildCache(spec)
+    if err != nil {
+        return nil, err
+    }
+
+    lister := &nodeLister{byName: make(map[string]*framework.NodeInfo)}
+    for _, node := range nodeCache.GetAllNodes() {
+        info := framework.NewNodeInfo()
+        info.SetNode(node)
+        lister.nodes = append(lister.nodes, info)
+        lister.byName[node.Name] = info
+    }
+
+    handle, err := frameworkruntime.NewFramework(context.Background(), nil, nil,
+        frameworkruntime.WithSnapshotSharedLister(lister))
+    if err != nil {
+        return nil, fmt.Errorf("failed to create framework: %v", err)
+    }
+
+    scheduler := algorithm.NewTopologyScheduler(cache)
+    return &Fixture{
+        Config:    config,
+        Cache:     cache,
+        NodeCache: nodeCache,
+        Scheduler: scheduler,
+        Plugin:    topologyplugin.NewWithScheduler(handle, scheduler),
+        Nodes:     lister.nodes,
+    }, nil
+}
+
+// nodeLister serves the fixture nodes as the scheduler snapshot
+type nodeLister struct {
+    nodes  []*framework.NodeInfo
+    byName map[string]*framework.NodeInfo
+}
+
+func (l *nodeLister) NodeInfos() framework.NodeInfoLister {
+    return l
+}
+
+func (l *nodeLister) StorageInfos() framework.StorageInfoLister {
+    return l
+}
+
+func (l *nodeLister) List() ([]*framework.NodeInfo, error) {
+    return l.nodes, nil
+}
+
+func (l *nodeLister) HavePodsWithAffinityList() ([]*framework.NodeInfo, error) {
+    return nil, nil
+}
+
+func (l *nodeLister) HavePodsWithRequiredAntiAffinityList() ([]*framework.NodeInfo, error) {
+    return nil, nil
+}
+
+func (l *nodeLister) Get(nodeName string) (*framework.NodeInfo, error) {
+    info, ok := l.byName[nodeName]
+    if !ok {
+        return nil, fmt.Errorf("node %s not found", nodeName)
+    }
+    return info, nil
+}
+
+func (l *nodeLister) IsPVCUsedByPods(key string) bool {
+    return false
+}
+
+var _ framework.SharedLister = &nodeLister{}
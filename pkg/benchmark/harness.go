@@ -0,0 +1,296 @@
+package benchmark
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "math/rand"
+    "runtime"
+    "sort"
+    "sync"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/apimachinery/pkg/api/resource"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/kubernetes/pkg/scheduler/framework"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// Operation is the scheduler entry point being measured
+type Operation string
+
+const (
+    OpSchedule Operation = "schedule"
+    OpFilter   Operation = "filter"
+    OpScore    Operation = "score"
+)
+
+// Workload is a pod shape that exercises one placement strategy
+type Workload struct {
+    Strategy string
+    // Nodes is the number of full nodes the pod asks for
+    Nodes int
+}
+
+// DefaultWorkloads hit each strategy under the default thresholds
+func DefaultWorkloads() []Workload {
+    return []Workload{
+        {Strategy: "single-domain", Nodes: 1},
+        {Strategy: "complete-domain", Nodes: 4},
+        {Strategy: "adjacent-domains", Nodes: 6},
+        {Strategy: "multiple-domains", Nodes: 12},
+    }
+}
+
+// Config controls a benchmark run
+type Config struct {
+    Fixture    FixtureConfig
+    Workloads  []Workload
+    Operations []Operation
+    // Concurrency is the number of goroutines calling the scheduler at once
+    Concurrency int
+    // Iterations is the number of calls each goroutine makes per case
+    Iterations int
+}
+
+func DefaultConfig() Config {
+    return Config{
+        Fixture:     DefaultFixtureConfig(),
+        Workloads:   DefaultWorkloads(),
+        Operations:  []Operation{OpSchedule, OpFilter, OpScore},
+        Concurrency: 16,
+        Iterations:  200,
+    }
+}
+
+// Result is the measurement for one operation and strategy
+type Result struct {
+    Operation   Operation     `json:"operation"`
+    Strategy    string        `json:"strategy"`
+    Ops         int           `json:"ops"`
+    Errors      int           `json:"errors"`
+    Throughput  float64       `json:"throughputPerSecond"`
+    P50         time.Duration `json:"p50"`
+    P99         time.Duration `json:"p99"`
+    Max         time.Duration `json:"max"`
+    AllocsPerOp float64       `json:"allocsPerOp"`
+    BytesPerOp  float64       `json:"bytesPerOp"`
+}
+
+// Targets are the limits a run must stay within
+type Targets struct {
+    // MaxP99 bounds the p99 latency of every operation
+    MaxP99 time.Duration
+    // MinScheduleThroughput is the minimum Schedule calls per second
+    MinScheduleThroughput float64
+}
+
+// DesignTargets are the figures promised by the design: sub-500ms
+// decisions and 100 decisions per second
+func DesignTargets() Targets {
+    return Targets{
+        MaxP99:                500 * time.Millisecond,
+        MinScheduleThroughput: 100,
+    }
+}
+
+// Run builds the fixture and measures every operation and workload
+func Run(ctx context.Context, config Config) ([]Result, error) {
+    if config.Concurrency <= 0 || config.Iterations <= 0 {
+        return nil, fmt.Errorf("concurrency and iterations must be positive")
+    }
+
+    fixture, err := NewFixture(config.Fixture)
+    if err != nil {
+        return nil, err
+    }
+
+    var results []Result
+    for _, op := range config.Operations {
+        for _, workload := range config.Workloads {
+            if err := ctx.Err(); err != nil {
+                return nil, err
+            }
+            result, err := runCase(ctx, fixture, config, op, workload)
+            if err != nil {
+                return nil, err
+            }
+            results = append(results, result)
+        }
+    }
+    return results, nil
+}
+
+// runCase calls one operation from Concurrency goroutines. Scheduled pods
+// are released straight away so the cluster stays at the same fill level.
+func runCase(ctx context.Context, fixture *Fixture, config Config, op Operation, workload Workload) (Result, error) {
+    var call func(rng *rand.Rand, pod *v1.Pod) error
+    switch op {
+    case OpSchedule:
+        call = func(rng *rand.Rand, pod *v1.Pod) error {
+            node, err := fixture.Scheduler.Schedule(ctx, pod)
+            if err != nil {
+                return err
+            }
+            return fixture.release(pod, node)
+        }
+    case OpFilter:
+        call = func(rng *rand.Rand, pod *v1.Pod) error {
+            info := fixture.Nodes[rng.Intn(len(fixture.Nodes))]
+            // Rejecting the node is a valid outcome, only count failures
+            if status := fixture.Plugin.Filter(ctx, framework.NewCycleState(), pod, info); status.Code() == framework.Error {
+                return status.AsError()
+            }
+            return nil
+        }
+    case OpScore:
+        call = func(rng *rand.Rand, pod *v1.Pod) error {
+            info := fixture.Nodes[rng.Intn(len(fixture.Nodes))]
+            _, status := fixture.Plugin.Score(ctx, framework.NewCycleState(), pod, info.Node().Name)
+            return status.AsError()
+        }
+    default:
+        return Result{}, fmt.Errorf("unknown operation %q", op)
+    }
+
+    latencies := make([][]time.Duration, config.Concurrency)
+    errors := make([]int, config.Concurrency)
+
+    var before, after runtime.MemStats
+    runtime.GC()
+    runtime.ReadMemStats(&before)
+    start := time.Now()
+
+    var wg sync.WaitGroup
+    for w := 0; w < config.Concurrency; w++ {
+        wg.Add(1)
+        go func(worker int) {
+            defer wg.Done()
+            rng := rand.New(rand.NewSource(int64(worker)))
+            latencies[worker] = make([]time.Duration, 0, config.Iterations)
+            for i := 0; i < config.Iterations; i++ {
+                pod := newPod(fmt.Sprintf("bench-%s-%d-%d", workload.Strategy, worker, i),
+                    workload.Nodes*config.Fixture.GPUsPerNode)
+                callStart := time.Now()
+                if err := call(rng, pod); err != nil {
+                    errors[worker]++
+                }
+                latencies[worker] = append(latencies[worker], time.Since(callStart))
+            }
+        }(w)
+    }
+    wg.Wait()
+
+    elapsed := time.Since(start)
+    runtime.ReadMemStats(&after)
+
+    var all []time.Duration
+    result := Result{Operation: op, Strategy: workload.Strategy}
+    for w := range latencies {
+        all = append(all, latencies[w]...)
+        result.Errors += errors[w]
+    }
+    sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+    result.Ops = len(all)
+    result.Throughput = float64(result.Ops) / elapsed.Seconds()
+    result.P50 = percentile(all, 0.50)
+    result.P99 = percentile(all, 0.99)
+    result.Max = all[len(all)-1]
+    result.AllocsPerOp = float64(after.Mallocs-before.Mallocs) / float64(result.Ops)
+    result.BytesPerOp = float64(after.TotalAlloc-before.TotalAlloc) / float64(result.Ops)
+    return result, nil
+}
+
+// release frees every node the pod was placed on. Schedule returns only
+// the first of them.
+func (f *Fixture) release(pod *v1.Pod, first *v1.Node) error {
+    names := []string{first.Name}
+    for name := range f.Cache.GetJobNodes(pod.Namespace + "/" + pod.Labels[algorithm.JobLabel]) {
+        if name != first.Name {
+            names = append(names, name)
+        }
+    }
+
+    var firstErr error
+    for _, name := range names {
+        node, err := f.NodeCache.GetNode(name)
+        if err == nil {
+            err = f.Scheduler.Release(pod, node)
+        }
+        if err != nil && firstErr == nil {
+            firstErr = err
+        }
+    }
+    return firstErr
+}
+
+// Check returns the results that miss the targets. Any failed call is a
+// miss, since latency and throughput only mean something for calls that
+// succeeded.
+func Check(results []Result, targets Targets) []string {
+    var violations []string
+    for _, r := range results {
+        if r.Errors > 0 {
+            violations = append(violations, fmt.Sprintf("%s/%s: %d of %d calls failed",
+                r.Operation, r.Strategy, r.Errors, r.Ops))
+        }
+        if targets.MaxP99 > 0 && r.P99 > targets.MaxP99 {
+            violations = append(violations, fmt.Sprintf("%s/%s: p99 %v exceeds %v",
+                r.Operation, r.Strategy, r.P99, targets.MaxP99))
+        }
+        if r.Operation == OpSchedule && r.Throughput < targets.MinScheduleThroughput {
+            violations = append(violations, fmt.Sprintf("%s/%s: %.0f decisions/s below %.0f",
+                r.Operation, r.Strategy, r.Throughput, targets.MinScheduleThroughput))
+        }
+    }
+    return violations
+}
+
+// WriteTable prints the results as an aligned table
+func WriteTable(w io.Writer, results []Result) {
+    fmt.Fprintf(w, "%-10s %-18s %8s %7s %10s %12s %12s %12s %10s %12s\n",
+        "OPERATION", "STRATEGY", "OPS", "ERRORS", "OPS/S", "P50", "P99", "MAX", "ALLOCS/OP", "BYTES/OP")
+    for _, r := range results {
+        fmt.Fprintf(w, "%-10s %-18s %8d %7d %10.0f %12v %12v %12v %10.0f %12.0f\n",
+            r.Operation, r.Strategy, r.Ops, r.Errors, r.Throughput, r.P50, r.P99, r.Max, r.AllocsPerOp, r.BytesPerOp)
+    }
+}
+
+func newPod(name string, gpus int) *v1.Pod {
+    return &v1.Pod{
+        ObjectMeta: metav1.ObjectMeta{
+            Name:      name,
+            Namespace: "benchmark",
+            Labels: map[string]string{
+                algorithm.JobLabel: name,
+            },
+        },
+        Spec: v1.PodSpec{
+            Containers: []v1.Container{{
+                Name: "worker",
+                Resources: v1.ResourceRequirements{
+                    Limits: v1.ResourceList{
+                        "nvidia.com/gpu": *resource.NewQuantity(int64(gpus), resource.DecimalSI),
+                    },
+                },
+            }},
+        },
+    }
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+    if len(sorted) == 0 {
+        return 0
+    }
+    rank := int(p*float64(len(sorted))+0.5) - 1
+    if rank < 0 {
+        rank = 0
+    }
+    if rank >= len(sorted) {
+        rank = len(sorted) - 1
+    }
+    return sorted[rank]
+}
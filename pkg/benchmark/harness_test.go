@@ -0,0 +1,74 @@
+package benchmark
+
+import (
+    "context"
+    "strings"
+    "testing"
+)
+
+func TestRunSmallFixture(t *testing.T) {
+    config := Config{
+        Fixture: FixtureConfig{
+            Spines:         2,
+            LeavesPerSpine: 2,
+            NodesPerLeaf:   16,
+            GPUsPerNode:    8,
+        },
+        Workloads:   DefaultWorkloads(),
+        Operations:  []Operation{OpSchedule, OpFilter, OpScore},
+        Concurrency: 4,
+        Iterations:  10,
+    }
+    results, err := Run(context.Background(), config)
+    if err != nil {
+        t.Fatalf("Run() error = %v", err)
+    }
+    if want := len(config.Operations) * len(config.Workloads); len(results) != want {
+        t.Fatalf("got %d results, want %d", len(results), want)
+    }
+    for _, r := range results {
+        if r.Ops != config.Concurrency*config.Iterations {
+            t.Errorf("%s/%s: %d ops, want %d", r.Operation, r.Strategy, r.Ops, config.Concurrency*config.Iterations)
+        }
+    }
+    if violations := Check(results, Targets{}); len(violations) > 0 {
+        t.Errorf("Check() = %v, want no failed calls", violations)
+    }
+}
+
+// TestScheduleReleasesEveryNode checks that a pod spanning several leaves
+// gives back all of its nodes, so repeated calls see the same cluster
+func TestScheduleReleasesEveryNode(t *testing.T) {
+    fixture, err := NewFixture(FixtureConfig{Spines: 1, LeavesPerSpine: 2, NodesPerLeaf: 4, GPUsPerNode: 8})
+    if err != nil {
+        t.Fatal(err)
+    }
+    ctx := context.Background()
+    for i := 0; i < 3; i++ {
+        pod := newPod("bench-span", 6*fixture.Config.GPUsPerNode)
+        node, err := fixture.Scheduler.Schedule(ctx, pod)
+        if err != nil {
+            t.Fatalf("Schedule() call %d error = %v", i, err)
+        }
+        if nodes := fixture.Cache.GetJobNodes("benchmark/bench-span"); len(nodes) != 6 {
+            t.Fatalf("job holds %d nodes, want 6", len(nodes))
+        }
+        if err := fixture.release(pod, node); err != nil {
+            t.Fatalf("release() error = %v", err)
+        }
+        if nodes := fixture.Cache.GetJobNodes("benchmark/bench-span"); len(nodes) != 0 {
+            t.Fatalf("job still holds %v after release", nodes)
+        }
+    }
+}
+
+func TestCheckCountsFailedCalls(t *testing.T) {
+    results := []Result{
+        {Operation: OpSchedule, Strategy: "single-domain", Ops: 10, Throughput: 500},
+        {Operation: OpFilter, Strategy: "single-domain", Ops: 10, Errors: 2},
+    }
+    violations := Check(results, DesignTargets())
+    if len(violations) != 1 || !strings.Contains(violations[0], "filter/single-domain: 2 of 10 calls failed") {
+        t.Errorf("Check() = %v, want the failed filter calls", violations)
+    }
+}
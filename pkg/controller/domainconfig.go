@@ -0,0 +1,233 @@
+package controller
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "reflect"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/apimachinery/pkg/api/equality"
+    "k8s.io/apimachinery/pkg/api/meta"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/labels"
+    utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+    "k8s.io/apimachinery/pkg/util/wait"
+    coreinformers "k8s.io/client-go/informers/core/v1"
+    corelisters "k8s.io/client-go/listers/core/v1"
+    "k8s.io/client-go/tools/cache"
+    "k8s.io/client-go/util/workqueue"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/apis/topology/v1alpha1"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/discovery"
+    clientset "github.com/yourusername/topology-aware-gpu-scheduler/pkg/generated/clientset/versioned"
+    informers "github.com/yourusername/topology-aware-gpu-scheduler/pkg/generated/informers/externalversions/topology/v1alpha1"
+    listers "github.com/yourusername/topology-aware-gpu-scheduler/pkg/generated/listers/topology/v1alpha1"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// topologyKey is the only queue key. Links between leaves depend on all
+// DomainConfigs, so every change syncs the whole set.
+const topologyKey = "topology"
+
+// Controller reconciles DomainConfig objects into the scheduler's topology
+// and reports the node count, GPUs and health of each domain in its status
+type Controller struct {
+    topologyClient clientset.Interface
+    cache          *algorithm.TopologyCache
+    source         *discovery.DomainConfigSource
+    statusInterval time.Duration
+    domainLister   listers.DomainConfigLister
+    nodeLister     corelisters.NodeLister
+    synced         []cache.InformerSynced
+    queue          workqueue.RateLimitingInterface
+}
+
+// NewController creates a controller for the DomainConfigs. The status is
+// also refreshed every statusInterval, as GPU use changes with scheduling.
+func NewController(
+    topologyClient clientset.Interface,
+    domainInformer informers.DomainConfigInformer,
+    nodeInformer coreinformers.NodeInformer,
+    topologyCache *algorithm.TopologyCache,
+    statusInterval time.Duration,
+) *Controller {
+    c := &Controller{
+        topologyClient: topologyClient,
+        cache:          topologyCache,
+        source:         discovery.NewDomainConfigSource(topologyCache),
+        statusInterval: statusInterval,
+        domainLister:   domainInformer.Lister(),
+        nodeLister:     nodeInformer.Lister(),
+        synced:         []cache.InformerSynced{domainInformer.Informer().HasSynced, nodeInformer.Informer().HasSynced},
+        queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "DomainConfigs"),
+    }
+
+    domainInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+        AddFunc: c.enqueue,
+        UpdateFunc: func(old, new interface{}) {
+            if old.(*v1alpha1.DomainConfig).Generation != new.(*v1alpha1.DomainConfig).Generation {
+                c.enqueue(new)
+            }
+        },
+        DeleteFunc: c.enqueue,
+    })
+    nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+        AddFunc: c.enqueue,
+        UpdateFunc: func(old, new interface{}) {
+            if !reflect.DeepEqual(old.(*v1.Node).Labels, new.(*v1.Node).Labels) {
+                c.enqueue(new)
+            }
+        },
+        DeleteFunc: c.enqueue,
+    })
+    return c
+}
+
+// SetLabelSchema enables the warning for selected nodes whose labels name
+// another leaf
+func (c *Controller) SetLabelSchema(schema *algorithm.NodeLabelSchema) {
+    c.source.SetLabelSchema(schema)
+}
+
+// Run syncs the DomainConfigs until stopCh is closed. A single worker is
+// enough as every sync covers all objects.
+func (c *Controller) Run(stopCh <-chan struct{}) error {
+    defer utilruntime.HandleCrash()
+    defer c.queue.ShutDown()
+
+    klog.Info("Starting DomainConfig controller")
+    if !cache.WaitForCacheSync(stopCh, c.synced...) {
+        return fmt.Errorf("failed to wait for caches to sync")
+    }
+
+    go wait.Until(c.runWorker, time.Second, stopCh)
+    go wait.Until(func() { c.queue.Add(topologyKey) }, c.statusInterval, stopCh)
+
+    <-stopCh
+    klog.Info("Shutting down DomainConfig controller")
+    return nil
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+    c.queue.Add(topologyKey)
+}
+
+func (c *Controller) runWorker() {
+    for c.processNextItem() {
+    }
+}
+
+func (c *Controller) processNextItem() bool {
+    key, quit := c.queue.Get()
+    if quit {
+        return false
+    }
+    defer c.queue.Done(key)
+
+    if err := c.reconcile(); err != nil {
+        utilruntime.HandleError(fmt.Errorf("failed to sync DomainConfigs: %v", err))
+        c.queue.AddRateLimited(key)
+        return true
+    }
+    c.queue.Forget(key)
+    return true
+}
+
+// reconcile applies all DomainConfigs to the topology and updates their
+// status. A set that would leave the topology invalid is not applied and
+// every object reports why; it is retried when an object changes.
+func (c *Controller) reconcile() error {
+    configs, err := c.domainLister.List(labels.Everything())
+    if err != nil {
+        return err
+    }
+    nodes, err := c.nodeLister.List(labels.Everything())
+    if err != nil {
+        return err
+    }
+
+    _, invalid, syncErr := c.source.Sync(configs, nodes)
+    var rejected *algorithm.ValidationError
+    if syncErr != nil && !errors.As(syncErr, &rejected) {
+        return syncErr
+    }
+    if rejected != nil {
+        klog.Warningf("DomainConfigs not applied: %v", rejected)
+    }
+    for _, config := range configs {
+        if invalid[config.Name] != nil {
+            continue
+        }
+        if _, err := c.cache.GetDomain(config.Name); err != nil {
+            continue
+        }
+        if err := c.cache.SetDomainTaints(config.Name, config.Spec.Taints); err != nil {
+            return err
+        }
+    }
+
+    var updateErr error
+    for _, config := range configs {
+        status := c.status(config, invalid[config.Name], rejected)
+        if equality.Semantic.DeepEqual(config.Status, status) {
+            continue
+        }
+        updated := config.DeepCopy()
+        updated.Status = status
+        if _, err := c.topologyClient.TopologyV1alpha1().DomainConfigs().UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+            updateErr = err
+        }
+    }
+    return updateErr
+}
+
+// status reports the domain's condition and its capacity in the topology
+func (c *Controller) status(config *v1alpha1.DomainConfig, invalid error, rejected *algorithm.ValidationError) v1alpha1.DomainConfigStatus {
+    status := v1alpha1.DomainConfigStatus{ObservedGeneration: config.Generation}
+    status.Conditions = append(status.Conditions, config.Status.Conditions...)
+    ready := metav1.Condition{
+        Type:               v1alpha1.DomainConfigReady,
+        Status:             metav1.ConditionTrue,
+        Reason:             "Applied",
+        Message:            "Domain is part of the scheduler topology",
+        ObservedGeneration: config.Generation,
+    }
+    switch {
+    case invalid != nil:
+        ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "Invalid", invalid.Error()
+    case rejected != nil:
+        ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "TopologyRejected", rejected.Error()
+    }
+    meta.SetStatusCondition(&status.Conditions, ready)
+
+    // A spine adds up the leaves below it
+    var domains []*algorithm.Domain
+    for _, domain := range c.cache.GetAllDomains() {
+        if domain.Name == config.Name || (config.Spec.Level == string(algorithm.LevelSpine) && domain.Parent == config.Name) {
+            domains = append(domains, domain)
+        }
+    }
+    var health float64
+    for _, domain := range domains {
+        status.Nodes += int32(len(domain.Nodes))
+        status.GPUsTotal += int32(domain.TotalGPUs)
+        status.GPUsUsed += int32(domain.UsedGPUs)
+        if h, err := c.cache.GetDomainHealth(domain.Name); err == nil {
+            health += h * float64(len(domain.Nodes))
+        }
+    }
+    if status.Nodes > 0 {
+        switch health /= float64(status.Nodes); {
+        case health >= 1:
+            status.Health = v1alpha1.DomainHealthy
+        case health <= 0:
+            status.Health = v1alpha1.DomainUnhealthy
+        default:
+            status.Health = v1alpha1.DomainDegraded
+        }
+    }
+    return status
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/apimachinery/pkg/api/meta"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/apis/topology/v1alpha1"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+func domainNode(name string, ready bool) *v1.Node {
+    status := v1.ConditionFalse
+    if ready {
+        status = v1.ConditionTrue
+    }
+    return &v1.Node{
+        ObjectMeta: metav1.ObjectMeta{Name: name},
+        Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: status}}},
+    }
+}
+
+// newDomainConfigTestController builds spine-0 with leaf-0, whose two nodes
+// are ready, and leaf-1, where one of two is. leaf-2 under spine-1 has a
+// single node, which is not ready.
+func newDomainConfigTestController(t *testing.T) *Controller {
+    t.Helper()
+    tc := algorithm.NewTopologyCache(algorithm.NewNodeCache())
+    domains := []*algorithm.Domain{
+        {Name: "spine-0", Level: algorithm.LevelSpine},
+        {Name: "spine-1", Level: algorithm.LevelSpine},
+        {
+            Name:      "leaf-0",
+            Level:     algorithm.LevelLeaf,
+            Parent:    "spine-0",
+            Nodes:     []*v1.Node{domainNode("gpu-001", true), domainNode("gpu-002", true)},
+            TotalGPUs: 16,
+            UsedGPUs:  4,
+        },
+        {
+            Name:      "leaf-1",
+            Level:     algorithm.LevelLeaf,
+            Parent:    "spine-0",
+            Nodes:     []*v1.Node{domainNode("gpu-003", true), domainNode("gpu-004", false)},
+            TotalGPUs: 8,
+            UsedGPUs:  8,
+        },
+        {
+            Name:      "leaf-2",
+            Level:     algorithm.LevelLeaf,
+            Parent:    "spine-1",
+            Nodes:     []*v1.Node{domainNode("gpu-005", false)},
+            TotalGPUs: 8,
+        },
+    }
+    for _, domain := range domains {
+        domain.Jobs = make(map[string]*algorithm.PlacedJob)
+        if err := tc.AddDomain(domain); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return &Controller{cache: tc}
+}
+
+func domainConfig(name string, level algorithm.TopologyLevel) *v1alpha1.DomainConfig {
+    return &v1alpha1.DomainConfig{
+        ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 3},
+        Spec:       v1alpha1.DomainConfigSpec{Level: string(level)},
+    }
+}
+
+func TestDomainConfigStatus(t *testing.T) {
+    rejected := &algorithm.ValidationError{Report: &algorithm.ValidationReport{
+        Errors: []algorithm.TopologyIssue{{Message: "node gpu-001 is in leaf-0 and leaf-1"}},
+    }}
+    tests := []struct {
+        name       string
+        config     *v1alpha1.DomainConfig
+        invalid    error
+        rejected   *algorithm.ValidationError
+        wantReady  metav1.ConditionStatus
+        wantReason string
+        want       v1alpha1.DomainConfigStatus
+    }{
+        {
+            name:       "healthy leaf",
+            config:     domainConfig("leaf-0", algorithm.LevelLeaf),
+            wantReady:  metav1.ConditionTrue,
+            wantReason: "Applied",
+            want:       v1alpha1.DomainConfigStatus{Nodes: 2, GPUsTotal: 16, GPUsUsed: 4, Health: v1alpha1.DomainHealthy},
+        },
+        {
+            name:       "degraded leaf",
+            config:     domainConfig("leaf-1", algorithm.LevelLeaf),
+            wantReady:  metav1.ConditionTrue,
+            wantReason: "Applied",
+            want:       v1alpha1.DomainConfigStatus{Nodes: 2, GPUsTotal: 8, GPUsUsed: 8, Health: v1alpha1.DomainDegraded},
+        },
+        {
+            name:       "unhealthy leaf",
+            config:     domainConfig("leaf-2", algorithm.LevelLeaf),
+            wantReady:  metav1.ConditionTrue,
+            wantReason: "Applied",
+            want:       v1alpha1.DomainConfigStatus{Nodes: 1, GPUsTotal: 8, Health: v1alpha1.DomainUnhealthy},
+        },
+        {
+            name:       "spine adds up its leaves",
+            config:     domainConfig("spine-0", algorithm.LevelSpine),
+            wantReady:  metav1.ConditionTrue,
+            wantReason: "Applied",
+            want:       v1alpha1.DomainConfigStatus{Nodes: 4, GPUsTotal: 24, GPUsUsed: 12, Health: v1alpha1.DomainDegraded},
+        },
+        {
+            name:       "invalid object",
+            config:     domainConfig("leaf-3", algorithm.LevelLeaf),
+            invalid:    errors.New("leaf leaf-3 has no nodeSelector"),
+            wantReady:  metav1.ConditionFalse,
+            wantReason: "Invalid",
+        },
+        {
+            name:       "topology rejected",
+            config:     domainConfig("leaf-0", algorithm.LevelLeaf),
+            rejected:   rejected,
+            wantReady:  metav1.ConditionFalse,
+            wantReason: "TopologyRejected",
+            want:       v1alpha1.DomainConfigStatus{Nodes: 2, GPUsTotal: 16, GPUsUsed: 4, Health: v1alpha1.DomainHealthy},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := newDomainConfigTestController(t)
+            status := c.status(tt.config, tt.invalid, tt.rejected)
+
+            if status.ObservedGeneration != tt.config.Generation {
+                t.Errorf("ObservedGeneration = %d, want %d", status.ObservedGeneration, tt.config.Generation)
+            }
+            got := v1alpha1.DomainConfigStatus{Nodes: status.Nodes, GPUsTotal: status.GPUsTotal, GPUsUsed: status.GPUsUsed, Health: status.Health}
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("status() = %+v, want %+v", got, tt.want)
+            }
+            ready := meta.FindStatusCondition(status.Conditions, v1alpha1.DomainConfigReady)
+            if ready == nil {
+                t.Fatalf("status() has no %s condition", v1alpha1.DomainConfigReady)
+            }
+            if ready.Status != tt.wantReady || ready.Reason != tt.wantReason || ready.ObservedGeneration != tt.config.Generation {
+                t.Errorf("Ready = %s/%s at generation %d, want %s/%s at %d",
+                    ready.Status, ready.Reason, ready.ObservedGeneration, tt.wantReady, tt.wantReason, tt.config.Generation)
+            }
+        })
+    }
+}
+
+func TestDomainConfigStatusTransition(t *testing.T) {
+    c := newDomainConfigTestController(t)
+    config := domainConfig("leaf-0", algorithm.LevelLeaf)
+    since := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+    config.Status.Conditions = []metav1.Condition{
+        {Type: v1alpha1.DomainConfigReady, Status: metav1.ConditionTrue, Reason: "Applied", LastTransitionTime: since},
+        {Type: "Drained", Status: metav1.ConditionFalse, Reason: "Manual", LastTransitionTime: since},
+    }
+
+    status := c.status(config, nil, nil)
+    if ready := meta.FindStatusCondition(status.Conditions, v1alpha1.DomainConfigReady); !ready.LastTransitionTime.Equal(&since) {
+        t.Errorf("unchanged Ready moved to %v, want %v", ready.LastTransitionTime, since)
+    }
+    if meta.FindStatusCondition(status.Conditions, "Drained") == nil {
+        t.Errorf("status() dropped a condition it does not own")
+    }
+    if len(config.Status.Conditions) != 2 || config.Status.Conditions[0].Reason != "Applied" {
+        t.Errorf("status() changed the object: %v", config.Status.Conditions)
+    }
+
+    status = c.status(config, errors.New("invalid selector"), nil)
+    ready := meta.FindStatusCondition(status.Conditions, v1alpha1.DomainConfigReady)
+    if ready.Status != metav1.ConditionFalse || ready.Message != "invalid selector" {
+        t.Errorf("Ready = %s %q, want False %q", ready.Status, ready.Message, "invalid selector")
+    }
+    if ready.LastTransitionTime.Equal(&since) {
+        t.Errorf("Ready turned False without a new transition time")
+    }
+}
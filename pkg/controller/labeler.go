@@ -0,0 +1,281 @@
+package controller
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/apimachinery/pkg/api/errors"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/labels"
+    "k8s.io/apimachinery/pkg/types"
+    utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+    "k8s.io/apimachinery/pkg/util/wait"
+    coreinformers "k8s.io/client-go/informers/core/v1"
+    "k8s.io/client-go/kubernetes"
+    corelisters "k8s.io/client-go/listers/core/v1"
+    "k8s.io/client-go/tools/cache"
+    "k8s.io/client-go/util/workqueue"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/discovery"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+const (
+    // RailLabelPrefix is followed by the rail index, and the label holds
+    // the switch of that rail
+    RailLabelPrefix = "topology.scheduler/rail-"
+
+    // ManagedLabelsAnnotation lists the labels the labeler wrote, so it
+    // only ever removes its own
+    ManagedLabelsAnnotation = "topology.scheduler/managed-labels"
+)
+
+// NodeLabeler writes the leaf, spine and rail of every node from the
+// canonical topology document onto the Node as labels, for tools that
+// cannot read the topology themselves
+type NodeLabeler struct {
+    client      kubernetes.Interface
+    source      string
+    interval    time.Duration
+    nodeLister  corelisters.NodeLister
+    nodesSynced cache.InformerSynced
+    queue       workqueue.RateLimitingInterface
+
+    mu sync.RWMutex
+    // desired holds the labels of each node in the topology, nil until
+    // the document was loaded once
+    desired map[string]map[string]string
+}
+
+// NewNodeLabeler creates a labeler reading the topology document at source,
+// a file or a URL such as the scheduler's /topology/export, every interval
+func NewNodeLabeler(client kubernetes.Interface, nodeInformer coreinformers.NodeInformer, source string, interval time.Duration) *NodeLabeler {
+    l := &NodeLabeler{
+        client:      client,
+        source:      source,
+        interval:    interval,
+        nodeLister:  nodeInformer.Lister(),
+        nodesSynced: nodeInformer.Informer().HasSynced,
+        queue:       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "NodeLabeler"),
+    }
+
+    nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+        AddFunc: l.enqueue,
+        UpdateFunc: func(old, new interface{}) {
+            if !reflect.DeepEqual(old.(*v1.Node).Labels, new.(*v1.Node).Labels) {
+                l.enqueue(new)
+            }
+        },
+    })
+    return l
+}
+
+// Run reloads the topology every interval and labels nodes with the given
+// number of workers until stopCh is closed
+func (l *NodeLabeler) Run(workers int, stopCh <-chan struct{}) error {
+    defer utilruntime.HandleCrash()
+    defer l.queue.ShutDown()
+
+    klog.Info("Starting node topology labeler")
+    if !cache.WaitForCacheSync(stopCh, l.nodesSynced) {
+        return fmt.Errorf("failed to wait for node cache to sync")
+    }
+
+    go wait.Until(l.refresh, l.interval, stopCh)
+    for i := 0; i < workers; i++ {
+        go wait.Until(l.runWorker, time.Second, stopCh)
+    }
+
+    <-stopCh
+    klog.Info("Shutting down node topology labeler")
+    return nil
+}
+
+// refresh reloads the topology and queues every node when it changed. The
+// previous labels are kept when the document cannot be loaded.
+func (l *NodeLabeler) refresh() {
+    doc, err := discovery.FetchTopology(l.source)
+    if err != nil {
+        klog.Warningf("Failed to load topology for node labels: %v", err)
+        return
+    }
+    desired := DesiredLabels(doc)
+
+    l.mu.Lock()
+    changed := !reflect.DeepEqual(l.desired, desired)
+    l.desired = desired
+    l.mu.Unlock()
+    if !changed {
+        return
+    }
+
+    nodes, err := l.nodeLister.List(labels.Everything())
+    if err != nil {
+        klog.Warningf("Failed to list nodes: %v", err)
+        return
+    }
+    for _, node := range nodes {
+        l.enqueue(node)
+    }
+}
+
+func (l *NodeLabeler) enqueue(obj interface{}) {
+    key, err := cache.MetaNamespaceKeyFunc(obj)
+    if err != nil {
+        utilruntime.HandleError(err)
+        return
+    }
+    l.queue.Add(key)
+}
+
+func (l *NodeLabeler) runWorker() {
+    for l.processNextItem() {
+    }
+}
+
+func (l *NodeLabeler) processNextItem() bool {
+    key, quit := l.queue.Get()
+    if quit {
+        return false
+    }
+    defer l.queue.Done(key)
+
+    if err := l.reconcile(key.(string)); err != nil {
+        utilruntime.HandleError(fmt.Errorf("failed to label node %s: %v", key, err))
+        l.queue.AddRateLimited(key)
+        return true
+    }
+    l.queue.Forget(key)
+    return true
+}
+
+// reconcile sets the topology labels of the node and removes the ones it
+// wrote before that no longer apply
+func (l *NodeLabeler) reconcile(name string) error {
+    l.mu.RLock()
+    loaded := l.desired != nil
+    desired := l.desired[name]
+    l.mu.RUnlock()
+    if !loaded {
+        // Without a topology every managed label would be removed
+        return nil
+    }
+
+    node, err := l.nodeLister.Get(name)
+    if errors.IsNotFound(err) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+
+    patch := labelPatch(node, desired)
+    if patch == nil {
+        return nil
+    }
+    data, err := json.Marshal(patch)
+    if err != nil {
+        return err
+    }
+    _, err = l.client.CoreV1().Nodes().Patch(context.TODO(), name, types.MergePatchType, data, metav1.PatchOptions{})
+    if err == nil {
+        klog.V(2).Infof("Updated topology labels of node %s", name)
+    }
+    return err
+}
+
+// labelPatch returns the merge patch that brings the node's managed labels
+// to desired, nil when they already match
+func labelPatch(node *v1.Node, desired map[string]string) map[string]interface{} {
+    changes := make(map[string]interface{})
+    for key, val := range desired {
+        if current, ok := node.Labels[key]; !ok || current != val {
+            changes[key] = val
+        }
+    }
+    for _, key := range managedLabels(node) {
+        if _, ok := desired[key]; !ok {
+            if _, exists := node.Labels[key]; exists {
+                changes[key] = nil
+            }
+        }
+    }
+
+    keys := make([]string, 0, len(desired))
+    for key := range desired {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    managed := strings.Join(keys, ",")
+    if len(changes) == 0 && managed == node.Annotations[ManagedLabelsAnnotation] {
+        return nil
+    }
+
+    var annotation interface{} = managed
+    if managed == "" {
+        annotation = nil
+    }
+    return map[string]interface{}{
+        "metadata": map[string]interface{}{
+            "labels":      changes,
+            "annotations": map[string]interface{}{ManagedLabelsAnnotation: annotation},
+        },
+    }
+}
+
+func managedLabels(node *v1.Node) []string {
+    val := node.Annotations[ManagedLabelsAnnotation]
+    if val == "" {
+        return nil
+    }
+    return strings.Split(val, ",")
+}
+
+// DesiredLabels maps every node of the document to its leaf, spine and
+// rail labels
+func DesiredLabels(doc *discovery.TopologyDocument) map[string]map[string]string {
+    parents := make(map[string]string)
+    for _, domain := range doc.Domains {
+        parents[domain.Name] = domain.Parent
+    }
+
+    desired := make(map[string]map[string]string)
+    for _, leaf := range doc.Leaves() {
+        for _, node := range leaf.Nodes {
+            labels := map[string]string{algorithm.LeafLabel: NormalizeLabelValue(leaf.Name)}
+            if spine := parents[leaf.Name]; spine != "" {
+                labels[algorithm.SpineLabel] = NormalizeLabelValue(spine)
+            }
+            for i, rail := range node.Rails {
+                if rail != "" {
+                    labels[RailLabelPrefix+strconv.Itoa(i)] = NormalizeLabelValue(rail)
+                }
+            }
+            desired[node.Name] = labels
+        }
+    }
+    return desired
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// NormalizeLabelValue turns a switch name into a valid label value:
+// characters other than letters, digits, '-', '_' and '.' become '-', and
+// the value is cut to 63 characters and starts and ends alphanumerically
+func NormalizeLabelValue(name string) string {
+    val := invalidLabelChars.ReplaceAllString(name, "-")
+    if len(val) > 63 {
+        val = val[:63]
+    }
+    return strings.Trim(val, "-_.")
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+func labelerNode(labels map[string]string, managed string) *v1.Node {
+    node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-001", Labels: labels}}
+    if managed != "" {
+        node.Annotations = map[string]string{ManagedLabelsAnnotation: managed}
+    }
+    return node
+}
+
+func TestLabelPatch(t *testing.T) {
+    rail0 := RailLabelPrefix + "0"
+    tests := []struct {
+        name    string
+        node    *v1.Node
+        desired map[string]string
+        // want is the metadata of the patch, nil for no patch
+        want map[string]interface{}
+    }{
+        {
+            name:    "add labels",
+            node:    labelerNode(map[string]string{"team": "ml"}, ""),
+            desired: map[string]string{algorithm.LeafLabel: "leaf-0", algorithm.SpineLabel: "spine-0"},
+            want: map[string]interface{}{
+                "labels":      map[string]interface{}{algorithm.LeafLabel: "leaf-0", algorithm.SpineLabel: "spine-0"},
+                "annotations": map[string]interface{}{ManagedLabelsAnnotation: algorithm.LeafLabel + "," + algorithm.SpineLabel},
+            },
+        },
+        {
+            name:    "up to date",
+            node:    labelerNode(map[string]string{algorithm.LeafLabel: "leaf-0"}, algorithm.LeafLabel),
+            desired: map[string]string{algorithm.LeafLabel: "leaf-0"},
+        },
+        {
+            name:    "change a label",
+            node:    labelerNode(map[string]string{algorithm.LeafLabel: "leaf-0"}, algorithm.LeafLabel),
+            desired: map[string]string{algorithm.LeafLabel: "leaf-1"},
+            want: map[string]interface{}{
+                "labels":      map[string]interface{}{algorithm.LeafLabel: "leaf-1"},
+                "annotations": map[string]interface{}{ManagedLabelsAnnotation: algorithm.LeafLabel},
+            },
+        },
+        {
+            name:    "remove a managed label",
+            node:    labelerNode(map[string]string{algorithm.LeafLabel: "leaf-0", rail0: "rail-sw-0"}, algorithm.LeafLabel+","+rail0),
+            desired: map[string]string{algorithm.LeafLabel: "leaf-0"},
+            want: map[string]interface{}{
+                "labels":      map[string]interface{}{rail0: nil},
+                "annotations": map[string]interface{}{ManagedLabelsAnnotation: algorithm.LeafLabel},
+            },
+        },
+        {
+            name:    "leave unmanaged labels",
+            node:    labelerNode(map[string]string{algorithm.LeafLabel: "leaf-0", rail0: "set-by-hand"}, algorithm.LeafLabel),
+            desired: map[string]string{algorithm.LeafLabel: "leaf-0"},
+        },
+        {
+            name:    "node left the topology",
+            node:    labelerNode(map[string]string{algorithm.LeafLabel: "leaf-0"}, algorithm.LeafLabel),
+            desired: nil,
+            want: map[string]interface{}{
+                "labels":      map[string]interface{}{algorithm.LeafLabel: nil},
+                "annotations": map[string]interface{}{ManagedLabelsAnnotation: nil},
+            },
+        },
+        {
+            name:    "managed label already removed by hand",
+            node:    labelerNode(map[string]string{algorithm.LeafLabel: "leaf-0"}, algorithm.LeafLabel+","+rail0),
+            desired: map[string]string{algorithm.LeafLabel: "leaf-0"},
+            want: map[string]interface{}{
+                "labels":      map[string]interface{}{},
+                "annotations": map[string]interface{}{ManagedLabelsAnnotation: algorithm.LeafLabel},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            patch := labelPatch(tt.node, tt.desired)
+            if tt.want == nil {
+                if patch != nil {
+                    t.Errorf("labelPatch() = %v, want nil", patch)
+                }
+                return
+            }
+            if got := patch["metadata"]; !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("labelPatch() metadata = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestNormalizeLabelValue(t *testing.T) {
+    tests := []struct {
+        name string
+        want string
+    }{
+        {name: "leaf-0", want: "leaf-0"},
+        {name: "ib/switch 3", want: "ib-switch-3"},
+        {name: "-spine.0_", want: "spine.0"},
+        {name: strings.Repeat("a", 62) + "-b", want: strings.Repeat("a", 62)},
+    }
+
+    for _, tt := range tests {
+        if got := NormalizeLabelValue(tt.name); got != tt.want {
+            t.Errorf("NormalizeLabelValue(%q) = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,236 @@
+package controller
+
+import (
+    "context"
+    "fmt"
+    "sync/atomic"
+    "time"
+
+    "k8s.io/apimachinery/pkg/api/equality"
+    "k8s.io/apimachinery/pkg/api/errors"
+    "k8s.io/apimachinery/pkg/api/meta"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+    "k8s.io/apimachinery/pkg/util/wait"
+    "k8s.io/client-go/tools/cache"
+    "k8s.io/client-go/util/workqueue"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/apis/topology/v1alpha1"
+    clientset "github.com/yourusername/topology-aware-gpu-scheduler/pkg/generated/clientset/versioned"
+    informers "github.com/yourusername/topology-aware-gpu-scheduler/pkg/generated/informers/externalversions/topology/v1alpha1"
+    listers "github.com/yourusername/topology-aware-gpu-scheduler/pkg/generated/listers/topology/v1alpha1"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// SchedulerConfigController swaps the scoring weights and topology
+// constraints of one SchedulerConfig object into the running scheduler
+// and, on the leader, reports in its status whether they were accepted
+type SchedulerConfigController struct {
+    topologyClient clientset.Interface
+    scheduler      *algorithm.TopologyScheduler
+    // base is the configuration file, used for unset parts and when the
+    // object is deleted
+    base      algorithm.SchedulerConfigSpec
+    key       string
+    namespace string
+    name      string
+    lister    listers.SchedulerConfigLister
+    synced    cache.InformerSynced
+    queue     workqueue.RateLimitingInterface
+    // writeStatus is set once this replica leads; the others only apply
+    // the object
+    writeStatus atomic.Bool
+}
+
+// NewSchedulerConfigController watches the SchedulerConfig named by key,
+// in namespace/name form
+func NewSchedulerConfigController(
+    topologyClient clientset.Interface,
+    informer informers.SchedulerConfigInformer,
+    scheduler *algorithm.TopologyScheduler,
+    base *algorithm.SchedulerConfigSpec,
+    key string,
+) (*SchedulerConfigController, error) {
+    namespace, name, err := cache.SplitMetaNamespaceKey(key)
+    if err != nil || namespace == "" || name == "" {
+        return nil, fmt.Errorf("invalid SchedulerConfig %q, expected namespace/name", key)
+    }
+
+    c := &SchedulerConfigController{
+        topologyClient: topologyClient,
+        scheduler:      scheduler,
+        base:           *base,
+        key:            key,
+        namespace:      namespace,
+        name:           name,
+        lister:         informer.Lister(),
+        synced:         informer.Informer().HasSynced,
+        queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "SchedulerConfig"),
+    }
+    informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+        AddFunc: c.enqueue,
+        UpdateFunc: func(old, new interface{}) {
+            if old.(*v1alpha1.SchedulerConfig).Generation != new.(*v1alpha1.SchedulerConfig).Generation {
+                c.enqueue(new)
+            }
+        },
+        DeleteFunc: c.enqueue,
+    })
+    return c, nil
+}
+
+// Run applies changes of the object until stopCh is closed
+func (c *SchedulerConfigController) Run(stopCh <-chan struct{}) error {
+    defer utilruntime.HandleCrash()
+    defer c.queue.ShutDown()
+
+    klog.Infof("Starting SchedulerConfig controller for %s", c.key)
+    if !cache.WaitForCacheSync(stopCh, c.synced) {
+        return fmt.Errorf("failed to wait for SchedulerConfig cache to sync")
+    }
+    c.queue.Add(c.key)
+    go wait.Until(c.runWorker, time.Second, stopCh)
+
+    <-stopCh
+    klog.Info("Shutting down SchedulerConfig controller")
+    return nil
+}
+
+// WriteStatus makes the controller report the outcome in the object's
+// status from now on, starting with the settings already applied
+func (c *SchedulerConfigController) WriteStatus() {
+    c.writeStatus.Store(true)
+    c.queue.Add(c.key)
+}
+
+func (c *SchedulerConfigController) enqueue(obj interface{}) {
+    key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+    if err != nil {
+        utilruntime.HandleError(err)
+        return
+    }
+    if key == c.key {
+        c.queue.Add(key)
+    }
+}
+
+func (c *SchedulerConfigController) runWorker() {
+    for c.processNextItem() {
+    }
+}
+
+func (c *SchedulerConfigController) processNextItem() bool {
+    key, quit := c.queue.Get()
+    if quit {
+        return false
+    }
+    defer c.queue.Done(key)
+
+    if err := c.reconcile(); err != nil {
+        utilruntime.HandleError(fmt.Errorf("failed to sync SchedulerConfig %s: %v", key, err))
+        c.queue.AddRateLimited(key)
+        return true
+    }
+    c.queue.Forget(key)
+    return true
+}
+
+// reconcile applies the object, or the configuration file once the object
+// is deleted. An invalid spec leaves the scheduler running with the
+// settings it had.
+func (c *SchedulerConfigController) reconcile() error {
+    config, err := c.lister.SchedulerConfigs(c.namespace).Get(c.name)
+    if errors.IsNotFound(err) {
+        klog.Infof("SchedulerConfig %s not found, using the configuration file", c.key)
+        return c.scheduler.SetConfig(&c.base)
+    }
+    if err != nil {
+        return err
+    }
+
+    applyErr := c.scheduler.SetConfig(SchedulerConfigSpec(&c.base, config))
+    if applyErr != nil {
+        klog.Warningf("Rejected SchedulerConfig %s: %v", c.key, applyErr)
+    }
+    if !c.writeStatus.Load() {
+        return nil
+    }
+
+    status := schedulerConfigStatus(config, applyErr)
+    if equality.Semantic.DeepEqual(config.Status, status) {
+        return nil
+    }
+    updated := config.DeepCopy()
+    updated.Status = status
+    _, err = c.topologyClient.TopologyV1alpha1().SchedulerConfigs(c.namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+    return err
+}
+
+// SchedulerConfigSpec lays the parts set in the object over the base
+// configuration
+func SchedulerConfigSpec(base *algorithm.SchedulerConfigSpec, config *v1alpha1.SchedulerConfig) *algorithm.SchedulerConfigSpec {
+    spec := *base
+    if w := config.Spec.ScoringWeights; w != nil {
+        spec.ScoringWeights = &algorithm.ScoringWeightsConfig{
+            ResourceAvailability:  w.ResourceAvailability,
+            TopologyAlignment:     w.TopologyAlignment,
+            DomainUtilization:     w.DomainUtilization,
+            HistoricalPerformance: w.HistoricalPerformance,
+        }
+    }
+    if tc := config.Spec.TopologyConstraints; tc != nil {
+        spec.TopologyConstraints = &algorithm.TopologyConstraints{
+            LeafLimits: algorithm.LeafLimits{
+                MaxNodesPerLeaf: int(tc.MaxNodesPerLeaf),
+                MaxGPUsPerLeaf:  int(tc.MaxGPUsPerLeaf),
+            },
+        }
+        if tc.Tenant != nil {
+            spec.TopologyConstraints.Tenant = &algorithm.LeafLimits{
+                MaxNodesPerLeaf: int(tc.Tenant.MaxNodesPerLeaf),
+                MaxGPUsPerLeaf:  int(tc.Tenant.MaxGPUsPerLeaf),
+            }
+        }
+    }
+    if config.Spec.CommProfiles != nil {
+        spec.CommProfiles = make(map[algorithm.CommProfile]*algorithm.CommProfileWeights, len(config.Spec.CommProfiles))
+        for profile, w := range config.Spec.CommProfiles {
+            spec.CommProfiles[algorithm.CommProfile(profile)] = &algorithm.CommProfileWeights{
+                ResourceAvailability:  w.ResourceAvailability,
+                TopologyAlignment:     w.TopologyAlignment,
+                DomainUtilization:     w.DomainUtilization,
+                HistoricalPerformance: w.HistoricalPerformance,
+            }
+        }
+    }
+    return &spec
+}
+
+func schedulerConfigStatus(config *v1alpha1.SchedulerConfig, applyErr error) v1alpha1.SchedulerConfigStatus {
+    status := v1alpha1.SchedulerConfigStatus{ObservedGeneration: config.Generation}
+    status.Conditions = append(status.Conditions, config.Status.Conditions...)
+
+    accepted := metav1.Condition{
+        Type:               v1alpha1.SchedulerConfigAccepted,
+        Status:             metav1.ConditionTrue,
+        Reason:             "Applied",
+        Message:            "The scheduler runs with this configuration",
+        ObservedGeneration: config.Generation,
+    }
+    invalid := metav1.Condition{
+        Type:               v1alpha1.SchedulerConfigInvalid,
+        Status:             metav1.ConditionFalse,
+        Reason:             "Valid",
+        Message:            "The configuration is valid",
+        ObservedGeneration: config.Generation,
+    }
+    if applyErr != nil {
+        accepted.Status, accepted.Reason = metav1.ConditionFalse, "Invalid"
+        accepted.Message = "The scheduler keeps its previous configuration"
+        invalid.Status, invalid.Reason, invalid.Message = metav1.ConditionTrue, "ValidationFailed", applyErr.Error()
+    }
+    meta.SetStatusCondition(&status.Conditions, accepted)
+    meta.SetStatusCondition(&status.Conditions, invalid)
+    return status
+}
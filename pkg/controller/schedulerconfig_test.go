@@ -0,0 +1,197 @@
+package controller
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+
+    "k8s.io/apimachinery/pkg/api/meta"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/apis/topology/v1alpha1"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+func baseSchedulerConfig() *algorithm.SchedulerConfigSpec {
+    return &algorithm.SchedulerConfigSpec{
+        ScoringWeights: &algorithm.ScoringWeightsConfig{
+            ResourceAvailability:  0.4,
+            TopologyAlignment:     0.3,
+            DomainUtilization:     0.2,
+            HistoricalPerformance: 0.1,
+        },
+        TopologyConstraints: &algorithm.TopologyConstraints{LeafLimits: algorithm.LeafLimits{MaxNodesPerLeaf: 8}},
+        NodeLabels:          algorithm.DefaultNodeLabelSchema(),
+    }
+}
+
+func TestSchedulerConfigSpec(t *testing.T) {
+    factor := 2.0
+    tests := []struct {
+        name string
+        spec v1alpha1.SchedulerConfigSpec
+        // want changes the base spec to the expected one
+        want func(spec *algorithm.SchedulerConfigSpec)
+    }{
+        {
+            name: "empty object keeps the base",
+            want: func(spec *algorithm.SchedulerConfigSpec) {},
+        },
+        {
+            name: "scoring weights",
+            spec: v1alpha1.SchedulerConfigSpec{ScoringWeights: &v1alpha1.ScoringWeights{
+                ResourceAvailability:  0.1,
+                TopologyAlignment:     0.6,
+                DomainUtilization:     0.2,
+                HistoricalPerformance: 0.1,
+            }},
+            want: func(spec *algorithm.SchedulerConfigSpec) {
+                spec.ScoringWeights = &algorithm.ScoringWeightsConfig{
+                    ResourceAvailability:  0.1,
+                    TopologyAlignment:     0.6,
+                    DomainUtilization:     0.2,
+                    HistoricalPerformance: 0.1,
+                }
+            },
+        },
+        {
+            name: "topology constraints replace the base ones",
+            spec: v1alpha1.SchedulerConfigSpec{TopologyConstraints: &v1alpha1.TopologyConstraints{
+                MaxGPUsPerLeaf: 32,
+                Tenant:         &v1alpha1.LeafLimits{MaxNodesPerLeaf: 12},
+            }},
+            want: func(spec *algorithm.SchedulerConfigSpec) {
+                spec.TopologyConstraints = &algorithm.TopologyConstraints{
+                    LeafLimits: algorithm.LeafLimits{MaxGPUsPerLeaf: 32},
+                    Tenant:     &algorithm.LeafLimits{MaxNodesPerLeaf: 12},
+                }
+            },
+        },
+        {
+            name: "comm profiles",
+            spec: v1alpha1.SchedulerConfigSpec{CommProfiles: map[string]v1alpha1.CommProfileWeights{
+                string(algorithm.CommProfileAllReduceHeavy): {TopologyAlignment: &factor},
+            }},
+            want: func(spec *algorithm.SchedulerConfigSpec) {
+                spec.CommProfiles = map[algorithm.CommProfile]*algorithm.CommProfileWeights{
+                    algorithm.CommProfileAllReduceHeavy: {TopologyAlignment: &factor},
+                }
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            base := baseSchedulerConfig()
+            config := &v1alpha1.SchedulerConfig{Spec: tt.spec}
+            got := SchedulerConfigSpec(base, config)
+
+            want := baseSchedulerConfig()
+            tt.want(want)
+            if !reflect.DeepEqual(got, want) {
+                t.Errorf("SchedulerConfigSpec() = %+v, want %+v", got, want)
+            }
+            if !reflect.DeepEqual(base, baseSchedulerConfig()) {
+                t.Errorf("SchedulerConfigSpec() changed the base to %+v", base)
+            }
+        })
+    }
+}
+
+func TestSchedulerConfigStatus(t *testing.T) {
+    since := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+    accepted := []metav1.Condition{
+        {Type: v1alpha1.SchedulerConfigAccepted, Status: metav1.ConditionTrue, Reason: "Applied", ObservedGeneration: 1, LastTransitionTime: since},
+        {Type: v1alpha1.SchedulerConfigInvalid, Status: metav1.ConditionFalse, Reason: "Valid", ObservedGeneration: 1, LastTransitionTime: since},
+    }
+    rejected := []metav1.Condition{
+        {Type: v1alpha1.SchedulerConfigAccepted, Status: metav1.ConditionFalse, Reason: "Invalid", ObservedGeneration: 1, LastTransitionTime: since},
+        {Type: v1alpha1.SchedulerConfigInvalid, Status: metav1.ConditionTrue, Reason: "ValidationFailed", ObservedGeneration: 1, LastTransitionTime: since},
+    }
+    tests := []struct {
+        name         string
+        conditions   []metav1.Condition
+        applyErr     error
+        wantAccepted metav1.ConditionStatus
+        wantInvalid  metav1.ConditionStatus
+        // wantMoved is whether the conditions get a new transition time
+        wantMoved bool
+    }{
+        {
+            name:         "first accepted",
+            wantAccepted: metav1.ConditionTrue,
+            wantInvalid:  metav1.ConditionFalse,
+            wantMoved:    true,
+        },
+        {
+            name:         "first rejected",
+            applyErr:     errors.New("scoring weights sum to 0"),
+            wantAccepted: metav1.ConditionFalse,
+            wantInvalid:  metav1.ConditionTrue,
+            wantMoved:    true,
+        },
+        {
+            name:         "still accepted",
+            conditions:   accepted,
+            wantAccepted: metav1.ConditionTrue,
+            wantInvalid:  metav1.ConditionFalse,
+        },
+        {
+            name:         "accepted to rejected",
+            conditions:   accepted,
+            applyErr:     errors.New("scoring weights sum to 0"),
+            wantAccepted: metav1.ConditionFalse,
+            wantInvalid:  metav1.ConditionTrue,
+            wantMoved:    true,
+        },
+        {
+            name:         "rejected to accepted",
+            conditions:   rejected,
+            wantAccepted: metav1.ConditionTrue,
+            wantInvalid:  metav1.ConditionFalse,
+            wantMoved:    true,
+        },
+        {
+            name:         "still rejected",
+            conditions:   rejected,
+            applyErr:     errors.New("maxNodesPerLeaf must not be negative"),
+            wantAccepted: metav1.ConditionFalse,
+            wantInvalid:  metav1.ConditionTrue,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            config := &v1alpha1.SchedulerConfig{
+                ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "topology-scheduler", Generation: 2},
+                Status:     v1alpha1.SchedulerConfigStatus{ObservedGeneration: 1, Conditions: append([]metav1.Condition(nil), tt.conditions...)},
+            }
+            status := schedulerConfigStatus(config, tt.applyErr)
+
+            if status.ObservedGeneration != 2 {
+                t.Errorf("ObservedGeneration = %d, want 2", status.ObservedGeneration)
+            }
+            checks := map[string]metav1.ConditionStatus{
+                v1alpha1.SchedulerConfigAccepted: tt.wantAccepted,
+                v1alpha1.SchedulerConfigInvalid:  tt.wantInvalid,
+            }
+            for conditionType, want := range checks {
+                condition := meta.FindStatusCondition(status.Conditions, conditionType)
+                if condition == nil {
+                    t.Fatalf("status has no %s condition", conditionType)
+                }
+                if condition.Status != want || condition.ObservedGeneration != 2 {
+                    t.Errorf("%s = %s at generation %d, want %s at 2", conditionType, condition.Status, condition.ObservedGeneration, want)
+                }
+                if moved := !condition.LastTransitionTime.Equal(&since); moved != tt.wantMoved {
+                    t.Errorf("%s moved = %v, want %v", conditionType, moved, tt.wantMoved)
+                }
+            }
+            invalid := meta.FindStatusCondition(status.Conditions, v1alpha1.SchedulerConfigInvalid)
+            if tt.applyErr != nil && invalid.Message != tt.applyErr.Error() {
+                t.Errorf("Invalid message = %q, want %q", invalid.Message, tt.applyErr.Error())
+            }
+        })
+    }
+}
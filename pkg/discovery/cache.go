@@ -0,0 +1,303 @@
+package discovery
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "sort"
+    "strings"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/client-go/kubernetes"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// ConflictKind describes why discovery disagrees with the current state
+type ConflictKind string
+
+const (
+    // ConflictLabelMismatch means the manual labels name another leaf
+    ConflictLabelMismatch ConflictKind = "label-mismatch"
+    // ConflictMultipleLeaves means the node's interfaces reach several leaves
+    ConflictMultipleLeaves ConflictKind = "multiple-leaves"
+)
+
+// Conflict is a node whose discovered leaf disagrees with another source
+type Conflict struct {
+    Node       string       `json:"node"`
+    Kind       ConflictKind `json:"kind"`
+    Discovered string       `json:"discovered"`
+    Existing   string       `json:"existing"`
+}
+
+func (c Conflict) String() string {
+    return fmt.Sprintf("node %s: %s (discovered %s, existing %s)", c.Node, c.Kind, c.Discovered, c.Existing)
+}
+
+// DiscoveredTopology is the leaf membership and spine connectivity found by
+// a discovery source
+type DiscoveredTopology struct {
+    // Leaves maps each leaf switch to its nodes
+    Leaves map[string][]*v1.Node
+    // Spines maps each leaf switch to the spines it uplinks to
+    Spines map[string][]string
+    // Uplinks holds the bandwidth in Gbps from each leaf to each of its
+    // spines when the source knows it
+    Uplinks map[string]map[string]float64
+    // Upper maps each leaf switch to the switches above its spines. Leaves
+    // sharing one are connected like leaves sharing a spine.
+    Upper map[string][]string
+    // Links replaces the connections between leaves sharing a spine when
+    // the source lists them explicitly
+    Links     []Link
+    Conflicts []Conflict
+    // Undiscovered lists nodes the source has no data for
+    Undiscovered []string
+}
+
+// Link is a connection from one leaf to another. Zero bandwidth or
+// latency means unknown.
+type Link struct {
+    Source        string
+    Target        string
+    BandwidthGbps float64
+    Latency       time.Duration
+}
+
+func newDiscoveredTopology() *DiscoveredTopology {
+    return &DiscoveredTopology{
+        Leaves:  make(map[string][]*v1.Node),
+        Spines:  make(map[string][]string),
+        Uplinks: make(map[string]map[string]float64),
+        Upper:   make(map[string][]string),
+    }
+}
+
+// assign places the node in the leaf most of its links go to. counts holds
+// the number of links per leaf. Links to several leaves and disagreement
+// with the label schema are recorded as conflicts.
+func (d *DiscoveredTopology) assign(node *v1.Node, counts map[string]int, schema *algorithm.NodeLabelSchema) {
+    if len(counts) == 0 {
+        d.Undiscovered = append(d.Undiscovered, node.Name)
+        return
+    }
+
+    leaves := make([]string, 0, len(counts))
+    for leaf := range counts {
+        leaves = append(leaves, leaf)
+    }
+    sort.Slice(leaves, func(i, j int) bool {
+        if counts[leaves[i]] != counts[leaves[j]] {
+            return counts[leaves[i]] > counts[leaves[j]]
+        }
+        return leaves[i] < leaves[j]
+    })
+    leaf := leaves[0]
+    if len(leaves) > 1 {
+        d.Conflicts = append(d.Conflicts, Conflict{
+            Node:       node.Name,
+            Kind:       ConflictMultipleLeaves,
+            Discovered: leaf,
+            Existing:   strings.Join(leaves[1:], ","),
+        })
+    }
+    if schema != nil {
+        if labelled, err := schema.Resolve(node); err == nil && labelled.Leaf != leaf {
+            d.Conflicts = append(d.Conflicts, Conflict{
+                Node:       node.Name,
+                Kind:       ConflictLabelMismatch,
+                Discovered: leaf,
+                Existing:   labelled.Leaf,
+            })
+        }
+    }
+    d.Leaves[leaf] = append(d.Leaves[leaf], node)
+}
+
+// applyTopology adds the discovered leaves and their nodes to the cache and
+// connects leaves that share a spine. Nodes the cache places in another
+// domain are moved to the discovered leaf. Nothing is changed when the
+// result would not pass validation.
+func applyTopology(cache *algorithm.TopologyCache, discovered *DiscoveredTopology) error {
+    conflicts := len(discovered.Conflicts)
+    _, err := cache.Apply(func(tc *algorithm.TopologyCache) error {
+        discovered.Conflicts = discovered.Conflicts[:conflicts]
+        return addTopology(tc, discovered)
+    })
+    return err
+}
+
+func addTopology(cache *algorithm.TopologyCache, discovered *DiscoveredTopology) error {
+    existing := make(map[string]*algorithm.Domain)
+    for _, domain := range cache.GetAllDomains() {
+        existing[domain.Name] = domain
+    }
+
+    leaves := make([]string, 0, len(discovered.Leaves))
+    for leaf := range discovered.Leaves {
+        leaves = append(leaves, leaf)
+    }
+    sort.Strings(leaves)
+
+    for _, leaf := range leaves {
+        var spine string
+        if spines := discovered.Spines[leaf]; len(spines) > 0 {
+            spine = spines[0]
+        }
+
+        var unassigned, moved []*v1.Node
+        for _, node := range discovered.Leaves[leaf] {
+            if domain, err := cache.GetDomainForNode(node.Name); err == nil {
+                if domain.Name != leaf {
+                    moved = append(moved, node)
+                }
+                continue
+            }
+            if err := cache.RefreshNode(node); err != nil {
+                return err
+            }
+            unassigned = append(unassigned, node)
+        }
+
+        if _, ok := existing[leaf]; !ok {
+            domain := &algorithm.Domain{
+                ID:          leaf,
+                Name:        leaf,
+                Level:       algorithm.LevelLeaf,
+                Parent:      spine,
+                LeafSwitch:  leaf,
+                SpineSwitch: spine,
+                Nodes:       unassigned,
+                Jobs:        make(map[string]*algorithm.PlacedJob),
+            }
+            if err := cache.AddDomain(domain); err != nil {
+                return err
+            }
+            if err := cache.RefreshDomainCapacity(leaf); err != nil {
+                return err
+            }
+        } else {
+            for _, node := range unassigned {
+                if err := cache.AddNodeToDomain(node.Name, leaf); err != nil {
+                    return err
+                }
+            }
+            if len(unassigned) > 0 {
+                if err := cache.RefreshDomainCapacity(leaf); err != nil {
+                    return err
+                }
+            }
+        }
+
+        // Nodes recabled to another leaf move there with their jobs
+        for _, node := range moved {
+            klog.Infof("Node %s moved to leaf %s", node.Name, leaf)
+            if err := cache.MoveNode(node.Name, leaf); err != nil {
+                return err
+            }
+        }
+    }
+
+    return connectLeaves(cache, discovered)
+}
+
+// connectLeaves adds the missing connections between leaves, either the
+// listed links or between leaves with a spine or a switch above their
+// spines in common. When uplink
+// bandwidths are known the bandwidth between two leaves is the sum, over
+// their shared spines, of the slower uplink.
+func connectLeaves(cache *algorithm.TopologyCache, discovered *DiscoveredTopology) error {
+    connected := make(map[[2]string]bool)
+    for _, source := range cache.GetAllDomains() {
+        targets, err := cache.GetConnectedDomains(source.Name)
+        if err != nil {
+            continue
+        }
+        for _, target := range targets {
+            connected[[2]string{source.Name, target.Name}] = true
+        }
+    }
+
+    if discovered.Links != nil {
+        return addLinks(cache, discovered.Links, connected)
+    }
+
+    bySpine := make(map[string][]string)
+    for _, switches := range []map[string][]string{discovered.Spines, discovered.Upper} {
+        for leaf, names := range switches {
+            for _, spine := range names {
+                bySpine[spine] = appendUnique(bySpine[spine], leaf)
+            }
+        }
+    }
+
+    bandwidth := make(map[[2]string]float64)
+    for spine, leaves := range bySpine {
+        sort.Strings(leaves)
+        for _, source := range leaves {
+            for _, target := range leaves {
+                link := [2]string{source, target}
+                if source == target {
+                    continue
+                }
+                up, down := discovered.Uplinks[source][spine], discovered.Uplinks[target][spine]
+                bandwidth[link] += math.Min(up, down)
+                if connected[link] {
+                    continue
+                }
+                if err := cache.AddSpineConnection(source, target); err != nil {
+                    return err
+                }
+                connected[link] = true
+            }
+        }
+    }
+
+    for link, gbps := range bandwidth {
+        if gbps <= 0 {
+            continue
+        }
+        if err := cache.SetLinkBandwidth(link[0], link[1], gbps); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func addLinks(cache *algorithm.TopologyCache, links []Link, connected map[[2]string]bool) error {
+    for _, link := range links {
+        if !connected[[2]string{link.Source, link.Target}] {
+            if err := cache.AddSpineConnection(link.Source, link.Target); err != nil {
+                return err
+            }
+            connected[[2]string{link.Source, link.Target}] = true
+        }
+        if link.BandwidthGbps > 0 {
+            if err := cache.SetLinkBandwidth(link.Source, link.Target, link.BandwidthGbps); err != nil {
+                return err
+            }
+        }
+        if link.Latency > 0 {
+            if err := cache.SetLinkLatency(link.Source, link.Target, link.Latency); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func listNodes(ctx context.Context, client kubernetes.Interface) ([]*v1.Node, error) {
+    nodeList, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+    if err != nil {
+        return nil, fmt.Errorf("failed to list nodes: %v", err)
+    }
+    nodes := make([]*v1.Node, 0, len(nodeList.Items))
+    for i := range nodeList.Items {
+        nodes = append(nodes, &nodeList.Items[i])
+    }
+    return nodes, nil
+}
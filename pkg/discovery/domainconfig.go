@@ -0,0 +1,285 @@
+package discovery
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/labels"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/apis/topology/v1alpha1"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// DomainConfigTopology builds the topology declared by DomainConfig
+// objects. Leaves take the nodes their selector matches and uplink to
+// their parent spine. Leaves under the same spine are linked with the
+// smaller of their uplink bandwidths and the sum of their uplink
+// latencies. Objects that cannot be used are returned with the reason.
+func DomainConfigTopology(configs []*v1alpha1.DomainConfig, nodes []*v1.Node, schema *algorithm.NodeLabelSchema) (*DiscoveredTopology, map[string]error) {
+    discovered := newDiscoveredTopology()
+    invalid := make(map[string]error)
+
+    sorted := append([]*v1alpha1.DomainConfig(nil), configs...)
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+    spines := make(map[string]bool)
+    for _, config := range sorted {
+        if config.Spec.Level == string(algorithm.LevelSpine) {
+            spines[config.Name] = true
+        }
+    }
+
+    selectors := make(map[string]labels.Selector)
+    var leaves []*v1alpha1.DomainConfig
+    for _, config := range sorted {
+        spec := config.Spec
+        switch spec.Level {
+        case string(algorithm.LevelSpine):
+            if spec.Parent != "" || spec.NodeSelector != nil {
+                invalid[config.Name] = fmt.Errorf("spine %s cannot have a parent or a node selector", config.Name)
+            }
+            continue
+        case string(algorithm.LevelLeaf):
+        default:
+            invalid[config.Name] = fmt.Errorf("unknown level %q", spec.Level)
+            continue
+        }
+        if spec.Parent != "" && !spines[spec.Parent] {
+            invalid[config.Name] = fmt.Errorf("parent %s is not a spine DomainConfig", spec.Parent)
+            continue
+        }
+        if spec.NodeSelector == nil {
+            invalid[config.Name] = fmt.Errorf("leaf %s has no node selector", config.Name)
+            continue
+        }
+        selector, err := metav1.LabelSelectorAsSelector(spec.NodeSelector)
+        if err != nil {
+            invalid[config.Name] = fmt.Errorf("invalid node selector: %v", err)
+            continue
+        }
+        if selector.Empty() {
+            invalid[config.Name] = fmt.Errorf("leaf %s selects every node", config.Name)
+            continue
+        }
+        if spec.BandwidthGbps < 0 {
+            invalid[config.Name] = fmt.Errorf("negative bandwidth %d", spec.BandwidthGbps)
+            continue
+        }
+        if spec.Latency != nil && spec.Latency.Duration < 0 {
+            invalid[config.Name] = fmt.Errorf("negative latency %s", spec.Latency.Duration)
+            continue
+        }
+        if spec.Limits.MaxGPUs < 0 {
+            invalid[config.Name] = fmt.Errorf("negative GPU limit %d", spec.Limits.MaxGPUs)
+            continue
+        }
+        selectors[config.Name] = selector
+        leaves = append(leaves, config)
+    }
+
+    for _, leaf := range leaves {
+        discovered.Leaves[leaf.Name] = []*v1.Node{}
+        if leaf.Spec.Parent != "" {
+            discovered.Spines[leaf.Name] = []string{leaf.Spec.Parent}
+        }
+    }
+    for _, node := range nodes {
+        counts := make(map[string]int)
+        for _, leaf := range leaves {
+            if selectors[leaf.Name].Matches(labels.Set(node.Labels)) {
+                counts[leaf.Name] = 1
+            }
+        }
+        discovered.assign(node, counts, schema)
+    }
+
+    // Link the leaves under each spine
+    for i, source := range leaves {
+        for _, target := range leaves[i+1:] {
+            if source.Spec.Parent == "" || source.Spec.Parent != target.Spec.Parent {
+                continue
+            }
+            link := Link{Source: source.Name, Target: target.Name}
+            if source.Spec.BandwidthGbps > 0 && target.Spec.BandwidthGbps > 0 {
+                link.BandwidthGbps = float64(source.Spec.BandwidthGbps)
+                if target.Spec.BandwidthGbps < source.Spec.BandwidthGbps {
+                    link.BandwidthGbps = float64(target.Spec.BandwidthGbps)
+                }
+            }
+            for _, leaf := range []*v1alpha1.DomainConfig{source, target} {
+                if leaf.Spec.Latency != nil {
+                    link.Latency += leaf.Spec.Latency.Duration
+                }
+            }
+            discovered.Links = append(discovered.Links, link, Link{
+                Source:        link.Target,
+                Target:        link.Source,
+                BandwidthGbps: link.BandwidthGbps,
+                Latency:       link.Latency,
+            })
+        }
+    }
+    if discovered.Links == nil {
+        discovered.Links = []Link{}
+    }
+    return discovered, invalid
+}
+
+// DomainConfigSource keeps the topology cache in step with the DomainConfig
+// objects of the cluster. Unlike the other sources it also removes the
+// leaves and links of objects that were deleted or changed.
+type DomainConfigSource struct {
+    cache  *algorithm.TopologyCache
+    schema *algorithm.NodeLabelSchema
+
+    mu sync.Mutex
+    // managed holds the leaves added from DomainConfigs
+    managed map[string]bool
+}
+
+func NewDomainConfigSource(cache *algorithm.TopologyCache) *DomainConfigSource {
+    return &DomainConfigSource{
+        cache:   cache,
+        managed: make(map[string]bool),
+    }
+}
+
+// SetLabelSchema enables checking the selected leaves against manual labels
+func (s *DomainConfigSource) SetLabelSchema(schema *algorithm.NodeLabelSchema) {
+    s.schema = schema
+}
+
+// Sync applies the DomainConfigs to the cache. The objects that could not
+// be used are returned with the reason, and leaves added before keep
+// their last valid state. Nothing is changed when the result would not
+// pass validation.
+func (s *DomainConfigSource) Sync(configs []*v1alpha1.DomainConfig, nodes []*v1.Node) (*DiscoveredTopology, map[string]error, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    discovered, invalid := DomainConfigTopology(configs, nodes, s.schema)
+    kept := make(map[string]bool)
+    for name := range invalid {
+        if s.managed[name] {
+            kept[name] = true
+        }
+    }
+    limits := make(map[string]int)
+    for _, config := range configs {
+        if _, ok := discovered.Leaves[config.Name]; ok {
+            limits[config.Name] = int(config.Spec.Limits.MaxGPUs)
+        }
+    }
+
+    conflicts := len(discovered.Conflicts)
+    _, err := s.cache.Apply(func(tc *algorithm.TopologyCache) error {
+        discovered.Conflicts = discovered.Conflicts[:conflicts]
+        return s.sync(tc, discovered, limits, kept)
+    })
+    if err != nil {
+        return nil, invalid, err
+    }
+
+    s.managed = kept
+    for leaf := range discovered.Leaves {
+        s.managed[leaf] = true
+    }
+    for _, conflict := range discovered.Conflicts {
+        klog.Warningf("DomainConfig conflict: %s", conflict)
+    }
+    if len(discovered.Undiscovered) > 0 {
+        klog.V(4).Infof("No DomainConfig selects nodes %s", strings.Join(discovered.Undiscovered, ", "))
+    }
+    return discovered, invalid, nil
+}
+
+func (s *DomainConfigSource) sync(tc *algorithm.TopologyCache, discovered *DiscoveredTopology, limits map[string]int, kept map[string]bool) error {
+    // Drop the leaves of deleted objects and the links between managed
+    // leaves that are no longer under the same spine
+    wanted := make(map[[2]string]bool, len(discovered.Links))
+    for _, link := range discovered.Links {
+        wanted[[2]string{link.Source, link.Target}] = true
+    }
+    var removed []string
+    for leaf := range s.managed {
+        if _, ok := discovered.Leaves[leaf]; !ok && !kept[leaf] {
+            removed = append(removed, leaf)
+        }
+    }
+    sort.Strings(removed)
+    for _, leaf := range removed {
+        if err := tc.RemoveDomain(leaf); err != nil {
+            klog.V(4).Infof("Leaf %s of a deleted DomainConfig is already gone", leaf)
+        }
+    }
+    for leaf := range s.managed {
+        if kept[leaf] {
+            continue
+        }
+        targets, err := tc.GetConnectedDomains(leaf)
+        if err != nil {
+            continue
+        }
+        for _, target := range targets {
+            if s.managed[target.Name] && !kept[target.Name] && !wanted[[2]string{leaf, target.Name}] {
+                if err := tc.RemoveSpineConnection(leaf, target.Name); err != nil {
+                    return err
+                }
+            }
+        }
+    }
+
+    for leaf := range discovered.Leaves {
+        var parent string
+        if spines := discovered.Spines[leaf]; len(spines) > 0 {
+            parent = spines[0]
+        }
+        if _, err := tc.GetDomain(leaf); err == nil {
+            if err := tc.SetDomainParent(leaf, parent); err != nil {
+                return err
+            }
+        }
+    }
+    if err := addTopology(tc, discovered); err != nil {
+        return err
+    }
+
+    // Nodes the selector of their leaf no longer matches leave it
+    for leaf, nodes := range discovered.Leaves {
+        domain, err := tc.GetDomain(leaf)
+        if err != nil {
+            continue
+        }
+        selected := make(map[string]bool, len(nodes))
+        for _, node := range nodes {
+            selected[node.Name] = true
+        }
+        var deselected []string
+        for _, node := range domain.Nodes {
+            if !selected[node.Name] {
+                deselected = append(deselected, node.Name)
+            }
+        }
+        for _, node := range deselected {
+            if err := tc.RemoveNodeFromDomain(node, leaf); err != nil {
+                return err
+            }
+        }
+        if len(deselected) > 0 {
+            if err := tc.RefreshDomainCapacity(leaf); err != nil {
+                return err
+            }
+        }
+    }
+    for leaf, limit := range limits {
+        if err := tc.SetDomainLimit(leaf, limit); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,414 @@
+package discovery
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/apimachinery/pkg/api/resource"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/klog/v2"
+    "sigs.k8s.io/yaml"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+const (
+    TopologyAPIVersion = "topology.scheduler/v1alpha1"
+    TopologyKind       = "Topology"
+)
+
+// TopologyDocument is the versioned file format of a cluster topology.
+// Spine domains are listed for completeness; the cache only holds leaves
+// and knows their spine through the parent.
+//
+//  apiVersion: topology.scheduler/v1alpha1
+//  kind: Topology
+//  domains:
+//  - {name: spine-0, level: spine}
+//  - name: leaf-0
+//    level: leaf
+//    parent: spine-0
+//    nodes:
+//    - {name: gpu-001, gpus: 8, rails: [rail-0-a, rail-1-a]}
+//  links:
+//  - {source: leaf-0, target: leaf-1, bandwidthGbps: 400, latency: 2us}
+type TopologyDocument struct {
+    APIVersion string           `json:"apiVersion"`
+    Kind       string           `json:"kind"`
+    Domains    []TopologyDomain `json:"domains"`
+    // Links between leaves. When empty the leaves under the same spine
+    // are connected to each other.
+    Links []TopologyLink `json:"links,omitempty"`
+}
+
+type TopologyDomain struct {
+    Name   string                  `json:"name"`
+    Level  algorithm.TopologyLevel `json:"level"`
+    Parent string                  `json:"parent,omitempty"`
+    Nodes  []TopologyNode          `json:"nodes,omitempty"`
+}
+
+type TopologyNode struct {
+    Name string `json:"name"`
+    GPUs int    `json:"gpus,omitempty"`
+    // Rails lists the switch each NIC of a rail-optimized node connects
+    // to, indexed by rail
+    Rails []string `json:"rails,omitempty"`
+}
+
+// TopologyLink connects the source leaf to the target leaf
+type TopologyLink struct {
+    Source        string          `json:"source"`
+    Target        string          `json:"target"`
+    BandwidthGbps float64         `json:"bandwidthGbps,omitempty"`
+    Latency       *metav1.Duration `json:"latency,omitempty"`
+}
+
+// LoadTopologyFile reads a topology document in YAML or JSON
+func LoadTopologyFile(path string) (*TopologyDocument, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open topology %s: %v", path, err)
+    }
+    defer f.Close()
+
+    doc, err := ReadTopology(f)
+    if err != nil {
+        return nil, fmt.Errorf("failed to load topology %s: %v", path, err)
+    }
+    return doc, nil
+}
+
+// FetchTopology loads a topology document from a file, or over HTTP when
+// source is a URL such as the scheduler's /topology/export
+func FetchTopology(source string) (*TopologyDocument, error) {
+    if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+        return LoadTopologyFile(source)
+    }
+
+    client := &http.Client{Timeout: 10 * time.Second}
+    resp, err := client.Get(source)
+    if err != nil {
+        return nil, fmt.Errorf("failed to fetch topology from %s: %v", source, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("failed to fetch topology from %s: %s", source, resp.Status)
+    }
+
+    doc, err := ReadTopology(resp.Body)
+    if err != nil {
+        return nil, fmt.Errorf("failed to load topology from %s: %v", source, err)
+    }
+    return doc, nil
+}
+
+func ReadTopology(r io.Reader) (*TopologyDocument, error) {
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return nil, err
+    }
+
+    doc := &TopologyDocument{}
+    if err := yaml.UnmarshalStrict(data, doc); err != nil {
+        return nil, fmt.Errorf("invalid topology document: %v", err)
+    }
+    if err := doc.Validate(); err != nil {
+        return nil, err
+    }
+    return doc, nil
+}
+
+// WriteTopology writes the document as YAML, or as JSON when format is
+// "json"
+func WriteTopology(w io.Writer, doc *TopologyDocument, format string) error {
+    var data []byte
+    var err error
+    switch format {
+    case "json":
+        data, err = json.MarshalIndent(doc, "", "  ")
+        data = append(data, '\n')
+    case "", "yaml":
+        data, err = yaml.Marshal(doc)
+    default:
+        return fmt.Errorf("unknown topology format %q", format)
+    }
+    if err != nil {
+        return err
+    }
+    _, err = w.Write(data)
+    return err
+}
+
+func (d *TopologyDocument) Validate() error {
+    if d.APIVersion != TopologyAPIVersion {
+        return fmt.Errorf("unsupported apiVersion %q, expected %s", d.APIVersion, TopologyAPIVersion)
+    }
+    if d.Kind != TopologyKind {
+        return fmt.Errorf("unexpected kind %q, expected %s", d.Kind, TopologyKind)
+    }
+
+    domains := make(map[string]*TopologyDomain)
+    nodes := make(map[string]string)
+    for i := range d.Domains {
+        domain := &d.Domains[i]
+        if domain.Name == "" {
+            return fmt.Errorf("domain %d has no name", i)
+        }
+        if _, exists := domains[domain.Name]; exists {
+            return fmt.Errorf("domain %s is listed twice", domain.Name)
+        }
+        domains[domain.Name] = domain
+
+        switch domain.Level {
+        case algorithm.LevelLeaf:
+        case algorithm.LevelSpine:
+            if len(domain.Nodes) > 0 {
+                return fmt.Errorf("spine %s cannot hold nodes", domain.Name)
+            }
+        default:
+            return fmt.Errorf("domain %s has unknown level %q", domain.Name, domain.Level)
+        }
+
+        for _, node := range domain.Nodes {
+            if node.Name == "" || node.GPUs < 0 {
+                return fmt.Errorf("domain %s has a node without a name or with negative GPUs", domain.Name)
+            }
+            if other, exists := nodes[node.Name]; exists {
+                return fmt.Errorf("node %s is in both %s and %s", node.Name, other, domain.Name)
+            }
+            nodes[node.Name] = domain.Name
+        }
+    }
+
+    for _, domain := range d.Domains {
+        if domain.Parent == "" {
+            continue
+        }
+        parent, ok := domains[domain.Parent]
+        if !ok {
+            return fmt.Errorf("domain %s has unknown parent %s", domain.Name, domain.Parent)
+        }
+        if domain.Level != algorithm.LevelLeaf || parent.Level != algorithm.LevelSpine {
+            return fmt.Errorf("domain %s: only leaves can have a parent, and it must be a spine", domain.Name)
+        }
+    }
+
+    for _, link := range d.Links {
+        for _, end := range []string{link.Source, link.Target} {
+            if domain, ok := domains[end]; !ok || domain.Level != algorithm.LevelLeaf {
+                return fmt.Errorf("link %s -> %s: %s is not a leaf", link.Source, link.Target, end)
+            }
+        }
+        if link.Source == link.Target {
+            return fmt.Errorf("link %s -> %s connects a leaf to itself", link.Source, link.Target)
+        }
+        if link.BandwidthGbps < 0 || (link.Latency != nil && link.Latency.Duration < 0) {
+            return fmt.Errorf("link %s -> %s has negative bandwidth or latency", link.Source, link.Target)
+        }
+    }
+    return nil
+}
+
+// Leaves returns the leaf domains in document order
+func (d *TopologyDocument) Leaves() []TopologyDomain {
+    var leaves []TopologyDomain
+    for _, domain := range d.Domains {
+        if domain.Level == algorithm.LevelLeaf {
+            leaves = append(leaves, domain)
+        }
+    }
+    return leaves
+}
+
+// Nodes creates a ready node with the listed GPUs for every node in the
+// document, for tests and simulations without a cluster
+func (d *TopologyDocument) Nodes() []*v1.Node {
+    var nodes []*v1.Node
+    for _, leaf := range d.Leaves() {
+        for _, spec := range leaf.Nodes {
+            quantity := *resource.NewQuantity(int64(spec.GPUs), resource.DecimalSI)
+            nodes = append(nodes, &v1.Node{
+                ObjectMeta: metav1.ObjectMeta{
+                    Name:   spec.Name,
+                    Labels: map[string]string{"nvidia.com/gpu.count": strconv.Itoa(spec.GPUs)},
+                },
+                Status: v1.NodeStatus{
+                    Capacity:    v1.ResourceList{"nvidia.com/gpu": quantity},
+                    Allocatable: v1.ResourceList{"nvidia.com/gpu": quantity},
+                    Conditions: []v1.NodeCondition{
+                        {Type: v1.NodeReady, Status: v1.ConditionTrue},
+                    },
+                },
+            })
+        }
+    }
+    return nodes
+}
+
+// Populate adds the document's leaves, node membership and links to the
+// cache. nodes are the cluster nodes; listed nodes that are not in the
+// cluster are skipped and cluster nodes the document does not list are
+// returned as undiscovered.
+func (d *TopologyDocument) Populate(cache *algorithm.TopologyCache, nodes []*v1.Node) (*DiscoveredTopology, error) {
+    byName := make(map[string]*v1.Node, len(nodes))
+    for _, node := range nodes {
+        byName[node.Name] = node
+    }
+
+    discovered := newDiscoveredTopology()
+    listed := make(map[string]bool)
+    var missing []string
+    for _, leaf := range d.Leaves() {
+        if leaf.Parent != "" {
+            discovered.Spines[leaf.Name] = []string{leaf.Parent}
+        }
+        discovered.Leaves[leaf.Name] = []*v1.Node{}
+        for _, spec := range leaf.Nodes {
+            listed[spec.Name] = true
+            node, ok := byName[spec.Name]
+            if !ok {
+                missing = append(missing, spec.Name)
+                continue
+            }
+            discovered.Leaves[leaf.Name] = append(discovered.Leaves[leaf.Name], node)
+        }
+    }
+    for _, node := range nodes {
+        if !listed[node.Name] {
+            discovered.Undiscovered = append(discovered.Undiscovered, node.Name)
+        }
+    }
+    sort.Strings(discovered.Undiscovered)
+
+    if len(d.Links) > 0 {
+        discovered.Links = make([]Link, 0, len(d.Links))
+        for _, link := range d.Links {
+            l := Link{Source: link.Source, Target: link.Target, BandwidthGbps: link.BandwidthGbps}
+            if link.Latency != nil {
+                l.Latency = link.Latency.Duration
+            }
+            discovered.Links = append(discovered.Links, l)
+        }
+    }
+
+    if len(missing) > 0 {
+        klog.Warningf("Topology lists nodes that are not in the cluster: %s", strings.Join(missing, ", "))
+    }
+    if err := applyTopology(cache, discovered); err != nil {
+        return nil, err
+    }
+    for _, conflict := range discovered.Conflicts {
+        klog.Warningf("Topology file conflict: %s", conflict)
+    }
+    return discovered, nil
+}
+
+// ExportTopology describes the leaves, nodes and links held in the cache
+func ExportTopology(cache *algorithm.TopologyCache) *TopologyDocument {
+    domains := cache.GetAllDomains()
+    sort.Slice(domains, func(i, j int) bool {
+        return domains[i].Name < domains[j].Name
+    })
+
+    doc := &TopologyDocument{APIVersion: TopologyAPIVersion, Kind: TopologyKind}
+    spines := make(map[string]bool)
+    var leaves []TopologyDomain
+    for _, domain := range domains {
+        if domain.Level != "" && domain.Level != algorithm.LevelLeaf {
+            continue
+        }
+        parent := domain.Parent
+        if parent == "" {
+            parent = domain.SpineSwitch
+        }
+        if parent != "" {
+            spines[parent] = true
+        }
+
+        leaf := TopologyDomain{Name: domain.Name, Level: algorithm.LevelLeaf, Parent: parent}
+        for _, node := range domain.Nodes {
+            gpus := node.Status.Capacity["nvidia.com/gpu"]
+            leaf.Nodes = append(leaf.Nodes, TopologyNode{Name: node.Name, GPUs: int(gpus.Value())})
+        }
+        sort.Slice(leaf.Nodes, func(i, j int) bool {
+            return leaf.Nodes[i].Name < leaf.Nodes[j].Name
+        })
+        leaves = append(leaves, leaf)
+
+        targets, err := cache.GetConnectedDomains(domain.Name)
+        if err != nil {
+            continue
+        }
+        sort.Slice(targets, func(i, j int) bool {
+            return targets[i].Name < targets[j].Name
+        })
+        for _, target := range targets {
+            link := TopologyLink{Source: domain.Name, Target: target.Name}
+            link.BandwidthGbps, _ = cache.GetLinkBandwidth(domain.Name, target.Name)
+            if latency, ok := cache.GetLinkLatency(domain.Name, target.Name); ok {
+                link.Latency = &metav1.Duration{Duration: latency}
+            }
+            doc.Links = append(doc.Links, link)
+        }
+    }
+
+    names := make([]string, 0, len(spines))
+    for name := range spines {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        doc.Domains = append(doc.Domains, TopologyDomain{Name: name, Level: algorithm.LevelSpine})
+    }
+    doc.Domains = append(doc.Domains, leaves...)
+    return doc
+}
+
+// ExportHandler serves the cache as a topology document, in YAML unless
+// the format query parameter asks for json, or for slurm to get a Slurm
+// topology.conf
+func ExportHandler(cache *algorithm.TopologyCache) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet {
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        doc := ExportTopology(cache)
+        format := r.URL.Query().Get("format")
+        var err error
+        switch format {
+        case "slurm":
+            w.Header().Set("Content-Type", "text/plain")
+            err = WriteSlurmTopology(w, doc)
+        case "json":
+            w.Header().Set("Content-Type", "application/json")
+            err = WriteTopology(w, doc, format)
+        default:
+            w.Header().Set("Content-Type", "application/yaml")
+            err = WriteTopology(w, doc, format)
+        }
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+        }
+    })
+}
+
+// RegisterDomains adds the leaves held in the cache to the domain manager
+// used for failure recovery
+func RegisterDomains(cache *algorithm.TopologyCache, dm *algorithm.DomainManager) error {
+    for _, domain := range cache.GetAllDomains() {
+        if err := dm.AddDomain(domain); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,188 @@
+package discovery
+
+import (
+    "bytes"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// topologySample is a topology document as written by ExportTopology: the
+// spines first, then the leaves with their nodes, then the links
+const topologySample = `apiVersion: topology.scheduler/v1alpha1
+kind: Topology
+domains:
+- {name: spine-0, level: spine}
+- name: leaf-0
+  level: leaf
+  parent: spine-0
+  nodes:
+  - {name: gpu-001, gpus: 8}
+  - {name: gpu-002, gpus: 8}
+- name: leaf-1
+  level: leaf
+  parent: spine-0
+  nodes:
+  - {name: gpu-003, gpus: 8}
+links:
+- {source: leaf-0, target: leaf-1, bandwidthGbps: 400, latency: 2us}
+- {source: leaf-1, target: leaf-0, bandwidthGbps: 400, latency: 2us}
+`
+
+func TestReadTopology(t *testing.T) {
+    header := "apiVersion: topology.scheduler/v1alpha1\nkind: Topology\n"
+    tests := []struct {
+        name    string
+        input   string
+        wantErr string
+    }{
+        {name: "sample", input: topologySample},
+        {
+            name:  "json",
+            input: `{"apiVersion":"topology.scheduler/v1alpha1","kind":"Topology","domains":[{"name":"leaf-0","level":"leaf","nodes":[{"name":"gpu-001","gpus":8,"rails":["rail-0-a","rail-1-a"]}]}]}`,
+        },
+        {
+            name:    "unknown field",
+            input:   header + "domains:\n- {name: leaf-0, level: leaf, switch: leaf-0}\n",
+            wantErr: "invalid topology document",
+        },
+        {
+            name:    "wrong apiVersion",
+            input:   "apiVersion: topology.scheduler/v1\nkind: Topology\n",
+            wantErr: "unsupported apiVersion",
+        },
+        {
+            name:    "wrong kind",
+            input:   "apiVersion: topology.scheduler/v1alpha1\nkind: Cluster\n",
+            wantErr: "unexpected kind",
+        },
+        {
+            name:    "unknown level",
+            input:   header + "domains:\n- {name: core-0, level: core}\n",
+            wantErr: "unknown level",
+        },
+        {
+            name:    "domain listed twice",
+            input:   header + "domains:\n- {name: leaf-0, level: leaf}\n- {name: leaf-0, level: leaf}\n",
+            wantErr: "listed twice",
+        },
+        {
+            name:    "spine with nodes",
+            input:   header + "domains:\n- name: spine-0\n  level: spine\n  nodes: [{name: gpu-001}]\n",
+            wantErr: "cannot hold nodes",
+        },
+        {
+            name:    "node in two leaves",
+            input:   header + "domains:\n- {name: leaf-0, level: leaf, nodes: [{name: gpu-001}]}\n- {name: leaf-1, level: leaf, nodes: [{name: gpu-001}]}\n",
+            wantErr: "is in both leaf-0 and leaf-1",
+        },
+        {
+            name:    "negative GPUs",
+            input:   header + "domains:\n- {name: leaf-0, level: leaf, nodes: [{name: gpu-001, gpus: -8}]}\n",
+            wantErr: "negative GPUs",
+        },
+        {
+            name:    "unknown parent",
+            input:   header + "domains:\n- {name: leaf-0, level: leaf, parent: spine-9}\n",
+            wantErr: "unknown parent",
+        },
+        {
+            name:    "leaf under a leaf",
+            input:   header + "domains:\n- {name: leaf-0, level: leaf}\n- {name: leaf-1, level: leaf, parent: leaf-0}\n",
+            wantErr: "must be a spine",
+        },
+        {
+            name:    "link to a spine",
+            input:   header + "domains:\n- {name: spine-0, level: spine}\n- {name: leaf-0, level: leaf}\nlinks:\n- {source: leaf-0, target: spine-0}\n",
+            wantErr: "spine-0 is not a leaf",
+        },
+        {
+            name:    "link to itself",
+            input:   header + "domains:\n- {name: leaf-0, level: leaf}\nlinks:\n- {source: leaf-0, target: leaf-0}\n",
+            wantErr: "to itself",
+        },
+        {
+            name:    "negative latency",
+            input:   header + "domains:\n- {name: leaf-0, level: leaf}\n- {name: leaf-1, level: leaf}\nlinks:\n- {source: leaf-0, target: leaf-1, latency: -2us}\n",
+            wantErr: "negative bandwidth or latency",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := ReadTopology(strings.NewReader(tt.input))
+            if tt.wantErr == "" {
+                if err != nil {
+                    t.Fatalf("ReadTopology() error = %v", err)
+                }
+                return
+            }
+            if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("ReadTopology() error = %v, want one containing %q", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestReadTopologySample(t *testing.T) {
+    doc, err := ReadTopology(strings.NewReader(topologySample))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    leaves := doc.Leaves()
+    if len(leaves) != 2 || leaves[0].Name != "leaf-0" || leaves[1].Name != "leaf-1" {
+        t.Fatalf("Leaves() = %+v, want leaf-0 and leaf-1", leaves)
+    }
+    if got := nodeNames(doc.Nodes()); !reflect.DeepEqual(got, []string{"gpu-001", "gpu-002", "gpu-003"}) {
+        t.Errorf("Nodes() = %v", got)
+    }
+    link := doc.Links[0]
+    if link.BandwidthGbps != 400 || link.Latency == nil || link.Latency.Duration != 2*time.Microsecond {
+        t.Errorf("link = %+v, want 400 Gbps and 2us", link)
+    }
+}
+
+// TestTopologyRoundTrip populates a cache from the sample and checks that
+// exporting it, in both formats, reads back to the same document
+func TestTopologyRoundTrip(t *testing.T) {
+    doc, err := ReadTopology(strings.NewReader(topologySample))
+    if err != nil {
+        t.Fatal(err)
+    }
+    cache := algorithm.NewTopologyCache(algorithm.NewNodeCache())
+    nodes := append(doc.Nodes(), testNode("gpu-004", nil, nil))
+    discovered, err := doc.Populate(cache, nodes)
+    if err != nil {
+        t.Fatalf("Populate() error = %v", err)
+    }
+    if want := []string{"gpu-004"}; !reflect.DeepEqual(discovered.Undiscovered, want) {
+        t.Errorf("undiscovered = %v, want %v", discovered.Undiscovered, want)
+    }
+
+    exported := ExportTopology(cache)
+    if !reflect.DeepEqual(exported, doc) {
+        t.Errorf("ExportTopology() = %+v, want %+v", exported, doc)
+    }
+
+    for _, format := range []string{"yaml", "json"} {
+        var buf bytes.Buffer
+        if err := WriteTopology(&buf, exported, format); err != nil {
+            t.Fatalf("WriteTopology(%s) error = %v", format, err)
+        }
+        read, err := ReadTopology(&buf)
+        if err != nil {
+            t.Fatalf("ReadTopology(%s) error = %v", format, err)
+        }
+        if !reflect.DeepEqual(read, doc) {
+            t.Errorf("%s round trip = %+v, want %+v", format, read, doc)
+        }
+    }
+
+    if err := WriteTopology(&bytes.Buffer{}, exported, "toml"); err == nil {
+        t.Error("WriteTopology() accepted an unknown format")
+    }
+}
@@ -0,0 +1,474 @@
+package discovery
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/client-go/kubernetes"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// HCAGUIDsAnnotation lists the port or node GUIDs of a node's HCAs,
+// separated by commas
+const HCAGUIDsAnnotation = "topology.scheduler/hca-guids"
+
+type IBDeviceType string
+
+const (
+    IBSwitch IBDeviceType = "switch"
+    IBHost   IBDeviceType = "ca"
+)
+
+// laneGbps is the signalling rate of one lane for each link speed
+var laneGbps = map[string]float64{
+    "SDR":   2.5,
+    "DDR":   5,
+    "QDR":   10,
+    "FDR10": 10.3125,
+    "FDR":   14.0625,
+    "EDR":   25.78125,
+    "HDR":   53.125,
+    "NDR":   106.25,
+    "XDR":   212.5,
+}
+
+// IBDevice is a switch or channel adapter in the fabric
+type IBDevice struct {
+    GUID        string
+    Type        IBDeviceType
+    Description string
+    // PortGUIDs of a channel adapter, used to match the node annotation
+    PortGUIDs []string
+}
+
+// Name returns the switch name from a description such as
+// "MF0;leaf01:MSX6036/U1", or the description itself
+func (d *IBDevice) Name() string {
+    desc := d.Description
+    if i := strings.Index(desc, ";"); i >= 0 {
+        desc = desc[i+1:]
+        if j := strings.Index(desc, ":"); j >= 0 {
+            desc = desc[:j]
+        }
+    }
+    if desc == "" {
+        return "S-" + d.GUID
+    }
+    return desc
+}
+
+// IBLink is a cable between two device ports
+type IBLink struct {
+    From     string
+    FromPort int
+    To       string
+    ToPort   int
+    Width    int
+    LaneGbps float64
+}
+
+// Bandwidth returns the link rate in Gbps
+func (l IBLink) Bandwidth() float64 {
+    return float64(l.Width) * l.LaneGbps
+}
+
+// IBFabric is the device graph read from a fabric dump, keyed by GUID
+type IBFabric struct {
+    Devices map[string]*IBDevice
+    Links   []IBLink
+    seen    map[string]bool
+}
+
+func newIBFabric() *IBFabric {
+    return &IBFabric{
+        Devices: make(map[string]*IBDevice),
+        seen:    make(map[string]bool),
+    }
+}
+
+// addLink records a link once even when both ends list it
+func (f *IBFabric) addLink(link IBLink) {
+    a := fmt.Sprintf("%s/%d", link.From, link.FromPort)
+    b := fmt.Sprintf("%s/%d", link.To, link.ToPort)
+    if a > b {
+        a, b = b, a
+    }
+    if f.seen[a+"-"+b] {
+        return
+    }
+    f.seen[a+"-"+b] = true
+    f.Links = append(f.Links, link)
+}
+
+// device returns the device with the GUID, creating it when first seen
+func (f *IBFabric) device(guid string, typ IBDeviceType) *IBDevice {
+    dev, ok := f.Devices[guid]
+    if !ok {
+        dev = &IBDevice{GUID: guid, Type: typ}
+        f.Devices[guid] = dev
+    }
+    return dev
+}
+
+// LoadIBFabric reads an ibnetdiscover or iblinkinfo dump
+func LoadIBFabric(path string) (*IBFabric, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read fabric dump %s: %v", path, err)
+    }
+
+    var fabric *IBFabric
+    if bytes.Contains(data, []byte("==(")) {
+        fabric, err = ParseIBLinkInfo(bytes.NewReader(data))
+    } else {
+        fabric, err = ParseIBNetDiscover(bytes.NewReader(data))
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse fabric dump %s: %v", path, err)
+    }
+    return fabric, nil
+}
+
+var (
+    ibndDevice = regexp.MustCompile(`^(Switch|Ca|Rt)\s+\d+\s+"[SHR]-([0-9a-fA-F]+)"\s*#\s*"([^"]*)"`)
+    ibndPort   = regexp.MustCompile(`^\[(\d+)\](?:\(([0-9a-fA-F]+)\))?\s+"([SHR])-([0-9a-fA-F]+)"\[(\d+)\](?:\(([0-9a-fA-F]+)\))?\s*#\s*(.*)$`)
+    ibSpeed    = regexp.MustCompile(`(\d+)[xX]\s*([A-Z]+[0-9]*)\s*$`)
+)
+
+// ParseIBNetDiscover reads the output of ibnetdiscover. Every device block
+// starts with a Switch or Ca line followed by one line per connected port.
+func ParseIBNetDiscover(r io.Reader) (*IBFabric, error) {
+    fabric := newIBFabric()
+    var current *IBDevice
+
+    scanner := bufio.NewScanner(r)
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+
+        if m := ibndDevice.FindStringSubmatch(line); m != nil {
+            typ := IBHost
+            if m[1] == "Switch" {
+                typ = IBSwitch
+            }
+            current = fabric.device(normalizeGUID(m[2]), typ)
+            current.Description = m[3]
+            continue
+        }
+
+        m := ibndPort.FindStringSubmatch(line)
+        if m == nil {
+            continue
+        }
+        if current == nil {
+            return nil, fmt.Errorf("line %d: port before any device", lineNo)
+        }
+        width, lane, err := parseIBSpeed(m[7])
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %v", lineNo, err)
+        }
+
+        localPort, _ := strconv.Atoi(m[1])
+        remotePort, _ := strconv.Atoi(m[5])
+        peerType := IBHost
+        if m[3] == "S" {
+            peerType = IBSwitch
+        }
+        peer := fabric.device(normalizeGUID(m[4]), peerType)
+        if peerType == IBHost && m[6] != "" {
+            peer.PortGUIDs = appendUnique(peer.PortGUIDs, normalizeGUID(m[6]))
+        }
+        if current.Type == IBHost && m[2] != "" {
+            current.PortGUIDs = appendUnique(current.PortGUIDs, normalizeGUID(m[2]))
+        }
+        // The comment of a switch port names the peer, use it until the
+        // peer's own block is read
+        if peer.Description == "" {
+            if desc := quoted(m[7]); desc != "" {
+                peer.Description = desc
+            }
+        }
+
+        fabric.addLink(IBLink{
+            From:     current.GUID,
+            FromPort: localPort,
+            To:       peer.GUID,
+            ToPort:   remotePort,
+            Width:    width,
+            LaneGbps: lane,
+        })
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return fabric, nil
+}
+
+var (
+    iblSwitch = regexp.MustCompile(`^Switch:?\s+(?:0x)?([0-9a-fA-F]+)\s+(.*?):?\s*$`)
+    iblPort   = regexp.MustCompile(`^(\d+)\s+(\d+)\[[^\]]*\]\s+==\(\s*(\d+)[xX]\s+([0-9.]+)\s*Gbps\s+Active\S*\s*/\s*\S+\)==>\s+(\d+)\s+(\d+)\[[^\]]*\]\s+"([^"]*)"`)
+)
+
+// ParseIBLinkInfo reads the output of iblinkinfo. Only switch sections are
+// used. iblinkinfo does not print the GUIDs of channel adapters, so hosts
+// are keyed by LID and matched to nodes by their description.
+func ParseIBLinkInfo(r io.Reader) (*IBFabric, error) {
+    fabric := newIBFabric()
+    type pendingLink struct {
+        link    IBLink
+        peerLID string
+        desc    string
+    }
+    var pending []pendingLink
+    switchByLID := make(map[string]string)
+    var current *IBDevice
+
+    scanner := bufio.NewScanner(r)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if m := iblSwitch.FindStringSubmatch(line); m != nil {
+            current = fabric.device(normalizeGUID(m[1]), IBSwitch)
+            current.Description = m[2]
+            continue
+        }
+        if strings.HasPrefix(line, "CA:") {
+            current = nil
+            continue
+        }
+
+        m := iblPort.FindStringSubmatch(line)
+        if m == nil || current == nil {
+            continue
+        }
+        switchByLID[m[1]] = current.GUID
+        width, _ := strconv.Atoi(m[3])
+        lane, err := strconv.ParseFloat(m[4], 64)
+        if err != nil {
+            return nil, fmt.Errorf("invalid link speed %q", m[4])
+        }
+        localPort, _ := strconv.Atoi(m[2])
+        remotePort, _ := strconv.Atoi(m[6])
+        pending = append(pending, pendingLink{
+            link: IBLink{
+                From:     current.GUID,
+                FromPort: localPort,
+                ToPort:   remotePort,
+                Width:    width,
+                LaneGbps: lane,
+            },
+            peerLID: m[5],
+            desc:    m[7],
+        })
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+
+    // Peers are resolved once every switch LID is known
+    for _, p := range pending {
+        if guid, ok := switchByLID[p.peerLID]; ok {
+            p.link.To = guid
+        } else {
+            host := fabric.device("lid-"+p.peerLID, IBHost)
+            host.Description = p.desc
+            p.link.To = host.GUID
+        }
+        fabric.addLink(p.link)
+    }
+    return fabric, nil
+}
+
+// Topology derives leaf membership, spine uplinks and their bandwidth.
+// Leaves are switches with hosts attached and spines are the switches the
+// leaves connect to. Channel adapters are matched to nodes through the
+// HCA GUID annotation, or by the host name at the start of their
+// description.
+func (f *IBFabric) Topology(nodes []*v1.Node, schema *algorithm.NodeLabelSchema) *DiscoveredTopology {
+    result := newDiscoveredTopology()
+
+    byGUID := make(map[string]*v1.Node)
+    byName := make(map[string]*v1.Node)
+    for _, node := range nodes {
+        byName[node.Name] = node
+        for _, guid := range strings.Split(node.Annotations[HCAGUIDsAnnotation], ",") {
+            if guid = strings.TrimSpace(guid); guid != "" {
+                byGUID[normalizeGUID(guid)] = node
+            }
+        }
+    }
+
+    leaves := make(map[string]bool)
+    for _, link := range f.Links {
+        from, to := f.Devices[link.From], f.Devices[link.To]
+        if from.Type == IBSwitch && to.Type == IBHost {
+            leaves[from.GUID] = true
+        } else if from.Type == IBHost && to.Type == IBSwitch {
+            leaves[to.GUID] = true
+        }
+    }
+
+    counts := make(map[string]map[string]int)
+    for _, link := range f.Links {
+        from, to := f.Devices[link.From], f.Devices[link.To]
+        if from.Type == IBHost {
+            from, to = to, from
+        }
+        switch {
+        case from.Type == IBSwitch && to.Type == IBHost:
+            node := f.nodeFor(to, byGUID, byName)
+            if node == nil {
+                klog.V(4).Infof("No node for HCA %s %q", to.GUID, to.Description)
+                continue
+            }
+            if counts[node.Name] == nil {
+                counts[node.Name] = make(map[string]int)
+            }
+            counts[node.Name][from.Name()]++
+        case leaves[from.GUID] && !leaves[to.GUID]:
+            result.addUplink(from.Name(), to.Name(), link.Bandwidth())
+        case leaves[to.GUID] && !leaves[from.GUID]:
+            result.addUplink(to.Name(), from.Name(), link.Bandwidth())
+        }
+    }
+
+    for _, node := range nodes {
+        result.assign(node, counts[node.Name], schema)
+    }
+    for leaf := range result.Spines {
+        sort.Strings(result.Spines[leaf])
+    }
+    sort.Strings(result.Undiscovered)
+    return result
+}
+
+func (d *DiscoveredTopology) addUplink(leaf, spine string, gbps float64) {
+    if d.Uplinks[leaf] == nil {
+        d.Uplinks[leaf] = make(map[string]float64)
+    }
+    if _, ok := d.Uplinks[leaf][spine]; !ok {
+        d.Spines[leaf] = append(d.Spines[leaf], spine)
+    }
+    d.Uplinks[leaf][spine] += gbps
+}
+
+func (f *IBFabric) nodeFor(dev *IBDevice, byGUID, byName map[string]*v1.Node) *v1.Node {
+    if node, ok := byGUID[dev.GUID]; ok {
+        return node
+    }
+    for _, guid := range dev.PortGUIDs {
+        if node, ok := byGUID[guid]; ok {
+            return node
+        }
+    }
+    if fields := strings.Fields(dev.Description); len(fields) > 0 {
+        return byName[fields[0]]
+    }
+    return nil
+}
+
+// IBSource loads an InfiniBand fabric dump into the topology cache
+type IBSource struct {
+    cache  *algorithm.TopologyCache
+    schema *algorithm.NodeLabelSchema
+}
+
+func NewIBSource(cache *algorithm.TopologyCache) *IBSource {
+    return &IBSource{cache: cache}
+}
+
+// SetLabelSchema enables checking discovered leaves against manual labels
+func (s *IBSource) SetLabelSchema(schema *algorithm.NodeLabelSchema) {
+    s.schema = schema
+}
+
+// Import applies the fabric topology of the nodes with link bandwidths and
+// logs conflicts
+func (s *IBSource) Import(fabric *IBFabric, nodes []*v1.Node) (*DiscoveredTopology, error) {
+    discovered := fabric.Topology(nodes, s.schema)
+    if err := applyTopology(s.cache, discovered); err != nil {
+        return nil, err
+    }
+
+    for _, conflict := range discovered.Conflicts {
+        klog.Warningf("InfiniBand import conflict: %s", conflict)
+    }
+    if len(discovered.Undiscovered) > 0 {
+        klog.V(2).Infof("No HCA in the fabric for nodes %s", strings.Join(discovered.Undiscovered, ", "))
+    }
+    return discovered, nil
+}
+
+// ImportFile lists the nodes and imports the fabric dump at path
+func (s *IBSource) ImportFile(ctx context.Context, client kubernetes.Interface, path string) (*DiscoveredTopology, error) {
+    fabric, err := LoadIBFabric(path)
+    if err != nil {
+        return nil, err
+    }
+
+    nodes, err := listNodes(ctx, client)
+    if err != nil {
+        return nil, err
+    }
+    return s.Import(fabric, nodes)
+}
+
+// parseIBSpeed reads the width and lane rate at the end of an
+// ibnetdiscover port comment, such as 4xEDR
+func parseIBSpeed(comment string) (int, float64, error) {
+    m := ibSpeed.FindStringSubmatch(comment)
+    if m == nil {
+        return 0, 0, fmt.Errorf("no link speed in %q", comment)
+    }
+    width, _ := strconv.Atoi(m[1])
+    lane, ok := laneGbps[m[2]]
+    if !ok {
+        return 0, 0, fmt.Errorf("unknown link speed %s", m[2])
+    }
+    return width, lane, nil
+}
+
+// normalizeGUID strips the 0x prefix and pads to 16 lower-case hex digits
+func normalizeGUID(guid string) string {
+    guid = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(guid, "0x"), "0X"))
+    if len(guid) < 16 {
+        guid = strings.Repeat("0", 16-len(guid)) + guid
+    }
+    return guid
+}
+
+// quoted returns the first double-quoted string in s
+func quoted(s string) string {
+    start := strings.Index(s, `"`)
+    if start < 0 {
+        return ""
+    }
+    end := strings.Index(s[start+1:], `"`)
+    if end < 0 {
+        return ""
+    }
+    return s[start+1 : start+1+end]
+}
+
+func appendUnique(list []string, val string) []string {
+    for _, v := range list {
+        if v == val {
+            return list
+        }
+    }
+    return append(list, val)
+}
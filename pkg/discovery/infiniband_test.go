@@ -0,0 +1,290 @@
+package discovery
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "strings"
+    "testing"
+
+    v1 "k8s.io/api/core/v1"
+)
+
+// ibnetdiscoverSample is ibnetdiscover output of two NDR leaves under two
+// spines. leaf01 has two cables to spine01 and an HDR cable to spine02.
+// gpu-003 was not given a host name, so its HCA is only known by GUID.
+const ibnetdiscoverSample = `#
+# Topology file: generated on Mon Oct 16 10:00:00 2023
+#
+# Initiated from node 0c42a10300a1b2c0 port 0c42a10300a1b2c1
+
+vendid=0x2c9
+devid=0xd2f0
+sysimgguid=0x1070fd0300aa0000
+switchguid=0x1070fd0300aa0000(1070fd0300aa0000)
+Switch	64 "S-1070fd0300aa0000"		# "MF0;spine01:MQM9700/U1" enhanced port 0 lid 1 lmc 0
+[1]	"S-1070fd0300bb0000"[33]		# "MF0;leaf01:MQM9700/U1" lid 3 4xNDR
+[2]	"S-1070fd0300bb0000"[34]		# "MF0;leaf01:MQM9700/U1" lid 3 4xNDR
+[3]	"S-1070fd0300cc0000"[33]		# "MF0;leaf02:MQM9700/U1" lid 4 4xNDR
+
+vendid=0x2c9
+devid=0xd2f0
+sysimgguid=0x1070fd0300dd0000
+switchguid=0x1070fd0300dd0000(1070fd0300dd0000)
+Switch	40 "S-1070fd0300dd0000"		# "MF0;spine02:MQM8700/U1" enhanced port 0 lid 2 lmc 0
+[1]	"S-1070fd0300bb0000"[35]		# "MF0;leaf01:MQM9700/U1" lid 3 4xHDR
+
+vendid=0x2c9
+devid=0xd2f0
+sysimgguid=0x1070fd0300bb0000
+switchguid=0x1070fd0300bb0000(1070fd0300bb0000)
+Switch	64 "S-1070fd0300bb0000"		# "MF0;leaf01:MQM9700/U1" enhanced port 0 lid 3 lmc 0
+[1]	"H-0c42a10300a1b2c0"[1](0c42a10300a1b2c1) 		# "gpu-001 mlx5_0" lid 10 4xNDR
+[2]	"H-0c42a10300a1b2d0"[1](0c42a10300a1b2d1) 		# "gpu-002 mlx5_0" lid 11 4xNDR
+[33]	"S-1070fd0300aa0000"[1]		# "MF0;spine01:MQM9700/U1" lid 1 4xNDR
+[34]	"S-1070fd0300aa0000"[2]		# "MF0;spine01:MQM9700/U1" lid 1 4xNDR
+[35]	"S-1070fd0300dd0000"[1]		# "MF0;spine02:MQM8700/U1" lid 2 4xHDR
+
+vendid=0x2c9
+devid=0xd2f0
+sysimgguid=0x1070fd0300cc0000
+switchguid=0x1070fd0300cc0000(1070fd0300cc0000)
+Switch	64 "S-1070fd0300cc0000"		# "MF0;leaf02:MQM9700/U1" enhanced port 0 lid 4 lmc 0
+[1]	"H-0c42a10300a1b2e0"[1](0c42a10300a1b2e1) 		# "localhost mlx5_0" lid 12 4xNDR
+[33]	"S-1070fd0300aa0000"[3]		# "MF0;spine01:MQM9700/U1" lid 1 4xNDR
+
+vendid=0x15b3
+devid=0x1021
+sysimgguid=0x0c42a10300a1b2c0
+caguid=0x0c42a10300a1b2c0
+Ca	1 "H-0c42a10300a1b2c0"		# "gpu-001 mlx5_0"
+[1](0c42a10300a1b2c1) 	"S-1070fd0300bb0000"[1]		# lid 10 lmc 0 "MF0;leaf01:MQM9700/U1" lid 3 4xNDR
+
+vendid=0x15b3
+devid=0x1021
+sysimgguid=0x0c42a10300a1b2e0
+caguid=0x0c42a10300a1b2e0
+Ca	1 "H-0c42a10300a1b2e0"		# "localhost mlx5_0"
+[1](0c42a10300a1b2e1) 	"S-1070fd0300cc0000"[1]		# lid 12 lmc 0 "MF0;leaf02:MQM9700/U1" lid 4 4xNDR
+`
+
+// iblinkinfoSample is iblinkinfo output of the same fabric without spine02
+const iblinkinfoSample = `CA: gpu-001 mlx5_0:
+      0x0c42a10300a1b2c1     10    1[  ] ==( 4X       106.25 Gbps Active/  LinkUp)==>       3    1[  ] "MF0;leaf01:MQM9700/U1" ( )
+Switch: 0x1070fd0300aa0000 MF0;spine01:MQM9700/U1:
+           1    1[  ] ==( 4X       106.25 Gbps Active/  LinkUp)==>       3   33[  ] "MF0;leaf01:MQM9700/U1" ( )
+           1    2[  ] ==( 4X       106.25 Gbps Active/  LinkUp)==>       3   34[  ] "MF0;leaf01:MQM9700/U1" ( )
+           1    3[  ] ==( 4X       106.25 Gbps Active/  LinkUp)==>       4   33[  ] "MF0;leaf02:MQM9700/U1" ( )
+           1    4[  ] ==(                Down/ Polling)==>             [  ] "" ( )
+Switch: 0x1070fd0300bb0000 MF0;leaf01:MQM9700/U1:
+           3    1[  ] ==( 4X       106.25 Gbps Active/  LinkUp)==>      10    1[  ] "gpu-001 mlx5_0" ( )
+           3    2[  ] ==( 4X       106.25 Gbps Active/  LinkUp)==>      11    1[  ] "gpu-002 mlx5_0" ( )
+           3   33[  ] ==( 4X       106.25 Gbps Active/  LinkUp)==>       1    1[  ] "MF0;spine01:MQM9700/U1" ( )
+           3   34[  ] ==( 4X       106.25 Gbps Active/  LinkUp)==>       1    2[  ] "MF0;spine01:MQM9700/U1" ( )
+Switch: 0x1070fd0300cc0000 MF0;leaf02:MQM9700/U1:
+           4    1[  ] ==( 4X        53.125 Gbps Active/  LinkUp)==>      12    1[  ] "gpu-003 mlx5_0" ( )
+           4   33[  ] ==( 4X       106.25 Gbps Active/  LinkUp)==>       1    3[  ] "MF0;spine01:MQM9700/U1" ( )
+`
+
+// linkNames lists the links of the fabric by device name and port, with
+// their bandwidth, sorted
+func linkNames(f *IBFabric) []string {
+    var links []string
+    for _, link := range f.Links {
+        a := fmt.Sprintf("%s/%d", f.Devices[link.From].Name(), link.FromPort)
+        b := fmt.Sprintf("%s/%d", f.Devices[link.To].Name(), link.ToPort)
+        if a > b {
+            a, b = b, a
+        }
+        links = append(links, fmt.Sprintf("%s %s %g", a, b, link.Bandwidth()))
+    }
+    sort.Strings(links)
+    return links
+}
+
+func TestParseIBNetDiscover(t *testing.T) {
+    fabric, err := ParseIBNetDiscover(strings.NewReader(ibnetdiscoverSample))
+    if err != nil {
+        t.Fatalf("ParseIBNetDiscover() error = %v", err)
+    }
+
+    want := []string{
+        "gpu-001 mlx5_0/1 leaf01/1 425",
+        "gpu-002 mlx5_0/1 leaf01/2 425",
+        "leaf01/33 spine01/1 425",
+        "leaf01/34 spine01/2 425",
+        "leaf01/35 spine02/1 212.5",
+        "leaf02/1 localhost mlx5_0/1 425",
+        "leaf02/33 spine01/3 425",
+    }
+    if got := linkNames(fabric); !reflect.DeepEqual(got, want) {
+        t.Errorf("links = %q, want %q", got, want)
+    }
+
+    devices := map[string]IBDeviceType{
+        "1070fd0300aa0000": IBSwitch,
+        "1070fd0300bb0000": IBSwitch,
+        "1070fd0300cc0000": IBSwitch,
+        "1070fd0300dd0000": IBSwitch,
+        "0c42a10300a1b2c0": IBHost,
+        "0c42a10300a1b2d0": IBHost,
+        "0c42a10300a1b2e0": IBHost,
+    }
+    if len(fabric.Devices) != len(devices) {
+        t.Errorf("got %d devices, want %d", len(fabric.Devices), len(devices))
+    }
+    for guid, typ := range devices {
+        if dev, ok := fabric.Devices[guid]; !ok || dev.Type != typ {
+            t.Errorf("device %s = %+v, want type %s", guid, dev, typ)
+        }
+    }
+    if got := fabric.Devices["0c42a10300a1b2e0"].PortGUIDs; !reflect.DeepEqual(got, []string{"0c42a10300a1b2e1"}) {
+        t.Errorf("port GUIDs of gpu-003 HCA = %v", got)
+    }
+}
+
+func TestParseIBNetDiscoverErrors(t *testing.T) {
+    tests := []struct {
+        name  string
+        input string
+    }{
+        {
+            name:  "port before any device",
+            input: `[1]	"S-1070fd0300aa0000"[33]		# "MF0;spine01:MQM9700/U1" lid 1 4xNDR` + "\n",
+        },
+        {
+            name: "unknown link speed",
+            input: `Switch	64 "S-1070fd0300bb0000"		# "MF0;leaf01:MQM9700/U1" enhanced port 0 lid 3 lmc 0
+[33]	"S-1070fd0300aa0000"[1]		# "MF0;spine01:MQM9700/U1" lid 1 4xGDR
+`,
+        },
+        {
+            name: "missing link speed",
+            input: `Switch	64 "S-1070fd0300bb0000"		# "MF0;leaf01:MQM9700/U1" enhanced port 0 lid 3 lmc 0
+[33]	"S-1070fd0300aa0000"[1]		# "MF0;spine01:MQM9700/U1" lid 1
+`,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, err := ParseIBNetDiscover(strings.NewReader(tt.input)); err == nil {
+                t.Error("ParseIBNetDiscover() succeeded, want an error")
+            }
+        })
+    }
+}
+
+func TestParseIBLinkInfo(t *testing.T) {
+    fabric, err := ParseIBLinkInfo(strings.NewReader(iblinkinfoSample))
+    if err != nil {
+        t.Fatalf("ParseIBLinkInfo() error = %v", err)
+    }
+
+    want := []string{
+        "gpu-001 mlx5_0/1 leaf01/1 425",
+        "gpu-002 mlx5_0/1 leaf01/2 425",
+        "gpu-003 mlx5_0/1 leaf02/1 212.5",
+        "leaf01/33 spine01/1 425",
+        "leaf01/34 spine01/2 425",
+        "leaf02/33 spine01/3 425",
+    }
+    if got := linkNames(fabric); !reflect.DeepEqual(got, want) {
+        t.Errorf("links = %q, want %q", got, want)
+    }
+    if dev, ok := fabric.Devices["lid-12"]; !ok || dev.Type != IBHost {
+        t.Errorf("host with LID 12 = %+v, want a channel adapter", dev)
+    }
+}
+
+func TestIBFabricTopology(t *testing.T) {
+    nodes := []*v1.Node{
+        testNode("gpu-001", nil, nil),
+        testNode("gpu-002", nil, nil),
+        testNode("gpu-003", map[string]string{HCAGUIDsAnnotation: "0x0C42A10300A1B2E1, 0x0c42a10300a1b2e9"}, nil),
+        testNode("gpu-004", nil, nil),
+    }
+
+    tests := []struct {
+        name         string
+        dump         string
+        leaves       map[string][]string
+        spines       map[string][]string
+        uplinks      map[string]map[string]float64
+        undiscovered []string
+    }{
+        {
+            name:   "ibnetdiscover",
+            dump:   ibnetdiscoverSample,
+            leaves: map[string][]string{"leaf01": {"gpu-001", "gpu-002"}, "leaf02": {"gpu-003"}},
+            spines: map[string][]string{"leaf01": {"spine01", "spine02"}, "leaf02": {"spine01"}},
+            uplinks: map[string]map[string]float64{
+                "leaf01": {"spine01": 850, "spine02": 212.5},
+                "leaf02": {"spine01": 425},
+            },
+            undiscovered: []string{"gpu-004"},
+        },
+        {
+            name:   "iblinkinfo matches hosts by description",
+            dump:   iblinkinfoSample,
+            leaves: map[string][]string{"leaf01": {"gpu-001", "gpu-002"}, "leaf02": {"gpu-003"}},
+            spines: map[string][]string{"leaf01": {"spine01"}, "leaf02": {"spine01"}},
+            uplinks: map[string]map[string]float64{
+                "leaf01": {"spine01": 850},
+                "leaf02": {"spine01": 425},
+            },
+            undiscovered: []string{"gpu-004"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "fabric.txt")
+            if err := os.WriteFile(path, []byte(tt.dump), 0o644); err != nil {
+                t.Fatal(err)
+            }
+            fabric, err := LoadIBFabric(path)
+            if err != nil {
+                t.Fatalf("LoadIBFabric() error = %v", err)
+            }
+
+            discovered := fabric.Topology(nodes, nil)
+            leaves := make(map[string][]string)
+            for leaf, members := range discovered.Leaves {
+                leaves[leaf] = nodeNames(members)
+            }
+            if !reflect.DeepEqual(leaves, tt.leaves) {
+                t.Errorf("leaves = %v, want %v", leaves, tt.leaves)
+            }
+            if !reflect.DeepEqual(discovered.Spines, tt.spines) {
+                t.Errorf("spines = %v, want %v", discovered.Spines, tt.spines)
+            }
+            if !reflect.DeepEqual(discovered.Uplinks, tt.uplinks) {
+                t.Errorf("uplinks = %v, want %v", discovered.Uplinks, tt.uplinks)
+            }
+            if !reflect.DeepEqual(discovered.Undiscovered, tt.undiscovered) {
+                t.Errorf("undiscovered = %v, want %v", discovered.Undiscovered, tt.undiscovered)
+            }
+            if len(discovered.Conflicts) > 0 {
+                t.Errorf("unexpected conflicts %v", discovered.Conflicts)
+            }
+        })
+    }
+}
+
+func TestNormalizeGUID(t *testing.T) {
+    tests := []struct {
+        in, want string
+    }{
+        {"0x0C42A10300A1B2E1", "0c42a10300a1b2e1"},
+        {"0X1070fd0300aa0000", "1070fd0300aa0000"},
+        {"c42a10300a1b2e1", "0c42a10300a1b2e1"},
+        {"1", "0000000000000001"},
+    }
+    for _, tt := range tests {
+        if got := normalizeGUID(tt.in); got != tt.want {
+            t.Errorf("normalizeGUID(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,242 @@
+package discovery
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/client-go/kubernetes"
+    "k8s.io/klog/v2"
+    "sigs.k8s.io/yaml"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+const (
+    // LLDPAnnotation holds a JSON list of Neighbor seen by the node
+    LLDPAnnotation = "topology.scheduler/lldp-neighbors"
+
+    // NFDLabelPrefix starts the labels Node Feature Discovery publishes for
+    // a feature file with lines such as lldp.eth0.sysname=leaf-12. The
+    // fields are sysname, chassis and port.
+    NFDLabelPrefix = "feature.node.kubernetes.io/lldp."
+)
+
+// Neighbor is one LLDP neighbour seen on a local interface
+type Neighbor struct {
+    Interface  string `json:"interface"`
+    SystemName string `json:"systemName"`
+    ChassisID  string `json:"chassisID,omitempty"`
+    PortID     string `json:"portID,omitempty"`
+}
+
+// NodeNeighbors reads the LLDP neighbours of a node from the annotation,
+// falling back to the NFD labels
+func NodeNeighbors(node *v1.Node) ([]Neighbor, error) {
+    if val, ok := node.Annotations[LLDPAnnotation]; ok && val != "" {
+        var neighbors []Neighbor
+        if err := json.Unmarshal([]byte(val), &neighbors); err != nil {
+            return nil, fmt.Errorf("invalid %s annotation on node %s: %v", LLDPAnnotation, node.Name, err)
+        }
+        return neighbors, nil
+    }
+
+    byInterface := make(map[string]*Neighbor)
+    for key, val := range node.Labels {
+        if !strings.HasPrefix(key, NFDLabelPrefix) {
+            continue
+        }
+        parts := strings.SplitN(strings.TrimPrefix(key, NFDLabelPrefix), ".", 2)
+        if len(parts) != 2 {
+            continue
+        }
+        neighbor, ok := byInterface[parts[0]]
+        if !ok {
+            neighbor = &Neighbor{Interface: parts[0]}
+            byInterface[parts[0]] = neighbor
+        }
+        switch parts[1] {
+        case "sysname":
+            neighbor.SystemName = val
+        case "chassis":
+            neighbor.ChassisID = val
+        case "port":
+            neighbor.PortID = val
+        }
+    }
+
+    neighbors := make([]Neighbor, 0, len(byInterface))
+    for _, neighbor := range byInterface {
+        if neighbor.SystemName != "" {
+            neighbors = append(neighbors, *neighbor)
+        }
+    }
+    sort.Slice(neighbors, func(i, j int) bool {
+        return neighbors[i].Interface < neighbors[j].Interface
+    })
+    return neighbors, nil
+}
+
+// LoadSwitchNeighbors reads the LLDP tables of the leaf switches, keyed by
+// switch name, as collected by network automation
+func LoadSwitchNeighbors(path string) (map[string][]Neighbor, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read switch neighbors %s: %v", path, err)
+    }
+
+    switches := make(map[string][]Neighbor)
+    if err := yaml.Unmarshal(data, &switches); err != nil {
+        return nil, fmt.Errorf("failed to parse switch neighbors %s: %v", path, err)
+    }
+    return switches, nil
+}
+
+type LLDPConfig struct {
+    // InterfacePattern selects the fabric interfaces, all when empty
+    InterfacePattern string
+    // SpinePattern picks the spines among the neighbours of a leaf switch,
+    // all neighbours that are not nodes when empty
+    SpinePattern string
+}
+
+// LLDPSource derives the topology from LLDP neighbour data and populates
+// the topology cache with it
+type LLDPSource struct {
+    cache    *algorithm.TopologyCache
+    schema   *algorithm.NodeLabelSchema
+    switches map[string][]Neighbor
+    ifaces   *regexp.Regexp
+    spines   *regexp.Regexp
+}
+
+func NewLLDPSource(cache *algorithm.TopologyCache, config LLDPConfig) (*LLDPSource, error) {
+    s := &LLDPSource{cache: cache}
+    var err error
+    if config.InterfacePattern != "" {
+        if s.ifaces, err = regexp.Compile(config.InterfacePattern); err != nil {
+            return nil, fmt.Errorf("invalid interface pattern: %v", err)
+        }
+    }
+    if config.SpinePattern != "" {
+        if s.spines, err = regexp.Compile(config.SpinePattern); err != nil {
+            return nil, fmt.Errorf("invalid spine pattern: %v", err)
+        }
+    }
+    return s, nil
+}
+
+// SetLabelSchema enables checking discovered leaves against manual labels
+func (s *LLDPSource) SetLabelSchema(schema *algorithm.NodeLabelSchema) {
+    s.schema = schema
+}
+
+// SetSwitchNeighbors supplies the leaf switch LLDP tables used to find the
+// spines. Without them leaves are not connected.
+func (s *LLDPSource) SetSwitchNeighbors(switches map[string][]Neighbor) {
+    s.switches = switches
+}
+
+// Discover assigns every node to the leaf most of its fabric interfaces
+// connect to
+func (s *LLDPSource) Discover(nodes []*v1.Node) (*DiscoveredTopology, error) {
+    result := newDiscoveredTopology()
+    nodeNames := make(map[string]bool, len(nodes))
+    for _, node := range nodes {
+        nodeNames[node.Name] = true
+    }
+
+    for _, node := range nodes {
+        neighbors, err := NodeNeighbors(node)
+        if err != nil {
+            return nil, err
+        }
+
+        counts := make(map[string]int)
+        for _, neighbor := range neighbors {
+            if s.ifaces != nil && !s.ifaces.MatchString(neighbor.Interface) {
+                continue
+            }
+            counts[neighbor.SystemName]++
+        }
+        result.assign(node, counts, s.schema)
+    }
+
+    for leaf := range result.Leaves {
+        seen := make(map[string]bool)
+        for _, neighbor := range s.switches[leaf] {
+            name := neighbor.SystemName
+            if name == "" || nodeNames[name] || seen[name] {
+                continue
+            }
+            if s.spines != nil && !s.spines.MatchString(name) {
+                continue
+            }
+            seen[name] = true
+            result.Spines[leaf] = append(result.Spines[leaf], name)
+        }
+        sort.Strings(result.Spines[leaf])
+    }
+    sort.Strings(result.Undiscovered)
+    return result, nil
+}
+
+// Apply adds the discovered leaves and their nodes to the cache and
+// connects leaves that share a spine
+func (s *LLDPSource) Apply(discovered *DiscoveredTopology) error {
+    return applyTopology(s.cache, discovered)
+}
+
+// Sync discovers the topology of the nodes, applies it and logs conflicts
+func (s *LLDPSource) Sync(nodes []*v1.Node) (*DiscoveredTopology, error) {
+    discovered, err := s.Discover(nodes)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.Apply(discovered); err != nil {
+        return nil, err
+    }
+
+    for _, conflict := range discovered.Conflicts {
+        klog.Warningf("LLDP discovery conflict: %s", conflict)
+    }
+    if len(discovered.Undiscovered) > 0 {
+        klog.V(2).Infof("No LLDP data for nodes %s", strings.Join(discovered.Undiscovered, ", "))
+    }
+    return discovered, nil
+}
+
+// Run lists the nodes and syncs the discovered topology every interval
+// until ctx is cancelled
+func (s *LLDPSource) Run(ctx context.Context, client kubernetes.Interface, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        nodeList, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+        if err != nil {
+            klog.Warningf("Failed to list nodes for LLDP discovery: %v", err)
+        } else {
+            nodes := make([]*v1.Node, 0, len(nodeList.Items))
+            for i := range nodeList.Items {
+                nodes = append(nodes, &nodeList.Items[i])
+            }
+            if _, err := s.Sync(nodes); err != nil {
+                klog.Warningf("LLDP discovery failed: %v", err)
+            }
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
@@ -0,0 +1,248 @@
+package discovery
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testNode(name string, annotations, labels map[string]string) *v1.Node {
+    return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations, Labels: labels}}
+}
+
+func nodeNames(nodes []*v1.Node) []string {
+    names := make([]string, 0, len(nodes))
+    for _, node := range nodes {
+        names = append(names, node.Name)
+    }
+    return names
+}
+
+// switchNeighborsSample is the LLDP table of two leaf switches as dumped by
+// network automation: the GPU nodes on the downlinks, the spines on the
+// uplinks and an out-of-band management switch
+const switchNeighborsSample = `leaf-0:
+- {interface: Ethernet1/1, systemName: gpu-001, chassisID: "b8:ce:f6:10:00:01", portID: enp12s0np0}
+- {interface: Ethernet1/2, systemName: gpu-002, chassisID: "b8:ce:f6:10:00:02", portID: enp12s0np0}
+- {interface: Ethernet1/31, systemName: spine-0, chassisID: "0c:42:a1:aa:00:00", portID: Ethernet1/1}
+- {interface: Ethernet1/32, systemName: spine-1, chassisID: "0c:42:a1:bb:00:00", portID: Ethernet1/1}
+- {interface: mgmt0, systemName: oob-mgmt-0, chassisID: "00:1c:73:00:00:01", portID: Ethernet12}
+leaf-1:
+- {interface: Ethernet1/1, systemName: gpu-003, chassisID: "b8:ce:f6:10:00:03", portID: enp12s0np0}
+- {interface: Ethernet1/31, systemName: spine-0, chassisID: "0c:42:a1:aa:00:00", portID: Ethernet1/2}
+- {interface: Ethernet1/32, systemName: spine-1, chassisID: "0c:42:a1:bb:00:00", portID: Ethernet1/2}
+- {interface: Ethernet1/32, systemName: spine-1, chassisID: "0c:42:a1:bb:00:00", portID: Ethernet1/2}
+`
+
+func TestNodeNeighbors(t *testing.T) {
+    tests := []struct {
+        name    string
+        node    *v1.Node
+        want    []Neighbor
+        wantErr bool
+    }{
+        {
+            name: "annotation",
+            node: testNode("gpu-001", map[string]string{
+                LLDPAnnotation: `[{"interface":"enp12s0np0","systemName":"leaf-0","chassisID":"0c:42:a1:10:00:00","portID":"Ethernet1/1"},` +
+                    `{"interface":"eno1","systemName":"oob-mgmt-0","portID":"Ethernet3"}]`,
+            }, nil),
+            want: []Neighbor{
+                {Interface: "enp12s0np0", SystemName: "leaf-0", ChassisID: "0c:42:a1:10:00:00", PortID: "Ethernet1/1"},
+                {Interface: "eno1", SystemName: "oob-mgmt-0", PortID: "Ethernet3"},
+            },
+        },
+        {
+            name: "annotation wins over NFD labels",
+            node: testNode("gpu-001",
+                map[string]string{LLDPAnnotation: `[{"interface":"enp12s0np0","systemName":"leaf-0"}]`},
+                map[string]string{NFDLabelPrefix + "enp12s0np0.sysname": "leaf-9"}),
+            want: []Neighbor{{Interface: "enp12s0np0", SystemName: "leaf-0"}},
+        },
+        {
+            name: "NFD labels sorted by interface",
+            node: testNode("gpu-002", nil, map[string]string{
+                NFDLabelPrefix + "enp13s0np0.sysname": "leaf-0",
+                NFDLabelPrefix + "enp13s0np0.port":    "Ethernet1-2",
+                NFDLabelPrefix + "enp12s0np0.sysname": "leaf-0",
+                NFDLabelPrefix + "enp12s0np0.chassis": "0c-42-a1-10-00-00",
+                NFDLabelPrefix + "enp12s0np0.port":    "Ethernet1-1",
+                "kubernetes.io/hostname":              "gpu-002",
+            }),
+            want: []Neighbor{
+                {Interface: "enp12s0np0", SystemName: "leaf-0", ChassisID: "0c-42-a1-10-00-00", PortID: "Ethernet1-1"},
+                {Interface: "enp13s0np0", SystemName: "leaf-0", PortID: "Ethernet1-2"},
+            },
+        },
+        {
+            name: "NFD interfaces without a system name are dropped",
+            node: testNode("gpu-003", nil, map[string]string{
+                NFDLabelPrefix + "eno1.port":          "Ethernet3",
+                NFDLabelPrefix + "enp12s0np0.sysname": "leaf-1",
+                NFDLabelPrefix + "malformed":          "x",
+            }),
+            want: []Neighbor{{Interface: "enp12s0np0", SystemName: "leaf-1"}},
+        },
+        {
+            name: "no LLDP data",
+            node: testNode("gpu-004", nil, nil),
+            want: []Neighbor{},
+        },
+        {
+            name:    "invalid annotation",
+            node:    testNode("gpu-005", map[string]string{LLDPAnnotation: `{"interface":"enp12s0np0"}`}, nil),
+            wantErr: true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := NodeNeighbors(tt.node)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("NodeNeighbors() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("NodeNeighbors() = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestLoadSwitchNeighbors(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "switches.yaml")
+    if err := os.WriteFile(path, []byte(switchNeighborsSample), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    switches, err := LoadSwitchNeighbors(path)
+    if err != nil {
+        t.Fatalf("LoadSwitchNeighbors() error = %v", err)
+    }
+    if len(switches["leaf-0"]) != 5 || len(switches["leaf-1"]) != 4 {
+        t.Fatalf("LoadSwitchNeighbors() = %+v, want 5 neighbours of leaf-0 and 4 of leaf-1", switches)
+    }
+    want := Neighbor{Interface: "Ethernet1/31", SystemName: "spine-0", ChassisID: "0c:42:a1:aa:00:00", PortID: "Ethernet1/1"}
+    if switches["leaf-0"][2] != want {
+        t.Errorf("leaf-0 uplink = %+v, want %+v", switches["leaf-0"][2], want)
+    }
+
+    if _, err := LoadSwitchNeighbors(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+        t.Error("LoadSwitchNeighbors() of a missing file succeeded")
+    }
+}
+
+func TestLLDPDiscover(t *testing.T) {
+    fabric := func(names ...string) map[string]string {
+        neighbors := "["
+        for i, name := range names {
+            if i > 0 {
+                neighbors += ","
+            }
+            neighbors += fmt.Sprintf(`{"interface":"enp%ds0np0","systemName":%q}`, 12+i, name)
+        }
+        return map[string]string{LLDPAnnotation: neighbors + `,{"interface":"eno1","systemName":"oob-mgmt-0"}]`}
+    }
+    nodes := []*v1.Node{
+        testNode("gpu-001", fabric("leaf-0", "leaf-0"), nil),
+        testNode("gpu-002", fabric("leaf-0", "leaf-0", "leaf-1"), nil),
+        testNode("gpu-003", fabric("leaf-1"), nil),
+        testNode("gpu-004", nil, nil),
+    }
+
+    tests := []struct {
+        name      string
+        config    LLDPConfig
+        switches  string
+        leaves    map[string][]string
+        spines    map[string][]string
+        conflicts []Conflict
+    }{
+        {
+            name:     "fabric interfaces with spines from the switch tables",
+            config:   LLDPConfig{InterfacePattern: "^enp"},
+            switches: switchNeighborsSample,
+            leaves:   map[string][]string{"leaf-0": {"gpu-001", "gpu-002"}, "leaf-1": {"gpu-003"}},
+            spines: map[string][]string{
+                "leaf-0": {"oob-mgmt-0", "spine-0", "spine-1"},
+                "leaf-1": {"spine-0", "spine-1"},
+            },
+            conflicts: []Conflict{{Node: "gpu-002", Kind: ConflictMultipleLeaves, Discovered: "leaf-0", Existing: "leaf-1"}},
+        },
+        {
+            name:     "spine pattern drops the management switch",
+            config:   LLDPConfig{InterfacePattern: "^enp", SpinePattern: "^spine-"},
+            switches: switchNeighborsSample,
+            leaves:   map[string][]string{"leaf-0": {"gpu-001", "gpu-002"}, "leaf-1": {"gpu-003"}},
+            spines: map[string][]string{
+                "leaf-0": {"spine-0", "spine-1"},
+                "leaf-1": {"spine-0", "spine-1"},
+            },
+            conflicts: []Conflict{{Node: "gpu-002", Kind: ConflictMultipleLeaves, Discovered: "leaf-0", Existing: "leaf-1"}},
+        },
+        {
+            name:   "all interfaces without switch tables",
+            config: LLDPConfig{},
+            leaves: map[string][]string{"leaf-0": {"gpu-001", "gpu-002"}, "leaf-1": {"gpu-003"}},
+            spines: map[string][]string{},
+            conflicts: []Conflict{
+                {Node: "gpu-001", Kind: ConflictMultipleLeaves, Discovered: "leaf-0", Existing: "oob-mgmt-0"},
+                {Node: "gpu-002", Kind: ConflictMultipleLeaves, Discovered: "leaf-0", Existing: "leaf-1,oob-mgmt-0"},
+                {Node: "gpu-003", Kind: ConflictMultipleLeaves, Discovered: "leaf-1", Existing: "oob-mgmt-0"},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            source, err := NewLLDPSource(nil, tt.config)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if tt.switches != "" {
+                path := filepath.Join(t.TempDir(), "switches.yaml")
+                if err := os.WriteFile(path, []byte(tt.switches), 0o644); err != nil {
+                    t.Fatal(err)
+                }
+                switches, err := LoadSwitchNeighbors(path)
+                if err != nil {
+                    t.Fatal(err)
+                }
+                source.SetSwitchNeighbors(switches)
+            }
+
+            discovered, err := source.Discover(nodes)
+            if err != nil {
+                t.Fatalf("Discover() error = %v", err)
+            }
+            leaves := make(map[string][]string)
+            for leaf, members := range discovered.Leaves {
+                leaves[leaf] = nodeNames(members)
+            }
+            if !reflect.DeepEqual(leaves, tt.leaves) {
+                t.Errorf("leaves = %v, want %v", leaves, tt.leaves)
+            }
+            if !reflect.DeepEqual(discovered.Spines, tt.spines) {
+                t.Errorf("spines = %v, want %v", discovered.Spines, tt.spines)
+            }
+            if !reflect.DeepEqual(discovered.Conflicts, tt.conflicts) {
+                t.Errorf("conflicts = %v, want %v", discovered.Conflicts, tt.conflicts)
+            }
+            if want := []string{"gpu-004"}; !reflect.DeepEqual(discovered.Undiscovered, want) {
+                t.Errorf("undiscovered = %v, want %v", discovered.Undiscovered, want)
+            }
+        })
+    }
+}
+
+func TestNewLLDPSourceInvalidPattern(t *testing.T) {
+    if _, err := NewLLDPSource(nil, LLDPConfig{InterfacePattern: "enp["}); err == nil {
+        t.Error("NewLLDPSource() accepted an invalid interface pattern")
+    }
+    if _, err := NewLLDPSource(nil, LLDPConfig{SpinePattern: "(spine"}); err == nil {
+        t.Error("NewLLDPSource() accepted an invalid spine pattern")
+    }
+}
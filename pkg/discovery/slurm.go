@@ -0,0 +1,503 @@
+package discovery
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/client-go/kubernetes"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// maxHostlistSize bounds the expansion of a single hostlist expression
+const maxHostlistSize = 1 << 16
+
+// SlurmSwitch is one SwitchName line of a Slurm topology.conf, with the
+// hostlist expressions expanded
+type SlurmSwitch struct {
+    Name     string
+    Switches []string
+    Nodes    []string
+    // LinkSpeed is relative to the other switches and has no unit, so it
+    // does not set link bandwidth
+    LinkSpeed int
+}
+
+// SlurmTopology is a tree topology in the topology.conf format of the
+// topology/tree plugin
+type SlurmTopology struct {
+    Switches []SlurmSwitch
+}
+
+// LoadSlurmTopology reads a Slurm topology.conf
+func LoadSlurmTopology(path string) (*SlurmTopology, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open Slurm topology %s: %v", path, err)
+    }
+    defer f.Close()
+
+    topology, err := ParseSlurmTopology(f)
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse Slurm topology %s: %v", path, err)
+    }
+    return topology, nil
+}
+
+// ParseSlurmTopology parses SwitchName lines such as
+//
+//  SwitchName=s0 Nodes=gpu[001-032]
+//  SwitchName=core Switches=s[0-3] LinkSpeed=100
+//
+// Comments start with # and lines ending in \ continue on the next line.
+func ParseSlurmTopology(r io.Reader) (*SlurmTopology, error) {
+    topology := &SlurmTopology{}
+    seen := make(map[string]bool)
+
+    scanner := bufio.NewScanner(r)
+    lineNo := 0
+    var line string
+    for scanner.Scan() {
+        lineNo++
+        text := scanner.Text()
+        if i := strings.Index(text, "#"); i >= 0 {
+            text = text[:i]
+        }
+        text = strings.TrimSpace(text)
+        if strings.HasSuffix(text, `\`) {
+            line += strings.TrimSuffix(text, `\`) + " "
+            continue
+        }
+        line += text
+        if strings.TrimSpace(line) == "" {
+            line = ""
+            continue
+        }
+
+        sw, err := parseSlurmSwitch(line)
+        line = ""
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %v", lineNo, err)
+        }
+        if seen[sw.Name] {
+            return nil, fmt.Errorf("line %d: switch %s defined twice", lineNo, sw.Name)
+        }
+        seen[sw.Name] = true
+        topology.Switches = append(topology.Switches, sw)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    if strings.TrimSpace(line) != "" {
+        return nil, fmt.Errorf("line %d: unterminated continuation", lineNo)
+    }
+
+    for _, sw := range topology.Switches {
+        for _, child := range sw.Switches {
+            if !seen[child] {
+                return nil, fmt.Errorf("switch %s lists undefined switch %s", sw.Name, child)
+            }
+        }
+    }
+    return topology, nil
+}
+
+func parseSlurmSwitch(line string) (SlurmSwitch, error) {
+    var sw SlurmSwitch
+    for _, field := range strings.Fields(line) {
+        parts := strings.SplitN(field, "=", 2)
+        if len(parts) != 2 {
+            return sw, fmt.Errorf("expected key=value, got %q", field)
+        }
+        var err error
+        switch strings.ToLower(parts[0]) {
+        case "switchname":
+            sw.Name = parts[1]
+        case "switches":
+            sw.Switches, err = ExpandHostlist(parts[1])
+        case "nodes":
+            sw.Nodes, err = ExpandHostlist(parts[1])
+        case "linkspeed":
+            sw.LinkSpeed, err = strconv.Atoi(parts[1])
+        default:
+            return sw, fmt.Errorf("unsupported key %s", parts[0])
+        }
+        if err != nil {
+            return sw, fmt.Errorf("invalid %s: %v", parts[0], err)
+        }
+    }
+    if sw.Name == "" {
+        return sw, fmt.Errorf("missing SwitchName")
+    }
+    if len(sw.Switches) > 0 && len(sw.Nodes) > 0 {
+        return sw, fmt.Errorf("switch %s has both Switches and Nodes", sw.Name)
+    }
+    return sw, nil
+}
+
+// Topology maps the tree to leaves and spines. Switches with nodes become
+// leaves and the switches directly above them their spines. Switches
+// higher up are kept as the leaves' upper switches, so leaves under
+// different spines of one tree stay connected. Nodes are matched by name
+// or by short host name.
+func (t *SlurmTopology) Topology(nodes []*v1.Node, schema *algorithm.NodeLabelSchema) *DiscoveredTopology {
+    result := newDiscoveredTopology()
+
+    byName := make(map[string]*v1.Node)
+    for _, node := range nodes {
+        byName[shortHostname(node.Name)] = node
+    }
+    for _, node := range nodes {
+        byName[node.Name] = node
+    }
+
+    leaves := make(map[string]bool)
+    counts := make(map[string]map[string]int)
+    for _, sw := range t.Switches {
+        if len(sw.Nodes) == 0 {
+            continue
+        }
+        leaves[sw.Name] = true
+        for _, name := range sw.Nodes {
+            node, ok := byName[name]
+            if !ok {
+                node, ok = byName[shortHostname(name)]
+            }
+            if !ok {
+                klog.V(4).Infof("Slurm node %s is not in the cluster", name)
+                continue
+            }
+            if counts[node.Name] == nil {
+                counts[node.Name] = make(map[string]int)
+            }
+            counts[node.Name][sw.Name]++
+        }
+    }
+
+    parents := make(map[string][]string)
+    for _, sw := range t.Switches {
+        for _, child := range sw.Switches {
+            parents[child] = appendUnique(parents[child], sw.Name)
+            if leaves[child] {
+                result.Spines[child] = appendUnique(result.Spines[child], sw.Name)
+            }
+        }
+    }
+    for leaf, spines := range result.Spines {
+        if upper := upperSwitches(leaf, spines, parents); len(upper) > 0 {
+            result.Upper[leaf] = upper
+        }
+    }
+
+    for _, node := range nodes {
+        result.assign(node, counts[node.Name], schema)
+    }
+    for leaf := range result.Spines {
+        sort.Strings(result.Spines[leaf])
+    }
+    sort.Strings(result.Undiscovered)
+    return result
+}
+
+// upperSwitches returns the switches above the spines of the leaf, other
+// than its spines, sorted
+func upperSwitches(leaf string, spines []string, parents map[string][]string) []string {
+    seen := map[string]bool{leaf: true}
+    for _, spine := range spines {
+        seen[spine] = true
+    }
+    var upper []string
+    queue := append([]string(nil), spines...)
+    for len(queue) > 0 {
+        name := queue[0]
+        queue = queue[1:]
+        for _, parent := range parents[name] {
+            if seen[parent] {
+                continue
+            }
+            seen[parent] = true
+            upper = append(upper, parent)
+            queue = append(queue, parent)
+        }
+    }
+    sort.Strings(upper)
+    return upper
+}
+
+// slurmRootSwitch names the switch WriteSlurmTopology puts above the spines
+const slurmRootSwitch = "root"
+
+// WriteSlurmTopology writes the document as a topology.conf: one line per
+// leaf with its nodes, one line per spine with its leaves, and a root
+// switch over all spines when there are several. Leaves without a spine
+// have no common switch, so Slurm will not place a job across them.
+func WriteSlurmTopology(w io.Writer, doc *TopologyDocument) error {
+    children := make(map[string][]string)
+    names := make(map[string]bool)
+    var leaves, spines []TopologyDomain
+    for _, domain := range doc.Domains {
+        names[domain.Name] = true
+        switch domain.Level {
+        case algorithm.LevelSpine:
+            spines = append(spines, domain)
+        default:
+            leaves = append(leaves, domain)
+            if domain.Parent != "" {
+                children[domain.Parent] = append(children[domain.Parent], domain.Name)
+            }
+        }
+    }
+
+    bw := bufio.NewWriter(w)
+    fmt.Fprintln(bw, "# topology.conf generated by topology-aware-gpu-scheduler")
+    for _, leaf := range leaves {
+        names := make([]string, 0, len(leaf.Nodes))
+        for _, node := range leaf.Nodes {
+            names = append(names, node.Name)
+        }
+        if len(names) == 0 {
+            fmt.Fprintf(bw, "SwitchName=%s\n", leaf.Name)
+            continue
+        }
+        fmt.Fprintf(bw, "SwitchName=%s Nodes=%s\n", leaf.Name, CompressHostlist(names))
+    }
+    var used []string
+    for _, spine := range spines {
+        if len(children[spine.Name]) == 0 {
+            continue
+        }
+        fmt.Fprintf(bw, "SwitchName=%s Switches=%s\n", spine.Name, CompressHostlist(children[spine.Name]))
+        used = append(used, spine.Name)
+    }
+    if len(used) > 1 {
+        root := slurmRootSwitch
+        for i := 1; names[root]; i++ {
+            root = fmt.Sprintf("%s-%d", slurmRootSwitch, i)
+        }
+        fmt.Fprintf(bw, "SwitchName=%s Switches=%s\n", root, CompressHostlist(used))
+    }
+    return bw.Flush()
+}
+
+// SlurmSource imports a Slurm topology.conf into the topology cache
+type SlurmSource struct {
+    cache  *algorithm.TopologyCache
+    schema *algorithm.NodeLabelSchema
+}
+
+func NewSlurmSource(cache *algorithm.TopologyCache) *SlurmSource {
+    return &SlurmSource{cache: cache}
+}
+
+// SetLabelSchema enables checking imported leaves against manual labels
+func (s *SlurmSource) SetLabelSchema(schema *algorithm.NodeLabelSchema) {
+    s.schema = schema
+}
+
+// Import applies the Slurm topology of the nodes and logs conflicts
+func (s *SlurmSource) Import(topology *SlurmTopology, nodes []*v1.Node) (*DiscoveredTopology, error) {
+    discovered := topology.Topology(nodes, s.schema)
+    if err := applyTopology(s.cache, discovered); err != nil {
+        return nil, err
+    }
+
+    for _, conflict := range discovered.Conflicts {
+        klog.Warningf("Slurm topology import conflict: %s", conflict)
+    }
+    if len(discovered.Undiscovered) > 0 {
+        klog.V(2).Infof("No Slurm switch for nodes %s", strings.Join(discovered.Undiscovered, ", "))
+    }
+    return discovered, nil
+}
+
+// ImportFile lists the nodes and imports the topology.conf at path
+func (s *SlurmSource) ImportFile(ctx context.Context, client kubernetes.Interface, path string) (*DiscoveredTopology, error) {
+    topology, err := LoadSlurmTopology(path)
+    if err != nil {
+        return nil, err
+    }
+
+    nodes, err := listNodes(ctx, client)
+    if err != nil {
+        return nil, err
+    }
+    return s.Import(topology, nodes)
+}
+
+// ExpandHostlist expands a Slurm hostlist expression such as
+// gpu[001-004,010],login1 into host names. Several bracket groups in one
+// name are expanded as a cross product.
+func ExpandHostlist(expr string) ([]string, error) {
+    var hosts []string
+    for _, part := range splitHostlist(expr) {
+        if part == "" {
+            continue
+        }
+        expanded, err := expandHost(part)
+        if err != nil {
+            return nil, err
+        }
+        hosts = append(hosts, expanded...)
+        if len(hosts) > maxHostlistSize {
+            return nil, fmt.Errorf("hostlist %q expands to more than %d hosts", expr, maxHostlistSize)
+        }
+    }
+    return hosts, nil
+}
+
+// splitHostlist splits at the commas outside brackets
+func splitHostlist(expr string) []string {
+    var parts []string
+    depth, start := 0, 0
+    for i, c := range expr {
+        switch c {
+        case '[':
+            depth++
+        case ']':
+            depth--
+        case ',':
+            if depth == 0 {
+                parts = append(parts, expr[start:i])
+                start = i + 1
+            }
+        }
+    }
+    return append(parts, expr[start:])
+}
+
+func expandHost(host string) ([]string, error) {
+    open := strings.Index(host, "[")
+    if open < 0 {
+        if strings.Contains(host, "]") {
+            return nil, fmt.Errorf("unbalanced brackets in %q", host)
+        }
+        return []string{host}, nil
+    }
+    end := strings.Index(host[open:], "]")
+    if end < 0 {
+        return nil, fmt.Errorf("unbalanced brackets in %q", host)
+    }
+    end += open
+    prefix, ranges, rest := host[:open], host[open+1:end], host[end+1:]
+
+    suffixes, err := expandHost(rest)
+    if err != nil {
+        return nil, err
+    }
+
+    var hosts []string
+    for _, r := range strings.Split(ranges, ",") {
+        lo, hi := r, r
+        if i := strings.Index(r, "-"); i >= 0 {
+            lo, hi = r[:i], r[i+1:]
+        }
+        start, err := strconv.Atoi(lo)
+        if err != nil {
+            return nil, fmt.Errorf("invalid range %q in %q", r, host)
+        }
+        stop, err := strconv.Atoi(hi)
+        if err != nil || stop < start {
+            return nil, fmt.Errorf("invalid range %q in %q", r, host)
+        }
+        if (stop-start+1)*len(suffixes) > maxHostlistSize {
+            return nil, fmt.Errorf("hostlist %q expands to more than %d hosts", host, maxHostlistSize)
+        }
+        for n := start; n <= stop; n++ {
+            for _, suffix := range suffixes {
+                hosts = append(hosts, fmt.Sprintf("%s%0*d%s", prefix, len(lo), n, suffix))
+            }
+        }
+    }
+    return hosts, nil
+}
+
+// CompressHostlist writes host names as a hostlist expression, merging
+// names that differ only in a trailing number. Zero-padded numbers are
+// only merged with numbers of the same width.
+func CompressHostlist(hosts []string) string {
+    type group struct {
+        prefix string
+        width  int
+    }
+    split := func(host string) (string, string) {
+        i := len(host)
+        for i > 0 && host[i-1] >= '0' && host[i-1] <= '9' {
+            i--
+        }
+        return host[:i], host[i:]
+    }
+    padded := make(map[group]bool)
+    for _, host := range hosts {
+        if prefix, digits := split(host); len(digits) > 1 && digits[0] == '0' {
+            padded[group{prefix: prefix, width: len(digits)}] = true
+        }
+    }
+
+    numbers := make(map[group][]int)
+    var groups []group
+    var plain []string
+    for _, host := range hosts {
+        prefix, digits := split(host)
+        n, err := strconv.Atoi(digits)
+        if digits == "" || err != nil {
+            plain = append(plain, host)
+            continue
+        }
+        g := group{prefix: prefix}
+        if padded[group{prefix: prefix, width: len(digits)}] {
+            g.width = len(digits)
+        }
+        if _, ok := numbers[g]; !ok {
+            groups = append(groups, g)
+        }
+        numbers[g] = append(numbers[g], n)
+    }
+    sort.Slice(groups, func(i, j int) bool {
+        if groups[i].prefix != groups[j].prefix {
+            return groups[i].prefix < groups[j].prefix
+        }
+        return groups[i].width < groups[j].width
+    })
+
+    parts := append([]string(nil), plain...)
+    sort.Strings(parts)
+    for _, g := range groups {
+        nums := numbers[g]
+        sort.Ints(nums)
+        if len(nums) == 1 {
+            parts = append(parts, fmt.Sprintf("%s%0*d", g.prefix, g.width, nums[0]))
+            continue
+        }
+        var ranges []string
+        for i := 0; i < len(nums); {
+            j := i
+            for j+1 < len(nums) && nums[j+1] <= nums[j]+1 {
+                j++
+            }
+            if nums[i] == nums[j] {
+                ranges = append(ranges, fmt.Sprintf("%0*d", g.width, nums[i]))
+            } else {
+                ranges = append(ranges, fmt.Sprintf("%0*d-%0*d", g.width, nums[i], g.width, nums[j]))
+            }
+            i = j + 1
+        }
+        parts = append(parts, fmt.Sprintf("%s[%s]", g.prefix, strings.Join(ranges, ",")))
+    }
+    return strings.Join(parts, ",")
+}
+
+// shortHostname strips the domain from a fully qualified host name
+func shortHostname(name string) string {
+    if i := strings.Index(name, "."); i > 0 {
+        return name[:i]
+    }
+    return name
+}
@@ -0,0 +1,225 @@
+package discovery
+
+import (
+    "bytes"
+    "reflect"
+    "strings"
+    "testing"
+
+    v1 "k8s.io/api/core/v1"
+)
+
+func TestExpandHostlist(t *testing.T) {
+    tests := []struct {
+        expr    string
+        want    []string
+        wantErr bool
+    }{
+        {expr: "node[01-04,07]", want: []string{"node01", "node02", "node03", "node04", "node07"}},
+        {expr: "gpu[001-003],login1", want: []string{"gpu001", "gpu002", "gpu003", "login1"}},
+        {expr: "rack[1-2]-gpu[1-2]", want: []string{"rack1-gpu1", "rack1-gpu2", "rack2-gpu1", "rack2-gpu2"}},
+        {expr: "gpu[8-11]", want: []string{"gpu8", "gpu9", "gpu10", "gpu11"}},
+        {expr: "s[0-1],,core", want: []string{"s0", "s1", "core"}},
+        {expr: "login1", want: []string{"login1"}},
+        {expr: "node[04-01]", wantErr: true},
+        {expr: "node[a-c]", wantErr: true},
+        {expr: "node[01-04", wantErr: true},
+        {expr: "node01]", wantErr: true},
+        {expr: "node[0-99999]", wantErr: true},
+        {expr: "r[0-999]n[0-999]", wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.expr, func(t *testing.T) {
+            got, err := ExpandHostlist(tt.expr)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ExpandHostlist(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+            }
+            if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("ExpandHostlist(%q) = %v, want %v", tt.expr, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestCompressHostlist(t *testing.T) {
+    tests := []struct {
+        hosts []string
+        want  string
+    }{
+        {hosts: []string{"node07", "node01", "node03", "node02", "node04"}, want: "node[01-04,07]"},
+        {hosts: []string{"gpu8", "gpu9", "gpu10", "gpu11"}, want: "gpu[8-11]"},
+        {hosts: []string{"gpu001", "gpu2", "login"}, want: "login,gpu2,gpu001"},
+        {hosts: []string{"leaf-1", "leaf-0", "spine-0"}, want: "leaf-[0-1],spine-0"},
+        {hosts: []string{"login"}, want: "login"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.want, func(t *testing.T) {
+            if got := CompressHostlist(tt.hosts); got != tt.want {
+                t.Errorf("CompressHostlist(%v) = %q, want %q", tt.hosts, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestHostlistRoundTrip(t *testing.T) {
+    for _, expr := range []string{"node[01-04,07]", "gpu[001-032,040]", "gpu[8-11]", "leaf-[0-3]"} {
+        hosts, err := ExpandHostlist(expr)
+        if err != nil {
+            t.Fatalf("ExpandHostlist(%q) error = %v", expr, err)
+        }
+        if got := CompressHostlist(hosts); got != expr {
+            t.Errorf("CompressHostlist(ExpandHostlist(%q)) = %q", expr, got)
+        }
+    }
+}
+
+// slurmSample is a three-level topology.conf: leaves under two aggregation
+// switches under one core switch
+const slurmSample = `# topology.conf
+SwitchName=leaf-0 Nodes=gpu[001-002]
+SwitchName=leaf-1 Nodes=gpu[003-004] \
+    LinkSpeed=100
+SwitchName=leaf-2 Nodes=gpu005.cluster.local,gpu099   # gpu099 is not in the cluster
+SwitchName=agg-0 Switches=leaf-[0-1]
+SwitchName=agg-1 Switches=leaf-2
+SwitchName=core Switches=agg-[0-1]
+`
+
+func TestParseSlurmTopology(t *testing.T) {
+    topology, err := ParseSlurmTopology(strings.NewReader(slurmSample))
+    if err != nil {
+        t.Fatalf("ParseSlurmTopology() error = %v", err)
+    }
+    want := []SlurmSwitch{
+        {Name: "leaf-0", Nodes: []string{"gpu001", "gpu002"}},
+        {Name: "leaf-1", Nodes: []string{"gpu003", "gpu004"}, LinkSpeed: 100},
+        {Name: "leaf-2", Nodes: []string{"gpu005.cluster.local", "gpu099"}},
+        {Name: "agg-0", Switches: []string{"leaf-0", "leaf-1"}},
+        {Name: "agg-1", Switches: []string{"leaf-2"}},
+        {Name: "core", Switches: []string{"agg-0", "agg-1"}},
+    }
+    if !reflect.DeepEqual(topology.Switches, want) {
+        t.Errorf("switches = %+v, want %+v", topology.Switches, want)
+    }
+}
+
+func TestParseSlurmTopologyErrors(t *testing.T) {
+    tests := []struct {
+        name    string
+        input   string
+        wantErr string
+    }{
+        {name: "missing name", input: "Nodes=gpu[001-002]\n", wantErr: "missing SwitchName"},
+        {name: "unsupported key", input: "SwitchName=s0 Ports=1\n", wantErr: "unsupported key"},
+        {name: "not key=value", input: "SwitchName=s0 gpu001\n", wantErr: "expected key=value"},
+        {name: "switches and nodes", input: "SwitchName=s0 Switches=s1 Nodes=gpu001\nSwitchName=s1\n", wantErr: "both Switches and Nodes"},
+        {name: "defined twice", input: "SwitchName=s0 Nodes=gpu001\nSwitchName=s0 Nodes=gpu002\n", wantErr: "line 2: switch s0 defined twice"},
+        {name: "undefined switch", input: "SwitchName=core Switches=s[0-1]\nSwitchName=s0\n", wantErr: "undefined switch s1"},
+        {name: "bad hostlist", input: "SwitchName=s0 Nodes=gpu[002-001]\n", wantErr: "invalid Nodes"},
+        {name: "bad link speed", input: "SwitchName=s0 LinkSpeed=fast\n", wantErr: "invalid LinkSpeed"},
+        {name: "unterminated continuation", input: "SwitchName=s0 \\\n", wantErr: "unterminated continuation"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := ParseSlurmTopology(strings.NewReader(tt.input))
+            if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("ParseSlurmTopology() error = %v, want one containing %q", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestSlurmTopology(t *testing.T) {
+    topology, err := ParseSlurmTopology(strings.NewReader(slurmSample))
+    if err != nil {
+        t.Fatal(err)
+    }
+    nodes := []*v1.Node{
+        testNode("gpu001", nil, nil),
+        testNode("gpu002", nil, nil),
+        testNode("gpu003", nil, nil),
+        testNode("gpu004", nil, nil),
+        testNode("gpu005", nil, nil),
+        testNode("gpu006", nil, nil),
+    }
+
+    discovered := topology.Topology(nodes, nil)
+    leaves := make(map[string][]string)
+    for leaf, members := range discovered.Leaves {
+        leaves[leaf] = nodeNames(members)
+    }
+    wantLeaves := map[string][]string{
+        "leaf-0": {"gpu001", "gpu002"},
+        "leaf-1": {"gpu003", "gpu004"},
+        "leaf-2": {"gpu005"},
+    }
+    if !reflect.DeepEqual(leaves, wantLeaves) {
+        t.Errorf("leaves = %v, want %v", leaves, wantLeaves)
+    }
+    wantSpines := map[string][]string{"leaf-0": {"agg-0"}, "leaf-1": {"agg-0"}, "leaf-2": {"agg-1"}}
+    if !reflect.DeepEqual(discovered.Spines, wantSpines) {
+        t.Errorf("spines = %v, want %v", discovered.Spines, wantSpines)
+    }
+    wantUpper := map[string][]string{"leaf-0": {"core"}, "leaf-1": {"core"}, "leaf-2": {"core"}}
+    if !reflect.DeepEqual(discovered.Upper, wantUpper) {
+        t.Errorf("upper = %v, want %v", discovered.Upper, wantUpper)
+    }
+    if want := []string{"gpu006"}; !reflect.DeepEqual(discovered.Undiscovered, want) {
+        t.Errorf("undiscovered = %v, want %v", discovered.Undiscovered, want)
+    }
+}
+
+func TestWriteSlurmTopology(t *testing.T) {
+    // The spine named root makes the writer pick another name for the
+    // switch above the spines
+    doc, err := ReadTopology(strings.NewReader(`apiVersion: topology.scheduler/v1alpha1
+kind: Topology
+domains:
+- {name: spine-0, level: spine}
+- {name: spine-1, level: spine}
+- {name: root, level: spine}
+- {name: leaf-0, level: leaf, parent: spine-0, nodes: [{name: node01}, {name: node02}, {name: node03}, {name: node04}, {name: node07}]}
+- {name: leaf-1, level: leaf, parent: spine-0, nodes: [{name: node08}]}
+- {name: leaf-2, level: leaf, parent: spine-1}
+- {name: leaf-3, level: leaf, nodes: [{name: login1}]}
+`))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var buf bytes.Buffer
+    if err := WriteSlurmTopology(&buf, doc); err != nil {
+        t.Fatalf("WriteSlurmTopology() error = %v", err)
+    }
+    want := `# topology.conf generated by topology-aware-gpu-scheduler
+SwitchName=leaf-0 Nodes=node[01-04,07]
+SwitchName=leaf-1 Nodes=node08
+SwitchName=leaf-2
+SwitchName=leaf-3 Nodes=login1
+SwitchName=spine-0 Switches=leaf-[0-1]
+SwitchName=spine-1 Switches=leaf-2
+SwitchName=root-1 Switches=spine-[0-1]
+`
+    if buf.String() != want {
+        t.Errorf("WriteSlurmTopology() =\n%s\nwant\n%s", buf.String(), want)
+    }
+
+    // The written file reads back with the same spines and the root switch
+    // above them. leaf-2 has no nodes, so it is not a leaf for Slurm.
+    topology, err := ParseSlurmTopology(&buf)
+    if err != nil {
+        t.Fatalf("ParseSlurmTopology() of the written file error = %v", err)
+    }
+    discovered := topology.Topology(doc.Nodes(), nil)
+    wantSpines := map[string][]string{"leaf-0": {"spine-0"}, "leaf-1": {"spine-0"}}
+    if !reflect.DeepEqual(discovered.Spines, wantSpines) {
+        t.Errorf("spines = %v, want %v", discovered.Spines, wantSpines)
+    }
+    wantUpper := map[string][]string{"leaf-0": {"root-1"}, "leaf-1": {"root-1"}}
+    if !reflect.DeepEqual(discovered.Upper, wantUpper) {
+        t.Errorf("upper = %v, want %v", discovered.Upper, wantUpper)
+    }
+}
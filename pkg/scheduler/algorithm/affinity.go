@@ -0,0 +1,241 @@
+package algorithm
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+    // JobLabel groups the pods of one job; pods without it are their own job
+    JobLabel = "topology.scheduler/job"
+
+    DomainAffinityAnnotation     = "topology.scheduler/domain-affinity"
+    DomainAntiAffinityAnnotation = "topology.scheduler/domain-anti-affinity"
+)
+
+// DomainAffinityTerm matches jobs by label within the domain enclosing a
+// candidate node at the given topology level.
+type DomainAffinityTerm struct {
+    LabelSelector *metav1.LabelSelector `json:"labelSelector"`
+    Level         TopologyLevel         `json:"level"`
+    // Weight is only used for preferred terms, in the range 1-100
+    Weight int32 `json:"weight,omitempty"`
+}
+
+// DomainAffinity is the content of the affinity and anti-affinity annotations
+type DomainAffinity struct {
+    Required  []DomainAffinityTerm `json:"required,omitempty"`
+    Preferred []DomainAffinityTerm `json:"preferred,omitempty"`
+}
+
+func parseDomainAffinity(pod *v1.Pod, annotation string) (*DomainAffinity, error) {
+    val, ok := pod.Annotations[annotation]
+    if !ok || val == "" {
+        return nil, nil
+    }
+
+    affinity := &DomainAffinity{}
+    if err := json.Unmarshal([]byte(val), affinity); err != nil {
+        return nil, fmt.Errorf("invalid %s annotation: %v", annotation, err)
+    }
+    for i := range affinity.Required {
+        if affinity.Required[i].Level == "" {
+            affinity.Required[i].Level = LevelLeaf
+        }
+    }
+    for i := range affinity.Preferred {
+        if affinity.Preferred[i].Level == "" {
+            affinity.Preferred[i].Level = LevelLeaf
+        }
+    }
+    return affinity, nil
+}
+
+func jobNameForPod(pod *v1.Pod) string {
+    if name, ok := pod.Labels[JobLabel]; ok && name != "" {
+        return pod.Namespace + "/" + name
+    }
+    return pod.Namespace + "/" + pod.Name
+}
+
+// jobNamespace returns the namespace part of a job name, which is the
+// tenant the job belongs to
+func jobNamespace(jobName string) string {
+    if i := strings.Index(jobName, "/"); i >= 0 {
+        return jobName[:i]
+    }
+    return ""
+}
+
+// affinityTerm is a DomainAffinityTerm with its selector compiled
+type affinityTerm struct {
+    DomainAffinityTerm
+    selector labels.Selector
+}
+
+// podDomainAffinity holds the parsed affinity and anti-affinity terms of a
+// pod, so the annotations are read once per scheduling cycle rather than
+// for every candidate
+type podDomainAffinity struct {
+    required      []affinityTerm
+    preferred     []affinityTerm
+    antiRequired  []affinityTerm
+    antiPreferred []affinityTerm
+}
+
+// parsePodDomainAffinity reads the affinity and anti-affinity annotations
+// of the pod and compiles their selectors
+func parsePodDomainAffinity(pod *v1.Pod) (*podDomainAffinity, error) {
+    affinity, err := parseDomainAffinity(pod, DomainAffinityAnnotation)
+    if err != nil {
+        return nil, err
+    }
+    antiAffinity, err := parseDomainAffinity(pod, DomainAntiAffinityAnnotation)
+    if err != nil {
+        return nil, err
+    }
+
+    parsed := &podDomainAffinity{}
+    for _, set := range []struct {
+        source              *DomainAffinity
+        required, preferred *[]affinityTerm
+    }{
+        {affinity, &parsed.required, &parsed.preferred},
+        {antiAffinity, &parsed.antiRequired, &parsed.antiPreferred},
+    } {
+        if set.source == nil {
+            continue
+        }
+        if *set.required, err = compileTerms(set.source.Required); err != nil {
+            return nil, err
+        }
+        if *set.preferred, err = compileTerms(set.source.Preferred); err != nil {
+            return nil, err
+        }
+    }
+    return parsed, nil
+}
+
+func compileTerms(terms []DomainAffinityTerm) ([]affinityTerm, error) {
+    compiled := make([]affinityTerm, 0, len(terms))
+    for _, term := range terms {
+        selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+        if err != nil {
+            return nil, fmt.Errorf("invalid label selector: %v", err)
+        }
+        compiled = append(compiled, affinityTerm{DomainAffinityTerm: term, selector: selector})
+    }
+    return compiled, nil
+}
+
+// termMatches reports whether any job other than the pod's own has been
+// placed under the term's scope around the given domain.
+func (ts *TopologyScheduler) termMatches(pod *v1.Pod, domain *Domain, term affinityTerm) (bool, error) {
+    jobs, err := ts.cache.GetJobsInScope(domain.Name, term.Level)
+    if err != nil {
+        return false, err
+    }
+
+    self := jobNameForPod(pod)
+    for _, job := range jobs {
+        if job.Name == self {
+            continue
+        }
+        if term.selector.Matches(labels.Set(job.Labels)) {
+            return true, nil
+        }
+    }
+    return false, nil
+}
+
+// checkDomainAffinity enforces the required affinity and anti-affinity terms
+// of the pod against the jobs already placed around domain.
+func (ts *TopologyScheduler) checkDomainAffinity(pod *v1.Pod, affinity *podDomainAffinity, domain *Domain) error {
+    for _, term := range affinity.required {
+        matched, err := ts.termMatches(pod, domain, term)
+        if err != nil {
+            return err
+        }
+        if !matched {
+            return fmt.Errorf("no matching job in %s of domain %s", term.Level, domain.Name)
+        }
+    }
+
+    for _, term := range affinity.antiRequired {
+        matched, err := ts.termMatches(pod, domain, term)
+        if err != nil {
+            return err
+        }
+        if matched {
+            return fmt.Errorf("conflicting job in %s of domain %s", term.Level, domain.Name)
+        }
+    }
+    return nil
+}
+
+// scoreDomainAffinity returns a score in [0, 1] from the preferred terms;
+// 0.5 means no preference either way.
+func (ts *TopologyScheduler) scoreDomainAffinity(pod *v1.Pod, affinity *podDomainAffinity, domain *Domain) float64 {
+    var total, score int32
+    for _, term := range affinity.preferred {
+        total += term.Weight
+        if matched, err := ts.termMatches(pod, domain, term); err == nil && matched {
+            score += term.Weight
+        }
+    }
+    for _, term := range affinity.antiPreferred {
+        total += term.Weight
+        if matched, err := ts.termMatches(pod, domain, term); err == nil && !matched {
+            score += term.Weight
+        }
+    }
+
+    if total == 0 {
+        return 0.5
+    }
+    return float64(score) / float64(total)
+}
+
+// recordJobPlacement registers the pod's job with every domain it landed in
+// so later affinity terms and the topology constraints can see it. Each
+// node is charged gpus GPUs.
+func (ts *TopologyScheduler) recordJobPlacement(pod *v1.Pod, nodes []*v1.Node, gpus int) {
+    if tuner := ts.Tuner(); tuner != nil {
+        tuner.ObserveQueueWait(jobNameForPod(pod), time.Since(pod.CreationTimestamp.Time))
+    }
+    for _, node := range nodes {
+        domain, err := ts.cache.GetDomainForNode(node.Name)
+        if err != nil {
+            continue
+        }
+        ts.cache.AddJobToDomain(domain.Name, &PlacedJob{
+            Name:   jobNameForPod(pod),
+            Labels: pod.Labels,
+            GPUs:   gpus,
+            Nodes:  map[string]int{node.Name: gpus},
+        })
+        ts.recordHistory(pod, domain, gpus)
+    }
+}
+
+// releaseJobPlacement undoes recordJobPlacement for a pod on a single node
+// and forgets the job's history and step times once its last pod is gone.
+func (ts *TopologyScheduler) releaseJobPlacement(pod *v1.Pod, nodeName string) {
+    ts.stragglers.ForgetPod(pod)
+    domain, err := ts.cache.GetDomainForNode(nodeName)
+    if err != nil {
+        return
+    }
+    jobName := jobNameForPod(pod)
+    ts.cache.RemoveJobFromDomain(domain.Name, jobName, nodeName, getGPURequirements(pod))
+    if len(ts.cache.GetDomainsWithJob(jobName)) == 0 {
+        ts.forgetJobHistory(jobName)
+        ts.stragglers.ForgetJob(jobName)
+    }
+}
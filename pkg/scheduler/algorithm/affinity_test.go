@@ -0,0 +1,196 @@
+package algorithm
+
+import (
+    "testing"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newAffinityTestScheduler builds leaf-0 and leaf-1 under spine-0 and
+// leaf-2 under spine-1. An etl job runs in leaf-0 and the train job, which
+// the test pod belongs to, in leaf-1.
+func newAffinityTestScheduler(t *testing.T) *TopologyScheduler {
+    t.Helper()
+    tc := NewTopologyCache(NewNodeCache())
+    for _, leaf := range [][2]string{{"leaf-0", "spine-0"}, {"leaf-1", "spine-0"}, {"leaf-2", "spine-1"}} {
+        domain := &Domain{Name: leaf[0], Level: LevelLeaf, SpineSwitch: leaf[1], Jobs: make(map[string]*PlacedJob)}
+        if err := tc.AddDomain(domain); err != nil {
+            t.Fatal(err)
+        }
+    }
+    jobs := map[string]*PlacedJob{
+        "leaf-0": {Name: "default/etl", Labels: map[string]string{"app": "etl"}, GPUs: 8},
+        "leaf-1": {Name: "default/train", Labels: map[string]string{"app": "train", JobLabel: "train"}, GPUs: 8},
+    }
+    for leaf, job := range jobs {
+        if err := tc.AddJobToDomain(leaf, job); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return NewTopologyScheduler(tc)
+}
+
+func affinityPod(affinity, antiAffinity string) *v1.Pod {
+    pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+        Namespace:   "default",
+        Name:        "train-1",
+        Labels:      map[string]string{"app": "train", JobLabel: "train"},
+        Annotations: map[string]string{},
+    }}
+    if affinity != "" {
+        pod.Annotations[DomainAffinityAnnotation] = affinity
+    }
+    if antiAffinity != "" {
+        pod.Annotations[DomainAntiAffinityAnnotation] = antiAffinity
+    }
+    return pod
+}
+
+func TestCheckDomainAffinity(t *testing.T) {
+    tests := []struct {
+        name         string
+        affinity     string
+        antiAffinity string
+        // allowed lists the leaves the pod may use
+        allowed map[string]bool
+    }{
+        {
+            name:    "no terms",
+            allowed: map[string]bool{"leaf-0": true, "leaf-1": true, "leaf-2": true},
+        },
+        {
+            name:     "required affinity in the leaf",
+            affinity: `{"required": [{"labelSelector": {"matchLabels": {"app": "etl"}}}]}`,
+            allowed:  map[string]bool{"leaf-0": true},
+        },
+        {
+            name:     "required affinity in the spine",
+            affinity: `{"required": [{"labelSelector": {"matchLabels": {"app": "etl"}}, "level": "spine"}]}`,
+            allowed:  map[string]bool{"leaf-0": true, "leaf-1": true},
+        },
+        {
+            name:         "required anti-affinity in the leaf",
+            antiAffinity: `{"required": [{"labelSelector": {"matchLabels": {"app": "etl"}}}]}`,
+            allowed:      map[string]bool{"leaf-1": true, "leaf-2": true},
+        },
+        {
+            name:         "required anti-affinity in the spine",
+            antiAffinity: `{"required": [{"labelSelector": {"matchLabels": {"app": "etl"}}, "level": "spine"}]}`,
+            allowed:      map[string]bool{"leaf-2": true},
+        },
+        {
+            // The pod's own job never counts, so it cannot attract itself
+            name:     "affinity to the own job",
+            affinity: `{"required": [{"labelSelector": {"matchLabels": {"app": "train"}}}]}`,
+            allowed:  map[string]bool{},
+        },
+        {
+            // nor push itself away
+            name:         "anti-affinity to the own job",
+            antiAffinity: `{"required": [{"labelSelector": {"matchLabels": {"app": "train"}}}]}`,
+            allowed:      map[string]bool{"leaf-0": true, "leaf-1": true, "leaf-2": true},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ts := newAffinityTestScheduler(t)
+            affinity, err := parsePodDomainAffinity(affinityPod(tt.affinity, tt.antiAffinity))
+            if err != nil {
+                t.Fatalf("parsePodDomainAffinity() error = %v", err)
+            }
+            for _, name := range []string{"leaf-0", "leaf-1", "leaf-2"} {
+                domain, err := ts.cache.GetDomain(name)
+                if err != nil {
+                    t.Fatal(err)
+                }
+                err = ts.checkDomainAffinity(affinityPod(tt.affinity, tt.antiAffinity), affinity, domain)
+                if (err == nil) != tt.allowed[name] {
+                    t.Errorf("checkDomainAffinity(%s) error = %v, want allowed %v", name, err, tt.allowed[name])
+                }
+            }
+        })
+    }
+}
+
+func TestScoreDomainAffinity(t *testing.T) {
+    tests := []struct {
+        name         string
+        affinity     string
+        antiAffinity string
+        want         map[string]float64
+    }{
+        {
+            name: "no preference",
+            want: map[string]float64{"leaf-0": 0.5, "leaf-1": 0.5},
+        },
+        {
+            name:     "preferred affinity",
+            affinity: `{"preferred": [{"labelSelector": {"matchLabels": {"app": "etl"}}, "weight": 10}]}`,
+            want:     map[string]float64{"leaf-0": 1, "leaf-1": 0},
+        },
+        {
+            name:         "preferred anti-affinity",
+            antiAffinity: `{"preferred": [{"labelSelector": {"matchLabels": {"app": "etl"}}, "weight": 10}]}`,
+            want:         map[string]float64{"leaf-0": 0, "leaf-1": 1},
+        },
+        {
+            name:         "weights add up",
+            affinity:     `{"preferred": [{"labelSelector": {"matchLabels": {"app": "etl"}}, "level": "spine", "weight": 30}]}`,
+            antiAffinity: `{"preferred": [{"labelSelector": {"matchLabels": {"app": "etl"}}, "weight": 10}]}`,
+            want:         map[string]float64{"leaf-0": 0.75, "leaf-1": 1},
+        },
+        {
+            name:     "own job is not preferred",
+            affinity: `{"preferred": [{"labelSelector": {"matchLabels": {"app": "train"}}, "weight": 10}]}`,
+            want:     map[string]float64{"leaf-0": 0, "leaf-1": 0},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ts := newAffinityTestScheduler(t)
+            pod := affinityPod(tt.affinity, tt.antiAffinity)
+            affinity, err := parsePodDomainAffinity(pod)
+            if err != nil {
+                t.Fatalf("parsePodDomainAffinity() error = %v", err)
+            }
+            for name, want := range tt.want {
+                domain, err := ts.cache.GetDomain(name)
+                if err != nil {
+                    t.Fatal(err)
+                }
+                if got := ts.scoreDomainAffinity(pod, affinity, domain); got != want {
+                    t.Errorf("scoreDomainAffinity(%s) = %v, want %v", name, got, want)
+                }
+            }
+        })
+    }
+}
+
+func TestParsePodDomainAffinity(t *testing.T) {
+    tests := []struct {
+        name         string
+        affinity     string
+        antiAffinity string
+        wantErr      bool
+    }{
+        {name: "no annotations"},
+        {name: "invalid JSON", affinity: `{"required": [`, wantErr: true},
+        {
+            name:         "invalid selector",
+            antiAffinity: `{"preferred": [{"labelSelector": {"matchExpressions": [{"key": "app", "operator": "Bogus"}]}, "weight": 1}]}`,
+            wantErr:      true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := parsePodDomainAffinity(affinityPod(tt.affinity, tt.antiAffinity))
+            if (err != nil) != tt.wantErr {
+                t.Errorf("parsePodDomainAffinity() error = %v, wantErr %v", err, tt.wantErr)
+            }
+        })
+    }
+}
@@ -0,0 +1,220 @@
+package algorithm
+
+import (
+    "fmt"
+    "strconv"
+
+    v1 "k8s.io/api/core/v1"
+)
+
+// CommProfileAnnotation declares how communication-bound a job is
+const CommProfileAnnotation = "topology.scheduler/comm-profile"
+
+// JobGPUsAnnotation gives the GPUs of the whole job across its pods, so
+// that a profile requiring a single leaf keeps room for all of them
+const JobGPUsAnnotation = "topology.scheduler/job-gpus"
+
+// CommProfile is the communication pattern of a job
+type CommProfile string
+
+const (
+    // CommProfileDefault is used when the annotation is absent and keeps the
+    // scheduler-wide weights unscaled
+    CommProfileDefault        CommProfile = ""
+    CommProfileComputeBound   CommProfile = "compute-bound"
+    CommProfileAllReduceHeavy CommProfile = "allreduce-heavy"
+    CommProfileAllToAllHeavy  CommProfile = "alltoall-heavy"
+    CommProfileInference      CommProfile = "inference"
+)
+
+// StrategyThresholds maps the number of nodes a job needs to a placement
+// strategy. A threshold of zero disables that strategy.
+type StrategyThresholds struct {
+    // SingleDomainMaxNodes is the largest job kept inside one leaf
+    SingleDomainMaxNodes int
+    // CompleteDomainNodes is the job size that gets a whole free leaf
+    CompleteDomainNodes int
+    // AdjacentDomainsMaxNodes is the largest job placed on adjacent leaves
+    AdjacentDomainsMaxNodes int
+    // RequireSingleLeaf rejects leaves that cannot hold the whole job and,
+    // once the job has pods, every leaf but the one they run in
+    RequireSingleLeaf bool
+}
+
+// CommProfileSettings are the weight factors and thresholds used for a
+// profile. The factors scale the scheduler-wide weights, configured or
+// tuned, which are then normalized again. With the default weights they
+// give the weights noted for each profile.
+type CommProfileSettings struct {
+    WeightFactors TopologyScore
+    Thresholds    StrategyThresholds
+}
+
+var defaultThresholds = StrategyThresholds{
+    SingleDomainMaxNodes:    2,
+    CompleteDomainNodes:     4,
+    AdjacentDomainsMaxNodes: 8,
+}
+
+var commProfiles = map[CommProfile]CommProfileSettings{
+    // Little traffic between workers: pack for utilization, ignore
+    // distance (0.4, 0.1, 0.4, 0.1)
+    CommProfileComputeBound: {
+        WeightFactors: TopologyScore{
+            ResourceAvailability: 1,
+            TopologyAlignment:    1.0 / 3,
+            DomainUtilization:    2,
+            HistoricalPerf:       1,
+        },
+        Thresholds: StrategyThresholds{
+            SingleDomainMaxNodes: 1,
+        },
+    },
+    // Ring/tree collectives tolerate a spine hop for large jobs
+    // (0.3, 0.4, 0.2, 0.1)
+    CommProfileAllReduceHeavy: {
+        WeightFactors: TopologyScore{
+            ResourceAvailability: 0.75,
+            TopologyAlignment:    4.0 / 3,
+            DomainUtilization:    1,
+            HistoricalPerf:       1,
+        },
+        Thresholds: defaultThresholds,
+    },
+    // All-to-all (e.g. MoE expert parallelism) saturates the spine, so the
+    // job must fit in a single leaf (0.2, 0.5, 0.2, 0.1)
+    CommProfileAllToAllHeavy: {
+        WeightFactors: TopologyScore{
+            ResourceAvailability: 0.5,
+            TopologyAlignment:    5.0 / 3,
+            DomainUtilization:    1,
+            HistoricalPerf:       1,
+        },
+        Thresholds: StrategyThresholds{
+            SingleDomainMaxNodes: int(^uint(0) >> 1),
+            RequireSingleLeaf:    true,
+        },
+    },
+    // Replicas are independent; spread load and favour free capacity
+    // (0.5, 0.1, 0.1, 0.3)
+    CommProfileInference: {
+        WeightFactors: TopologyScore{
+            ResourceAvailability: 1.25,
+            TopologyAlignment:    1.0 / 3,
+            DomainUtilization:    0.5,
+            HistoricalPerf:       3,
+        },
+        Thresholds: StrategyThresholds{
+            SingleDomainMaxNodes: 1,
+        },
+    },
+}
+
+func parseCommProfile(pod *v1.Pod) (CommProfile, error) {
+    val, ok := pod.Annotations[CommProfileAnnotation]
+    if !ok || val == "" {
+        return CommProfileDefault, nil
+    }
+
+    profile := CommProfile(val)
+    if _, ok := commProfiles[profile]; !ok {
+        return "", fmt.Errorf("unknown %s %q", CommProfileAnnotation, val)
+    }
+    return profile, nil
+}
+
+// annotateRequirements fills in the parts of the requirements that come
+// from pod metadata rather than resource requests.
+func (ts *TopologyScheduler) annotateRequirements(pod *v1.Pod, gpuReq *GPURequirements) error {
+    profile, err := parseCommProfile(pod)
+    if err != nil {
+        return err
+    }
+    gpuReq.CommProfile = profile
+    gpuReq.JobName = jobNameForPod(pod)
+
+    gpuReq.JobGPUs = gpuReq.TotalGPUs
+    if val, ok := pod.Annotations[JobGPUsAnnotation]; ok && val != "" {
+        gpus, err := strconv.Atoi(val)
+        if err != nil || gpus < 0 {
+            return fmt.Errorf("invalid %s %q", JobGPUsAnnotation, val)
+        }
+        if gpus > gpuReq.JobGPUs {
+            gpuReq.JobGPUs = gpus
+        }
+    }
+    return nil
+}
+
+// profileWeightFactors returns the weight factors of every profile, the
+// configured ones laid over the built-in ones
+func profileWeightFactors(configured map[CommProfile]*CommProfileWeights) map[CommProfile]TopologyScore {
+    factors := make(map[CommProfile]TopologyScore, len(commProfiles))
+    for profile, settings := range commProfiles {
+        factors[profile] = settings.WeightFactors
+        if weights := configured[profile]; weights != nil {
+            factors[profile] = weights.Factors(settings.WeightFactors)
+        }
+    }
+    return factors
+}
+
+// scaleWeights multiplies the weights by the factors and normalizes them
+// to sum to 1. The weights are kept when the factors zero all of them.
+func scaleWeights(weights, factors TopologyScore) TopologyScore {
+    scaled := TopologyScore{
+        ResourceAvailability: weights.ResourceAvailability * factors.ResourceAvailability,
+        TopologyAlignment:    weights.TopologyAlignment * factors.TopologyAlignment,
+        DomainUtilization:    weights.DomainUtilization * factors.DomainUtilization,
+        HistoricalPerf:       weights.HistoricalPerf * factors.HistoricalPerf,
+    }
+    sum := scaled.ResourceAvailability + scaled.TopologyAlignment + scaled.DomainUtilization + scaled.HistoricalPerf
+    if sum <= 0 {
+        return weights
+    }
+    scaled.ResourceAvailability /= sum
+    scaled.TopologyAlignment /= sum
+    scaled.DomainUtilization /= sum
+    scaled.HistoricalPerf /= sum
+    return scaled
+}
+
+func (ts *TopologyScheduler) thresholdsFor(profile CommProfile) StrategyThresholds {
+    if settings, ok := commProfiles[profile]; ok {
+        return settings.Thresholds
+    }
+    return defaultThresholds
+}
+
+// checkCommProfile keeps a job whose profile requires a single leaf in
+// one leaf. Before its first pod is placed the leaf must have room for the
+// whole job, afterwards the job is pinned to the leaf its pods run in,
+// which must have room for the rest of it.
+func (ts *TopologyScheduler) checkCommProfile(domain *Domain, gpuReq *GPURequirements) error {
+    if !ts.thresholdsFor(gpuReq.CommProfile).RequireSingleLeaf {
+        return nil
+    }
+
+    needed := gpuReq.JobGPUs
+    if leaves := ts.cache.GetDomainsWithJob(gpuReq.JobName); len(leaves) > 0 {
+        if !contains(leaves, domain.Name) {
+            return fmt.Errorf("%s job %s runs in leaf %s, not %s",
+                gpuReq.CommProfile, gpuReq.JobName, leaves[0], domain.Name)
+        }
+        job, _, err := ts.leafUsage(domain, gpuReq.JobName)
+        if err != nil {
+            return err
+        }
+        needed -= job.gpus
+    }
+    if needed < gpuReq.TotalGPUs {
+        needed = gpuReq.TotalGPUs
+    }
+
+    free := domain.TotalGPUs - domain.UsedGPUs
+    if free < needed {
+        return fmt.Errorf("%s job %s needs %d more GPUs in one leaf, domain %s has %d free",
+            gpuReq.CommProfile, gpuReq.JobName, needed, domain.Name, free)
+    }
+    return nil
+}
@@ -0,0 +1,214 @@
+package algorithm
+
+import (
+    "fmt"
+    "math"
+    "os"
+
+    "sigs.k8s.io/yaml"
+)
+
+// SchedulerConfig is the scheduler configuration file mounted from the
+// topology-scheduler-config ConfigMap
+type SchedulerConfig struct {
+    APIVersion string              `json:"apiVersion"`
+    Kind       string              `json:"kind"`
+    Spec       SchedulerConfigSpec `json:"spec"`
+}
+
+type SchedulerConfigSpec struct {
+    // ScoringWeights weighs the parts of the domain score, the default
+    // weights when unset
+    ScoringWeights *ScoringWeightsConfig `json:"scoringWeights,omitempty"`
+    // TopologyConstraints caps how much of a leaf one job may take
+    TopologyConstraints *TopologyConstraints `json:"topologyConstraints,omitempty"`
+    // CommProfiles overrides the weight factors of communication profiles
+    CommProfiles map[CommProfile]*CommProfileWeights `json:"commProfiles,omitempty"`
+    // NodeLabels maps node labels to topology levels, the default schema
+    // when unset
+    NodeLabels *NodeLabelSchema `json:"nodeLabels,omitempty"`
+}
+
+// ScoringWeightsConfig is the configured form of TopologyScore. The
+// weights are normalized to sum to 1.
+type ScoringWeightsConfig struct {
+    ResourceAvailability  float64 `json:"resourceAvailability"`
+    TopologyAlignment     float64 `json:"topologyAlignment"`
+    DomainUtilization     float64 `json:"domainUtilization"`
+    HistoricalPerformance float64 `json:"historicalPerformance"`
+}
+
+func (w *ScoringWeightsConfig) Validate() error {
+    weights := []struct {
+        name  string
+        value float64
+    }{
+        {"resourceAvailability", w.ResourceAvailability},
+        {"topologyAlignment", w.TopologyAlignment},
+        {"domainUtilization", w.DomainUtilization},
+        {"historicalPerformance", w.HistoricalPerformance},
+    }
+    var sum float64
+    for _, weight := range weights {
+        if weight.value < 0 || math.IsNaN(weight.value) || math.IsInf(weight.value, 0) {
+            return fmt.Errorf("%s must be a non-negative number, got %v", weight.name, weight.value)
+        }
+        sum += weight.value
+    }
+    if sum == 0 {
+        return fmt.Errorf("at least one weight must be positive")
+    }
+    return nil
+}
+
+// TopologyScore returns the normalized weights
+func (w *ScoringWeightsConfig) TopologyScore() TopologyScore {
+    sum := w.ResourceAvailability + w.TopologyAlignment + w.DomainUtilization + w.HistoricalPerformance
+    return TopologyScore{
+        ResourceAvailability: w.ResourceAvailability / sum,
+        TopologyAlignment:    w.TopologyAlignment / sum,
+        DomainUtilization:    w.DomainUtilization / sum,
+        HistoricalPerf:       w.HistoricalPerformance / sum,
+    }
+}
+
+// CommProfileWeights scales the scoring weights for the jobs of one
+// communication profile. Unset factors keep the built-in ones.
+type CommProfileWeights struct {
+    ResourceAvailability  *float64 `json:"resourceAvailability,omitempty"`
+    TopologyAlignment     *float64 `json:"topologyAlignment,omitempty"`
+    DomainUtilization     *float64 `json:"domainUtilization,omitempty"`
+    HistoricalPerformance *float64 `json:"historicalPerformance,omitempty"`
+}
+
+func (w *CommProfileWeights) Validate() error {
+    factors := []struct {
+        name  string
+        value *float64
+    }{
+        {"resourceAvailability", w.ResourceAvailability},
+        {"topologyAlignment", w.TopologyAlignment},
+        {"domainUtilization", w.DomainUtilization},
+        {"historicalPerformance", w.HistoricalPerformance},
+    }
+    for _, factor := range factors {
+        if factor.value == nil {
+            continue
+        }
+        if *factor.value < 0 || math.IsNaN(*factor.value) || math.IsInf(*factor.value, 0) {
+            return fmt.Errorf("%s must be a non-negative number, got %v", factor.name, *factor.value)
+        }
+    }
+    return nil
+}
+
+// Factors returns the set factors laid over base
+func (w *CommProfileWeights) Factors(base TopologyScore) TopologyScore {
+    factors := base
+    if w.ResourceAvailability != nil {
+        factors.ResourceAvailability = *w.ResourceAvailability
+    }
+    if w.TopologyAlignment != nil {
+        factors.TopologyAlignment = *w.TopologyAlignment
+    }
+    if w.DomainUtilization != nil {
+        factors.DomainUtilization = *w.DomainUtilization
+    }
+    if w.HistoricalPerformance != nil {
+        factors.HistoricalPerf = *w.HistoricalPerformance
+    }
+    return factors
+}
+
+// TopologyConstraints caps the share of a leaf one job can take, so that
+// a job cannot monopolize it. Tenant caps all jobs of one namespace
+// together. Zero means no cap.
+type TopologyConstraints struct {
+    LeafLimits
+    Tenant *LeafLimits `json:"tenant,omitempty"`
+}
+
+// LeafLimits bounds the nodes and GPUs held in a single leaf
+type LeafLimits struct {
+    MaxNodesPerLeaf int `json:"maxNodesPerLeaf,omitempty"`
+    MaxGPUsPerLeaf  int `json:"maxGPUsPerLeaf,omitempty"`
+}
+
+func (c *TopologyConstraints) Validate() error {
+    if err := c.LeafLimits.Validate(); err != nil {
+        return err
+    }
+    if c.Tenant != nil {
+        if err := c.Tenant.Validate(); err != nil {
+            return fmt.Errorf("tenant: %v", err)
+        }
+    }
+    return nil
+}
+
+func (l *LeafLimits) Validate() error {
+    if l.MaxNodesPerLeaf < 0 {
+        return fmt.Errorf("maxNodesPerLeaf must not be negative, got %d", l.MaxNodesPerLeaf)
+    }
+    if l.MaxGPUsPerLeaf < 0 {
+        return fmt.Errorf("maxGPUsPerLeaf must not be negative, got %d", l.MaxGPUsPerLeaf)
+    }
+    return nil
+}
+
+// LoadSchedulerConfig reads and validates the configuration file
+func LoadSchedulerConfig(path string) (*SchedulerConfig, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read scheduler config %s: %v", path, err)
+    }
+
+    config := &SchedulerConfig{}
+    if err := yaml.Unmarshal(data, config); err != nil {
+        return nil, fmt.Errorf("failed to parse scheduler config %s: %v", path, err)
+    }
+    if config.Spec.NodeLabels == nil {
+        config.Spec.NodeLabels = DefaultNodeLabelSchema()
+    }
+    if err := config.Validate(); err != nil {
+        return nil, fmt.Errorf("invalid scheduler config %s: %v", path, err)
+    }
+    return config, nil
+}
+
+func (c *SchedulerConfig) Validate() error {
+    if c.Kind != "" && c.Kind != "SchedulerConfig" {
+        return fmt.Errorf("unexpected kind %q", c.Kind)
+    }
+    if err := c.Spec.NodeLabels.Validate(); err != nil {
+        return fmt.Errorf("nodeLabels: %v", err)
+    }
+    return c.Spec.ValidateScheduling()
+}
+
+// ValidateScheduling checks the parts of the spec that can be changed while
+// the scheduler runs
+func (s *SchedulerConfigSpec) ValidateScheduling() error {
+    if s.ScoringWeights != nil {
+        if err := s.ScoringWeights.Validate(); err != nil {
+            return fmt.Errorf("scoringWeights: %v", err)
+        }
+    }
+    if s.TopologyConstraints != nil {
+        if err := s.TopologyConstraints.Validate(); err != nil {
+            return fmt.Errorf("topologyConstraints: %v", err)
+        }
+    }
+    for profile, weights := range s.CommProfiles {
+        if _, ok := commProfiles[profile]; !ok {
+            return fmt.Errorf("commProfiles: unknown profile %q", profile)
+        }
+        if weights == nil {
+            continue
+        }
+        if err := weights.Validate(); err != nil {
+            return fmt.Errorf("commProfiles.%s: %v", profile, err)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,147 @@
+package algorithm
+
+import (
+    "fmt"
+    "math"
+
+    v1 "k8s.io/api/core/v1"
+)
+
+// leafUsage counts the nodes and GPUs held in one leaf
+type leafUsage struct {
+    nodes map[string]bool
+    gpus  int
+}
+
+func newLeafUsage() *leafUsage {
+    return &leafUsage{nodes: make(map[string]bool)}
+}
+
+func (u *leafUsage) add(job *PlacedJob) {
+    for node := range job.Nodes {
+        u.nodes[node] = true
+    }
+    u.gpus += job.GPUs
+}
+
+// newNodes counts the nodes not held yet
+func (u *leafUsage) newNodes(nodes []string) int {
+    count := 0
+    for _, node := range nodes {
+        if !u.nodes[node] {
+            count++
+        }
+    }
+    return count
+}
+
+// leafUsage returns what the job and all jobs of its tenant hold in the
+// domain
+func (ts *TopologyScheduler) leafUsage(domain *Domain, jobName string) (job, tenant *leafUsage, err error) {
+    jobs, err := ts.cache.GetDomainJobs(domain.Name)
+    if err != nil {
+        return nil, nil, err
+    }
+    job, tenant = newLeafUsage(), newLeafUsage()
+    namespace := jobNamespace(jobName)
+    for _, placed := range jobs {
+        if placed.Name == jobName {
+            job.add(placed)
+        }
+        if jobNamespace(placed.Name) == namespace {
+            tenant.add(placed)
+        }
+    }
+    return job, tenant, nil
+}
+
+// checkTopologyConstraints rejects adding the given nodes of the job, each
+// with GPUsPerNode GPUs, to the domain when the job or its tenant would
+// exceed maxNodesPerLeaf or maxGPUsPerLeaf there
+func (ts *TopologyScheduler) checkTopologyConstraints(domain *Domain, gpuReq *GPURequirements, nodes []string) error {
+    constraints := ts.topologyConstraints()
+    if constraints.LeafLimits == (LeafLimits{}) && constraints.Tenant == nil {
+        return nil
+    }
+    job, tenant, err := ts.leafUsage(domain, gpuReq.JobName)
+    if err != nil {
+        return err
+    }
+
+    gpus := len(nodes) * gpuReq.GPUsPerNode
+    if err := constraints.LeafLimits.check(
+        "job "+gpuReq.JobName, "", domain.Name, len(job.nodes)+job.newNodes(nodes), job.gpus+gpus); err != nil {
+        return err
+    }
+    if constraints.Tenant != nil {
+        return constraints.Tenant.check(
+            "tenant "+jobNamespace(gpuReq.JobName), "tenant.", domain.Name, len(tenant.nodes)+tenant.newNodes(nodes), tenant.gpus+gpus)
+    }
+    return nil
+}
+
+func (l LeafLimits) check(holder, field, leaf string, nodes, gpus int) error {
+    if l.MaxNodesPerLeaf > 0 && nodes > l.MaxNodesPerLeaf {
+        return fmt.Errorf("%s would hold %d nodes in leaf %s, %smaxNodesPerLeaf is %d",
+            holder, nodes, leaf, field, l.MaxNodesPerLeaf)
+    }
+    if l.MaxGPUsPerLeaf > 0 && gpus > l.MaxGPUsPerLeaf {
+        return fmt.Errorf("%s would hold %d GPUs in leaf %s, %smaxGPUsPerLeaf is %d",
+            holder, gpus, leaf, field, l.MaxGPUsPerLeaf)
+    }
+    return nil
+}
+
+// leafNodeAllowance returns how many more nodes of the job the domain can
+// take within the topology constraints
+func (ts *TopologyScheduler) leafNodeAllowance(domain *Domain, gpuReq *GPURequirements) int {
+    constraints := ts.topologyConstraints()
+    if constraints.LeafLimits == (LeafLimits{}) && constraints.Tenant == nil {
+        return math.MaxInt32
+    }
+    job, tenant, err := ts.leafUsage(domain, gpuReq.JobName)
+    if err != nil {
+        return 0
+    }
+
+    allowance := constraints.LeafLimits.allowance(job, gpuReq.GPUsPerNode)
+    if constraints.Tenant != nil {
+        allowance = min(allowance, constraints.Tenant.allowance(tenant, gpuReq.GPUsPerNode))
+    }
+    if allowance < 0 {
+        return 0
+    }
+    return allowance
+}
+
+func (l LeafLimits) allowance(usage *leafUsage, gpusPerNode int) int {
+    allowance := math.MaxInt32
+    if l.MaxNodesPerLeaf > 0 {
+        allowance = l.MaxNodesPerLeaf - len(usage.nodes)
+    }
+    if l.MaxGPUsPerLeaf > 0 && gpusPerNode > 0 {
+        allowance = min(allowance, (l.MaxGPUsPerLeaf-usage.gpus)/gpusPerNode)
+    }
+    return allowance
+}
+
+// checkPlacementConstraints verifies a placement against the topology
+// constraints, leaf by leaf
+func (ts *TopologyScheduler) checkPlacementConstraints(gpuReq *GPURequirements, nodes []*v1.Node) error {
+    leaves := make(map[string]*Domain)
+    nodesByLeaf := make(map[string][]string)
+    for _, node := range nodes {
+        domain, err := ts.cache.GetDomainForNode(node.Name)
+        if err != nil {
+            return err
+        }
+        leaves[domain.Name] = domain
+        nodesByLeaf[domain.Name] = append(nodesByLeaf[domain.Name], node.Name)
+    }
+    for name, domain := range leaves {
+        if err := ts.checkTopologyConstraints(domain, gpuReq, nodesByLeaf[name]); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -3,26 +3,20 @@ package scheduler
 import (
     "fmt"
     "sync"
+    "k8s.io/api/core/v1"
 )
 
 type DomainManager struct {
     mu      sync.RWMutex
     domains map[string]*Domain
-}
-
-type Domain struct {
-    Name        string
-    Type        string // "leaf" or "spine"
-    Bandwidth   int64
-    Latency     float64
-    Nodes       map[string]*Node
-    Parent      string
-    Children    []string
+    // domainForNode maps each node to the domain it is in
+    domainForNode map[string]string
 }
 
 func NewDomainManager() *DomainManager {
     return &DomainManager{
-        domains: make(map[string]*Domain),
+        domains:       make(map[string]*Domain),
+        domainForNode: make(map[string]string),
     }
 }
 
@@ -35,17 +29,50 @@ func (dm *DomainManager) AddDomain(domain *Domain) error {
     }
 
     dm.domains[domain.Name] = domain
+    for _, node := range domain.Nodes {
+        dm.domainForNode[node.Name] = domain.Name
+    }
     return nil
 }
 
-func (dm *DomainManager) GetDomainByNode(nodeName string) (*Domain, error) {
+func (dm *DomainManager) GetDomain(name string) (*Domain, bool) {
     dm.mu.RLock()
     defer dm.mu.RUnlock()
 
-    for _, domain := range dm.domains {
-        if _, exists := domain.Nodes[nodeName]; exists {
-            return domain, nil
+    domain, exists := dm.domains[name]
+    return domain, exists
+}
+
+// AssignNode puts the node in the named domain and takes it out of the
+// domain it was in before. The node object is replaced when it is already
+// in the domain.
+func (dm *DomainManager) AssignNode(node *v1.Node, domainName string) error {
+    dm.mu.Lock()
+    defer dm.mu.Unlock()
+
+    target, exists := dm.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+    if previous, ok := dm.domains[dm.domainForNode[node.Name]]; ok {
+        for i, n := range previous.Nodes {
+            if n.Name == node.Name {
+                previous.Nodes = append(previous.Nodes[:i], previous.Nodes[i+1:]...)
+                break
+            }
         }
     }
+    target.Nodes = append(target.Nodes, node)
+    dm.domainForNode[node.Name] = domainName
+    return nil
+}
+
+func (dm *DomainManager) GetDomainByNode(nodeName string) (*Domain, error) {
+    dm.mu.RLock()
+    defer dm.mu.RUnlock()
+
+    if domain, exists := dm.domains[dm.domainForNode[nodeName]]; exists {
+        return domain, nil
+    }
     return nil, fmt.Errorf("no domain found for node %s", nodeName)
 }
@@ -0,0 +1,168 @@
+package algorithm
+
+import (
+    "context"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/extension"
+)
+
+// externalScoring blends scores from an external gRPC scoring service into
+// the built-in ones. Any error or timeout falls back to the built-in score.
+type externalScoring struct {
+    client  *extension.Client
+    weight  float64
+    cache   *TopologyCache
+    metrics *MetricsCollector
+}
+
+func newExternalScoring(client *extension.Client, weight float64, cache *TopologyCache, mc *MetricsCollector) *externalScoring {
+    if weight < 0 {
+        weight = 0
+    }
+    if weight > 1 {
+        weight = 1
+    }
+    return &externalScoring{
+        client:  client,
+        weight:  weight,
+        cache:   cache,
+        metrics: mc,
+    }
+}
+
+// blend returns (1-weight)*builtin + weight*external for every node the
+// external service scored, and the built-in score for all others.
+func (es *externalScoring) blend(
+    ctx context.Context,
+    pod *v1.Pod,
+    gpuReq *GPURequirements,
+    builtin map[string]float64,
+) map[string]float64 {
+    if es == nil || es.client == nil || len(builtin) == 0 {
+        return builtin
+    }
+
+    external, err := es.client.Score(ctx, es.buildRequest(pod, gpuReq, builtin))
+    if err != nil {
+        klog.V(2).Infof("External scorer unavailable for pod %s/%s, using built-in scores: %v",
+            pod.Namespace, pod.Name, err)
+        if es.metrics != nil {
+            es.metrics.IncSchedulingError("external_score_fallback")
+        }
+        return builtin
+    }
+
+    blended := make(map[string]float64, len(builtin))
+    for node, score := range builtin {
+        if ext, ok := external[node]; ok {
+            score = (1-es.weight)*score + es.weight*ext
+        }
+        blended[node] = score
+    }
+    return blended
+}
+
+func (es *externalScoring) buildRequest(pod *v1.Pod, gpuReq *GPURequirements, builtin map[string]float64) *extension.ScoreRequest {
+    req := &extension.ScoreRequest{
+        Pod: extension.PodRequirements{
+            Namespace:   pod.Namespace,
+            Name:        pod.Name,
+            JobName:     gpuReq.JobName,
+            TotalGPUs:   int32(gpuReq.TotalGPUs),
+            GPUsPerNode: int32(gpuReq.GPUsPerNode),
+            NodesNeeded: int32(gpuReq.NodesNeeded),
+            CommProfile: string(gpuReq.CommProfile),
+            Labels:      pod.Labels,
+        },
+    }
+
+    for nodeName, score := range builtin {
+        features := extension.CandidateFeatures{
+            Node:         nodeName,
+            BuiltinScore: score,
+        }
+        if domain, err := es.cache.GetDomainForNode(nodeName); err == nil {
+            features.Domain = domain.Name
+            features.DomainTotalGPUs = int32(domain.TotalGPUs)
+            features.DomainUsedGPUs = int32(domain.UsedGPUs)
+            features.DomainNodes = int32(len(domain.Nodes))
+            if spine, err := es.cache.GetAncestorAtLevel(domain.Name, LevelSpine); err == nil {
+                features.Spine = spine.Name
+            }
+            if health, err := es.cache.GetDomainHealth(domain.Name); err == nil {
+                features.DomainHealth = health
+            }
+        }
+        if available, err := es.cache.GetNodeAvailableGPUs(nodeName); err == nil {
+            features.NodeAvailableGPUs = int32(available)
+        }
+        req.Candidates = append(req.Candidates, features)
+    }
+    return req
+}
+
+// SetExternalScorer enables blending of external scores into the scores
+// of Schedule and of the plugin's Score phase. weight is the share given
+// to the external score.
+func (ts *TopologyScheduler) SetExternalScorer(client *extension.Client, weight float64) {
+    ts.configMu.Lock()
+    defer ts.configMu.Unlock()
+    ts.external = newExternalScoring(client, weight, ts.cache, ts.metrics)
+}
+
+// externalScorer returns the external scorer, or nil when none is
+// configured
+func (ts *TopologyScheduler) externalScorer() *externalScoring {
+    ts.configMu.RLock()
+    defer ts.configMu.RUnlock()
+    return ts.external
+}
+
+// externalScores blends the external service's answer into the given
+// built-in scores, which are returned unchanged when none is configured.
+func (ts *TopologyScheduler) externalScores(
+    ctx context.Context,
+    pod *v1.Pod,
+    gpuReq *GPURequirements,
+    builtin map[string]float64,
+) map[string]float64 {
+    return ts.externalScorer().blend(ctx, pod, gpuReq, builtin)
+}
+
+// blendExternalScores has the external scorer rate the free nodes of the
+// candidate leaves, each starting from the score of its leaf, and gives
+// every leaf the mean blended score of its nodes.
+func (ts *TopologyScheduler) blendExternalScores(
+    ctx context.Context,
+    pod *v1.Pod,
+    gpuReq *GPURequirements,
+    candidates []*Domain,
+    scores map[string]float64,
+) {
+    external := ts.externalScorer()
+    if external == nil {
+        return
+    }
+
+    builtin := make(map[string]float64)
+    leafOf := make(map[string]string)
+    for _, domain := range candidates {
+        for _, node := range ts.getAvailableNodes(domain, gpuReq) {
+            builtin[node.Name] = scores[domain.Name]
+            leafOf[node.Name] = domain.Name
+        }
+    }
+
+    sums := make(map[string]float64)
+    counts := make(map[string]int)
+    for node, score := range external.blend(ctx, pod, gpuReq, builtin) {
+        sums[leafOf[node]] += score
+        counts[leafOf[node]]++
+    }
+    for name, sum := range sums {
+        scores[name] = sum / float64(counts[name])
+    }
+}
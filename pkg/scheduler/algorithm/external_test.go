@@ -0,0 +1,110 @@
+package algorithm
+
+import (
+    "context"
+    "net"
+    "reflect"
+    "testing"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/extension"
+)
+
+// stubScorer answers every candidate with the same score after a delay
+type stubScorer struct {
+    delay time.Duration
+    score float64
+}
+
+func (s stubScorer) Score(ctx context.Context, req *extension.ScoreRequest) (*extension.ScoreResponse, error) {
+    select {
+    case <-time.After(s.delay):
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+    resp := &extension.ScoreResponse{}
+    for _, candidate := range req.Candidates {
+        resp.Scores = append(resp.Scores, extension.CandidateScore{Node: candidate.Node, Score: s.score})
+    }
+    return resp, nil
+}
+
+// startStubScorer serves the stub on a free local port and returns a
+// client for it with the given deadline
+func startStubScorer(t *testing.T, stub stubScorer, timeout time.Duration) *extension.Client {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    server := extension.NewServer()
+    extension.RegisterScorerServer(server, stub)
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    client, err := extension.NewClient(listener.Addr().String(), timeout)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { client.Close() })
+    return client
+}
+
+func TestExternalScoringBlend(t *testing.T) {
+    builtin := map[string]float64{"gpu-001": 0.4, "gpu-002": 0.8}
+    tests := []struct {
+        name string
+        stub stubScorer
+        want map[string]float64
+    }{
+        {
+            name: "answer within the deadline",
+            stub: stubScorer{score: 1},
+            want: map[string]float64{"gpu-001": 0.7, "gpu-002": 0.9},
+        },
+        {
+            name: "timeout falls back to the built-in scores",
+            stub: stubScorer{delay: time.Second, score: 1},
+            want: builtin,
+        },
+        {
+            name: "score out of range falls back to the built-in scores",
+            stub: stubScorer{score: 2},
+            want: builtin,
+        },
+    }
+
+    pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "train-0"}}
+    gpuReq := &GPURequirements{TotalGPUs: 8, GPUsPerNode: 8, NodesNeeded: 1, JobName: "default/train"}
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            client := startStubScorer(t, tt.stub, 50*time.Millisecond)
+            es := newExternalScoring(client, 0.5, NewTopologyCache(NewNodeCache()), NewMetricsCollector())
+
+            start := time.Now()
+            got := es.blend(context.Background(), pod, gpuReq, builtin)
+            if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+                t.Errorf("blend took %v, want it cut off by the deadline", elapsed)
+            }
+            if len(got) != len(tt.want) {
+                t.Fatalf("blend() = %v, want %v", got, tt.want)
+            }
+            for node, score := range tt.want {
+                if diff := got[node] - score; diff > 1e-9 || diff < -1e-9 {
+                    t.Errorf("blend()[%s] = %v, want %v", node, got[node], score)
+                }
+            }
+        })
+    }
+}
+
+func TestExternalScoringDisabled(t *testing.T) {
+    builtin := map[string]float64{"gpu-001": 0.4}
+    var es *externalScoring
+    if got := es.blend(context.Background(), &v1.Pod{}, &GPURequirements{}, builtin); !reflect.DeepEqual(got, builtin) {
+        t.Errorf("blend() without a scorer = %v, want %v", got, builtin)
+    }
+}
@@ -0,0 +1,249 @@
+package algorithm
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/history"
+)
+
+const (
+    // WorkloadLabel groups jobs whose throughput is comparable, e.g. runs of
+    // the same model; jobs without it are only compared with themselves.
+    WorkloadLabel = "topology.scheduler/workload"
+
+    // ThroughputAnnotation carries the job throughput reported by a pod
+    ThroughputAnnotation = "topology.scheduler/job-throughput"
+
+    // historyQueueSize bounds the reports waiting for the history writer
+    historyQueueSize = 1024
+)
+
+// historyEventKind says what a historyEvent asks the writer to do
+type historyEventKind int
+
+const (
+    throughputEvent historyEventKind = iota
+    placementEvent
+    forgetEvent
+)
+
+// historyEvent is a throughput report, a placement of the job in a domain
+// or the end of the job, for the history writer
+type historyEvent struct {
+    kind       historyEventKind
+    job        string
+    throughput float64
+    workload   string
+    domain     string
+    spine      string
+    gpus       int
+}
+
+// SetHistoryStore enables historical performance scoring. Placements and
+// reports from pod annotations are written by a background goroutine, so
+// that bbolt writes block neither scheduling nor the informers.
+func (ts *TopologyScheduler) SetHistoryStore(store *history.Store) {
+    ts.Lock()
+    defer ts.Unlock()
+    ts.history = store
+    ts.historyEvents = make(chan historyEvent, historyQueueSize)
+    ts.lastThroughput = make(map[string]float64)
+    go ts.runHistoryWriter(store, ts.historyEvents)
+}
+
+// historyStore returns the history store and the queue of its writer, or
+// a nil store when historical scoring is disabled
+func (ts *TopologyScheduler) historyStore() (*history.Store, chan<- historyEvent) {
+    ts.RLock()
+    defer ts.RUnlock()
+    return ts.history, ts.historyEvents
+}
+
+func (ts *TopologyScheduler) runHistoryWriter(store *history.Store, events <-chan historyEvent) {
+    for event := range events {
+        switch event.kind {
+        case placementEvent:
+            if err := store.RecordPlacement(event.job, event.workload, event.domain, event.spine, event.gpus); err != nil {
+                klog.Warningf("Failed to record placement of job %s in domain %s: %v", event.job, event.domain, err)
+            }
+        case forgetEvent:
+            if tuner := ts.Tuner(); tuner != nil {
+                tuner.ForgetJob(event.job)
+            }
+            if err := store.ForgetJob(event.job); err != nil {
+                klog.Warningf("Failed to forget job %s in the history store: %v", event.job, err)
+            }
+        default:
+            if err := ts.reportThroughput(store, event.job, event.throughput); err != nil {
+                klog.V(4).Infof("Ignoring throughput report of job %s: %v", event.job, err)
+            }
+        }
+    }
+}
+
+// queueHistoryEvent hands the event to the writer. A throughput report is
+// dropped when the writer is behind, and taken again from the next pod
+// update; placements and forgets wait for room, since losing one would
+// leave the store out of step with the jobs.
+func (ts *TopologyScheduler) queueHistoryEvent(events chan<- historyEvent, event historyEvent) {
+    if event.kind != throughputEvent {
+        events <- event
+        return
+    }
+    select {
+    case events <- event:
+    default:
+        klog.Warningf("History writer is behind, dropping throughput report of job %s", event.job)
+        ts.historyMu.Lock()
+        delete(ts.lastThroughput, event.job)
+        ts.historyMu.Unlock()
+    }
+}
+
+// historicalPerfScore maps the performance prior of the domain, and of the
+// pairs it would form with the job's other domains, into [0, 1] with 0.5
+// meaning average.
+func (ts *TopologyScheduler) historicalPerfScore(domain *Domain, jobName string) float64 {
+    store, _ := ts.historyStore()
+    if store == nil {
+        return 0.5
+    }
+
+    prior := store.DomainPrior(domain.Name)
+    if jobName != "" {
+        var sum float64
+        var count int
+        for _, other := range ts.cache.GetDomainsWithJob(jobName) {
+            if other == domain.Name {
+                continue
+            }
+            sum += store.PairPrior(domain.Name, other)
+            count++
+        }
+        if count > 0 {
+            prior = (prior + sum/float64(count)) / 2
+        }
+    }
+
+    score := prior / 2
+    if score > 1 {
+        score = 1
+    }
+    return score
+}
+
+// recordHistory queues the placement of the pod's job in the domain for
+// the history writer
+func (ts *TopologyScheduler) recordHistory(pod *v1.Pod, domain *Domain, gpus int) {
+    store, events := ts.historyStore()
+    if store == nil {
+        return
+    }
+    event := historyEvent{
+        kind:     placementEvent,
+        job:      jobNameForPod(pod),
+        workload: pod.Labels[WorkloadLabel],
+        domain:   domain.Name,
+        gpus:     gpus,
+    }
+    if ancestor, err := ts.cache.GetAncestorAtLevel(domain.Name, LevelSpine); err == nil {
+        event.spine = ancestor.Name
+    }
+    ts.queueHistoryEvent(events, event)
+}
+
+// ObservePodThroughput queues the throughput annotation of a running pod.
+// Every pod of a job may carry the same value and the annotation stays
+// through later pod updates, so a value is taken once per job until it
+// changes.
+func (ts *TopologyScheduler) ObservePodThroughput(pod *v1.Pod) error {
+    val, ok := pod.Annotations[ThroughputAnnotation]
+    if !ok {
+        return nil
+    }
+    store, events := ts.historyStore()
+    if store == nil {
+        return nil
+    }
+
+    throughput, err := strconv.ParseFloat(val, 64)
+    if err != nil {
+        return fmt.Errorf("invalid %s annotation on pod %s: %v", ThroughputAnnotation, pod.Name, err)
+    }
+
+    jobName := jobNameForPod(pod)
+    ts.historyMu.Lock()
+    last, seen := ts.lastThroughput[jobName]
+    ts.lastThroughput[jobName] = throughput
+    ts.historyMu.Unlock()
+    if !seen || last != throughput {
+        ts.queueHistoryEvent(events, historyEvent{kind: throughputEvent, job: jobName, throughput: throughput})
+    }
+    return nil
+}
+
+// forgetJobHistory drops the finished job's placement record, so a rerun
+// under the same name starts a new one, and its tuner assignment. The
+// writer forgets the assignment after the job's queued throughput reports.
+func (ts *TopologyScheduler) forgetJobHistory(jobName string) {
+    store, events := ts.historyStore()
+    if store == nil {
+        if tuner := ts.Tuner(); tuner != nil {
+            tuner.ForgetJob(jobName)
+        }
+        return
+    }
+    ts.historyMu.Lock()
+    delete(ts.lastThroughput, jobName)
+    ts.historyMu.Unlock()
+    ts.queueHistoryEvent(events, historyEvent{kind: forgetEvent, job: jobName})
+}
+
+func (ts *TopologyScheduler) reportThroughput(store *history.Store, jobName string, throughput float64) error {
+    ratio, err := store.ReportThroughput(jobName, throughput)
+    if err != nil {
+        return err
+    }
+    if tuner := ts.Tuner(); tuner != nil {
+        tuner.ObserveThroughput(jobName, ratio)
+    }
+    return nil
+}
+
+type throughputReport struct {
+    Job        string  `json:"job"`
+    Throughput float64 `json:"throughput"`
+}
+
+// HistoryHandler accepts POSTed {"job": "namespace/name", "throughput": N}
+// reports for the historical performance store.
+func (ts *TopologyScheduler) HistoryHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        store, _ := ts.historyStore()
+        if store == nil {
+            http.Error(w, "history store disabled", http.StatusServiceUnavailable)
+            return
+        }
+
+        var report throughputReport
+        if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+            http.Error(w, fmt.Sprintf("invalid report: %v", err), http.StatusBadRequest)
+            return
+        }
+        if err := ts.reportThroughput(store, report.Job, report.Throughput); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    })
+}
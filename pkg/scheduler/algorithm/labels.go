@@ -0,0 +1,135 @@
+package algorithm
+
+import (
+    "fmt"
+    "regexp"
+    "sort"
+
+    v1 "k8s.io/api/core/v1"
+)
+
+const (
+    // LeafLabel and SpineLabel are read by the default label schema
+    LeafLabel  = "topology.scheduler/leaf"
+    SpineLabel = "topology.scheduler/spine"
+)
+
+// LabelSource names a node label or annotation holding the switch name of
+// one topology level. When Pattern is set the value must match it and the
+// first capture group, if any, is used as the name.
+type LabelSource struct {
+    Label      string `json:"label,omitempty"`
+    Annotation string `json:"annotation,omitempty"`
+    Pattern    string `json:"pattern,omitempty"`
+
+    re *regexp.Regexp
+}
+
+// NodeLabelSchema maps node labels and annotations to the leaf and spine a
+// node belongs to. Sources are tried in order and the first match wins.
+// Every node needs a leaf; the spine is optional.
+type NodeLabelSchema struct {
+    Leaf  []LabelSource `json:"leaf"`
+    Spine []LabelSource `json:"spine,omitempty"`
+}
+
+// NodeTopology is where the schema places a node
+type NodeTopology struct {
+    Leaf  string
+    Spine string
+}
+
+func DefaultNodeLabelSchema() *NodeLabelSchema {
+    return &NodeLabelSchema{
+        Leaf:  []LabelSource{{Label: LeafLabel}},
+        Spine: []LabelSource{{Label: SpineLabel}},
+    }
+}
+
+// Validate checks the schema and compiles its patterns. A schema that was
+// not validated still resolves, compiling its patterns on every call.
+func (s *NodeLabelSchema) Validate() error {
+    if len(s.Leaf) == 0 {
+        return fmt.Errorf("node label schema has no leaf sources")
+    }
+    levels := map[TopologyLevel][]LabelSource{LevelLeaf: s.Leaf, LevelSpine: s.Spine}
+    for level, sources := range levels {
+        for i := range sources {
+            src := &sources[i]
+            if (src.Label == "") == (src.Annotation == "") {
+                return fmt.Errorf("%s source %d must set exactly one of label and annotation", level, i)
+            }
+            if src.Pattern == "" {
+                continue
+            }
+            re, err := regexp.Compile(src.Pattern)
+            if err != nil {
+                return fmt.Errorf("%s source %d has invalid pattern: %v", level, i, err)
+            }
+            src.re = re
+        }
+    }
+    return nil
+}
+
+// Resolve returns the leaf and spine of the node
+func (s *NodeLabelSchema) Resolve(node *v1.Node) (NodeTopology, error) {
+    leaf, ok, err := resolveLevel(node, s.Leaf)
+    if err != nil {
+        return NodeTopology{}, err
+    }
+    if !ok {
+        return NodeTopology{}, fmt.Errorf("node %s matches no leaf source of the label schema", node.Name)
+    }
+    spine, _, err := resolveLevel(node, s.Spine)
+    if err != nil {
+        return NodeTopology{}, err
+    }
+    return NodeTopology{Leaf: leaf, Spine: spine}, nil
+}
+
+// UnmatchedNodes returns the sorted names of nodes without a leaf
+func (s *NodeLabelSchema) UnmatchedNodes(nodes []*v1.Node) []string {
+    var unmatched []string
+    for _, node := range nodes {
+        if _, err := s.Resolve(node); err != nil {
+            unmatched = append(unmatched, node.Name)
+        }
+    }
+    sort.Strings(unmatched)
+    return unmatched
+}
+
+func resolveLevel(node *v1.Node, sources []LabelSource) (string, bool, error) {
+    for _, src := range sources {
+        var val string
+        var ok bool
+        if src.Label != "" {
+            val, ok = node.Labels[src.Label]
+        } else {
+            val, ok = node.Annotations[src.Annotation]
+        }
+        if !ok || val == "" {
+            continue
+        }
+        re := src.re
+        if re == nil && src.Pattern != "" {
+            var err error
+            if re, err = regexp.Compile(src.Pattern); err != nil {
+                return "", false, fmt.Errorf("invalid label schema pattern %q: %v", src.Pattern, err)
+            }
+        }
+        if re == nil {
+            return val, true, nil
+        }
+        match := re.FindStringSubmatch(val)
+        if match == nil {
+            continue
+        }
+        if len(match) > 1 && match[1] != "" {
+            return match[1], true, nil
+        }
+        return match[0], true, nil
+    }
+    return "", false, nil
+}
@@ -0,0 +1,111 @@
+package algorithm
+
+import (
+    "reflect"
+    "testing"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func schemaNode(name string, labels, annotations map[string]string) *v1.Node {
+    return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations}}
+}
+
+// rackSchema reads the leaf from a rack label such as rack-12-leaf, then
+// from a switch annotation, and the spine from a pod label
+func rackSchema() *NodeLabelSchema {
+    return &NodeLabelSchema{
+        Leaf: []LabelSource{
+            {Label: "example.com/rack", Pattern: `^rack-(\d+)-leaf$`},
+            {Annotation: "example.com/switch"},
+        },
+        Spine: []LabelSource{{Label: "example.com/pod", Pattern: `^pod-[a-z]$`}},
+    }
+}
+
+func TestNodeLabelSchemaResolve(t *testing.T) {
+    tests := []struct {
+        name     string
+        schema   *NodeLabelSchema
+        validate bool
+        node     *v1.Node
+        want     NodeTopology
+        wantErr  bool
+    }{
+        {
+            name:     "default labels",
+            schema:   DefaultNodeLabelSchema(),
+            validate: true,
+            node:     schemaNode("gpu-001", map[string]string{LeafLabel: "leaf-0", SpineLabel: "spine-0"}, nil),
+            want:     NodeTopology{Leaf: "leaf-0", Spine: "spine-0"},
+        },
+        {
+            name:     "capture group and whole match",
+            schema:   rackSchema(),
+            validate: true,
+            node:     schemaNode("gpu-001", map[string]string{"example.com/rack": "rack-12-leaf", "example.com/pod": "pod-b"}, nil),
+            want:     NodeTopology{Leaf: "12", Spine: "pod-b"},
+        },
+        {
+            name:     "falls through to the annotation",
+            schema:   rackSchema(),
+            validate: true,
+            node:     schemaNode("gpu-001", map[string]string{"example.com/rack": "storage"}, map[string]string{"example.com/switch": "sw-3"}),
+            want:     NodeTopology{Leaf: "sw-3"},
+        },
+        {
+            name:   "patterns apply without Validate",
+            schema: rackSchema(),
+            node:   schemaNode("gpu-001", map[string]string{"example.com/rack": "rack-7-leaf", "example.com/pod": "pod-1"}, nil),
+            want:   NodeTopology{Leaf: "7"},
+        },
+        {
+            name:    "invalid pattern without Validate",
+            schema:  &NodeLabelSchema{Leaf: []LabelSource{{Label: "example.com/rack", Pattern: `rack-(`}}},
+            node:    schemaNode("gpu-001", map[string]string{"example.com/rack": "rack-7"}, nil),
+            wantErr: true,
+        },
+        {
+            name:     "no leaf",
+            schema:   rackSchema(),
+            validate: true,
+            node:     schemaNode("gpu-001", map[string]string{"example.com/pod": "pod-a"}, nil),
+            wantErr:  true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if tt.validate {
+                if err := tt.schema.Validate(); err != nil {
+                    t.Fatalf("Validate() error = %v", err)
+                }
+            }
+            got, err := tt.schema.Resolve(tt.node)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if !tt.wantErr && got != tt.want {
+                t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestNodeLabelSchemaUnmatchedNodes(t *testing.T) {
+    schema := rackSchema()
+    if err := schema.Validate(); err != nil {
+        t.Fatal(err)
+    }
+    nodes := []*v1.Node{
+        schemaNode("gpu-003", map[string]string{"example.com/rack": "storage"}, nil),
+        schemaNode("gpu-001", map[string]string{"example.com/rack": "rack-1-leaf"}, nil),
+        schemaNode("gpu-002", nil, nil),
+        schemaNode("gpu-004", nil, map[string]string{"example.com/switch": "sw-1"}),
+    }
+    want := []string{"gpu-002", "gpu-003"}
+    if got := schema.UnmatchedNodes(nodes); !reflect.DeepEqual(got, want) {
+        t.Errorf("UnmatchedNodes() = %v, want %v", got, want)
+    }
+}
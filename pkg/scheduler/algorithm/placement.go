@@ -27,11 +27,7 @@ func (pm *PlacementManager) FindOptimalPlacement(
     requirements := extractResourceRequirements(pod)
     
     // Score all nodes
-    nodeScores := make(map[string]float64)
-    for _, node := range nodes {
-        score := pm.scorer.ScoreNode(node, requirements, constraints)
-        nodeScores[node.Name] = score
-    }
+    nodeScores := pm.scorer.ScoreNodes(ctx, pod, nodes, requirements, constraints)
 
     // Sort nodes by score
     sortedNodes := sortNodesByScore(nodeScores)
@@ -3,14 +3,24 @@ package algorithm
 import (
     "fmt"
     "sync"
+    "time"
     "context"
     v1 "k8s.io/api/core/v1"
     metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    utilerrors "k8s.io/apimachinery/pkg/util/errors"
+    "k8s.io/klog/v2"
 )
 
+// PodMover deletes and recreates pods when they are migrated
+type PodMover interface {
+    DeletePod(ctx context.Context, pod *v1.Pod) error
+    CreatePod(ctx context.Context, pod *v1.Pod) (*v1.Pod, error)
+}
+
 type RecoveryManager struct {
     domainManager *DomainManager
     scheduler     *TopologyScheduler
+    mover         PodMover
     recoveryLock  sync.Mutex
 }
 
@@ -18,9 +28,18 @@ func NewRecoveryManager(dm *DomainManager, scheduler *TopologyScheduler) *Recove
     return &RecoveryManager{
         domainManager: dm,
         scheduler:     scheduler,
+        mover:         scheduler,
     }
 }
 
+// SetPodMover replaces the API calls used to migrate pods, e.g. with the
+// simulator's bookkeeping
+func (rm *RecoveryManager) SetPodMover(mover PodMover) {
+    rm.recoveryLock.Lock()
+    defer rm.recoveryLock.Unlock()
+    rm.mover = mover
+}
+
 func (rm *RecoveryManager) HandleNodeFailure(node *v1.Node, pods []*v1.Pod) error {
     rm.recoveryLock.Lock()
     defer rm.recoveryLock.Unlock()
@@ -48,6 +67,104 @@ func (rm *RecoveryManager) HandleNodeFailure(node *v1.Node, pods []*v1.Pod) erro
     return rm.domainManager.HandleNodeRemoval(node.Name)
 }
 
+// HandleDomainNoExecute moves the pods running in a domain that do not
+// tolerate its NoExecute taints, including inherited ones, or whose
+// TolerationSeconds have run out. The replacement nodes are found through
+// the regular scheduling path, which already filters out the tainted
+// domain. A pod that cannot be moved does not stop the others. The
+// returned duration is the time until the next toleration runs out, zero
+// when none is pending.
+func (rm *RecoveryManager) HandleDomainNoExecute(domain *Domain, pods []*v1.Pod) (time.Duration, error) {
+    rm.recoveryLock.Lock()
+    defer rm.recoveryLock.Unlock()
+
+    now := time.Now()
+    var evicted []*v1.Pod
+    var errs []error
+    var wait time.Duration
+    for _, pod := range pods {
+        if pod.DeletionTimestamp != nil || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+            continue
+        }
+        evict, left, err := rm.scheduler.noExecuteEviction(pod, domain, now)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("failed to check taints for pod %s: %v", pod.Name, err))
+            continue
+        }
+        if evict {
+            evicted = append(evicted, pod)
+        } else if left > 0 && (wait == 0 || left < wait) {
+            wait = left
+        }
+    }
+
+    sortPodsByPriority(evicted)
+    for _, pod := range evicted {
+        newNode, err := rm.scheduler.FindNodeForPod(pod)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("failed to find replacement node for pod %s: %v", pod.Name, err))
+            continue
+        }
+        if err := rm.migratePod(pod, newNode); err != nil {
+            errs = append(errs, err)
+        }
+    }
+    return wait, utilerrors.NewAggregate(errs)
+}
+
+// EvictNoExecute moves the pods out of every domain under a NoExecute
+// taint, own or inherited. pods lists the pods running on the given nodes.
+// It returns the time until the next toleration runs out, zero when none
+// is pending.
+func (rm *RecoveryManager) EvictNoExecute(pods func(nodes []string) ([]*v1.Pod, error)) (time.Duration, error) {
+    cache := rm.scheduler.cache
+    var errs []error
+    var wait time.Duration
+    for _, domain := range cache.GetAllDomains() {
+        taints, err := cache.GetEffectiveTaints(domain.Name)
+        if err != nil || !hasEffect(v1.TaintEffectNoExecute, taintEffects(taints)) {
+            continue
+        }
+        nodes, err := cache.GetDomainNodeNames(domain.Name)
+        if err != nil || len(nodes) == 0 {
+            continue
+        }
+        inDomain, err := pods(nodes)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("failed to list pods in domain %s: %v", domain.Name, err))
+            continue
+        }
+        left, err := rm.HandleDomainNoExecute(domain, inDomain)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("domain %s: %v", domain.Name, err))
+        }
+        if left > 0 && (wait == 0 || left < wait) {
+            wait = left
+        }
+    }
+    return wait, utilerrors.NewAggregate(errs)
+}
+
+// RunNoExecute calls EvictNoExecute every interval, and as soon as a
+// toleration runs out, until stopCh is closed
+func (rm *RecoveryManager) RunNoExecute(stopCh <-chan struct{}, interval time.Duration, pods func(nodes []string) ([]*v1.Pod, error)) {
+    for {
+        next := interval
+        left, err := rm.EvictNoExecute(pods)
+        if err != nil {
+            klog.Warningf("Failed to move pods out of NoExecute domains: %v", err)
+        }
+        if left > 0 && left < next {
+            next = left
+        }
+        select {
+        case <-stopCh:
+            return
+        case <-time.After(next):
+        }
+    }
+}
+
 func (rm *RecoveryManager) categorizePods(pods []*v1.Pod) (gpuPods []*v1.Pod, nonGpuPods []*v1.Pod) {
     for _, pod := range pods {
         if requiresGPU(pod) {
@@ -133,12 +250,12 @@ func (rm *RecoveryManager) migratePod(pod *v1.Pod, newNode *v1.Node) error {
     newPod.Status.StartTime = &now
 
     // Delete old pod and create new one
-    err := rm.scheduler.DeletePod(context.Background(), pod)
+    err := rm.mover.DeletePod(context.Background(), pod)
     if err != nil {
         return fmt.Errorf("failed to delete old pod %s: %v", pod.Name, err)
     }
 
-    _, err = rm.scheduler.CreatePod(context.Background(), newPod)
+    _, err = rm.mover.CreatePod(context.Background(), newPod)
     if err != nil {
         return fmt.Errorf("failed to create new pod %s on node %s: %v", 
             newPod.Name, newNode.Name, err)
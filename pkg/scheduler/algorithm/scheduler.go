@@ -7,35 +7,101 @@ import (
     "sync"
     "time"
     v1 "k8s.io/api/core/v1"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/history"
 )
 
 type TopologyScheduler struct {
     sync.RWMutex
     cache            *TopologyCache
+    // configMu guards the settings swapped in by SetConfig, the tuner and
+    // the external scorer
+    configMu         sync.RWMutex
     scoreWeights     TopologyScore
+    constraints      TopologyConstraints
+    // profileFactors scales scoreWeights for each communication profile
+    profileFactors   map[CommProfile]TopologyScore
     domains          map[string]*Domain
     spineConnections map[string][]string
     metrics          *MetricsCollector
     monitor          *DomainMonitor
+    stragglers       *StragglerDetector
+    history          *history.Store
+    historyEvents    chan historyEvent
+    // historyMu guards the last throughput taken from each job
+    historyMu        sync.Mutex
+    lastThroughput   map[string]float64
+    tuner            *WeightTuner
+    external         *externalScoring
 }
 
 func NewTopologyScheduler(cache *TopologyCache) *TopologyScheduler {
     ts := &TopologyScheduler{
-        cache: cache,
-        scoreWeights: TopologyScore{
-            ResourceAvailability: 0.4,
-            TopologyAlignment:    0.3,
-            DomainUtilization:    0.2,
-            HistoricalPerf:       0.1,
-        },
+        cache:            cache,
+        scoreWeights:     DefaultScoringWeights(),
+        profileFactors:   profileWeightFactors(nil),
         domains:          make(map[string]*Domain),
         spineConnections: make(map[string][]string),
         metrics:          NewMetricsCollector(),
     }
     ts.monitor = NewDomainMonitor(ts)
+    ts.stragglers = NewStragglerDetector(cache, DefaultStragglerConfig(), ts.metrics)
     return ts
 }
 
+func DefaultScoringWeights() TopologyScore {
+    return TopologyScore{
+        ResourceAvailability: 0.4,
+        TopologyAlignment:    0.3,
+        DomainUtilization:    0.2,
+        HistoricalPerf:       0.1,
+    }
+}
+
+// SetConfig validates the scoring weights, profile weight factors and
+// topology constraints of the spec and swaps them into the running
+// scheduler at once. Unset parts return to their defaults. A weight tuner
+// restarts from the new weights, clamped to its bounds.
+func (ts *TopologyScheduler) SetConfig(spec *SchedulerConfigSpec) error {
+    if err := spec.ValidateScheduling(); err != nil {
+        return err
+    }
+    weights := DefaultScoringWeights()
+    if spec.ScoringWeights != nil {
+        weights = spec.ScoringWeights.TopologyScore()
+    }
+    var constraints TopologyConstraints
+    if spec.TopologyConstraints != nil {
+        constraints = *spec.TopologyConstraints
+    }
+    factors := profileWeightFactors(spec.CommProfiles)
+    tuner := ts.Tuner()
+    if tuner != nil {
+        weights = tuner.bound(weights)
+    }
+
+    ts.configMu.Lock()
+    changed := ts.scoreWeights != weights
+    ts.scoreWeights = weights
+    ts.constraints = constraints
+    ts.profileFactors = factors
+    ts.configMu.Unlock()
+
+    if tuner != nil && changed {
+        tuner.SetWeights(weights)
+    }
+    klog.Infof("Scheduler configuration updated: weights %+v, constraints %+v", weights, constraints)
+    return nil
+}
+
+// topologyConstraints returns the constraints set by SetConfig
+func (ts *TopologyScheduler) topologyConstraints() TopologyConstraints {
+    ts.configMu.RLock()
+    defer ts.configMu.RUnlock()
+    return ts.constraints
+}
+
 func (ts *TopologyScheduler) Schedule(ctx context.Context, pod *v1.Pod) (*v1.Node, error) {
     startTime := time.Now()
     defer func() {
@@ -47,6 +113,10 @@ func (ts *TopologyScheduler) Schedule(ctx context.Context, pod *v1.Pod) (*v1.Nod
         ts.metrics.IncSchedulingError("invalid_gpu_requirements")
         return nil, fmt.Errorf("failed to get GPU requirements: %v", err)
     }
+    if err := ts.annotateRequirements(pod, gpuReq); err != nil {
+        ts.metrics.IncSchedulingError("invalid_comm_profile")
+        return nil, err
+    }
 
     strategy := ts.getPlacementStrategy(gpuReq)
     var result *PlacementResult
@@ -69,26 +139,135 @@ func (ts *TopologyScheduler) Schedule(ctx context.Context, pod *v1.Pod) (*v1.Nod
         ts.metrics.IncSchedulingError(fmt.Sprintf("placement_%s", strategy))
         return nil, err
     }
+    if err := ts.checkPlacementConstraints(gpuReq, result.Nodes); err != nil {
+        ts.metrics.IncSchedulingError("topology_constraints")
+        return nil, err
+    }
 
     ts.metrics.ObservePlacementResult(result)
     ts.updateDomainState(result)
+    ts.recordJobPlacement(pod, result.Nodes, gpuReq.GPUsPerNode)
 
     return result.Nodes[0], nil
 }
 
+// Release returns the GPUs a finished or evicted pod holds on the node to
+// its domain and forgets the pod's job there, undoing the state updates
+// made by Schedule. A pod placed on several nodes is released once for
+// each of them. The job is forgotten in the topology cache even when the
+// scheduler does not track the node's domain itself.
+func (ts *TopologyScheduler) Release(pod *v1.Pod, node *v1.Node) error {
+    gpus := getGPURequirements(pod)
+    if held, ok := ts.cache.GetJobNodes(jobNameForPod(pod))[node.Name]; ok && held < gpus {
+        gpus = held
+    }
+
+    ts.Lock()
+    domain := ts.getDomainForNode(node)
+    if domain != nil {
+        domain.UsedGPUs -= gpus
+        if domain.UsedGPUs < 0 {
+            domain.UsedGPUs = 0
+        }
+    }
+    ts.Unlock()
+
+    ts.releaseJobPlacement(pod, node.Name)
+    if domain == nil {
+        return fmt.Errorf("node %s not found in any domain", node.Name)
+    }
+    return nil
+}
+
+// StragglerDetector returns the detector fed by step time reports
+func (ts *TopologyScheduler) StragglerDetector() *StragglerDetector {
+    return ts.stragglers
+}
+
 func (ts *TopologyScheduler) getPlacementStrategy(gpuReq *GPURequirements) PlacementStrategy {
+    thresholds := ts.thresholdsFor(gpuReq.CommProfile)
     switch {
-    case gpuReq.NodesNeeded <= 2:
+    case gpuReq.NodesNeeded <= thresholds.SingleDomainMaxNodes:
         return SingleDomain
-    case gpuReq.NodesNeeded == 4:
+    case thresholds.CompleteDomainNodes > 0 && gpuReq.NodesNeeded == thresholds.CompleteDomainNodes:
         return CompleteDomain
-    case gpuReq.NodesNeeded <= 8:
+    case gpuReq.NodesNeeded <= thresholds.AdjacentDomainsMaxNodes:
         return AdjacentDomains
     default:
         return MultipleDomains
     }
 }
 
+// calculateDomainScore combines the weighted scoring factors into [0, 1]
+func (ts *TopologyScheduler) calculateDomainScore(domain *Domain, gpuReq *GPURequirements) float64 {
+    if domain.TotalGPUs == 0 {
+        return 0
+    }
+
+    free := domain.TotalGPUs - domain.UsedGPUs
+    availability := float64(free) / float64(domain.TotalGPUs)
+
+    alignment := 1.0
+    if gpuReq.TotalGPUs > free {
+        alignment = float64(free) / float64(gpuReq.TotalGPUs)
+    }
+
+    // Jobs spanning leaves prefer domains with faster links to the others
+    if strategy := ts.getPlacementStrategy(gpuReq); strategy == AdjacentDomains || strategy == MultipleDomains {
+        alignment *= ts.cache.GetBandwidthRatio(domain.Name)
+    }
+
+    // Prefer filling partially used domains to keep others whole
+    utilization := float64(domain.UsedGPUs) / float64(domain.TotalGPUs)
+
+    historical := ts.historicalPerfScore(domain, gpuReq.JobName)
+
+    weights := ts.weightsFor(gpuReq)
+    return weights.ResourceAvailability*availability +
+        weights.TopologyAlignment*alignment +
+        weights.DomainUtilization*utilization +
+        weights.HistoricalPerf*historical
+}
+
+// EnableTuner starts online tuning of the scoring weights from the
+// current ones, clamped to the tuner bounds.
+func (ts *TopologyScheduler) EnableTuner(config TunerConfig) error {
+    if err := config.Validate(); err != nil {
+        return fmt.Errorf("invalid tuner configuration: %v", err)
+    }
+
+    ts.configMu.Lock()
+    defer ts.configMu.Unlock()
+    ts.tuner = NewWeightTuner(ts.scoreWeights, config, ts.metrics)
+    ts.scoreWeights = ts.tuner.current
+    return nil
+}
+
+// Tuner returns the weight tuner, or nil when tuning is disabled
+func (ts *TopologyScheduler) Tuner() *WeightTuner {
+    ts.configMu.RLock()
+    defer ts.configMu.RUnlock()
+    return ts.tuner
+}
+
+// weightsFor returns the scheduler-wide (possibly tuned) weights, scaled
+// by the factors of the job's communication profile.
+func (ts *TopologyScheduler) weightsFor(gpuReq *GPURequirements) TopologyScore {
+    ts.configMu.RLock()
+    weights := ts.scoreWeights
+    factors, scaled := ts.profileFactors[gpuReq.CommProfile]
+    tuner := ts.tuner
+    ts.configMu.RUnlock()
+
+    if tuner != nil {
+        weights = tuner.WeightsForJob(gpuReq.JobName)
+    }
+    if !scaled {
+        return weights
+    }
+    return scaleWeights(weights, factors)
+}
+
 func (ts *TopologyScheduler) findCompleteFreeDomains() []*Domain {
     var freeDomains []*Domain
     for _, domain := range ts.domains {
@@ -104,12 +283,13 @@ func (ts *TopologyScheduler) selectNodesAcrossDomains(domains []*Domain, gpuReq
     remainingNodes := gpuReq.NodesNeeded
 
     for _, domain := range domains {
-        availableNodes := ts.getAvailableNodes(domain)
-        if len(availableNodes) == 0 {
+        availableNodes := ts.getAvailableNodes(domain, gpuReq)
+        allowance := ts.leafNodeAllowance(domain, gpuReq)
+        if len(availableNodes) == 0 || allowance == 0 {
             continue
         }
 
-        nodesFromDomain := min(remainingNodes, len(availableNodes))
+        nodesFromDomain := min(remainingNodes, min(len(availableNodes), allowance))
         selectedNodes = append(selectedNodes, availableNodes[:nodesFromDomain]...)
         remainingNodes -= nodesFromDomain
 
@@ -119,7 +299,7 @@ func (ts *TopologyScheduler) selectNodesAcrossDomains(domains []*Domain, gpuReq
     }
 
     if remainingNodes > 0 {
-        return nil, fmt.Errorf("insufficient nodes across domains")
+        return nil, fmt.Errorf("insufficient nodes across domains within the topology constraints")
     }
 
     return selectedNodes, nil
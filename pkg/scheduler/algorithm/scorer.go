@@ -1,13 +1,17 @@
 package scheduler
 
 import (
+    "context"
     "k8s.io/api/core/v1"
     "math"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/extension"
 )
 
 type Scorer struct {
     topology *TopologyManager
     weights  *ScoringWeights
+    external *externalScoring
 }
 
 type ScoringWeights struct {
@@ -24,6 +28,37 @@ func NewScorer(topology *TopologyManager, weights *ScoringWeights) *Scorer {
     }
 }
 
+// SetExternalScorer blends scores from an external scoring service into
+// ScoreNodes, giving them the given share of the final score.
+func (s *Scorer) SetExternalScorer(client *extension.Client, weight float64, cache *TopologyCache, mc *MetricsCollector) {
+    s.external = newExternalScoring(client, weight, cache, mc)
+}
+
+// ScoreNodes scores all candidate nodes for a pod, consulting the external
+// scorer once for the whole set when one is configured.
+func (s *Scorer) ScoreNodes(
+    ctx context.Context,
+    pod *v1.Pod,
+    nodes []*v1.Node,
+    requirements *ResourceRequirements,
+    constraints *SchedulingConstraints,
+) map[string]float64 {
+    scores := make(map[string]float64, len(nodes))
+    for _, node := range nodes {
+        scores[node.Name] = s.ScoreNode(node, requirements, constraints)
+    }
+    if s.external == nil {
+        return scores
+    }
+
+    gpuReq := &GPURequirements{TotalGPUs: getGPURequirements(pod)}
+    if profile, err := parseCommProfile(pod); err == nil {
+        gpuReq.CommProfile = profile
+    }
+    gpuReq.JobName = jobNameForPod(pod)
+    return s.external.blend(ctx, pod, gpuReq, scores)
+}
+
 func (s *Scorer) ScoreNode(
     node *v1.Node,
     requirements *ResourceRequirements,
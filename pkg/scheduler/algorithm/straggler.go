@@ -0,0 +1,355 @@
+package algorithm
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/apimachinery/pkg/types"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/utils/metrics"
+)
+
+// StepTimeAnnotation lets a running job publish the step time, in
+// milliseconds, observed by the pod on its node.
+const StepTimeAnnotation = "topology.scheduler/step-time-ms"
+
+// StepTimeReportedAtAnnotation optionally carries the time of the step time
+// report, so that a repeated value is taken as a new report.
+const StepTimeReportedAtAnnotation = "topology.scheduler/step-time-reported-at"
+
+// StragglerConfig controls when a node is considered a straggler
+type StragglerConfig struct {
+    // SlowRatio is how much slower than the median of its domain peers a
+    // node must be
+    SlowRatio float64
+    // MinSlowReports is the number of consecutive slow reports before quarantine
+    MinSlowReports int
+    // RecoveryReports is the number of consecutive normal reports to leave quarantine
+    RecoveryReports int
+    // Alpha is the smoothing factor for the per-node step time average
+    Alpha float64
+    // SlowPenalty scales the score of a node that is slow but not yet quarantined
+    SlowPenalty float64
+    // ReportTTL drops nodes that have stopped reporting
+    ReportTTL time.Duration
+}
+
+func DefaultStragglerConfig() StragglerConfig {
+    return StragglerConfig{
+        SlowRatio:       1.25,
+        MinSlowReports:  5,
+        RecoveryReports: 10,
+        Alpha:           0.3,
+        SlowPenalty:     0.5,
+        ReportTTL:       30 * time.Minute,
+    }
+}
+
+type nodeStepStats struct {
+    avgStepTime  float64
+    slowStreak   int
+    normalStreak int
+    quarantined  bool
+    lastReport   time.Time
+}
+
+// StragglerDetector tracks the step times of the ranks of each job and
+// flags nodes that are persistently slower than their domain peers running
+// the same job.
+type StragglerDetector struct {
+    mu     sync.RWMutex
+    cache  *TopologyCache
+    config StragglerConfig
+    // stats holds the step times of each job by node
+    stats map[string]map[string]*nodeStepStats
+    // observed holds the last annotation report ingested for each pod
+    observed map[types.UID]string
+    metrics  *MetricsCollector
+}
+
+func NewStragglerDetector(cache *TopologyCache, config StragglerConfig, mc *MetricsCollector) *StragglerDetector {
+    return &StragglerDetector{
+        cache:    cache,
+        config:   config,
+        stats:    make(map[string]map[string]*nodeStepStats),
+        observed: make(map[types.UID]string),
+        metrics:  mc,
+    }
+}
+
+// ReportStepTime records one step time sample for the rank of the job on
+// the node
+func (sd *StragglerDetector) ReportStepTime(jobName, nodeName string, stepTime time.Duration) error {
+    if stepTime <= 0 {
+        return fmt.Errorf("invalid step time %v for node %s", stepTime, nodeName)
+    }
+
+    sd.mu.Lock()
+    defer sd.mu.Unlock()
+
+    sd.report(jobName, nodeName, stepTime)
+    return nil
+}
+
+// report must be called with sd.mu held
+func (sd *StragglerDetector) report(jobName, nodeName string, stepTime time.Duration) {
+    ranks, ok := sd.stats[jobName]
+    if !ok {
+        ranks = make(map[string]*nodeStepStats)
+        sd.stats[jobName] = ranks
+    }
+    stats, ok := ranks[nodeName]
+    if !ok {
+        stats = &nodeStepStats{avgStepTime: stepTime.Seconds()}
+        ranks[nodeName] = stats
+    } else {
+        stats.avgStepTime = metrics.CalculateEMA(stats.avgStepTime, stepTime.Seconds(), sd.config.Alpha)
+    }
+    stats.lastReport = time.Now()
+
+    sd.evaluate(jobName, nodeName, stats)
+}
+
+// ReportThroughput records a throughput sample (e.g. samples per second),
+// which is treated as the inverse of step time.
+func (sd *StragglerDetector) ReportThroughput(jobName, nodeName string, throughput float64) error {
+    if throughput <= 0 {
+        return fmt.Errorf("invalid throughput %v for node %s", throughput, nodeName)
+    }
+    return sd.ReportStepTime(jobName, nodeName, time.Duration(float64(time.Second)/throughput))
+}
+
+// ObservePod ingests the step time annotation of a running pod. Pod
+// updates that leave the annotation and its report time unchanged are not
+// counted again.
+func (sd *StragglerDetector) ObservePod(pod *v1.Pod) error {
+    val, ok := pod.Annotations[StepTimeAnnotation]
+    if !ok || pod.Spec.NodeName == "" {
+        return nil
+    }
+
+    ms, err := strconv.ParseFloat(val, 64)
+    if err != nil {
+        return fmt.Errorf("invalid %s annotation on pod %s: %v", StepTimeAnnotation, pod.Name, err)
+    }
+    stepTime := time.Duration(ms * float64(time.Millisecond))
+    if stepTime <= 0 {
+        return fmt.Errorf("invalid step time %v on pod %s", stepTime, pod.Name)
+    }
+
+    sd.mu.Lock()
+    defer sd.mu.Unlock()
+
+    report := val + "@" + pod.Annotations[StepTimeReportedAtAnnotation]
+    if sd.observed[pod.UID] == report {
+        return nil
+    }
+    sd.observed[pod.UID] = report
+    sd.report(jobNameForPod(pod), pod.Spec.NodeName, stepTime)
+    return nil
+}
+
+// ForgetPod drops the last report ingested from the pod
+func (sd *StragglerDetector) ForgetPod(pod *v1.Pod) {
+    sd.mu.Lock()
+    defer sd.mu.Unlock()
+
+    delete(sd.observed, pod.UID)
+}
+
+// ForgetJob drops the step times of a job that no longer runs and clears
+// the quarantine it caused
+func (sd *StragglerDetector) ForgetJob(jobName string) {
+    sd.mu.Lock()
+    defer sd.mu.Unlock()
+
+    ranks, ok := sd.stats[jobName]
+    if !ok {
+        return
+    }
+    delete(sd.stats, jobName)
+    for nodeName := range ranks {
+        sd.updateMetrics(nodeName)
+    }
+}
+
+// evaluate compares the rank on the node against the median of its domain
+// peers, the other nodes of the node's domain with a recent report. Step
+// times of different jobs are not comparable, so a peer counts with its
+// report for the same job. Must be called with sd.mu held.
+func (sd *StragglerDetector) evaluate(jobName, nodeName string, stats *nodeStepStats) {
+    domain, err := sd.cache.GetDomainForNode(nodeName)
+    if err != nil {
+        return
+    }
+    nodeNames, err := sd.cache.GetDomainNodeNames(domain.Name)
+    if err != nil {
+        return
+    }
+
+    ranks := sd.stats[jobName]
+    var peers []float64
+    for _, peerName := range nodeNames {
+        peer, ok := ranks[peerName]
+        if peerName == nodeName || !ok || time.Since(peer.lastReport) > sd.config.ReportTTL {
+            continue
+        }
+        peers = append(peers, peer.avgStepTime)
+    }
+    // A straggler can only be identified relative to others
+    if len(peers) == 0 {
+        return
+    }
+
+    median := medianOf(peers)
+    if stats.avgStepTime > median*sd.config.SlowRatio {
+        stats.slowStreak++
+        stats.normalStreak = 0
+        if !stats.quarantined && stats.slowStreak >= sd.config.MinSlowReports {
+            stats.quarantined = true
+            klog.Infof("Quarantining straggler node %s: avg step %.3fs vs median %.3fs of domain %s in job %s",
+                nodeName, stats.avgStepTime, median, domain.Name, jobName)
+        }
+    } else {
+        stats.normalStreak++
+        stats.slowStreak = 0
+        if stats.quarantined && stats.normalStreak >= sd.config.RecoveryReports {
+            stats.quarantined = false
+            klog.Infof("Node %s recovered from straggler quarantine", nodeName)
+        }
+    }
+
+    sd.updateMetrics(nodeName)
+}
+
+// nodeState reports whether any job with a recent report has quarantined
+// the node or sees it trending slow. Must be called with sd.mu held.
+func (sd *StragglerDetector) nodeState(nodeName string) (quarantined, slow bool) {
+    for _, ranks := range sd.stats {
+        stats, ok := ranks[nodeName]
+        if !ok || time.Since(stats.lastReport) > sd.config.ReportTTL {
+            continue
+        }
+        quarantined = quarantined || stats.quarantined
+        slow = slow || stats.slowStreak > 0
+    }
+    return quarantined, slow
+}
+
+// updateMetrics must be called with sd.mu held
+func (sd *StragglerDetector) updateMetrics(nodeName string) {
+    if sd.metrics == nil {
+        return
+    }
+    domain, err := sd.cache.GetDomainForNode(nodeName)
+    if err != nil {
+        return
+    }
+    quarantined, _ := sd.nodeState(nodeName)
+    sd.metrics.UpdateStragglerStatus(nodeName, domain.Name, quarantined)
+}
+
+// IsQuarantined reports whether the node is currently quarantined
+func (sd *StragglerDetector) IsQuarantined(nodeName string) bool {
+    sd.mu.RLock()
+    defer sd.mu.RUnlock()
+
+    quarantined, _ := sd.nodeState(nodeName)
+    return quarantined
+}
+
+// NodeFactor returns the multiplier applied to a node's score: 0 while
+// quarantined, SlowPenalty while trending slow, 1 otherwise.
+func (sd *StragglerDetector) NodeFactor(nodeName string) float64 {
+    sd.mu.RLock()
+    defer sd.mu.RUnlock()
+
+    quarantined, slow := sd.nodeState(nodeName)
+    switch {
+    case quarantined:
+        return 0.0
+    case slow:
+        return sd.config.SlowPenalty
+    default:
+        return 1.0
+    }
+}
+
+type stepReport struct {
+    Job        string  `json:"job"`
+    Node       string  `json:"node"`
+    StepTimeMs float64 `json:"stepTimeMs,omitempty"`
+    Throughput float64 `json:"throughput,omitempty"`
+}
+
+// ServeHTTP accepts POSTed JSON reports, either a single object or a list,
+// each carrying a job, a node and a step time or throughput. A list is
+// applied only when every report in it is valid.
+func (sd *StragglerDetector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    var reports []stepReport
+    decoder := json.NewDecoder(r.Body)
+    var raw json.RawMessage
+    if err := decoder.Decode(&raw); err != nil {
+        http.Error(w, fmt.Sprintf("invalid report: %v", err), http.StatusBadRequest)
+        return
+    }
+    if err := json.Unmarshal(raw, &reports); err != nil {
+        var single stepReport
+        if err := json.Unmarshal(raw, &single); err != nil {
+            http.Error(w, fmt.Sprintf("invalid report: %v", err), http.StatusBadRequest)
+            return
+        }
+        reports = []stepReport{single}
+    }
+
+    // Check the whole batch first so a bad report applies none of it
+    stepTimes := make([]time.Duration, len(reports))
+    for i, report := range reports {
+        switch {
+        case report.Job == "":
+            http.Error(w, fmt.Sprintf("report for node %s names no job", report.Node), http.StatusBadRequest)
+            return
+        case report.Node == "":
+            http.Error(w, fmt.Sprintf("report for job %s names no node", report.Job), http.StatusBadRequest)
+            return
+        case report.StepTimeMs > 0:
+            stepTimes[i] = time.Duration(report.StepTimeMs * float64(time.Millisecond))
+        case report.Throughput > 0:
+            stepTimes[i] = time.Duration(float64(time.Second) / report.Throughput)
+        }
+        if stepTimes[i] <= 0 {
+            http.Error(w, fmt.Sprintf("report for node %s has no valid step time or throughput", report.Node), http.StatusBadRequest)
+            return
+        }
+    }
+
+    sd.mu.Lock()
+    defer sd.mu.Unlock()
+
+    for i, report := range reports {
+        sd.report(report.Job, report.Node, stepTimes[i])
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func medianOf(values []float64) float64 {
+    sorted := append([]float64(nil), values...)
+    sort.Float64s(sorted)
+    mid := len(sorted) / 2
+    if len(sorted)%2 == 0 {
+        return (sorted[mid-1] + sorted[mid]) / 2
+    }
+    return sorted[mid]
+}
@@ -0,0 +1,135 @@
+package algorithm
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newStragglerTestDetector builds leaf-0 with gpu-001 to gpu-003 and
+// leaf-1 with gpu-004 and gpu-005, and a detector that quarantines after
+// two slow reports
+func newStragglerTestDetector(t *testing.T) *StragglerDetector {
+    t.Helper()
+    nodeCache := NewNodeCache()
+    tc := NewTopologyCache(nodeCache)
+    leaves := map[string][]string{
+        "leaf-0": {"gpu-001", "gpu-002", "gpu-003"},
+        "leaf-1": {"gpu-004", "gpu-005"},
+    }
+    for leaf, nodes := range leaves {
+        domain := &Domain{Name: leaf, Level: LevelLeaf, SpineSwitch: "spine-0", Jobs: make(map[string]*PlacedJob)}
+        for _, name := range nodes {
+            node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+            if err := nodeCache.AddNode(node); err != nil {
+                t.Fatal(err)
+            }
+            domain.Nodes = append(domain.Nodes, node)
+        }
+        if err := tc.AddDomain(domain); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    config := DefaultStragglerConfig()
+    config.MinSlowReports = 2
+    config.Alpha = 1
+    return NewStragglerDetector(tc, config, nil)
+}
+
+func TestStragglerComparedWithDomainPeers(t *testing.T) {
+    tests := []struct {
+        name string
+        // step times in ms reported by each node of the job, in rounds
+        steps           map[string]float64
+        wantQuarantined []string
+    }{
+        {
+            name:            "slower than its leaf",
+            steps:           map[string]float64{"gpu-001": 100, "gpu-002": 100, "gpu-003": 200},
+            wantQuarantined: []string{"gpu-003"},
+        },
+        {
+            // leaf-1 is slower as a whole, but its nodes match each other
+            name:  "slow leaf without stragglers",
+            steps: map[string]float64{"gpu-001": 100, "gpu-002": 100, "gpu-004": 200, "gpu-005": 200},
+        },
+        {
+            name:  "no peer in the domain",
+            steps: map[string]float64{"gpu-001": 100, "gpu-004": 300},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            sd := newStragglerTestDetector(t)
+            for round := 0; round < 3; round++ {
+                for node, ms := range tt.steps {
+                    if err := sd.ReportStepTime("default/train", node, time.Duration(ms*float64(time.Millisecond))); err != nil {
+                        t.Fatal(err)
+                    }
+                }
+            }
+            want := make(map[string]bool)
+            for _, node := range tt.wantQuarantined {
+                want[node] = true
+            }
+            for node := range tt.steps {
+                if got := sd.IsQuarantined(node); got != want[node] {
+                    t.Errorf("IsQuarantined(%s) = %v, want %v", node, got, want[node])
+                }
+            }
+        })
+    }
+}
+
+func TestStragglerReportBatch(t *testing.T) {
+    tests := []struct {
+        name       string
+        body       string
+        wantStatus int
+        wantNodes  int
+    }{
+        {
+            name:       "single report",
+            body:       `{"job": "default/train", "node": "gpu-001", "stepTimeMs": 100}`,
+            wantStatus: http.StatusNoContent,
+            wantNodes:  1,
+        },
+        {
+            name:       "list of reports",
+            body:       `[{"job": "default/train", "node": "gpu-001", "stepTimeMs": 100}, {"job": "default/train", "node": "gpu-002", "throughput": 10}]`,
+            wantStatus: http.StatusNoContent,
+            wantNodes:  2,
+        },
+        {
+            name:       "report without a node",
+            body:       `[{"job": "default/train", "node": "gpu-001", "stepTimeMs": 100}, {"job": "default/train", "stepTimeMs": 100}]`,
+            wantStatus: http.StatusBadRequest,
+        },
+        {
+            name:       "report without a step time",
+            body:       `[{"job": "default/train", "node": "gpu-001", "stepTimeMs": 100}, {"job": "default/train", "node": "gpu-002"}]`,
+            wantStatus: http.StatusBadRequest,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            sd := newStragglerTestDetector(t)
+            rec := httptest.NewRecorder()
+            sd.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stragglers/report", strings.NewReader(tt.body)))
+            if rec.Code != tt.wantStatus {
+                t.Fatalf("ServeHTTP() status = %d, want %d", rec.Code, tt.wantStatus)
+            }
+            if got := len(sd.stats["default/train"]); got != tt.wantNodes {
+                t.Errorf("recorded %d nodes, want %d", got, tt.wantNodes)
+            }
+        })
+    }
+}
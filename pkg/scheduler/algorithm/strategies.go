@@ -0,0 +1,196 @@
+package algorithm
+
+import (
+    "context"
+    "fmt"
+    "sort"
+
+    v1 "k8s.io/api/core/v1"
+)
+
+// PlacementStrategy decides how many leaves a job may span
+type PlacementStrategy string
+
+const (
+    SingleDomain    PlacementStrategy = "single-domain"
+    CompleteDomain  PlacementStrategy = "complete-domain"
+    AdjacentDomains PlacementStrategy = "adjacent-domains"
+    MultipleDomains PlacementStrategy = "multiple-domains"
+)
+
+// PlacementResult holds the nodes chosen for a job and the mean score of
+// their leaves
+type PlacementResult struct {
+    Strategy PlacementStrategy
+    Nodes    []*v1.Node
+    Score    float64
+}
+
+// isDomainEligible reports whether the leaf can take another node of the
+// job: it has GPUsPerNode free GPUs and neither the job nor its tenant has
+// reached maxNodesPerLeaf or maxGPUsPerLeaf there
+func (ts *TopologyScheduler) isDomainEligible(domain *Domain, gpuReq *GPURequirements) bool {
+    if domain.Level != "" && domain.Level != LevelLeaf {
+        return false
+    }
+    if domain.TotalGPUs-domain.UsedGPUs < gpuReq.GPUsPerNode {
+        return false
+    }
+    return ts.leafNodeAllowance(domain, gpuReq) > 0
+}
+
+// candidateDomains returns the leaves the pod may use, best score first,
+// with the external scores blended in. Every strategy picks from these, so
+// a leaf the job may not grow into is passed over instead of failing the
+// pod.
+func (ts *TopologyScheduler) candidateDomains(ctx context.Context, pod *v1.Pod, gpuReq *GPURequirements) ([]*Domain, map[string]float64) {
+    var candidates []*Domain
+    scores := make(map[string]float64)
+    affinity, err := parsePodDomainAffinity(pod)
+    if err != nil {
+        return nil, scores
+    }
+    for _, domain := range ts.cache.GetAllDomains() {
+        if !ts.isDomainEligible(domain, gpuReq) {
+            continue
+        }
+        if ts.checkCommProfile(domain, gpuReq) != nil ||
+            ts.checkDomainTaints(pod, domain) != nil ||
+            ts.checkDomainAffinity(pod, affinity, domain) != nil {
+            continue
+        }
+        candidates = append(candidates, domain)
+        scores[domain.Name] = ts.calculateDomainScore(domain, gpuReq)
+    }
+    ts.blendExternalScores(ctx, pod, gpuReq, candidates, scores)
+    sort.Slice(candidates, func(i, j int) bool {
+        a, b := candidates[i], candidates[j]
+        if scores[a.Name] != scores[b.Name] {
+            return scores[a.Name] > scores[b.Name]
+        }
+        return a.Name < b.Name
+    })
+    return candidates, scores
+}
+
+// getAvailableNodes returns the nodes of the domain with GPUsPerNode free
+// GPUs
+func (ts *TopologyScheduler) getAvailableNodes(domain *Domain, gpuReq *GPURequirements) []*v1.Node {
+    var nodes []*v1.Node
+    for _, node := range domain.Nodes {
+        free, err := ts.cache.GetNodeAvailableGPUs(node.Name)
+        if err != nil || free < gpuReq.GPUsPerNode {
+            continue
+        }
+        nodes = append(nodes, node)
+    }
+    return nodes
+}
+
+// placePodSingleDomain puts the whole job into the best leaf that has room
+// for it within the topology constraints
+func (ts *TopologyScheduler) placePodSingleDomain(ctx context.Context, pod *v1.Pod, gpuReq *GPURequirements) (*PlacementResult, error) {
+    candidates, scores := ts.candidateDomains(ctx, pod, gpuReq)
+    for _, domain := range candidates {
+        if nodes := ts.nodesInDomain(domain, gpuReq); nodes != nil {
+            return &PlacementResult{Strategy: SingleDomain, Nodes: nodes, Score: scores[domain.Name]}, nil
+        }
+    }
+    return nil, fmt.Errorf("no leaf has %d nodes with %d free GPUs within the topology constraints",
+        gpuReq.NodesNeeded, gpuReq.GPUsPerNode)
+}
+
+// placeCompleteDomain gives the job a leaf nobody else uses, the smallest
+// that fits, and falls back to adjacent leaves when none is free
+func (ts *TopologyScheduler) placeCompleteDomain(ctx context.Context, pod *v1.Pod, gpuReq *GPURequirements) (*PlacementResult, error) {
+    candidates, scores := ts.candidateDomains(ctx, pod, gpuReq)
+    var best *Domain
+    var bestNodes []*v1.Node
+    for _, domain := range candidates {
+        if domain.UsedGPUs > 0 || (best != nil && len(domain.Nodes) >= len(best.Nodes)) {
+            continue
+        }
+        if nodes := ts.nodesInDomain(domain, gpuReq); nodes != nil {
+            best, bestNodes = domain, nodes
+        }
+    }
+    if best == nil {
+        return ts.placePodAdjacentDomains(ctx, pod, gpuReq)
+    }
+    return &PlacementResult{Strategy: CompleteDomain, Nodes: bestNodes, Score: scores[best.Name]}, nil
+}
+
+// placePodAdjacentDomains starts from each candidate leaf in turn and adds
+// the candidates linked to it until the job fits
+func (ts *TopologyScheduler) placePodAdjacentDomains(ctx context.Context, pod *v1.Pod, gpuReq *GPURequirements) (*PlacementResult, error) {
+    candidates, scores := ts.candidateDomains(ctx, pod, gpuReq)
+    eligible := make(map[string]*Domain, len(candidates))
+    for _, domain := range candidates {
+        eligible[domain.Name] = domain
+    }
+
+    for _, start := range candidates {
+        group := []*Domain{start}
+        connected, err := ts.cache.GetConnectedDomains(start.Name)
+        if err != nil {
+            continue
+        }
+        var neighbors []*Domain
+        for _, domain := range connected {
+            if candidate, ok := eligible[domain.Name]; ok {
+                neighbors = append(neighbors, candidate)
+            }
+        }
+        sort.Slice(neighbors, func(i, j int) bool {
+            if scores[neighbors[i].Name] != scores[neighbors[j].Name] {
+                return scores[neighbors[i].Name] > scores[neighbors[j].Name]
+            }
+            return neighbors[i].Name < neighbors[j].Name
+        })
+        group = append(group, neighbors...)
+
+        if nodes, err := ts.selectNodesAcrossDomains(group, gpuReq); err == nil {
+            return &PlacementResult{Strategy: AdjacentDomains, Nodes: nodes, Score: ts.placementScore(nodes, scores)}, nil
+        }
+    }
+    return nil, fmt.Errorf("no adjacent leaves have %d nodes with %d free GPUs within the topology constraints",
+        gpuReq.NodesNeeded, gpuReq.GPUsPerNode)
+}
+
+// placePodMultipleDomains spreads the job over the candidate leaves, best
+// first
+func (ts *TopologyScheduler) placePodMultipleDomains(ctx context.Context, pod *v1.Pod, gpuReq *GPURequirements) (*PlacementResult, error) {
+    candidates, scores := ts.candidateDomains(ctx, pod, gpuReq)
+    nodes, err := ts.selectNodesAcrossDomains(candidates, gpuReq)
+    if err != nil {
+        return nil, err
+    }
+    return &PlacementResult{Strategy: MultipleDomains, Nodes: nodes, Score: ts.placementScore(nodes, scores)}, nil
+}
+
+// nodesInDomain returns NodesNeeded available nodes of the domain, nil when
+// it has fewer or the topology constraints allow fewer
+func (ts *TopologyScheduler) nodesInDomain(domain *Domain, gpuReq *GPURequirements) []*v1.Node {
+    if ts.leafNodeAllowance(domain, gpuReq) < gpuReq.NodesNeeded {
+        return nil
+    }
+    nodes := ts.getAvailableNodes(domain, gpuReq)
+    if len(nodes) < gpuReq.NodesNeeded {
+        return nil
+    }
+    return nodes[:gpuReq.NodesNeeded]
+}
+
+// placementScore averages the scores of the leaves of the chosen nodes
+func (ts *TopologyScheduler) placementScore(nodes []*v1.Node, scores map[string]float64) float64 {
+    if len(nodes) == 0 {
+        return 0
+    }
+    var total float64
+    for _, node := range nodes {
+        if domain, err := ts.cache.GetDomainForNode(node.Name); err == nil {
+            total += scores[domain.Name]
+        }
+    }
+    return total / float64(len(nodes))
+}
@@ -0,0 +1,167 @@
+package algorithm
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+)
+
+// DomainTolerationsAnnotation holds a JSON list of v1.Toleration that are
+// matched against domain taints. Node tolerations in the pod spec are not
+// considered, so that a domain taint cannot be tolerated by accident.
+const DomainTolerationsAnnotation = "topology.scheduler/domain-tolerations"
+
+func parseDomainTolerations(pod *v1.Pod) ([]v1.Toleration, error) {
+    val, ok := pod.Annotations[DomainTolerationsAnnotation]
+    if !ok || val == "" {
+        return nil, nil
+    }
+
+    var tolerations []v1.Toleration
+    if err := json.Unmarshal([]byte(val), &tolerations); err != nil {
+        return nil, fmt.Errorf("invalid %s annotation: %v", DomainTolerationsAnnotation, err)
+    }
+    return tolerations, nil
+}
+
+func toleratesTaint(tolerations []v1.Toleration, taint *v1.Taint) bool {
+    for i := range tolerations {
+        if tolerations[i].ToleratesTaint(taint) {
+            return true
+        }
+    }
+    return false
+}
+
+// untoleratedTaints returns the effective taints of the domain with one of
+// the given effects that the pod does not tolerate.
+func (ts *TopologyScheduler) untoleratedTaints(pod *v1.Pod, domain *Domain, effects ...v1.TaintEffect) ([]v1.Taint, error) {
+    tolerations, err := parseDomainTolerations(pod)
+    if err != nil {
+        return nil, err
+    }
+
+    taints, err := ts.cache.GetEffectiveTaints(domain.Name)
+    if err != nil {
+        return nil, err
+    }
+
+    var result []v1.Taint
+    for i := range taints {
+        if !hasEffect(taints[i].Effect, effects) {
+            continue
+        }
+        if !toleratesTaint(tolerations, &taints[i]) {
+            result = append(result, taints[i])
+        }
+    }
+    return result, nil
+}
+
+func taintEffects(taints []v1.Taint) []v1.TaintEffect {
+    effects := make([]v1.TaintEffect, 0, len(taints))
+    for _, taint := range taints {
+        effects = append(effects, taint.Effect)
+    }
+    return effects
+}
+
+func hasEffect(effect v1.TaintEffect, effects []v1.TaintEffect) bool {
+    for _, e := range effects {
+        if e == effect {
+            return true
+        }
+    }
+    return false
+}
+
+// tolerationSeconds returns how long the tolerations let the pod stay
+// under the taint: nil when one tolerates it forever, otherwise the
+// shortest TolerationSeconds of those that match
+func tolerationSeconds(tolerations []v1.Toleration, taint *v1.Taint) (*int64, bool) {
+    var seconds *int64
+    for i := range tolerations {
+        if !tolerations[i].ToleratesTaint(taint) {
+            continue
+        }
+        if tolerations[i].TolerationSeconds == nil {
+            return nil, true
+        }
+        if seconds == nil || *tolerations[i].TolerationSeconds < *seconds {
+            seconds = tolerations[i].TolerationSeconds
+        }
+    }
+    return seconds, seconds != nil
+}
+
+// noExecuteEviction reports whether a NoExecute taint of the domain, own or
+// inherited, requires the pod to leave now. Otherwise it returns the time
+// until the first toleration with TolerationSeconds runs out, zero when
+// none is pending.
+func (ts *TopologyScheduler) noExecuteEviction(pod *v1.Pod, domain *Domain, now time.Time) (bool, time.Duration, error) {
+    tolerations, err := parseDomainTolerations(pod)
+    if err != nil {
+        return false, 0, err
+    }
+    taints, err := ts.cache.GetEffectiveTaints(domain.Name)
+    if err != nil {
+        return false, 0, err
+    }
+
+    var wait time.Duration
+    for i := range taints {
+        taint := &taints[i]
+        if taint.Effect != v1.TaintEffectNoExecute {
+            continue
+        }
+        seconds, tolerated := tolerationSeconds(tolerations, taint)
+        if !tolerated {
+            return true, 0, nil
+        }
+        if seconds == nil {
+            continue
+        }
+        added := now
+        if taint.TimeAdded != nil {
+            added = taint.TimeAdded.Time
+        }
+        left := added.Add(time.Duration(*seconds) * time.Second).Sub(now)
+        if left <= 0 {
+            return true, 0, nil
+        }
+        if wait == 0 || left < wait {
+            wait = left
+        }
+    }
+    return false, wait, nil
+}
+
+// checkDomainTaints rejects domains carrying a NoSchedule or NoExecute taint
+// that the pod does not tolerate.
+func (ts *TopologyScheduler) checkDomainTaints(pod *v1.Pod, domain *Domain) error {
+    taints, err := ts.untoleratedTaints(pod, domain, v1.TaintEffectNoSchedule, v1.TaintEffectNoExecute)
+    if err != nil {
+        return err
+    }
+    if len(taints) > 0 {
+        return fmt.Errorf("domain %s has untolerated taint %s", domain.Name, taints[0].ToString())
+    }
+    return nil
+}
+
+// scoreDomainTaints returns 1 for a domain without untolerated
+// PreferNoSchedule taints and halves the score for each one present.
+func (ts *TopologyScheduler) scoreDomainTaints(pod *v1.Pod, domain *Domain) float64 {
+    taints, err := ts.untoleratedTaints(pod, domain, v1.TaintEffectPreferNoSchedule)
+    if err != nil {
+        return 1.0
+    }
+
+    score := 1.0
+    for range taints {
+        score /= 2
+    }
+    return score
+}
@@ -0,0 +1,186 @@
+package algorithm
+
+import (
+    "testing"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newTaintTestScheduler builds leaf-0 under spine-0 with the given taints
+// on each
+func newTaintTestScheduler(t *testing.T, spineTaints, leafTaints []v1.Taint) (*TopologyScheduler, *Domain) {
+    t.Helper()
+    tc := NewTopologyCache(NewNodeCache())
+    spine := &Domain{Name: "spine-0", Level: LevelSpine, Jobs: make(map[string]*PlacedJob)}
+    leaf := &Domain{Name: "leaf-0", Level: LevelLeaf, Parent: "spine-0", SpineSwitch: "spine-0", Jobs: make(map[string]*PlacedJob)}
+    for _, domain := range []*Domain{spine, leaf} {
+        if err := tc.AddDomain(domain); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := tc.SetDomainTaints("spine-0", spineTaints); err != nil {
+        t.Fatal(err)
+    }
+    if err := tc.SetDomainTaints("leaf-0", leafTaints); err != nil {
+        t.Fatal(err)
+    }
+    return NewTopologyScheduler(tc), leaf
+}
+
+func podTolerating(tolerations string) *v1.Pod {
+    pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "train-0"}}
+    if tolerations != "" {
+        pod.Annotations = map[string]string{DomainTolerationsAnnotation: tolerations}
+    }
+    return pod
+}
+
+func TestCheckDomainTaints(t *testing.T) {
+    maintenance := v1.Taint{Key: "maintenance", Effect: v1.TaintEffectNoSchedule}
+    drain := v1.Taint{Key: "drain", Value: "true", Effect: v1.TaintEffectNoExecute}
+    tests := []struct {
+        name        string
+        spineTaints []v1.Taint
+        leafTaints  []v1.Taint
+        tolerations string
+        wantErr     bool
+    }{
+        {
+            name: "no taints",
+        },
+        {
+            name:       "untolerated NoSchedule",
+            leafTaints: []v1.Taint{maintenance},
+            wantErr:    true,
+        },
+        {
+            name:        "inherited NoExecute",
+            spineTaints: []v1.Taint{drain},
+            wantErr:     true,
+        },
+        {
+            name:        "tolerated with Exists",
+            leafTaints:  []v1.Taint{maintenance},
+            tolerations: `[{"key": "maintenance", "operator": "Exists"}]`,
+        },
+        {
+            name:        "toleration for another value",
+            spineTaints: []v1.Taint{drain},
+            tolerations: `[{"key": "drain", "operator": "Equal", "value": "false"}]`,
+            wantErr:     true,
+        },
+        {
+            name:        "toleration for another effect",
+            leafTaints:  []v1.Taint{maintenance},
+            tolerations: `[{"key": "maintenance", "operator": "Exists", "effect": "NoExecute"}]`,
+            wantErr:     true,
+        },
+        {
+            name:       "PreferNoSchedule does not filter",
+            leafTaints: []v1.Taint{{Key: "busy", Effect: v1.TaintEffectPreferNoSchedule}},
+        },
+        {
+            name:        "invalid annotation",
+            tolerations: `not json`,
+            wantErr:     true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ts, leaf := newTaintTestScheduler(t, tt.spineTaints, tt.leafTaints)
+            err := ts.checkDomainTaints(podTolerating(tt.tolerations), leaf)
+            if (err != nil) != tt.wantErr {
+                t.Errorf("checkDomainTaints() error = %v, wantErr %v", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestScoreDomainTaints(t *testing.T) {
+    busy := v1.Taint{Key: "busy", Effect: v1.TaintEffectPreferNoSchedule}
+    hot := v1.Taint{Key: "hot", Effect: v1.TaintEffectPreferNoSchedule}
+    tests := []struct {
+        name        string
+        spineTaints []v1.Taint
+        leafTaints  []v1.Taint
+        tolerations string
+        want        float64
+    }{
+        {name: "no taints", want: 1},
+        {name: "one on the leaf", leafTaints: []v1.Taint{busy}, want: 0.5},
+        {name: "one inherited and one own", spineTaints: []v1.Taint{hot}, leafTaints: []v1.Taint{busy}, want: 0.25},
+        {name: "tolerated", leafTaints: []v1.Taint{busy}, tolerations: `[{"key": "busy", "operator": "Exists"}]`, want: 1},
+        {name: "NoSchedule does not lower the score", leafTaints: []v1.Taint{{Key: "maintenance", Effect: v1.TaintEffectNoSchedule}}, want: 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ts, leaf := newTaintTestScheduler(t, tt.spineTaints, tt.leafTaints)
+            if got := ts.scoreDomainTaints(podTolerating(tt.tolerations), leaf); got != tt.want {
+                t.Errorf("scoreDomainTaints() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestNoExecuteEviction(t *testing.T) {
+    now := time.Now()
+    added := metav1.NewTime(now.Add(-time.Minute))
+    drain := v1.Taint{Key: "drain", Effect: v1.TaintEffectNoExecute, TimeAdded: &added}
+    tests := []struct {
+        name        string
+        taints      []v1.Taint
+        tolerations string
+        wantEvict   bool
+        wantWait    time.Duration
+    }{
+        {
+            name:   "no NoExecute taint",
+            taints: []v1.Taint{{Key: "maintenance", Effect: v1.TaintEffectNoSchedule}},
+        },
+        {
+            name:      "untolerated",
+            taints:    []v1.Taint{drain},
+            wantEvict: true,
+        },
+        {
+            name:        "tolerated forever",
+            taints:      []v1.Taint{drain},
+            tolerations: `[{"key": "drain", "operator": "Exists"}]`,
+        },
+        {
+            name:        "toleration not run out",
+            taints:      []v1.Taint{drain},
+            tolerations: `[{"key": "drain", "operator": "Exists", "tolerationSeconds": 300}]`,
+            wantWait:    4 * time.Minute,
+        },
+        {
+            name:        "toleration run out",
+            taints:      []v1.Taint{drain},
+            tolerations: `[{"key": "drain", "operator": "Exists", "tolerationSeconds": 30}]`,
+            wantEvict:   true,
+        },
+        {
+            name:        "shortest matching toleration counts",
+            taints:      []v1.Taint{drain},
+            tolerations: `[{"key": "drain", "operator": "Exists", "tolerationSeconds": 600}, {"operator": "Exists", "tolerationSeconds": 120}]`,
+            wantWait:    time.Minute,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ts, leaf := newTaintTestScheduler(t, tt.taints, nil)
+            evict, wait, err := ts.noExecuteEviction(podTolerating(tt.tolerations), leaf, now)
+            if err != nil {
+                t.Fatalf("noExecuteEviction() error = %v", err)
+            }
+            if evict != tt.wantEvict || wait != tt.wantWait {
+                t.Errorf("noExecuteEviction() = %v, %v, want %v, %v", evict, wait, tt.wantEvict, tt.wantWait)
+            }
+        })
+    }
+}
@@ -9,15 +9,27 @@ type TopologyManager struct {
     mu sync.RWMutex
     domainManager *DomainManager
     nodeManager   *NodeManager
+    labelSchema   *NodeLabelSchema
 }
 
 func NewTopologyManager() *TopologyManager {
     return &TopologyManager{
         domainManager: NewDomainManager(),
         nodeManager:   NewNodeManager(),
+        labelSchema:   DefaultNodeLabelSchema(),
     }
 }
 
+// SetLabelSchema replaces the schema used to find the leaf and spine of a
+// node. The schema must have been validated.
+func (tm *TopologyManager) SetLabelSchema(schema *NodeLabelSchema) {
+    tm.mu.Lock()
+    defer tm.mu.Unlock()
+    tm.labelSchema = schema
+}
+
+// UpdateNode places the node in the leaf its labels name, creating the
+// leaf when it is new. A node whose labels changed moves to the new leaf.
 func (tm *TopologyManager) UpdateNode(node *v1.Node) error {
     tm.mu.Lock()
     defer tm.mu.Unlock()
@@ -28,8 +40,16 @@ func (tm *TopologyManager) UpdateNode(node *v1.Node) error {
         NetworkBandwidth: parseNetworkBandwidth(node),
     }
 
-    domain := parseDomainInfo(node)
-    if err := tm.domainManager.AddDomain(domain); err != nil {
+    domain, err := tm.parseDomainInfo(node)
+    if err != nil {
+        return err
+    }
+    if _, exists := tm.domainManager.GetDomain(domain.Name); !exists {
+        if err := tm.domainManager.AddDomain(domain); err != nil {
+            return err
+        }
+    }
+    if err := tm.domainManager.AssignNode(node, domain.Name); err != nil {
         return err
     }
 
@@ -49,3 +69,20 @@ func (tm *TopologyManager) GetTopologyDistance(source, target string) (int, erro
 
     return calculateTopologyDistance(sourceDomain, targetDomain), nil
 }
+
+// parseDomainInfo returns the leaf named by the label schema, without nodes
+func (tm *TopologyManager) parseDomainInfo(node *v1.Node) (*Domain, error) {
+    placement, err := tm.labelSchema.Resolve(node)
+    if err != nil {
+        return nil, err
+    }
+    return &Domain{
+        ID:          placement.Leaf,
+        Name:        placement.Leaf,
+        Level:       LevelLeaf,
+        Parent:      placement.Spine,
+        LeafSwitch:  placement.Leaf,
+        SpineSwitch: placement.Spine,
+        Jobs:        make(map[string]*PlacedJob),
+    }, nil
+}
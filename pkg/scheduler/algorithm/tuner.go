@@ -0,0 +1,358 @@
+package algorithm
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "math/rand"
+    "net/http"
+    "sync"
+    "time"
+
+    "k8s.io/klog/v2"
+)
+
+// TunerConfig holds the guard rails of the weight tuner
+type TunerConfig struct {
+    // MinWeight and MaxWeight bound every individual weight
+    MinWeight float64
+    MaxWeight float64
+    // Delta is the size of the perturbation explored around the current weights
+    Delta float64
+    // MaxStep caps how far any weight may move in one update
+    MaxStep float64
+    // UpdateInterval is the minimum time between two weight updates
+    UpdateInterval time.Duration
+    // MinObservations is the number of rewards each arm needs before an update
+    MinObservations int
+    // Epsilon is the share of jobs that explore a perturbed arm
+    Epsilon float64
+    // QueueWaitTarget is the wait that costs as much reward as a 10% throughput loss
+    QueueWaitTarget time.Duration
+    // Frozen disables exploration and updates
+    Frozen bool
+}
+
+func DefaultTunerConfig() TunerConfig {
+    return TunerConfig{
+        MinWeight:       0.05,
+        MaxWeight:       0.6,
+        Delta:           0.05,
+        MaxStep:         0.05,
+        UpdateInterval:  time.Hour,
+        MinObservations: 20,
+        Epsilon:         0.2,
+        QueueWaitTarget: 5 * time.Minute,
+    }
+}
+
+type tunerArm struct {
+    weights TopologyScore
+    count   int
+    reward  float64
+}
+
+// maxTunedJobs bounds the jobs waiting for a reward; jobs scored beyond it
+// get the current weights and are left out of exploration
+const maxTunedJobs = 10000
+
+type jobAssignment struct {
+    arm       int
+    queueWait time.Duration
+    // credited is set by the first throughput report of the job
+    credited bool
+}
+
+// WeightTuner adjusts the TopologyScore weights online with an
+// epsilon-greedy bandit. Arm 0 is the current weights and the other arms
+// move one weight up or down by Delta. Jobs are assigned an arm when they
+// are first scored and credited with their first throughput report, and
+// keep the arm until they are forgotten; once every arm has enough rewards
+// the weights move toward the best one.
+type WeightTuner struct {
+    mu         sync.Mutex
+    config     TunerConfig
+    current    TopologyScore
+    arms       []*tunerArm
+    jobs       map[string]*jobAssignment
+    lastUpdate time.Time
+    rng        *rand.Rand
+    metrics    *MetricsCollector
+}
+
+func NewWeightTuner(initial TopologyScore, config TunerConfig, mc *MetricsCollector) *WeightTuner {
+    wt := &WeightTuner{
+        config:     config,
+        jobs:       make(map[string]*jobAssignment),
+        lastUpdate: time.Now(),
+        rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+        metrics:    mc,
+    }
+    wt.current = wt.bound(initial)
+    wt.resetArms()
+    wt.exportMetrics()
+    return wt
+}
+
+// WeightsForJob returns the weights to use when scoring for a job,
+// assigning the job to an arm on first use.
+func (wt *WeightTuner) WeightsForJob(jobName string) TopologyScore {
+    wt.mu.Lock()
+    defer wt.mu.Unlock()
+
+    if wt.config.Frozen || jobName == "" {
+        return wt.current
+    }
+
+    assignment, ok := wt.jobs[jobName]
+    if !ok {
+        if len(wt.jobs) >= maxTunedJobs {
+            return wt.current
+        }
+        arm := 0
+        if wt.rng.Float64() < wt.config.Epsilon {
+            arm = 1 + wt.rng.Intn(len(wt.arms)-1)
+        }
+        assignment = &jobAssignment{arm: arm}
+        wt.jobs[jobName] = assignment
+    }
+    return wt.arms[assignment.arm].weights
+}
+
+// ForgetJob drops the arm assignment of a job that no longer runs
+func (wt *WeightTuner) ForgetJob(jobName string) {
+    wt.mu.Lock()
+    defer wt.mu.Unlock()
+    delete(wt.jobs, jobName)
+}
+
+// Weights returns the current weights without exploration
+func (wt *WeightTuner) Weights() TopologyScore {
+    wt.mu.Lock()
+    defer wt.mu.Unlock()
+    return wt.current
+}
+
+// ObserveQueueWait records how long the job waited before being placed
+func (wt *WeightTuner) ObserveQueueWait(jobName string, wait time.Duration) {
+    wt.mu.Lock()
+    defer wt.mu.Unlock()
+
+    if assignment, ok := wt.jobs[jobName]; ok && assignment.queueWait == 0 {
+        assignment.queueWait = wait
+    }
+}
+
+// ObserveThroughput credits the job's arm with its throughput relative to
+// the workload average, less a penalty for queue wait. Only the first
+// report of a job counts; later ones would weigh long jobs more.
+func (wt *WeightTuner) ObserveThroughput(jobName string, relativeThroughput float64) {
+    wt.mu.Lock()
+    defer wt.mu.Unlock()
+
+    assignment, ok := wt.jobs[jobName]
+    if !ok || assignment.credited {
+        return
+    }
+    assignment.credited = true
+
+    reward := relativeThroughput
+    if wt.config.QueueWaitTarget > 0 {
+        reward -= 0.1 * assignment.queueWait.Seconds() / wt.config.QueueWaitTarget.Seconds()
+    }
+
+    arm := wt.arms[assignment.arm]
+    arm.count++
+    arm.reward += (reward - arm.reward) / float64(arm.count)
+
+    wt.maybeUpdate()
+}
+
+// SetFrozen toggles the freeze switch; a frozen tuner serves the current
+// weights to every job and never changes them.
+func (wt *WeightTuner) SetFrozen(frozen bool) {
+    wt.mu.Lock()
+    defer wt.mu.Unlock()
+
+    wt.config.Frozen = frozen
+    if frozen {
+        wt.jobs = make(map[string]*jobAssignment)
+    }
+    wt.exportMetrics()
+}
+
+// SetWeights replaces the current weights, e.g. after a configuration
+// change, and restarts exploration around them. The weights are clamped to
+// the tuner bounds and rescaled to sum to one.
+func (wt *WeightTuner) SetWeights(weights TopologyScore) {
+    wt.mu.Lock()
+    defer wt.mu.Unlock()
+
+    wt.current = wt.bound(weights)
+    wt.jobs = make(map[string]*jobAssignment)
+    wt.resetArms()
+    wt.exportMetrics()
+}
+
+// maybeUpdate moves the current weights toward the best arm once every arm
+// has enough observations and the rate limit allows. Must be called with
+// wt.mu held.
+func (wt *WeightTuner) maybeUpdate() {
+    if wt.config.Frozen || time.Since(wt.lastUpdate) < wt.config.UpdateInterval {
+        return
+    }
+    for _, arm := range wt.arms {
+        if arm.count < wt.config.MinObservations {
+            return
+        }
+    }
+
+    best := 0
+    for i, arm := range wt.arms {
+        if arm.reward > wt.arms[best].reward {
+            best = i
+        }
+    }
+    if best == 0 {
+        wt.resetArms()
+        wt.lastUpdate = time.Now()
+        return
+    }
+
+    cur := scoreToVector(wt.current)
+    target := scoreToVector(wt.arms[best].weights)
+    for i := range cur {
+        step := target[i] - cur[i]
+        step = math.Max(-wt.config.MaxStep, math.Min(wt.config.MaxStep, step))
+        cur[i] += step
+    }
+    next := vectorToScore(wt.normalize(cur))
+
+    klog.Infof("Weight tuner moving weights from %+v to %+v (arm %d reward %.3f vs %.3f)",
+        wt.current, next, best, wt.arms[best].reward, wt.arms[0].reward)
+    wt.current = next
+    wt.lastUpdate = time.Now()
+    wt.jobs = make(map[string]*jobAssignment)
+    wt.resetArms()
+    if wt.metrics != nil {
+        wt.metrics.IncTunerUpdates()
+    }
+    wt.exportMetrics()
+}
+
+// resetArms rebuilds the perturbed arms around the current weights. Must be
+// called with wt.mu held.
+func (wt *WeightTuner) resetArms() {
+    base := scoreToVector(wt.current)
+    wt.arms = []*tunerArm{{weights: wt.current}}
+    for i := range base {
+        for _, sign := range []float64{1, -1} {
+            v := base
+            v[i] += sign * wt.config.Delta
+            wt.arms = append(wt.arms, &tunerArm{weights: vectorToScore(wt.normalize(v))})
+        }
+    }
+}
+
+// bound returns the weights clamped to the tuner bounds and rescaled to sum
+// to one
+func (wt *WeightTuner) bound(weights TopologyScore) TopologyScore {
+    return vectorToScore(wt.normalize(scoreToVector(weights)))
+}
+
+// normalize clamps each weight into bounds and spreads what the weights
+// miss or exceed of one over those that can still move. Rescaling instead
+// could push a weight back out of its bounds.
+func (wt *WeightTuner) normalize(v [4]float64) [4]float64 {
+    clamp := func(w float64) float64 {
+        return math.Max(wt.config.MinWeight, math.Min(wt.config.MaxWeight, w))
+    }
+    for i := range v {
+        v[i] = clamp(v[i])
+    }
+    // Every round either balances the weights or pins one more to a bound
+    for round := 0; round <= len(v); round++ {
+        diff := 1.0
+        for _, w := range v {
+            diff -= w
+        }
+        if math.Abs(diff) < 1e-9 {
+            break
+        }
+        movable := func(w float64) bool {
+            return (diff > 0 && w < wt.config.MaxWeight) || (diff < 0 && w > wt.config.MinWeight)
+        }
+        free := 0
+        for _, w := range v {
+            if movable(w) {
+                free++
+            }
+        }
+        if free == 0 {
+            break
+        }
+        share := diff / float64(free)
+        for i := range v {
+            if movable(v[i]) {
+                v[i] = clamp(v[i] + share)
+            }
+        }
+    }
+    return v
+}
+
+func (wt *WeightTuner) exportMetrics() {
+    if wt.metrics == nil {
+        return
+    }
+    wt.metrics.UpdateTunerState(wt.current, wt.config.Frozen, wt.config.MinWeight, wt.config.MaxWeight, wt.config.MaxStep)
+}
+
+func scoreToVector(s TopologyScore) [4]float64 {
+    return [4]float64{s.ResourceAvailability, s.TopologyAlignment, s.DomainUtilization, s.HistoricalPerf}
+}
+
+func vectorToScore(v [4]float64) TopologyScore {
+    return TopologyScore{
+        ResourceAvailability: v[0],
+        TopologyAlignment:    v[1],
+        DomainUtilization:    v[2],
+        HistoricalPerf:       v[3],
+    }
+}
+
+// Validate checks the guard rails are consistent
+func (c TunerConfig) Validate() error {
+    if c.MinWeight < 0 || c.MaxWeight > 1 || c.MinWeight >= c.MaxWeight {
+        return fmt.Errorf("invalid weight bounds [%v, %v]", c.MinWeight, c.MaxWeight)
+    }
+    if c.MinWeight*4 > 1 || c.MaxWeight*4 < 1 {
+        return fmt.Errorf("weight bounds [%v, %v] cannot sum to one", c.MinWeight, c.MaxWeight)
+    }
+    if c.Delta <= 0 || c.MaxStep <= 0 {
+        return fmt.Errorf("delta and max step must be positive")
+    }
+    if c.Epsilon < 0 || c.Epsilon > 1 {
+        return fmt.Errorf("epsilon %v must be in [0, 1]", c.Epsilon)
+    }
+    return nil
+}
+
+// ServeHTTP reports the tuner state. The endpoint is read-only; the freeze
+// switch is set with --tuner-frozen.
+func (wt *WeightTuner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    wt.mu.Lock()
+    state := struct {
+        Weights TopologyScore `json:"weights"`
+        Frozen  bool          `json:"frozen"`
+    }{wt.current, wt.config.Frozen}
+    wt.mu.Unlock()
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(state)
+}
@@ -0,0 +1,76 @@
+package algorithm
+
+import (
+    "math"
+    "testing"
+)
+
+func TestTunerCreditsFirstReportOnly(t *testing.T) {
+    config := DefaultTunerConfig()
+    config.Epsilon = 0
+    wt := NewWeightTuner(DefaultScoringWeights(), config, nil)
+
+    wt.WeightsForJob("default/train")
+    for _, throughput := range []float64{1.2, 0.4, 0.4} {
+        wt.ObserveThroughput("default/train", throughput)
+    }
+    if arm := wt.arms[0]; arm.count != 1 || arm.reward != 1.2 {
+        t.Errorf("arm 0 has %d rewards averaging %v, want the first report 1.2 once", arm.count, arm.reward)
+    }
+    if _, ok := wt.jobs["default/train"]; !ok {
+        t.Error("job lost its arm after the first report, want it kept until ForgetJob")
+    }
+
+    wt.ForgetJob("default/train")
+    wt.ObserveThroughput("default/train", 1)
+    if _, ok := wt.jobs["default/train"]; ok {
+        t.Error("job still has an arm after ForgetJob")
+    }
+    if count := wt.arms[0].count; count != 1 {
+        t.Errorf("arm 0 has %d rewards after ForgetJob, want 1", count)
+    }
+}
+
+func TestTunerBoundsWeights(t *testing.T) {
+    config := DefaultTunerConfig()
+    tests := []struct {
+        name    string
+        weights TopologyScore
+    }{
+        {
+            name:    "weight above the bound",
+            weights: TopologyScore{ResourceAvailability: 0.9, TopologyAlignment: 0.05, DomainUtilization: 0.03, HistoricalPerf: 0.02},
+        },
+        {
+            name:    "weights not summing to one",
+            weights: TopologyScore{ResourceAvailability: 1, TopologyAlignment: 1, DomainUtilization: 1, HistoricalPerf: 1},
+        },
+        {
+            name:    "weight below the bound",
+            weights: TopologyScore{ResourceAvailability: 0.5, TopologyAlignment: 0.5},
+        },
+    }
+
+    check := func(t *testing.T, name string, got TopologyScore) {
+        t.Helper()
+        sum := 0.0
+        for _, w := range scoreToVector(got) {
+            if w < config.MinWeight-1e-9 || w > config.MaxWeight+1e-9 {
+                t.Errorf("%s weights %+v leave [%v, %v]", name, got, config.MinWeight, config.MaxWeight)
+            }
+            sum += w
+        }
+        if math.Abs(sum-1) > 1e-6 {
+            t.Errorf("%s weights %+v sum to %v, want 1", name, got, sum)
+        }
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            check(t, "NewWeightTuner", NewWeightTuner(tt.weights, config, nil).Weights())
+
+            wt := NewWeightTuner(DefaultScoringWeights(), config, nil)
+            wt.SetWeights(tt.weights)
+            check(t, "SetWeights", wt.Weights())
+        })
+    }
+}
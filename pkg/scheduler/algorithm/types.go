@@ -12,18 +12,56 @@ type TopologyScore struct {
     HistoricalPerf      float64
 }
 
+// TopologyLevel identifies a layer of the network hierarchy
+type TopologyLevel string
+
+const (
+    LevelLeaf  TopologyLevel = "leaf"
+    LevelSpine TopologyLevel = "spine"
+)
+
 // Domain represents a leaf switch domain containing nodes
 type Domain struct {
     ID          string
+    Name        string
+    Level       TopologyLevel
+    Parent      string
     Nodes       []*v1.Node
     TotalGPUs   int
     UsedGPUs    int
+    // MaxGPUs caps TotalGPUs when set
+    MaxGPUs     int
     LeafSwitch  string
     SpineSwitch string
+    // Jobs placed in this domain, keyed by job name
+    Jobs        map[string]*PlacedJob
+    // Taints apply to this domain and every domain below it
+    Taints      []v1.Taint
+}
+
+// PlacedJob records a job that has pods running in a domain
+type PlacedJob struct {
+    Name   string
+    Labels map[string]string
+    GPUs   int
+    // Nodes holds the GPUs the job uses on each node of the domain
+    Nodes  map[string]int
+}
+
+// GPURequirements describes the GPUs a pod needs and how many nodes they span
+type GPURequirements struct {
+    TotalGPUs   int
+    GPUsPerNode int
+    NodesNeeded int
+    // JobName identifies the job the pod belongs to, see jobNameForPod
+    JobName     string
+    // JobGPUs is the GPUs of the whole job, at least TotalGPUs
+    JobGPUs     int
+    CommProfile CommProfile
 }
 
 // TopologyState represents the current state of the cluster topology
 type TopologyState struct {
     Domains          map[string]*Domain
     SpineConnections map[string][]string
-}
\ No newline at end of file
+}
@@ -0,0 +1,125 @@
+package algorithm
+
+import (
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/history"
+)
+
+// SetChangeLog records every later topology change in the log. Changes
+// since the last recorded version, such as those made while the scheduler
+// was down, are recorded first.
+func (tc *TopologyCache) SetChangeLog(log *history.ChangeLog) error {
+    tc.Lock()
+    defer tc.Unlock()
+    tc.logMu.Lock()
+    defer tc.logMu.Unlock()
+
+    if err := log.Sync(tc.topologyState()); err != nil {
+        return err
+    }
+    tc.changeLog = log
+    return nil
+}
+
+// TopologyState returns the domains, node membership and links in the
+// form recorded by the change log
+func (tc *TopologyCache) TopologyState() *history.TopologyState {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    return tc.topologyState()
+}
+
+func (tc *TopologyCache) topologyState() *history.TopologyState {
+    state := history.NewTopologyState()
+    for name, domain := range tc.domains {
+        state.Domains[name] = history.DomainState{
+            Level:   string(domainLevel(domain)),
+            Parent:  parentOf(domain),
+            MaxGPUs: domain.MaxGPUs,
+            Taints:  historyTaints(domain.Taints),
+        }
+    }
+    for node, domain := range tc.domainForNode {
+        if _, exists := tc.domains[domain]; exists {
+            state.Nodes[node] = domain
+        }
+    }
+    for source, targets := range tc.spineConnections {
+        for _, target := range targets {
+            state.Links[[2]string{source, target}] = history.LinkState{
+                BandwidthGbps: tc.linkBandwidth[source][target],
+                Latency:       tc.linkLatency[source][target],
+            }
+        }
+    }
+    return state
+}
+
+// domainChange describes the domain as added or updated, with its limit
+// and taints
+func domainChange(kind history.ChangeKind, domain *Domain) history.TopologyChange {
+    return history.TopologyChange{
+        Kind:    kind,
+        Domain:  domain.Name,
+        Level:   string(domainLevel(domain)),
+        Parent:  parentOf(domain),
+        MaxGPUs: domain.MaxGPUs,
+        Taints:  historyTaints(domain.Taints),
+    }
+}
+
+func historyTaints(taints []v1.Taint) []history.Taint {
+    if len(taints) == 0 {
+        return nil
+    }
+    out := make([]history.Taint, 0, len(taints))
+    for _, taint := range taints {
+        out = append(out, history.Taint{Key: taint.Key, Value: taint.Value, Effect: string(taint.Effect)})
+    }
+    return out
+}
+
+// nodeChange describes adding the node to the domain, a move when the
+// node is already in another domain
+func (tc *TopologyCache) nodeChange(nodeName, domainName string) history.TopologyChange {
+    change := history.TopologyChange{Kind: history.NodeAdded, Node: nodeName, Domain: domainName}
+    if previous, exists := tc.domainForNode[nodeName]; exists && previous != domainName {
+        change.Kind = history.NodeMoved
+        change.From = previous
+    }
+    return change
+}
+
+func (tc *TopologyCache) linkChange(source, target string) history.TopologyChange {
+    return history.TopologyChange{
+        Kind:          history.LinkUpdated,
+        Source:        source,
+        Target:        target,
+        BandwidthGbps: tc.linkBandwidth[source][target],
+        Latency:       tc.linkLatency[source][target],
+    }
+}
+
+// record keeps the changes made on a copy staged by Apply, which writes
+// them to the change log once the copy replaced the topology. Callers hold
+// the lock.
+func (tc *TopologyCache) record(changes ...history.TopologyChange) {
+    if tc.staged {
+        tc.pending = append(tc.pending, changes...)
+    }
+}
+
+// writeChanges writes the changes of an applied copy to the log. Apply
+// calls it holding logMu but not the lock, so that readers of the cache do
+// not wait for the disk.
+func writeChanges(log *history.ChangeLog, changes []history.TopologyChange) {
+    if log == nil || len(changes) == 0 {
+        return
+    }
+    if err := log.Record(changes...); err != nil {
+        klog.Warningf("Failed to record topology change: %v", err)
+    }
+}
@@ -5,6 +5,8 @@ import (
     "sync"
     "time"
     v1 "k8s.io/api/core/v1"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/utils/topology"
 )
 
 type NodeCache struct {
@@ -12,6 +14,8 @@ type NodeCache struct {
     nodes             map[string]*v1.Node
     gpuAllocations    map[string]int
     lastNodeUpdate    map[string]time.Time
+    gpuHealth         map[string][]topology.GPUDeviceHealth
+    healthFromNode    map[string]bool
     metrics           *MetricsCollector
 }
 
@@ -20,6 +24,8 @@ func NewNodeCache() *NodeCache {
         nodes:          make(map[string]*v1.Node),
         gpuAllocations: make(map[string]int),
         lastNodeUpdate: make(map[string]time.Time),
+        gpuHealth:      make(map[string][]topology.GPUDeviceHealth),
+        healthFromNode: make(map[string]bool),
         metrics:        NewMetricsCollector(),
     }
 }
@@ -34,10 +40,42 @@ func (nc *NodeCache) AddNode(node *v1.Node) error {
 
     nc.nodes[node.Name] = node
     nc.gpuAllocations[node.Name] = 0
+    nc.setNodeGPUHealth(node)
     nc.lastNodeUpdate[node.Name] = time.Now()
     return nil
 }
 
+// UpdateNode replaces the cached node object, keeping its GPU allocation,
+// and adds the node when it is new
+func (nc *NodeCache) UpdateNode(node *v1.Node) {
+    nc.Lock()
+    defer nc.Unlock()
+
+    if _, exists := nc.nodes[node.Name]; !exists {
+        nc.gpuAllocations[node.Name] = 0
+    }
+    nc.nodes[node.Name] = node
+    nc.setNodeGPUHealth(node)
+    nc.lastNodeUpdate[node.Name] = time.Now()
+}
+
+// setNodeGPUHealth records the health the node object reports. Health
+// that came from an earlier version of the node is cleared once the node
+// stops reporting it; health reported by the exporter is kept.
+func (nc *NodeCache) setNodeGPUHealth(node *v1.Node) {
+    devices, err := topology.ParseGPUHealth(node)
+    if err != nil {
+        return
+    }
+    if devices != nil {
+        nc.gpuHealth[node.Name] = devices
+        nc.healthFromNode[node.Name] = true
+    } else if nc.healthFromNode[node.Name] {
+        delete(nc.gpuHealth, node.Name)
+        delete(nc.healthFromNode, node.Name)
+    }
+}
+
 func (nc *NodeCache) RemoveNode(nodeName string) error {
     nc.Lock()
     defer nc.Unlock()
@@ -49,6 +87,8 @@ func (nc *NodeCache) RemoveNode(nodeName string) error {
     delete(nc.nodes, nodeName)
     delete(nc.gpuAllocations, nodeName)
     delete(nc.lastNodeUpdate, nodeName)
+    delete(nc.gpuHealth, nodeName)
+    delete(nc.healthFromNode, nodeName)
     return nil
 }
 
@@ -96,3 +136,65 @@ func (nc *NodeCache) GetAllNodes() []*v1.Node {
     }
     return nodes
 }
+
+func (nc *NodeCache) UpdateGPUHealth(nodeName string, devices []topology.GPUDeviceHealth) error {
+    nc.Lock()
+    defer nc.Unlock()
+
+    node, exists := nc.nodes[nodeName]
+    if !exists {
+        return fmt.Errorf("node %s not found", nodeName)
+    }
+
+    nc.gpuHealth[nodeName] = devices
+    delete(nc.healthFromNode, nodeName)
+    nc.lastNodeUpdate[nodeName] = time.Now()
+
+    info, err := topology.ExtractNodeGPUInfo(node)
+    if err == nil {
+        healthy := topology.CountHealthyGPUs(info.TotalGPUs, devices)
+        nc.metrics.UpdateNodeMetrics(nodeName, "", nc.gpuAllocations[nodeName], healthy == info.TotalGPUs)
+    }
+    return nil
+}
+
+// GetGPUHealth returns nil when no health has been reported for the node.
+func (nc *NodeCache) GetGPUHealth(nodeName string) []topology.GPUDeviceHealth {
+    nc.RLock()
+    defer nc.RUnlock()
+
+    return nc.gpuHealth[nodeName]
+}
+
+// GetHealthyGPUCount returns the node's GPU capacity excluding unhealthy devices
+func (nc *NodeCache) GetHealthyGPUCount(nodeName string) (int, error) {
+    nc.RLock()
+    defer nc.RUnlock()
+
+    node, exists := nc.nodes[nodeName]
+    if !exists {
+        return 0, fmt.Errorf("node %s not found", nodeName)
+    }
+
+    info, err := topology.ExtractNodeGPUInfo(node)
+    if err != nil {
+        return 0, err
+    }
+    return topology.CountHealthyGPUs(info.TotalGPUs, nc.gpuHealth[nodeName]), nil
+}
+
+// GetAvailableGPUs returns healthy capacity minus current allocations
+func (nc *NodeCache) GetAvailableGPUs(nodeName string) (int, error) {
+    healthy, err := nc.GetHealthyGPUCount(nodeName)
+    if err != nil {
+        return 0, err
+    }
+    allocated, err := nc.GetGPUAllocation(nodeName)
+    if err != nil {
+        return 0, err
+    }
+    if allocated > healthy {
+        return 0, nil
+    }
+    return healthy - allocated, nil
+}
@@ -0,0 +1,154 @@
+package algorithm
+
+import (
+    "reflect"
+
+    v1 "k8s.io/api/core/v1"
+    toolscache "k8s.io/client-go/tools/cache"
+    "k8s.io/klog/v2"
+)
+
+// RefreshNode stores the node object with the GPU health it reports and
+// refreshes the capacity of its domain
+func (tc *TopologyCache) RefreshNode(node *v1.Node) error {
+    tc.nodeCache.UpdateNode(node)
+    if current, err := tc.GetDomainForNode(node.Name); err == nil {
+        return tc.RefreshDomainCapacity(current.Name)
+    }
+    return nil
+}
+
+// UpdateNode refreshes the node and places it in the leaf its labels name
+// under the label schema, creating the leaf under its spine when it is new.
+// A node that is in another domain is moved there with MoveNode, and a leaf
+// whose nodes name another spine is moved under it. The topology does not
+// change without a label schema, and the change is refused when it would
+// leave the topology invalid.
+func (tc *TopologyCache) UpdateNode(node *v1.Node) error {
+    if err := tc.RefreshNode(node); err != nil {
+        return err
+    }
+    tc.RLock()
+    schema := tc.labelSchema
+    tc.RUnlock()
+    if schema == nil {
+        return nil
+    }
+    placement, err := schema.Resolve(node)
+    if err != nil {
+        return err
+    }
+
+    current, err := tc.GetDomainForNode(node.Name)
+    if err == nil && current.Name == placement.Leaf {
+        tc.RLock()
+        parent := parentOf(current)
+        tc.RUnlock()
+        if placement.Spine == "" || parent == placement.Spine {
+            return nil
+        }
+    }
+    moved := err != nil || current.Name != placement.Leaf
+
+    _, err = tc.Apply(func(c *TopologyCache) error {
+        if _, err := c.GetDomain(placement.Leaf); err != nil {
+            leaf := &Domain{
+                ID:          placement.Leaf,
+                Name:        placement.Leaf,
+                Level:       LevelLeaf,
+                Parent:      placement.Spine,
+                LeafSwitch:  placement.Leaf,
+                SpineSwitch: placement.Spine,
+                Jobs:        make(map[string]*PlacedJob),
+            }
+            if err := c.AddDomain(leaf); err != nil {
+                return err
+            }
+        } else if placement.Spine != "" {
+            if err := c.SetDomainParent(placement.Leaf, placement.Spine); err != nil {
+                return err
+            }
+        }
+        if !moved {
+            return nil
+        }
+        return c.MoveNode(node.Name, placement.Leaf)
+    })
+    return err
+}
+
+// RemoveNode drops a deleted node from its domain, whose capacity is
+// recomputed, and from the node cache. The domain stays even when it is
+// left without nodes.
+func (tc *TopologyCache) RemoveNode(nodeName string) error {
+    if current, err := tc.GetDomainForNode(nodeName); err == nil {
+        if err := tc.RemoveNodeFromDomain(nodeName, current.Name); err != nil {
+            return err
+        }
+        if err := tc.RefreshDomainCapacity(current.Name); err != nil {
+            return err
+        }
+    }
+    if _, err := tc.nodeCache.GetNode(nodeName); err != nil {
+        return nil
+    }
+    return tc.nodeCache.RemoveNode(nodeName)
+}
+
+// NodeEventHandler keeps the cache in step with node objects. Every event
+// refreshes the node's GPU health. Nodes that are not in the topology yet
+// are placed by their labels when they are added, nodes are moved when a
+// label change names another leaf or spine, and deleted nodes leave the
+// topology.
+func (tc *TopologyCache) NodeEventHandler() toolscache.ResourceEventHandler {
+    update := func(node *v1.Node) {
+        if err := tc.UpdateNode(node); err != nil {
+            klog.Warningf("Failed to update topology of node %s: %v", node.Name, err)
+        }
+    }
+    refresh := func(node *v1.Node) {
+        if err := tc.RefreshNode(node); err != nil {
+            klog.Warningf("Failed to refresh node %s: %v", node.Name, err)
+        }
+    }
+    return toolscache.ResourceEventHandlerFuncs{
+        AddFunc: func(obj interface{}) {
+            node, ok := obj.(*v1.Node)
+            if !ok {
+                return
+            }
+            if _, err := tc.GetDomainForNode(node.Name); err != nil {
+                update(node)
+                return
+            }
+            refresh(node)
+        },
+        UpdateFunc: func(oldObj, newObj interface{}) {
+            oldNode, ok := oldObj.(*v1.Node)
+            if !ok {
+                return
+            }
+            newNode, ok := newObj.(*v1.Node)
+            if !ok {
+                return
+            }
+            if reflect.DeepEqual(oldNode.Labels, newNode.Labels) {
+                refresh(newNode)
+                return
+            }
+            update(newNode)
+        },
+        DeleteFunc: func(obj interface{}) {
+            if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+                obj = tombstone.Obj
+            }
+            node, ok := obj.(*v1.Node)
+            if !ok {
+                return
+            }
+            if err := tc.RemoveNode(node.Name); err != nil {
+                klog.Warningf("Failed to remove node %s: %v", node.Name, err)
+            }
+        },
+    }
+}
@@ -0,0 +1,87 @@
+package algorithm
+
+import (
+    "testing"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    toolscache "k8s.io/client-go/tools/cache"
+)
+
+func labelledNode(name, leaf, spine string) *v1.Node {
+    labels := map[string]string{LeafLabel: leaf}
+    if spine != "" {
+        labels[SpineLabel] = spine
+    }
+    return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func newNodeEventsTestCache(t *testing.T) (*TopologyCache, toolscache.ResourceEventHandler) {
+    t.Helper()
+    schema := DefaultNodeLabelSchema()
+    if err := schema.Validate(); err != nil {
+        t.Fatal(err)
+    }
+    tc := NewTopologyCache(NewNodeCache())
+    tc.SetLabelSchema(schema)
+    handler := tc.NodeEventHandler()
+    handler.OnAdd(labelledNode("gpu-001", "leaf-0", "spine-0"), false)
+    handler.OnAdd(labelledNode("gpu-002", "leaf-0", "spine-0"), false)
+    return tc, handler
+}
+
+func TestNodeEventHandlerSpineChange(t *testing.T) {
+    tc, handler := newNodeEventsTestCache(t)
+    handler.OnUpdate(labelledNode("gpu-001", "leaf-0", "spine-0"), labelledNode("gpu-001", "leaf-0", "spine-1"))
+
+    leaf, err := tc.GetDomain("leaf-0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if parent := parentOf(leaf); parent != "spine-1" {
+        t.Errorf("leaf-0 parent = %s, want spine-1", parent)
+    }
+    if domain, err := tc.GetDomainForNode("gpu-001"); err != nil || domain.Name != "leaf-0" {
+        t.Errorf("GetDomainForNode(gpu-001) = %v, %v, want leaf-0", domain, err)
+    }
+}
+
+func TestNodeEventHandlerDelete(t *testing.T) {
+    tests := []struct {
+        name string
+        obj  interface{}
+    }{
+        {
+            name: "node",
+            obj:  labelledNode("gpu-001", "leaf-0", "spine-0"),
+        },
+        {
+            name: "tombstone",
+            obj: toolscache.DeletedFinalStateUnknown{
+                Key: "gpu-001",
+                Obj: labelledNode("gpu-001", "leaf-0", "spine-0"),
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tc, handler := newNodeEventsTestCache(t)
+            handler.OnDelete(tt.obj)
+
+            if _, err := tc.GetDomainForNode("gpu-001"); err == nil {
+                t.Error("deleted node gpu-001 is still in a domain")
+            }
+            if _, err := tc.nodeCache.GetNode("gpu-001"); err == nil {
+                t.Error("deleted node gpu-001 is still in the node cache")
+            }
+            names, err := tc.GetDomainNodeNames("leaf-0")
+            if err != nil {
+                t.Fatal(err)
+            }
+            if len(names) != 1 || names[0] != "gpu-002" {
+                t.Errorf("leaf-0 nodes = %v, want [gpu-002]", names)
+            }
+        })
+    }
+}
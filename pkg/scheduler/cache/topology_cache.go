@@ -1,10 +1,17 @@
 package algorithm
 
 import (
+    "context"
     "fmt"
+    "reflect"
     "sync"
     "time"
     v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/history"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/utils/topology"
 )
 
 type TopologyCache struct {
@@ -13,6 +20,22 @@ type TopologyCache struct {
     domains           map[string]*Domain
     spineConnections map[string][]string
     domainForNode    map[string]string
+    // linkBandwidth is the measured bandwidth in Gbps between connected
+    // domains, keyed by source and target
+    linkBandwidth    map[string]map[string]float64
+    linkLatency      map[string]map[string]time.Duration
+    labelSchema      *NodeLabelSchema
+    // staged is set on the copies Apply makes changes on, whose changes
+    // are validated as a whole. pending holds the changes made on such a
+    // copy until Apply records them.
+    staged           bool
+    pending          []history.TopologyChange
+    // logMu keeps the change log in the order Apply made the changes
+    logMu            sync.Mutex
+    changeLog        *history.ChangeLog
+    // podSource lists the pods on a node so that their jobs follow it
+    // when it moves
+    podSource        func(nodeName string) ([]*v1.Pod, error)
     lastUpdated      time.Time
 }
 
@@ -22,11 +45,17 @@ func NewTopologyCache(nodeCache *NodeCache) *TopologyCache {
         domains:         make(map[string]*Domain),
         spineConnections: make(map[string][]string),
         domainForNode:   make(map[string]string),
+        linkBandwidth:   make(map[string]map[string]float64),
+        linkLatency:     make(map[string]map[string]time.Duration),
         lastUpdated:     time.Now(),
     }
 }
 
 func (tc *TopologyCache) AddDomain(domain *Domain) error {
+    return tc.change(func(c *TopologyCache) error { return c.addDomain(domain) })
+}
+
+func (tc *TopologyCache) addDomain(domain *Domain) error {
     tc.Lock()
     defer tc.Unlock()
 
@@ -35,14 +64,21 @@ func (tc *TopologyCache) AddDomain(domain *Domain) error {
     }
 
     tc.domains[domain.Name] = domain
+    changes := []history.TopologyChange{domainChange(history.DomainAdded, domain)}
     for _, node := range domain.Nodes {
+        changes = append(changes, tc.nodeChange(node.Name, domain.Name))
         tc.domainForNode[node.Name] = domain.Name
     }
+    tc.record(changes...)
     tc.lastUpdated = time.Now()
     return nil
 }
 
 func (tc *TopologyCache) AddNodeToDomain(nodeName, domainName string) error {
+    return tc.change(func(c *TopologyCache) error { return c.addNodeToDomain(nodeName, domainName) })
+}
+
+func (tc *TopologyCache) addNodeToDomain(nodeName, domainName string) error {
     tc.Lock()
     defer tc.Unlock()
 
@@ -57,12 +93,109 @@ func (tc *TopologyCache) AddNodeToDomain(nodeName, domainName string) error {
     }
 
     domain.Nodes = append(domain.Nodes, node)
+    tc.record(tc.nodeChange(nodeName, domainName))
+    tc.domainForNode[nodeName] = domainName
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+// SetPodSource supplies the pods running on a node, so that MoveNode can
+// move their GPUs and jobs along with the node
+func (tc *TopologyCache) SetPodSource(pods func(nodeName string) ([]*v1.Pod, error)) {
+    tc.Lock()
+    defer tc.Unlock()
+    tc.podSource = pods
+}
+
+// MoveNode moves a node to another domain, for example after it was
+// recabled to a different leaf. The capacity of both domains is
+// recomputed, and the GPUs and jobs of the pods running on the node move
+// with it. A node that is in no domain yet is added.
+func (tc *TopologyCache) MoveNode(nodeName, domainName string) error {
+    return tc.change(func(c *TopologyCache) error { return c.moveNode(nodeName, domainName) })
+}
+
+func (tc *TopologyCache) moveNode(nodeName, domainName string) error {
+    tc.RLock()
+    podSource := tc.podSource
+    tc.RUnlock()
+
+    var pods []*v1.Pod
+    if podSource != nil {
+        var err error
+        if pods, err = podSource(nodeName); err != nil {
+            return fmt.Errorf("failed to list pods on node %s: %v", nodeName, err)
+        }
+    }
+
+    tc.Lock()
+    defer tc.Unlock()
+
+    target, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+    node, err := tc.nodeCache.GetNode(nodeName)
+    if err != nil {
+        return fmt.Errorf("node %s not found in node cache", nodeName)
+    }
+    sourceName, assigned := tc.domainForNode[nodeName]
+    if assigned && sourceName == domainName {
+        return nil
+    }
+    tc.record(tc.nodeChange(nodeName, domainName))
+
+    if source, exists := tc.domains[sourceName]; assigned && exists {
+        for i, n := range source.Nodes {
+            if n.Name == nodeName {
+                source.Nodes = append(source.Nodes[:i], source.Nodes[i+1:]...)
+                break
+            }
+        }
+        for _, pod := range pods {
+            gpus := getGPURequirements(pod)
+            if gpus == 0 || podFinished(pod) {
+                continue
+            }
+            source.UsedGPUs -= gpus
+            if source.UsedGPUs < 0 {
+                source.UsedGPUs = 0
+            }
+            removeJob(source, jobNameForPod(pod), nodeName, gpus)
+        }
+        tc.refreshCapacity(source)
+    }
+
+    target.Nodes = append(target.Nodes, node)
     tc.domainForNode[nodeName] = domainName
+    for _, pod := range pods {
+        gpus := getGPURequirements(pod)
+        if gpus == 0 || podFinished(pod) {
+            continue
+        }
+        target.UsedGPUs += gpus
+        addJob(target, &PlacedJob{
+            Name:   jobNameForPod(pod),
+            Labels: pod.Labels,
+            GPUs:   gpus,
+            Nodes:  map[string]int{nodeName: gpus},
+        })
+    }
+    tc.refreshCapacity(target)
     tc.lastUpdated = time.Now()
+    klog.V(2).Infof("Moved node %s from domain %q to %s with %d pods", nodeName, sourceName, domainName, len(pods))
     return nil
 }
 
+func podFinished(pod *v1.Pod) bool {
+    return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
+
 func (tc *TopologyCache) RemoveNodeFromDomain(nodeName, domainName string) error {
+    return tc.change(func(c *TopologyCache) error { return c.removeNodeFromDomain(nodeName, domainName) })
+}
+
+func (tc *TopologyCache) removeNodeFromDomain(nodeName, domainName string) error {
     tc.Lock()
     defer tc.Unlock()
 
@@ -75,6 +208,7 @@ func (tc *TopologyCache) RemoveNodeFromDomain(nodeName, domainName string) error
         if node.Name == nodeName {
             domain.Nodes = append(domain.Nodes[:i], domain.Nodes[i+1:]...)
             delete(tc.domainForNode, nodeName)
+            tc.record(history.TopologyChange{Kind: history.NodeRemoved, Node: nodeName, Domain: domainName})
             tc.lastUpdated = time.Now()
             return nil
         }
@@ -82,7 +216,110 @@ func (tc *TopologyCache) RemoveNodeFromDomain(nodeName, domainName string) error
     return fmt.Errorf("node %s not found in domain %s", nodeName, domainName)
 }
 
+// RemoveDomain removes a domain with its links. Its nodes leave the
+// topology until another domain takes them.
+func (tc *TopologyCache) RemoveDomain(domainName string) error {
+    return tc.change(func(c *TopologyCache) error { return c.removeDomain(domainName) })
+}
+
+func (tc *TopologyCache) removeDomain(domainName string) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+
+    var changes []history.TopologyChange
+    for _, node := range domain.Nodes {
+        if tc.domainForNode[node.Name] == domainName {
+            delete(tc.domainForNode, node.Name)
+        }
+        changes = append(changes, history.TopologyChange{Kind: history.NodeRemoved, Node: node.Name, Domain: domainName})
+    }
+    for _, target := range tc.spineConnections[domainName] {
+        changes = append(changes, history.TopologyChange{Kind: history.LinkRemoved, Source: domainName, Target: target})
+    }
+    delete(tc.spineConnections, domainName)
+    delete(tc.linkBandwidth, domainName)
+    delete(tc.linkLatency, domainName)
+    for source, targets := range tc.spineConnections {
+        for i, target := range targets {
+            if target == domainName {
+                tc.spineConnections[source] = append(targets[:i], targets[i+1:]...)
+                delete(tc.linkBandwidth[source], domainName)
+                delete(tc.linkLatency[source], domainName)
+                changes = append(changes, history.TopologyChange{Kind: history.LinkRemoved, Source: source, Target: domainName})
+                break
+            }
+        }
+    }
+    delete(tc.domains, domainName)
+    changes = append(changes, history.TopologyChange{Kind: history.DomainRemoved, Domain: domainName})
+    tc.record(changes...)
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+// SetDomainParent moves a domain under another parent, such as a leaf
+// under another spine
+func (tc *TopologyCache) SetDomainParent(domainName, parent string) error {
+    return tc.change(func(c *TopologyCache) error { return c.setDomainParent(domainName, parent) })
+}
+
+func (tc *TopologyCache) setDomainParent(domainName, parent string) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+    if parentOf(domain) == parent {
+        return nil
+    }
+    domain.Parent = parent
+    if domainLevel(domain) == LevelLeaf {
+        domain.SpineSwitch = parent
+    }
+    tc.record(domainChange(history.DomainUpdated, domain))
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+// SetDomainLimit caps the GPUs that can be placed in a domain, whatever
+// its nodes hold. A limit of 0 removes the cap.
+func (tc *TopologyCache) SetDomainLimit(domainName string, maxGPUs int) error {
+    return tc.change(func(c *TopologyCache) error { return c.setDomainLimit(domainName, maxGPUs) })
+}
+
+func (tc *TopologyCache) setDomainLimit(domainName string, maxGPUs int) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+    if maxGPUs < 0 {
+        return fmt.Errorf("invalid GPU limit %d for domain %s", maxGPUs, domainName)
+    }
+    if domain.MaxGPUs == maxGPUs {
+        return nil
+    }
+    domain.MaxGPUs = maxGPUs
+    tc.refreshCapacity(domain)
+    tc.record(domainChange(history.DomainUpdated, domain))
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
 func (tc *TopologyCache) AddSpineConnection(source, target string) error {
+    return tc.change(func(c *TopologyCache) error { return c.addSpineConnection(source, target) })
+}
+
+func (tc *TopologyCache) addSpineConnection(source, target string) error {
     tc.Lock()
     defer tc.Unlock()
 
@@ -92,12 +329,147 @@ func (tc *TopologyCache) AddSpineConnection(source, target string) error {
     if _, exists := tc.domains[target]; !exists {
         return fmt.Errorf("target domain %s not found", target)
     }
+    if source == target {
+        return fmt.Errorf("cannot connect domain %s to itself", source)
+    }
+    if tc.connected(source, target) {
+        return nil
+    }
 
     tc.spineConnections[source] = append(tc.spineConnections[source], target)
+    tc.record(history.TopologyChange{Kind: history.LinkAdded, Source: source, Target: target})
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+// RemoveSpineConnection removes the connection from source to target with
+// its bandwidth and latency
+func (tc *TopologyCache) RemoveSpineConnection(source, target string) error {
+    return tc.change(func(c *TopologyCache) error { return c.removeSpineConnection(source, target) })
+}
+
+func (tc *TopologyCache) removeSpineConnection(source, target string) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    for i, conn := range tc.spineConnections[source] {
+        if conn == target {
+            tc.spineConnections[source] = append(tc.spineConnections[source][:i], tc.spineConnections[source][i+1:]...)
+            delete(tc.linkBandwidth[source], target)
+            delete(tc.linkLatency[source], target)
+            tc.record(history.TopologyChange{Kind: history.LinkRemoved, Source: source, Target: target})
+            tc.lastUpdated = time.Now()
+            return nil
+        }
+    }
+    return fmt.Errorf("domains %s and %s are not connected", source, target)
+}
+
+// SetLinkBandwidth records the bandwidth in Gbps of a spine connection
+func (tc *TopologyCache) SetLinkBandwidth(source, target string, gbps float64) error {
+    return tc.change(func(c *TopologyCache) error { return c.setLinkBandwidth(source, target, gbps) })
+}
+
+func (tc *TopologyCache) setLinkBandwidth(source, target string, gbps float64) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    if !tc.connected(source, target) {
+        return fmt.Errorf("domains %s and %s are not connected", source, target)
+    }
+    if tc.linkBandwidth[source] == nil {
+        tc.linkBandwidth[source] = make(map[string]float64)
+    }
+    tc.linkBandwidth[source][target] = gbps
+    tc.record(tc.linkChange(source, target))
     tc.lastUpdated = time.Now()
     return nil
 }
 
+// GetLinkBandwidth returns the bandwidth of a spine connection, false when
+// it is unknown
+func (tc *TopologyCache) GetLinkBandwidth(source, target string) (float64, bool) {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    gbps, ok := tc.linkBandwidth[source][target]
+    return gbps, ok
+}
+
+// SetLinkLatency records the latency of a spine connection
+func (tc *TopologyCache) SetLinkLatency(source, target string, latency time.Duration) error {
+    return tc.change(func(c *TopologyCache) error { return c.setLinkLatency(source, target, latency) })
+}
+
+func (tc *TopologyCache) setLinkLatency(source, target string, latency time.Duration) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    if !tc.connected(source, target) {
+        return fmt.Errorf("domains %s and %s are not connected", source, target)
+    }
+    if tc.linkLatency[source] == nil {
+        tc.linkLatency[source] = make(map[string]time.Duration)
+    }
+    tc.linkLatency[source][target] = latency
+    tc.record(tc.linkChange(source, target))
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+// GetLinkLatency returns the latency of a spine connection, false when it
+// is unknown
+func (tc *TopologyCache) GetLinkLatency(source, target string) (time.Duration, bool) {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    latency, ok := tc.linkLatency[source][target]
+    return latency, ok
+}
+
+func (tc *TopologyCache) connected(source, target string) bool {
+    for _, conn := range tc.spineConnections[source] {
+        if conn == target {
+            return true
+        }
+    }
+    return false
+}
+
+// GetBandwidthRatio compares the fastest link of the domain with the
+// fastest link in the cache. It is 1 when no bandwidths are known.
+func (tc *TopologyCache) GetBandwidthRatio(domainName string) float64 {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    var best, domainBest float64
+    for source, targets := range tc.linkBandwidth {
+        for _, gbps := range targets {
+            if gbps > best {
+                best = gbps
+            }
+            if source == domainName && gbps > domainBest {
+                domainBest = gbps
+            }
+        }
+    }
+    if best == 0 {
+        return 1.0
+    }
+    return domainBest / best
+}
+
+func (tc *TopologyCache) GetDomain(domainName string) (*Domain, error) {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return nil, fmt.Errorf("domain %s not found", domainName)
+    }
+    return domain, nil
+}
+
 func (tc *TopologyCache) GetDomainForNode(nodeName string) (*Domain, error) {
     tc.RLock()
     defer tc.RUnlock()
@@ -137,3 +509,420 @@ func (tc *TopologyCache) GetAllDomains() []*Domain {
     }
     return domains
 }
+
+func (tc *TopologyCache) AddJobToDomain(domainName string, job *PlacedJob) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+
+    addJob(domain, job)
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+func addJob(domain *Domain, job *PlacedJob) {
+    if domain.Jobs == nil {
+        domain.Jobs = make(map[string]*PlacedJob)
+    }
+    existing, ok := domain.Jobs[job.Name]
+    if !ok {
+        existing = &PlacedJob{Name: job.Name, Labels: job.Labels, Nodes: make(map[string]int)}
+        domain.Jobs[job.Name] = existing
+    }
+    existing.GPUs += job.GPUs
+    for node, gpus := range job.Nodes {
+        existing.Nodes[node] += gpus
+    }
+}
+
+// RemoveJobFromDomain takes gpus of the job on the named node off the domain
+func (tc *TopologyCache) RemoveJobFromDomain(domainName, jobName, nodeName string, gpus int) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+
+    if !removeJob(domain, jobName, nodeName, gpus) {
+        return fmt.Errorf("job %s not found in domain %s", jobName, domainName)
+    }
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+func removeJob(domain *Domain, jobName, nodeName string, gpus int) bool {
+    job, ok := domain.Jobs[jobName]
+    if !ok {
+        return false
+    }
+    // A node gives back no more than the job holds on it
+    if held, ok := job.Nodes[nodeName]; ok && held < gpus {
+        gpus = held
+    }
+    job.GPUs -= gpus
+    if job.Nodes[nodeName] -= gpus; job.Nodes[nodeName] <= 0 {
+        delete(job.Nodes, nodeName)
+    }
+    if job.GPUs <= 0 {
+        delete(domain.Jobs, jobName)
+    }
+    return true
+}
+
+// GetDomainJobs returns copies of the jobs placed in the named domain
+func (tc *TopologyCache) GetDomainJobs(domainName string) ([]*PlacedJob, error) {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return nil, fmt.Errorf("domain %s not found", domainName)
+    }
+    jobs := make([]*PlacedJob, 0, len(domain.Jobs))
+    for _, job := range domain.Jobs {
+        copied := *job
+        copied.Nodes = make(map[string]int, len(job.Nodes))
+        for node, gpus := range job.Nodes {
+            copied.Nodes[node] = gpus
+        }
+        jobs = append(jobs, &copied)
+    }
+    return jobs, nil
+}
+
+// GetDomainsWithJob returns the names of the domains the job has pods in
+func (tc *TopologyCache) GetDomainsWithJob(jobName string) []string {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    var names []string
+    for name, domain := range tc.domains {
+        if _, ok := domain.Jobs[jobName]; ok {
+            names = append(names, name)
+        }
+    }
+    return names
+}
+
+// GetJobNodes returns the GPUs the job holds on each of its nodes
+func (tc *TopologyCache) GetJobNodes(jobName string) map[string]int {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    nodes := make(map[string]int)
+    for _, domain := range tc.domains {
+        if job, ok := domain.Jobs[jobName]; ok {
+            for node, gpus := range job.Nodes {
+                nodes[node] += gpus
+            }
+        }
+    }
+    return nodes
+}
+
+// GetAncestorAtLevel walks up from the named domain and returns the first
+// domain at the given level, which may be the domain itself.
+func (tc *TopologyCache) GetAncestorAtLevel(domainName string, level TopologyLevel) (*Domain, error) {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    return tc.ancestorAtLevel(domainName, level)
+}
+
+// GetJobsInScope returns the jobs placed anywhere under the ancestor of
+// domainName at the given level.
+func (tc *TopologyCache) GetJobsInScope(domainName string, level TopologyLevel) ([]*PlacedJob, error) {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    scope, err := tc.ancestorAtLevel(domainName, level)
+    if err != nil {
+        return nil, err
+    }
+
+    var jobs []*PlacedJob
+    for _, domain := range tc.domains {
+        if len(domain.Jobs) == 0 {
+            continue
+        }
+        ancestor, err := tc.ancestorAtLevel(domain.Name, level)
+        if err != nil || ancestor.Name != scope.Name {
+            continue
+        }
+        for _, job := range domain.Jobs {
+            jobs = append(jobs, job)
+        }
+    }
+    return jobs, nil
+}
+
+func (tc *TopologyCache) ancestorAtLevel(domainName string, level TopologyLevel) (*Domain, error) {
+    visited := make(map[string]bool)
+    name := domainName
+    for name != "" && !visited[name] {
+        visited[name] = true
+        domain, exists := tc.domains[name]
+        if !exists {
+            // Spines are often only known through the leaves' SpineSwitch
+            if name != domainName && level == LevelSpine {
+                return &Domain{Name: name, Level: LevelSpine}, nil
+            }
+            return nil, fmt.Errorf("domain %s not found", name)
+        }
+        if domainLevel(domain) == level {
+            return domain, nil
+        }
+        name = parentOf(domain)
+    }
+    return nil, fmt.Errorf("domain %s has no ancestor at level %s", domainName, level)
+}
+
+// domainLevel treats domains registered before levels existed as leaves.
+func domainLevel(domain *Domain) TopologyLevel {
+    if domain.Level == "" {
+        return LevelLeaf
+    }
+    return domain.Level
+}
+
+// parentOf falls back to the spine switch name for leaves without an
+// explicit parent.
+func parentOf(domain *Domain) string {
+    if domain.Parent != "" {
+        return domain.Parent
+    }
+    if domainLevel(domain) == LevelLeaf {
+        return domain.SpineSwitch
+    }
+    return ""
+}
+
+// AddDomainTaint adds or replaces a taint with the same key and effect.
+func (tc *TopologyCache) AddDomainTaint(domainName string, taint v1.Taint) error {
+    return tc.change(func(c *TopologyCache) error { return c.addDomainTaint(domainName, taint) })
+}
+
+func (tc *TopologyCache) addDomainTaint(domainName string, taint v1.Taint) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+
+    if taint.TimeAdded == nil {
+        now := metav1.Now()
+        taint.TimeAdded = &now
+    }
+    before := historyTaints(domain.Taints)
+    replaced := false
+    for i := range domain.Taints {
+        if domain.Taints[i].MatchTaint(&taint) {
+            domain.Taints[i] = taint
+            replaced = true
+            break
+        }
+    }
+    if !replaced {
+        domain.Taints = append(domain.Taints, taint)
+    }
+    if !reflect.DeepEqual(before, historyTaints(domain.Taints)) {
+        tc.record(domainChange(history.DomainUpdated, domain))
+    }
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+// SetDomainTaints replaces the taints of the domain. Taints that stay keep
+// the time they were added, so tolerations with TolerationSeconds do not
+// restart.
+func (tc *TopologyCache) SetDomainTaints(domainName string, taints []v1.Taint) error {
+    return tc.change(func(c *TopologyCache) error { return c.setDomainTaints(domainName, taints) })
+}
+
+func (tc *TopologyCache) setDomainTaints(domainName string, taints []v1.Taint) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+
+    now := metav1.Now()
+    updated := make([]v1.Taint, 0, len(taints))
+    for _, taint := range taints {
+        for i := range domain.Taints {
+            if domain.Taints[i].MatchTaint(&taint) && taint.TimeAdded == nil {
+                taint.TimeAdded = domain.Taints[i].TimeAdded
+            }
+        }
+        if taint.TimeAdded == nil {
+            taint.TimeAdded = &now
+        }
+        updated = append(updated, taint)
+    }
+    changed := !reflect.DeepEqual(historyTaints(domain.Taints), historyTaints(updated))
+    domain.Taints = updated
+    if changed {
+        tc.record(domainChange(history.DomainUpdated, domain))
+    }
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+// GetDomainNodeNames returns the names of the nodes in the domain
+func (tc *TopologyCache) GetDomainNodeNames(domainName string) ([]string, error) {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return nil, fmt.Errorf("domain %s not found", domainName)
+    }
+    names := make([]string, 0, len(domain.Nodes))
+    for _, node := range domain.Nodes {
+        names = append(names, node.Name)
+    }
+    return names, nil
+}
+
+func (tc *TopologyCache) RemoveDomainTaint(domainName string, taint v1.Taint) error {
+    return tc.change(func(c *TopologyCache) error { return c.removeDomainTaint(domainName, taint) })
+}
+
+func (tc *TopologyCache) removeDomainTaint(domainName string, taint v1.Taint) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+
+    for i := range domain.Taints {
+        if domain.Taints[i].MatchTaint(&taint) {
+            domain.Taints = append(domain.Taints[:i], domain.Taints[i+1:]...)
+            tc.record(domainChange(history.DomainUpdated, domain))
+            tc.lastUpdated = time.Now()
+            return nil
+        }
+    }
+    return fmt.Errorf("taint %s:%s not found on domain %s", taint.Key, taint.Effect, domainName)
+}
+
+// GetEffectiveTaints returns the taints of the domain together with those
+// inherited from all of its ancestors.
+func (tc *TopologyCache) GetEffectiveTaints(domainName string) ([]v1.Taint, error) {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    if _, exists := tc.domains[domainName]; !exists {
+        return nil, fmt.Errorf("domain %s not found", domainName)
+    }
+
+    var taints []v1.Taint
+    visited := make(map[string]bool)
+    for name := domainName; name != "" && !visited[name]; {
+        visited[name] = true
+        domain, exists := tc.domains[name]
+        if !exists {
+            break
+        }
+        taints = append(taints, domain.Taints...)
+        name = parentOf(domain)
+    }
+    return taints, nil
+}
+
+// RefreshDomainCapacity recomputes the domain's total GPUs from the healthy
+// devices of its nodes, so that failed GPUs are not offered to new jobs.
+func (tc *TopologyCache) RefreshDomainCapacity(domainName string) error {
+    tc.Lock()
+    defer tc.Unlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return fmt.Errorf("domain %s not found", domainName)
+    }
+
+    tc.refreshCapacity(domain)
+    tc.lastUpdated = time.Now()
+    return nil
+}
+
+func (tc *TopologyCache) refreshCapacity(domain *Domain) {
+    total := 0
+    for _, node := range domain.Nodes {
+        healthy, err := tc.nodeCache.GetHealthyGPUCount(node.Name)
+        if err != nil {
+            continue
+        }
+        total += healthy
+    }
+    if domain.MaxGPUs > 0 && total > domain.MaxGPUs {
+        total = domain.MaxGPUs
+    }
+    domain.TotalGPUs = total
+}
+
+// GetDomainHealth returns the GPU-weighted health of the domain in [0, 1]
+func (tc *TopologyCache) GetDomainHealth(domainName string) (float64, error) {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    domain, exists := tc.domains[domainName]
+    if !exists {
+        return 0, fmt.Errorf("domain %s not found", domainName)
+    }
+    return topology.CalculateDomainHealth(domain, tc.nodeCache.GetGPUHealth), nil
+}
+
+// SyncGPUHealth periodically scrapes the exporter, records device health in
+// the node cache and refreshes the capacity of the affected domains until
+// ctx is cancelled.
+func (tc *TopologyCache) SyncGPUHealth(ctx context.Context, scraper *topology.DCGMScraper, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        health, err := scraper.Scrape(ctx)
+        if err != nil {
+            klog.Warningf("Failed to scrape GPU health: %v", err)
+        }
+
+        changed := make(map[string]bool)
+        for nodeName, devices := range health {
+            if err := tc.nodeCache.UpdateGPUHealth(nodeName, devices); err != nil {
+                klog.V(4).Infof("Ignoring GPU health for unknown node %s", nodeName)
+                continue
+            }
+            if domain, err := tc.GetDomainForNode(nodeName); err == nil {
+                changed[domain.Name] = true
+            }
+        }
+        for domainName := range changed {
+            if err := tc.RefreshDomainCapacity(domainName); err != nil {
+                klog.Warningf("Failed to refresh capacity of domain %s: %v", domainName, err)
+            }
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// GetNodeAvailableGPUs returns the healthy, unallocated GPUs of a node
+func (tc *TopologyCache) GetNodeAvailableGPUs(nodeName string) (int, error) {
+    return tc.nodeCache.GetAvailableGPUs(nodeName)
+}
@@ -0,0 +1,433 @@
+package algorithm
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/history"
+)
+
+type IssueSeverity string
+
+const (
+    SeverityError   IssueSeverity = "error"
+    SeverityWarning IssueSeverity = "warning"
+)
+
+// TopologyIssue is one problem found by Validate
+type TopologyIssue struct {
+    Severity IssueSeverity `json:"severity"`
+    // Check names the rule that failed, such as duplicate-link
+    Check   string `json:"check"`
+    Domain  string `json:"domain,omitempty"`
+    Node    string `json:"node,omitempty"`
+    Message string `json:"message"`
+}
+
+func (i TopologyIssue) String() string {
+    return fmt.Sprintf("%s: %s: %s", i.Severity, i.Check, i.Message)
+}
+
+// ValidationReport lists the structural errors and the warnings of a
+// topology. A topology with errors is not used for scheduling.
+type ValidationReport struct {
+    Errors   []TopologyIssue `json:"errors"`
+    Warnings []TopologyIssue `json:"warnings"`
+}
+
+func (r *ValidationReport) Valid() bool {
+    return len(r.Errors) == 0
+}
+
+func (r *ValidationReport) add(severity IssueSeverity, check, domain, node, format string, args ...interface{}) {
+    issue := TopologyIssue{
+        Severity: severity,
+        Check:    check,
+        Domain:   domain,
+        Node:     node,
+        Message:  fmt.Sprintf(format, args...),
+    }
+    if severity == SeverityError {
+        r.Errors = append(r.Errors, issue)
+    } else {
+        r.Warnings = append(r.Warnings, issue)
+    }
+}
+
+// ValidationError is returned when a change would leave the topology with
+// structural errors
+type ValidationError struct {
+    Report *ValidationReport
+}
+
+func (e *ValidationError) Error() string {
+    messages := make([]string, 0, len(e.Report.Errors))
+    for _, issue := range e.Report.Errors {
+        messages = append(messages, issue.Message)
+    }
+    return fmt.Sprintf("invalid topology: %s", strings.Join(messages, "; "))
+}
+
+// SetLabelSchema enables the warning for nodes whose labels name another
+// leaf than the one they are in
+func (tc *TopologyCache) SetLabelSchema(schema *NodeLabelSchema) {
+    tc.Lock()
+    defer tc.Unlock()
+    tc.labelSchema = schema
+}
+
+// Validate checks the topology for nodes in several domains, orphan
+// domains, parent cycles, duplicate and dangling links and leaves that
+// cannot reach the others, which are errors, and for one-way links, empty
+// leaves and nodes whose labels disagree with their leaf, which are
+// warnings.
+func (tc *TopologyCache) Validate() *ValidationReport {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    return tc.validate()
+}
+
+func (tc *TopologyCache) validate() *ValidationReport {
+    report := &ValidationReport{}
+
+    names := make([]string, 0, len(tc.domains))
+    for name := range tc.domains {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    // Node membership
+    memberOf := make(map[string][]string)
+    for _, name := range names {
+        domain := tc.domains[name]
+        for _, node := range domain.Nodes {
+            memberOf[node.Name] = append(memberOf[node.Name], name)
+        }
+        if domainLevel(domain) == LevelLeaf && len(domain.Nodes) == 0 {
+            report.add(SeverityWarning, "empty-domain", name, "", "domain %s has no nodes", name)
+        }
+    }
+    nodes := make([]string, 0, len(memberOf))
+    for node := range memberOf {
+        nodes = append(nodes, node)
+    }
+    sort.Strings(nodes)
+    for _, node := range nodes {
+        if domains := memberOf[node]; len(domains) > 1 {
+            report.add(SeverityError, "node-in-multiple-domains", "", node,
+                "node %s is in domains %s", node, strings.Join(domains, ", "))
+        }
+    }
+    mapped := make([]string, 0, len(tc.domainForNode))
+    for node := range tc.domainForNode {
+        mapped = append(mapped, node)
+    }
+    sort.Strings(mapped)
+    for _, node := range mapped {
+        domain := tc.domainForNode[node]
+        if !contains(memberOf[node], domain) {
+            report.add(SeverityError, "stale-node-mapping", domain, node,
+                "node %s is mapped to domain %s but is not one of its nodes", node, domain)
+        }
+    }
+
+    // Hierarchy
+    for _, name := range names {
+        domain := tc.domains[name]
+        parent := parentOf(domain)
+        if parent == "" {
+            continue
+        }
+        if _, exists := tc.domains[parent]; !exists && parent != domain.SpineSwitch {
+            report.add(SeverityError, "orphan-domain", name, "", "domain %s has unknown parent %s", name, parent)
+            continue
+        }
+        visited := map[string]bool{name: true}
+        for p := parent; p != ""; {
+            if visited[p] {
+                report.add(SeverityError, "parent-cycle", name, "", "domain %s is its own ancestor", name)
+                break
+            }
+            visited[p] = true
+            ancestor, exists := tc.domains[p]
+            if !exists {
+                break
+            }
+            p = parentOf(ancestor)
+        }
+    }
+
+    // Links
+    sources := make([]string, 0, len(tc.spineConnections))
+    for source := range tc.spineConnections {
+        sources = append(sources, source)
+    }
+    sort.Strings(sources)
+    adjacent := make(map[string]map[string]bool)
+    linked := make(map[string]bool)
+    for _, source := range sources {
+        if _, exists := tc.domains[source]; !exists {
+            report.add(SeverityError, "dangling-link", source, "", "links from unknown domain %s", source)
+            continue
+        }
+        seen := make(map[string]bool)
+        for _, target := range tc.spineConnections[source] {
+            switch {
+            case target == source:
+                report.add(SeverityError, "self-link", source, "", "domain %s is linked to itself", source)
+                continue
+            case seen[target]:
+                report.add(SeverityError, "duplicate-link", source, "", "link %s -> %s is listed more than once", source, target)
+                continue
+            }
+            seen[target] = true
+            if _, exists := tc.domains[target]; !exists {
+                report.add(SeverityError, "dangling-link", source, "", "link %s -> %s targets an unknown domain", source, target)
+                continue
+            }
+            if !tc.connected(target, source) {
+                report.add(SeverityWarning, "asymmetric-link", source, "", "link %s -> %s has no reverse link", source, target)
+            }
+            if adjacent[source] == nil {
+                adjacent[source] = make(map[string]bool)
+            }
+            if adjacent[target] == nil {
+                adjacent[target] = make(map[string]bool)
+            }
+            adjacent[source][target] = true
+            adjacent[target][source] = true
+            linked[source], linked[target] = true, true
+        }
+    }
+
+    tc.validateReachability(report, names, adjacent, linked)
+    tc.validateLabels(report, names)
+    return report
+}
+
+// validateReachability reports the leaves that are neither linked to
+// another leaf nor under a spine. Spines are taken to reach each other
+// through the core. A flat cluster without links or spines only gets a
+// warning.
+func (tc *TopologyCache) validateReachability(report *ValidationReport, names []string, adjacent map[string]map[string]bool, linked map[string]bool) {
+    var leaves []string
+    connect := func(a, b string) {
+        if adjacent[a] == nil {
+            adjacent[a] = make(map[string]bool)
+        }
+        if adjacent[b] == nil {
+            adjacent[b] = make(map[string]bool)
+        }
+        adjacent[a][b], adjacent[b][a] = true, true
+    }
+    const core = "\x00core"
+    hasCore := false
+    for _, name := range names {
+        domain := tc.domains[name]
+        if domainLevel(domain) == LevelLeaf {
+            leaves = append(leaves, name)
+        }
+        parent := parentOf(domain)
+        if parent == "" {
+            continue
+        }
+        connect(name, parent)
+        if ancestor, exists := tc.domains[parent]; !exists || parentOf(ancestor) == "" {
+            connect(parent, core)
+            hasCore = true
+        }
+    }
+    if len(leaves) < 2 {
+        return
+    }
+    if !hasCore && len(linked) == 0 {
+        report.add(SeverityWarning, "no-links", "", "", "none of the %d leaves are linked", len(leaves))
+        return
+    }
+
+    // Find the part of the fabric reached from the core, or the largest
+    // group of linked leaves when there are no spines
+    reached := func(start string) map[string]bool {
+        seen := map[string]bool{start: true}
+        queue := []string{start}
+        for len(queue) > 0 {
+            current := queue[0]
+            queue = queue[1:]
+            for next := range adjacent[current] {
+                if !seen[next] {
+                    seen[next] = true
+                    queue = append(queue, next)
+                }
+            }
+        }
+        return seen
+    }
+    var fabric map[string]bool
+    if hasCore {
+        fabric = reached(core)
+    } else {
+        for _, leaf := range leaves {
+            if group := reached(leaf); len(group) > len(fabric) {
+                fabric = group
+            }
+        }
+    }
+    for _, leaf := range leaves {
+        if !fabric[leaf] {
+            report.add(SeverityError, "unreachable-leaf", leaf, "", "leaf %s cannot reach the rest of the fabric", leaf)
+        }
+    }
+}
+
+// validateLabels warns about nodes whose labels under the label schema
+// name another leaf
+func (tc *TopologyCache) validateLabels(report *ValidationReport, names []string) {
+    if tc.labelSchema == nil {
+        return
+    }
+    for _, name := range names {
+        domain := tc.domains[name]
+        if domainLevel(domain) != LevelLeaf {
+            continue
+        }
+        for _, node := range domain.Nodes {
+            labelled, err := tc.labelSchema.Resolve(node)
+            if err != nil || labelled.Leaf == name {
+                continue
+            }
+            report.add(SeverityWarning, "label-mismatch", name, node.Name,
+                "node %s is in leaf %s but its labels name %s", node.Name, name, labelled.Leaf)
+        }
+    }
+}
+
+// Apply makes a change only if it leaves the topology valid. fn makes the
+// change on a copy of the cache, which is validated and then takes the
+// place of the cache's topology, so a change that fails or is rejected
+// leaves the cache as it was. Domains looked up before the change keep
+// their old state. The cache stays locked meanwhile, so fn must only use
+// the cache it is given. Changes made inside fn are validated together
+// with it. The change log is written after the cache is unlocked.
+func (tc *TopologyCache) Apply(fn func(*TopologyCache) error) (*ValidationReport, error) {
+    if tc.staged {
+        return nil, fn(tc)
+    }
+    report, changes, err := tc.apply(fn)
+    if err != nil {
+        return report, err
+    }
+    defer tc.logMu.Unlock()
+    writeChanges(tc.changeLog, changes)
+    return report, nil
+}
+
+// apply makes the change and returns what to record, holding logMu so
+// that changes reach the log in the order they were made
+func (tc *TopologyCache) apply(fn func(*TopologyCache) error) (*ValidationReport, []history.TopologyChange, error) {
+    // Readers see the topology before or after the whole change
+    tc.Lock()
+    defer tc.Unlock()
+
+    candidate := tc.clone()
+    if err := fn(candidate); err != nil {
+        return nil, nil, err
+    }
+    report := candidate.Validate()
+    if !report.Valid() {
+        return report, nil, &ValidationError{Report: report}
+    }
+    for _, issue := range report.Warnings {
+        klog.V(2).Infof("Topology %s", issue)
+    }
+
+    tc.domains = candidate.domains
+    tc.spineConnections = candidate.spineConnections
+    tc.domainForNode = candidate.domainForNode
+    tc.linkBandwidth = candidate.linkBandwidth
+    tc.linkLatency = candidate.linkLatency
+    tc.lastUpdated = candidate.lastUpdated
+    tc.logMu.Lock()
+    return report, candidate.pending, nil
+}
+
+// change makes a single change through Apply
+func (tc *TopologyCache) change(fn func(*TopologyCache) error) error {
+    _, err := tc.Apply(fn)
+    return err
+}
+
+// clone copies the topology and the jobs and taints of its domains for
+// Apply to make a change on. The node cache and the node objects are
+// shared. Callers hold the lock.
+func (tc *TopologyCache) clone() *TopologyCache {
+    out := NewTopologyCache(tc.nodeCache)
+    out.labelSchema = tc.labelSchema
+    out.podSource = tc.podSource
+    out.lastUpdated = tc.lastUpdated
+    out.staged = true
+    for name, domain := range tc.domains {
+        copied := *domain
+        copied.Nodes = append([]*v1.Node(nil), domain.Nodes...)
+        copied.Taints = append([]v1.Taint(nil), domain.Taints...)
+        copied.Jobs = make(map[string]*PlacedJob, len(domain.Jobs))
+        for jobName, job := range domain.Jobs {
+            placed := *job
+            placed.Nodes = make(map[string]int, len(job.Nodes))
+            for node, gpus := range job.Nodes {
+                placed.Nodes[node] = gpus
+            }
+            copied.Jobs[jobName] = &placed
+        }
+        out.domains[name] = &copied
+    }
+    for source, targets := range tc.spineConnections {
+        out.spineConnections[source] = append([]string(nil), targets...)
+    }
+    for node, domain := range tc.domainForNode {
+        out.domainForNode[node] = domain
+    }
+    for source, targets := range tc.linkBandwidth {
+        out.linkBandwidth[source] = make(map[string]float64, len(targets))
+        for target, gbps := range targets {
+            out.linkBandwidth[source][target] = gbps
+        }
+    }
+    for source, targets := range tc.linkLatency {
+        out.linkLatency[source] = make(map[string]time.Duration, len(targets))
+        for target, latency := range targets {
+            out.linkLatency[source][target] = latency
+        }
+    }
+    return out
+}
+
+// ValidationHandler serves the validation report of the cache as JSON
+func (tc *TopologyCache) ValidationHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet {
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        if err := json.NewEncoder(w).Encode(tc.Validate()); err != nil {
+            klog.Warningf("Failed to write validation report: %v", err)
+        }
+    })
+}
+
+func contains(list []string, val string) bool {
+    for _, v := range list {
+        if v == val {
+            return true
+        }
+    }
+    return false
+}
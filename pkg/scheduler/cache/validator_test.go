@@ -0,0 +1,256 @@
+package algorithm
+
+import (
+    "errors"
+    "fmt"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "testing"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/history"
+)
+
+// newValidatorTestCache builds leaf-0 and leaf-1 under spine-0 and leaf-2,
+// which has no spine and reaches the others only through its one-way link
+// to leaf-0. gpu-004 is in no domain.
+func newValidatorTestCache(t *testing.T) *TopologyCache {
+    t.Helper()
+    nodeCache := NewNodeCache()
+    for _, name := range []string{"gpu-001", "gpu-002", "gpu-003", "gpu-004", "gpu-005"} {
+        if err := nodeCache.AddNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    tc := NewTopologyCache(nodeCache)
+    leaves := []struct {
+        name, spine string
+        nodes       []string
+    }{
+        {"leaf-0", "spine-0", []string{"gpu-001", "gpu-002"}},
+        {"leaf-1", "spine-0", []string{"gpu-003"}},
+        {"leaf-2", "", []string{"gpu-005"}},
+    }
+    _, err := tc.Apply(func(c *TopologyCache) error {
+        for _, leaf := range leaves {
+            domain := &Domain{Name: leaf.name, Level: LevelLeaf, SpineSwitch: leaf.spine, Jobs: make(map[string]*PlacedJob)}
+            for _, name := range leaf.nodes {
+                node, _ := nodeCache.GetNode(name)
+                domain.Nodes = append(domain.Nodes, node)
+            }
+            if err := c.AddDomain(domain); err != nil {
+                return err
+            }
+        }
+        for _, link := range [][2]string{{"leaf-0", "leaf-1"}, {"leaf-1", "leaf-0"}, {"leaf-2", "leaf-0"}} {
+            if err := c.AddSpineConnection(link[0], link[1]); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tc
+}
+
+// snapshot captures the parts of the cache a change can touch
+func snapshot(tc *TopologyCache) string {
+    tc.RLock()
+    defer tc.RUnlock()
+
+    state := tc.topologyState()
+    out := fmt.Sprintf("%v %v %v", state.Domains, state.Nodes, state.Links)
+    names := make([]string, 0, len(tc.domains))
+    for name := range tc.domains {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        domain := tc.domains[name]
+        out += fmt.Sprintf(" %s:%d:%d:%d:%v", name, len(domain.Nodes), domain.MaxGPUs, len(domain.Taints), domain.Jobs)
+    }
+    return out
+}
+
+func TestApplyRejectsInvalidChange(t *testing.T) {
+    tests := []struct {
+        name   string
+        change func(tc *TopologyCache) error
+        check  string
+    }{
+        {
+            name:   "node added to a second leaf",
+            change: func(tc *TopologyCache) error { return tc.AddNodeToDomain("gpu-001", "leaf-1") },
+            check:  "node-in-multiple-domains",
+        },
+        {
+            name:   "last link of a leaf removed",
+            change: func(tc *TopologyCache) error { return tc.RemoveSpineConnection("leaf-2", "leaf-0") },
+            check:  "unreachable-leaf",
+        },
+        {
+            name:   "leaf moved under itself",
+            change: func(tc *TopologyCache) error { return tc.SetDomainParent("leaf-1", "leaf-1") },
+            check:  "parent-cycle",
+        },
+        {
+            name: "several changes that are only invalid together",
+            change: func(tc *TopologyCache) error {
+                _, err := tc.Apply(func(c *TopologyCache) error {
+                    if err := c.AddSpineConnection("leaf-0", "leaf-2"); err != nil {
+                        return err
+                    }
+                    if err := c.RemoveSpineConnection("leaf-2", "leaf-0"); err != nil {
+                        return err
+                    }
+                    return c.RemoveSpineConnection("leaf-0", "leaf-2")
+                })
+                return err
+            },
+            check: "unreachable-leaf",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tc := newValidatorTestCache(t)
+            log, err := history.OpenChangeLog(filepath.Join(t.TempDir(), "changes.db"), 0)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer log.Close()
+            if err := tc.SetChangeLog(log); err != nil {
+                t.Fatal(err)
+            }
+            before, version := snapshot(tc), log.Version()
+
+            err = tt.change(tc)
+            var invalid *ValidationError
+            if !errors.As(err, &invalid) {
+                t.Fatalf("change error = %v, want a ValidationError", err)
+            }
+            found := false
+            for _, issue := range invalid.Report.Errors {
+                found = found || issue.Check == tt.check
+            }
+            if !found {
+                t.Errorf("errors = %v, want a %s error", invalid.Report.Errors, tt.check)
+            }
+            if after := snapshot(tc); after != before {
+                t.Errorf("rejected change modified the cache:\nbefore %s\nafter  %s", before, after)
+            }
+            if got := log.Version(); got != version {
+                t.Errorf("rejected change was recorded: version %d, want %d", got, version)
+            }
+            if report := tc.Validate(); !report.Valid() {
+                t.Errorf("cache is invalid after a rejected change: %v", report.Errors)
+            }
+        })
+    }
+}
+
+func TestApplyAcceptsValidChange(t *testing.T) {
+    tc := newValidatorTestCache(t)
+    log, err := history.OpenChangeLog(filepath.Join(t.TempDir(), "changes.db"), 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer log.Close()
+    if err := tc.SetChangeLog(log); err != nil {
+        t.Fatal(err)
+    }
+    version := log.Version()
+
+    // Dropping one direction of the leaf-0 link leaves it one-way, which
+    // is only a warning
+    report, err := tc.Apply(func(c *TopologyCache) error {
+        if err := c.RemoveSpineConnection("leaf-0", "leaf-1"); err != nil {
+            return err
+        }
+        if err := c.SetLinkLatency("leaf-1", "leaf-0", 3*time.Microsecond); err != nil {
+            return err
+        }
+        if err := c.AddNodeToDomain("gpu-004", "leaf-1"); err != nil {
+            return err
+        }
+        if err := c.SetDomainLimit("leaf-1", 8); err != nil {
+            return err
+        }
+        return c.AddDomainTaint("leaf-1", v1.Taint{Key: "maintenance", Effect: v1.TaintEffectNoSchedule})
+    })
+    if err != nil {
+        t.Fatalf("Apply() error = %v", err)
+    }
+    var warnings []string
+    for _, issue := range report.Warnings {
+        warnings = append(warnings, issue.Check+" "+issue.Domain)
+    }
+    want := []string{"asymmetric-link leaf-1", "asymmetric-link leaf-2"}
+    if !reflect.DeepEqual(warnings, want) {
+        t.Errorf("warnings = %v, want %v", warnings, want)
+    }
+
+    if domain, err := tc.GetDomainForNode("gpu-004"); err != nil || domain.Name != "leaf-1" {
+        t.Errorf("GetDomainForNode(gpu-004) = %v, %v, want leaf-1", domain, err)
+    }
+    if latency, ok := tc.GetLinkLatency("leaf-1", "leaf-0"); !ok || latency != 3*time.Microsecond {
+        t.Errorf("GetLinkLatency(leaf-1, leaf-0) = %v, %v", latency, ok)
+    }
+    if domains, _ := tc.GetConnectedDomains("leaf-0"); len(domains) != 0 {
+        t.Errorf("leaf-0 is still linked to %v", domains)
+    }
+    if domain, _ := tc.GetDomain("leaf-1"); domain.MaxGPUs != 8 || len(domain.Taints) != 1 {
+        t.Errorf("leaf-1 = %+v, want a limit of 8 GPUs and one taint", domain)
+    }
+    if got := log.Version(); got <= version {
+        t.Errorf("accepted change was not recorded: version %d", got)
+    }
+    state, err := log.State(log.Version())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(state, tc.TopologyState()) {
+        t.Errorf("recorded state = %+v, want %+v", state, tc.TopologyState())
+    }
+    if leaf := state.Domains["leaf-1"]; leaf.MaxGPUs != 8 || len(leaf.Taints) != 1 {
+        t.Errorf("recorded leaf-1 = %+v, want its limit and taint", leaf)
+    }
+}
+
+// TestApplyFailedChange checks that a change that fails partway leaves no
+// trace, since fn only ever runs on a copy
+func TestApplyFailedChange(t *testing.T) {
+    tc := newValidatorTestCache(t)
+    before := snapshot(tc)
+    leaf, _ := tc.GetDomain("leaf-0")
+
+    calls := 0
+    _, err := tc.Apply(func(c *TopologyCache) error {
+        calls++
+        if err := c.MoveNode("gpu-001", "leaf-1"); err != nil {
+            return err
+        }
+        if err := c.AddJobToDomain("leaf-1", &PlacedJob{Name: "default/train", GPUs: 8, Nodes: map[string]int{"gpu-001": 8}}); err != nil {
+            return err
+        }
+        return c.RemoveSpineConnection("leaf-1", "leaf-2")
+    })
+    if err == nil {
+        t.Fatal("Apply() succeeded, want the error of the missing link")
+    }
+    if calls != 1 {
+        t.Errorf("fn ran %d times, want once", calls)
+    }
+    if after := snapshot(tc); after != before {
+        t.Errorf("failed change modified the cache:\nbefore %s\nafter  %s", before, after)
+    }
+    if len(leaf.Nodes) != 2 {
+        t.Errorf("leaf-0 has %d nodes, want 2", len(leaf.Nodes))
+    }
+}
@@ -0,0 +1,163 @@
+// Package extension is the client and server side of the external scoring
+// contract. The scheduler calls the unary gRPC method
+// /topology.scheduler.scoring.v1.Scorer/Score, whose request and response
+// are the JSON documents described by api/scoring/v1/scoring.schema.json.
+// They travel with the "json" content-subtype (content-type
+// application/grpc+json) rather than as protobuf, so a server in another
+// language registers a JSON codec under that name; NewServer does this
+// for Go servers.
+package extension
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/credentials/insecure"
+)
+
+// The Go types below are the messages of api/scoring/v1/scoring.schema.json
+
+const (
+    serviceName = "topology.scheduler.scoring.v1.Scorer"
+    scoreMethod = "/" + serviceName + "/Score"
+)
+
+type PodRequirements struct {
+    Namespace   string            `json:"namespace"`
+    Name        string            `json:"name"`
+    JobName     string            `json:"jobName"`
+    TotalGPUs   int32             `json:"totalGpus"`
+    GPUsPerNode int32             `json:"gpusPerNode"`
+    NodesNeeded int32             `json:"nodesNeeded"`
+    CommProfile string            `json:"commProfile,omitempty"`
+    Labels      map[string]string `json:"labels,omitempty"`
+}
+
+type CandidateFeatures struct {
+    Node              string  `json:"node"`
+    Domain            string  `json:"domain"`
+    Spine             string  `json:"spine,omitempty"`
+    DomainTotalGPUs   int32   `json:"domainTotalGpus"`
+    DomainUsedGPUs    int32   `json:"domainUsedGpus"`
+    DomainNodes       int32   `json:"domainNodes"`
+    DomainHealth      float64 `json:"domainHealth"`
+    NodeAvailableGPUs int32   `json:"nodeAvailableGpus"`
+    BuiltinScore      float64 `json:"builtinScore"`
+}
+
+type ScoreRequest struct {
+    Pod        PodRequirements     `json:"pod"`
+    Candidates []CandidateFeatures `json:"candidates"`
+}
+
+type CandidateScore struct {
+    Node  string  `json:"node"`
+    Score float64 `json:"score"`
+}
+
+type ScoreResponse struct {
+    Scores       []CandidateScore `json:"scores"`
+    ModelVersion string           `json:"modelVersion,omitempty"`
+}
+
+// jsonOverGRPCCodec encodes the contract messages as JSON. It is forced on
+// both ends of the scoring connection only and not registered globally, so
+// other gRPC users in the process keep the protobuf codec.
+type jsonOverGRPCCodec struct{}
+
+func (jsonOverGRPCCodec) Marshal(v interface{}) ([]byte, error) {
+    return json.Marshal(v)
+}
+
+func (jsonOverGRPCCodec) Unmarshal(data []byte, v interface{}) error {
+    return json.Unmarshal(data, v)
+}
+
+// Name is the content-subtype sent on the wire
+func (jsonOverGRPCCodec) Name() string {
+    return "json"
+}
+
+// ScorerServer is implemented by external scoring services
+type ScorerServer interface {
+    Score(ctx context.Context, req *ScoreRequest) (*ScoreResponse, error)
+}
+
+func RegisterScorerServer(s *grpc.Server, impl ScorerServer) {
+    s.RegisterService(&grpc.ServiceDesc{
+        ServiceName: serviceName,
+        HandlerType: (*ScorerServer)(nil),
+        Methods: []grpc.MethodDesc{
+            {
+                MethodName: "Score",
+                Handler:    scoreHandler,
+            },
+        },
+        Metadata: "api/scoring/v1/scoring.schema.json",
+    }, impl)
+}
+
+func scoreHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+    req := &ScoreRequest{}
+    if err := dec(req); err != nil {
+        return nil, err
+    }
+    if interceptor == nil {
+        return srv.(ScorerServer).Score(ctx, req)
+    }
+    info := &grpc.UnaryServerInfo{Server: srv, FullMethod: scoreMethod}
+    handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+        return srv.(ScorerServer).Score(ctx, req.(*ScoreRequest))
+    }
+    return interceptor(ctx, req, info, handler)
+}
+
+// NewServer returns a gRPC server that speaks the JSON-over-gRPC contract
+func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+    return grpc.NewServer(append(opts, grpc.ForceServerCodec(jsonOverGRPCCodec{}))...)
+}
+
+// Client calls an external scoring service with a per-call deadline
+type Client struct {
+    conn    *grpc.ClientConn
+    timeout time.Duration
+}
+
+func NewClient(address string, timeout time.Duration) (*Client, error) {
+    conn, err := grpc.Dial(address,
+        grpc.WithTransportCredentials(insecure.NewCredentials()),
+        grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonOverGRPCCodec{})),
+    )
+    if err != nil {
+        return nil, fmt.Errorf("failed to dial external scorer %s: %v", address, err)
+    }
+    return &Client{conn: conn, timeout: timeout}, nil
+}
+
+func (c *Client) Close() error {
+    return c.conn.Close()
+}
+
+// Score returns the external scores keyed by node name. Scores outside
+// [0, 1] are rejected so a misbehaving model cannot dominate placement.
+func (c *Client) Score(ctx context.Context, req *ScoreRequest) (map[string]float64, error) {
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+
+    resp := &ScoreResponse{}
+    if err := c.conn.Invoke(ctx, scoreMethod, req, resp); err != nil {
+        return nil, err
+    }
+
+    scores := make(map[string]float64, len(resp.Scores))
+    for _, s := range resp.Scores {
+        if s.Score < 0 || s.Score > 1 {
+            return nil, fmt.Errorf("external score %v for node %s out of range", s.Score, s.Node)
+        }
+        scores[s.Node] = s.Score
+    }
+    return scores, nil
+}
@@ -0,0 +1,304 @@
+package history
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+)
+
+var (
+    jobsBucket      = []byte("jobs")
+    workloadsBucket = []byte("workloads")
+    domainsBucket   = []byte("domains")
+    pairsBucket     = []byte("domain_pairs")
+)
+
+// priorStrength is the number of neutral pseudo-observations a prior starts
+// with, so a single fast or slow job does not swing the score.
+const priorStrength = 5.0
+
+// PlacementRecord is the placement of one job together with the
+// throughput it reported.
+type PlacementRecord struct {
+    Job            string    `json:"job"`
+    Workload       string    `json:"workload,omitempty"`
+    Leaves         []string  `json:"leaves"`
+    Spines         []string  `json:"spines"`
+    GPUs           int       `json:"gpus"`
+    MaxHops        int       `json:"maxHops"`
+    SpineCrossings int       `json:"spineCrossings"`
+    Throughput     float64   `json:"throughput,omitempty"`
+    PlacedAt       time.Time `json:"placedAt"`
+    ReportedAt     time.Time `json:"reportedAt,omitempty"`
+}
+
+// runningMean accumulates observations of a ratio
+type runningMean struct {
+    Count float64 `json:"count"`
+    Sum   float64 `json:"sum"`
+}
+
+func (m runningMean) mean() float64 {
+    if m.Count == 0 {
+        return 0
+    }
+    return m.Sum / m.Count
+}
+
+// prior shrinks the observed mean toward the neutral value 1.0
+func (m runningMean) prior() float64 {
+    return (m.Sum + priorStrength) / (m.Count + priorStrength)
+}
+
+// Store persists placements and throughput reports in a local bbolt file and
+// derives per-domain and per-domain-pair performance priors from them. A
+// prior is the relative throughput of jobs placed there: 1.0 is average,
+// above 1.0 is faster than jobs of the same workload elsewhere.
+type Store struct {
+    mu      sync.RWMutex
+    db      *bolt.DB
+    domains map[string]runningMean
+    pairs   map[string]runningMean
+}
+
+func Open(path string) (*Store, error) {
+    db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("failed to open history store %s: %v", path, err)
+    }
+
+    s := &Store{
+        db:      db,
+        domains: make(map[string]runningMean),
+        pairs:   make(map[string]runningMean),
+    }
+
+    err = db.Update(func(tx *bolt.Tx) error {
+        for _, name := range [][]byte{jobsBucket, workloadsBucket, domainsBucket, pairsBucket} {
+            if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+                return err
+            }
+        }
+        if err := loadMeans(tx.Bucket(domainsBucket), s.domains); err != nil {
+            return err
+        }
+        return loadMeans(tx.Bucket(pairsBucket), s.pairs)
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to initialize history store: %v", err)
+    }
+    return s, nil
+}
+
+func (s *Store) Close() error {
+    return s.db.Close()
+}
+
+// RecordPlacement adds a leaf (and its spine) to the job's current
+// placement, creating the record on first use.
+func (s *Store) RecordPlacement(job, workload, leaf, spine string, gpus int) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(jobsBucket)
+        rec := &PlacementRecord{Job: job, Workload: workload, PlacedAt: time.Now()}
+        if data := bucket.Get([]byte(job)); data != nil {
+            if err := json.Unmarshal(data, rec); err != nil {
+                return err
+            }
+        }
+
+        rec.Leaves = addUnique(rec.Leaves, leaf)
+        if spine != "" {
+            rec.Spines = addUnique(rec.Spines, spine)
+        }
+        rec.GPUs += gpus
+        rec.MaxHops, rec.SpineCrossings = hopStats(rec)
+
+        return putJSON(bucket, job, rec)
+    })
+}
+
+// ReportThroughput attaches a throughput measurement to the job's placement
+// and folds it into the priors of every domain and domain pair it used. It
+// returns the job's throughput relative to its workload average.
+func (s *Store) ReportThroughput(job string, throughput float64) (float64, error) {
+    if throughput <= 0 {
+        return 0, fmt.Errorf("invalid throughput %v for job %s", throughput, job)
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    ratio := 1.0
+    err := s.db.Update(func(tx *bolt.Tx) error {
+        jobs := tx.Bucket(jobsBucket)
+        data := jobs.Get([]byte(job))
+        if data == nil {
+            return fmt.Errorf("no placement recorded for job %s", job)
+        }
+        rec := &PlacementRecord{}
+        if err := json.Unmarshal(data, rec); err != nil {
+            return err
+        }
+        if rec.GPUs == 0 || len(rec.Leaves) == 0 {
+            return fmt.Errorf("placement of job %s has no GPUs", job)
+        }
+
+        // Normalize against the average per-GPU throughput of the workload
+        perGPU := throughput / float64(rec.GPUs)
+        workloads := tx.Bucket(workloadsBucket)
+        key := rec.Workload
+        if key == "" {
+            key = rec.Job
+        }
+        var wl runningMean
+        if err := getJSON(workloads, key, &wl); err != nil {
+            return err
+        }
+        if wl.Count > 0 {
+            ratio = perGPU / wl.mean()
+        }
+        wl.Count++
+        wl.Sum += perGPU
+        if err := putJSON(workloads, key, wl); err != nil {
+            return err
+        }
+
+        domains := tx.Bucket(domainsBucket)
+        for _, leaf := range rec.Leaves {
+            m := s.domains[leaf]
+            m.Count++
+            m.Sum += ratio
+            s.domains[leaf] = m
+            if err := putJSON(domains, leaf, m); err != nil {
+                return err
+            }
+        }
+
+        pairs := tx.Bucket(pairsBucket)
+        for i := 0; i < len(rec.Leaves); i++ {
+            for j := i + 1; j < len(rec.Leaves); j++ {
+                k := PairKey(rec.Leaves[i], rec.Leaves[j])
+                m := s.pairs[k]
+                m.Count++
+                m.Sum += ratio
+                s.pairs[k] = m
+                if err := putJSON(pairs, k, m); err != nil {
+                    return err
+                }
+            }
+        }
+
+        rec.Throughput = throughput
+        rec.ReportedAt = time.Now()
+        return putJSON(jobs, job, rec)
+    })
+    return ratio, err
+}
+
+// ForgetJob drops the job's placement once it has finished
+func (s *Store) ForgetJob(job string) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(jobsBucket).Delete([]byte(job))
+    })
+}
+
+// GetPlacement returns the recorded placement of a job
+func (s *Store) GetPlacement(job string) (*PlacementRecord, error) {
+    rec := &PlacementRecord{}
+    err := s.db.View(func(tx *bolt.Tx) error {
+        data := tx.Bucket(jobsBucket).Get([]byte(job))
+        if data == nil {
+            return fmt.Errorf("no placement recorded for job %s", job)
+        }
+        return json.Unmarshal(data, rec)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return rec, nil
+}
+
+// DomainPrior returns the relative throughput of jobs placed in the domain,
+// 1.0 when nothing is known.
+func (s *Store) DomainPrior(domain string) float64 {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    return s.domains[domain].prior()
+}
+
+// PairPrior returns the relative throughput of jobs spanning both domains
+func (s *Store) PairPrior(a, b string) float64 {
+    if a == b {
+        return s.DomainPrior(a)
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    return s.pairs[PairKey(a, b)].prior()
+}
+
+// PairKey is the order-independent key of a domain pair
+func PairKey(a, b string) string {
+    if a > b {
+        a, b = b, a
+    }
+    return a + "|" + b
+}
+
+// hopStats derives the worst-case switch hops and number of spine
+// crossings of a placement in a two-tier leaf-spine fabric.
+func hopStats(rec *PlacementRecord) (maxHops, spineCrossings int) {
+    switch {
+    case len(rec.Leaves) <= 1:
+        return 0, 0
+    case len(rec.Spines) <= 1:
+        return 2, 0
+    default:
+        return 4, len(rec.Spines) - 1
+    }
+}
+
+func addUnique(values []string, value string) []string {
+    for _, v := range values {
+        if v == value {
+            return values
+        }
+    }
+    values = append(values, value)
+    sort.Strings(values)
+    return values
+}
+
+func putJSON(bucket *bolt.Bucket, key string, value interface{}) error {
+    data, err := json.Marshal(value)
+    if err != nil {
+        return err
+    }
+    return bucket.Put([]byte(key), data)
+}
+
+func getJSON(bucket *bolt.Bucket, key string, value interface{}) error {
+    data := bucket.Get([]byte(key))
+    if data == nil {
+        return nil
+    }
+    return json.Unmarshal(data, value)
+}
+
+func loadMeans(bucket *bolt.Bucket, into map[string]runningMean) error {
+    return bucket.ForEach(func(k, v []byte) error {
+        var m runningMean
+        if err := json.Unmarshal(v, &m); err != nil {
+            return err
+        }
+        into[string(k)] = m
+        return nil
+    })
+}
@@ -0,0 +1,538 @@
+package history
+
+import (
+    "encoding/binary"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+)
+
+var (
+    changesBucket = []byte("topology_changes")
+    // baseBucket holds the changes that rebuild the state at the oldest
+    // version still kept, once older changes were compacted
+    baseBucket = []byte("topology_base")
+    baseKey    = []byte("base")
+)
+
+type ChangeKind string
+
+const (
+    DomainAdded   ChangeKind = "domain-added"
+    DomainUpdated ChangeKind = "domain-updated"
+    DomainRemoved ChangeKind = "domain-removed"
+    NodeAdded     ChangeKind = "node-added"
+    NodeMoved     ChangeKind = "node-moved"
+    NodeRemoved   ChangeKind = "node-removed"
+    LinkAdded     ChangeKind = "link-added"
+    LinkUpdated   ChangeKind = "link-updated"
+    LinkRemoved   ChangeKind = "link-removed"
+)
+
+// TopologyChange is one mutation of the topology cache. Version and Time
+// are set when the change is recorded.
+type TopologyChange struct {
+    Version uint64     `json:"version,omitempty"`
+    Time    *time.Time `json:"time,omitempty"`
+    Kind    ChangeKind `json:"kind"`
+    Domain  string     `json:"domain,omitempty"`
+    Level   string     `json:"level,omitempty"`
+    Parent  string     `json:"parent,omitempty"`
+    Node    string     `json:"node,omitempty"`
+    // MaxGPUs and Taints are the GPU limit and the taints of an added or
+    // updated domain
+    MaxGPUs int     `json:"maxGPUs,omitempty"`
+    Taints  []Taint `json:"taints,omitempty"`
+    // From is the previous domain of a moved node
+    From          string        `json:"from,omitempty"`
+    Source        string        `json:"source,omitempty"`
+    Target        string        `json:"target,omitempty"`
+    BandwidthGbps float64       `json:"bandwidthGbps,omitempty"`
+    Latency       time.Duration `json:"latency,omitempty"`
+}
+
+// Taint is a domain taint without the time it was added
+type Taint struct {
+    Key    string `json:"key"`
+    Value  string `json:"value,omitempty"`
+    Effect string `json:"effect"`
+}
+
+type DomainState struct {
+    Level   string
+    Parent  string
+    MaxGPUs int
+    Taints  []Taint
+}
+
+func (d DomainState) equal(other DomainState) bool {
+    if d.Level != other.Level || d.Parent != other.Parent || d.MaxGPUs != other.MaxGPUs || len(d.Taints) != len(other.Taints) {
+        return false
+    }
+    for i := range d.Taints {
+        if d.Taints[i] != other.Taints[i] {
+            return false
+        }
+    }
+    return true
+}
+
+type LinkState struct {
+    BandwidthGbps float64
+    Latency       time.Duration
+}
+
+// TopologyState is the topology at one version: the domains, the domain
+// of every node and the links between domains
+type TopologyState struct {
+    Domains map[string]DomainState
+    Nodes   map[string]string
+    Links   map[[2]string]LinkState
+}
+
+func NewTopologyState() *TopologyState {
+    return &TopologyState{
+        Domains: make(map[string]DomainState),
+        Nodes:   make(map[string]string),
+        Links:   make(map[[2]string]LinkState),
+    }
+}
+
+// Apply updates the state with a change
+func (s *TopologyState) Apply(change TopologyChange) {
+    link := [2]string{change.Source, change.Target}
+    switch change.Kind {
+    case DomainAdded, DomainUpdated:
+        s.Domains[change.Domain] = DomainState{Level: change.Level, Parent: change.Parent, MaxGPUs: change.MaxGPUs, Taints: change.Taints}
+    case DomainRemoved:
+        delete(s.Domains, change.Domain)
+        for node, domain := range s.Nodes {
+            if domain == change.Domain {
+                delete(s.Nodes, node)
+            }
+        }
+        for key := range s.Links {
+            if key[0] == change.Domain || key[1] == change.Domain {
+                delete(s.Links, key)
+            }
+        }
+    case NodeAdded, NodeMoved:
+        s.Nodes[change.Node] = change.Domain
+    case NodeRemoved:
+        if s.Nodes[change.Node] == change.Domain {
+            delete(s.Nodes, change.Node)
+        }
+    case LinkAdded, LinkUpdated:
+        s.Links[link] = LinkState{BandwidthGbps: change.BandwidthGbps, Latency: change.Latency}
+    case LinkRemoved:
+        delete(s.Links, link)
+    }
+}
+
+// Diff returns the changes that turn the from state into the to state:
+// added domains first, then node and link changes, then removed domains
+func Diff(from, to *TopologyState) []TopologyChange {
+    var changes []TopologyChange
+
+    for _, name := range sortedDomains(to.Domains) {
+        domain := to.Domains[name]
+        change := TopologyChange{Domain: name, Level: domain.Level, Parent: domain.Parent, MaxGPUs: domain.MaxGPUs, Taints: domain.Taints}
+        old, ok := from.Domains[name]
+        switch {
+        case !ok:
+            change.Kind = DomainAdded
+        case !old.equal(domain):
+            change.Kind = DomainUpdated
+        default:
+            continue
+        }
+        changes = append(changes, change)
+    }
+
+    for _, node := range sortedNodes(to.Nodes) {
+        domain := to.Nodes[node]
+        old, ok := from.Nodes[node]
+        switch {
+        case !ok:
+            changes = append(changes, TopologyChange{Kind: NodeAdded, Node: node, Domain: domain})
+        case old != domain:
+            changes = append(changes, TopologyChange{Kind: NodeMoved, Node: node, Domain: domain, From: old})
+        }
+    }
+    for _, node := range sortedNodes(from.Nodes) {
+        if _, ok := to.Nodes[node]; !ok {
+            changes = append(changes, TopologyChange{Kind: NodeRemoved, Node: node, Domain: from.Nodes[node]})
+        }
+    }
+
+    for _, key := range sortedLinks(to.Links) {
+        link := to.Links[key]
+        old, ok := from.Links[key]
+        change := TopologyChange{Source: key[0], Target: key[1], BandwidthGbps: link.BandwidthGbps, Latency: link.Latency}
+        switch {
+        case !ok:
+            change.Kind = LinkAdded
+        case old != link:
+            change.Kind = LinkUpdated
+        default:
+            continue
+        }
+        changes = append(changes, change)
+    }
+    for _, key := range sortedLinks(from.Links) {
+        if _, ok := to.Links[key]; !ok {
+            changes = append(changes, TopologyChange{Kind: LinkRemoved, Source: key[0], Target: key[1]})
+        }
+    }
+
+    for _, name := range sortedDomains(from.Domains) {
+        if _, ok := to.Domains[name]; !ok {
+            changes = append(changes, TopologyChange{Kind: DomainRemoved, Domain: name})
+        }
+    }
+    return changes
+}
+
+// ChangeLog is the versioned history of topology changes, kept in a local
+// bbolt file so it survives restarts. Once it holds more than its
+// retention, the oldest changes are folded into a base state.
+type ChangeLog struct {
+    mu     sync.RWMutex
+    db     *bolt.DB
+    retain int
+    // base rebuilds the state at baseVersion, the oldest version that can
+    // still be replayed
+    base        []TopologyChange
+    baseVersion uint64
+    changes     []TopologyChange
+}
+
+// OpenChangeLog opens the history at path. It keeps at least the latest
+// retain changes and compacts the older ones, or keeps all of them when
+// retain is 0.
+func OpenChangeLog(path string, retain int) (*ChangeLog, error) {
+    if retain < 0 {
+        return nil, fmt.Errorf("invalid topology history retention %d", retain)
+    }
+    db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("failed to open topology history %s: %v", path, err)
+    }
+
+    l := &ChangeLog{db: db, retain: retain}
+    err = db.Update(func(tx *bolt.Tx) error {
+        base, err := tx.CreateBucketIfNotExists(baseBucket)
+        if err != nil {
+            return err
+        }
+        if data := base.Get(baseKey); data != nil {
+            if err := json.Unmarshal(data, &l.base); err != nil {
+                return fmt.Errorf("invalid base state: %v", err)
+            }
+            if len(l.base) > 0 {
+                l.baseVersion = l.base[0].Version
+            }
+        }
+        bucket, err := tx.CreateBucketIfNotExists(changesBucket)
+        if err != nil {
+            return err
+        }
+        return bucket.ForEach(func(k, v []byte) error {
+            var change TopologyChange
+            if err := json.Unmarshal(v, &change); err != nil {
+                return fmt.Errorf("invalid change %d: %v", binary.BigEndian.Uint64(k), err)
+            }
+            l.changes = append(l.changes, change)
+            return nil
+        })
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to load topology history: %v", err)
+    }
+    return l, nil
+}
+
+func (l *ChangeLog) Close() error {
+    return l.db.Close()
+}
+
+// Record stores the changes under the next versions in one transaction,
+// compacting the oldest changes when there are too many
+func (l *ChangeLog) Record(changes ...TopologyChange) error {
+    if len(changes) == 0 {
+        return nil
+    }
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := time.Now()
+    recorded := make([]TopologyChange, 0, len(changes))
+    err := l.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(changesBucket)
+        for _, change := range changes {
+            version, err := bucket.NextSequence()
+            if err != nil {
+                return err
+            }
+            change.Version = version
+            change.Time = &now
+            data, err := json.Marshal(change)
+            if err != nil {
+                return err
+            }
+            key := make([]byte, 8)
+            binary.BigEndian.PutUint64(key, version)
+            if err := bucket.Put(key, data); err != nil {
+                return err
+            }
+            recorded = append(recorded, change)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Errorf("failed to record topology change: %v", err)
+    }
+    l.changes = append(l.changes, recorded...)
+
+    // Compact in steps of a tenth of the retention rather than on every
+    // change, since the base is rewritten each time
+    if l.retain > 0 && len(l.changes) > l.retain+l.retain/10 {
+        if err := l.compact(len(l.changes) - l.retain); err != nil {
+            return fmt.Errorf("failed to compact topology history: %v", err)
+        }
+    }
+    return nil
+}
+
+// compact folds the oldest n changes into the base state. Callers hold
+// the lock.
+func (l *ChangeLog) compact(n int) error {
+    version := l.changes[n-1].Version
+    state := l.state(version)
+    base := Diff(NewTopologyState(), state)
+    for i := range base {
+        base[i].Version = version
+        base[i].Time = l.changes[n-1].Time
+    }
+    data, err := json.Marshal(base)
+    if err != nil {
+        return err
+    }
+
+    err = l.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(changesBucket)
+        for _, change := range l.changes[:n] {
+            key := make([]byte, 8)
+            binary.BigEndian.PutUint64(key, change.Version)
+            if err := bucket.Delete(key); err != nil {
+                return err
+            }
+        }
+        return tx.Bucket(baseBucket).Put(baseKey, data)
+    })
+    if err != nil {
+        return err
+    }
+    l.base = base
+    l.baseVersion = version
+    l.changes = append([]TopologyChange(nil), l.changes[n:]...)
+    return nil
+}
+
+// Sync records the difference between the last recorded state and the
+// current one, such as changes made while the scheduler was down
+func (l *ChangeLog) Sync(current *TopologyState) error {
+    last, _ := l.State(l.Version())
+    return l.Record(Diff(last, current)...)
+}
+
+// Version returns the latest version, 0 when nothing was recorded
+func (l *ChangeLog) Version() uint64 {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+
+    return l.version()
+}
+
+func (l *ChangeLog) version() uint64 {
+    if len(l.changes) == 0 {
+        return l.baseVersion
+    }
+    return l.changes[len(l.changes)-1].Version
+}
+
+// Oldest returns the oldest version that can still be replayed
+func (l *ChangeLog) Oldest() uint64 {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+
+    return l.baseVersion
+}
+
+// Changes returns the changes after version since. When since was
+// compacted they start with the changes that rebuild the oldest version.
+func (l *ChangeLog) Changes(since uint64) []TopologyChange {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+
+    var changes []TopologyChange
+    if since < l.baseVersion {
+        changes = append(changes, l.base...)
+    }
+    i := sort.Search(len(l.changes), func(i int) bool {
+        return l.changes[i].Version > since
+    })
+    return append(changes, l.changes[i:]...)
+}
+
+// State replays the changes up to and including version
+func (l *ChangeLog) State(version uint64) (*TopologyState, error) {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+
+    if version > l.version() {
+        return nil, fmt.Errorf("unknown topology version %d", version)
+    }
+    if version < l.baseVersion {
+        return nil, fmt.Errorf("topology version %d was compacted, the oldest is %d", version, l.baseVersion)
+    }
+    return l.state(version), nil
+}
+
+func (l *ChangeLog) state(version uint64) *TopologyState {
+    state := NewTopologyState()
+    for _, change := range l.base {
+        state.Apply(change)
+    }
+    for _, change := range l.changes {
+        if change.Version > version {
+            break
+        }
+        state.Apply(change)
+    }
+    return state
+}
+
+// Diff returns the changes between two versions
+func (l *ChangeLog) Diff(from, to uint64) ([]TopologyChange, error) {
+    fromState, err := l.State(from)
+    if err != nil {
+        return nil, err
+    }
+    toState, err := l.State(to)
+    if err != nil {
+        return nil, err
+    }
+    return Diff(fromState, toState), nil
+}
+
+type diffResponse struct {
+    From    uint64           `json:"from"`
+    To      uint64           `json:"to"`
+    Changes []TopologyChange `json:"changes"`
+}
+
+// Handler serves the history. GET /topology/history?since=N lists the
+// changes after version N and GET /topology/history/diff?from=N&to=M the
+// net changes between two versions, from the oldest kept version when from
+// is omitted and to the latest when to is omitted.
+func (l *ChangeLog) Handler() http.Handler {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/topology/history", func(w http.ResponseWriter, r *http.Request) {
+        since, err := versionParam(r, "since", 0)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        writeJSON(w, l.Changes(since))
+    })
+    mux.HandleFunc("/topology/history/diff", func(w http.ResponseWriter, r *http.Request) {
+        from, err := versionParam(r, "from", l.Oldest())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        to, err := versionParam(r, "to", l.Version())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if from > to {
+            http.Error(w, "from is after to", http.StatusBadRequest)
+            return
+        }
+        changes, err := l.Diff(from, to)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        writeJSON(w, diffResponse{From: from, To: to, Changes: changes})
+    })
+
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet {
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        mux.ServeHTTP(w, r)
+    })
+}
+
+func versionParam(r *http.Request, name string, def uint64) (uint64, error) {
+    val := r.URL.Query().Get(name)
+    if val == "" {
+        return def, nil
+    }
+    version, err := strconv.ParseUint(val, 10, 64)
+    if err != nil {
+        return 0, fmt.Errorf("invalid %s: %v", name, err)
+    }
+    return version, nil
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(value); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+func sortedDomains(m map[string]DomainState) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func sortedNodes(m map[string]string) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func sortedLinks(m map[[2]string]LinkState) [][2]string {
+    keys := make([][2]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        if keys[i][0] != keys[j][0] {
+            return keys[i][0] < keys[j][0]
+        }
+        return keys[i][1] < keys[j][1]
+    })
+    return keys
+}
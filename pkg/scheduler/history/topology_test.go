@@ -0,0 +1,109 @@
+package history
+
+import (
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+func TestChangeLogReplaysLimitsAndTaints(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "topology.db")
+    log, err := OpenChangeLog(path, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    taints := []Taint{{Key: "maintenance", Effect: "NoSchedule"}}
+    err = log.Record(
+        TopologyChange{Kind: DomainAdded, Domain: "leaf-0", Level: "leaf", Parent: "spine-0"},
+        TopologyChange{Kind: DomainUpdated, Domain: "leaf-0", Level: "leaf", Parent: "spine-0", MaxGPUs: 16},
+        TopologyChange{Kind: DomainUpdated, Domain: "leaf-0", Level: "leaf", Parent: "spine-0", MaxGPUs: 16, Taints: taints},
+    )
+    if err != nil {
+        t.Fatal(err)
+    }
+    log.Close()
+
+    // The history survives a restart
+    log, err = OpenChangeLog(path, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer log.Close()
+    state, err := log.State(2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := (DomainState{Level: "leaf", Parent: "spine-0", MaxGPUs: 16}); !state.Domains["leaf-0"].equal(want) {
+        t.Errorf("leaf-0 at version 2 = %+v, want %+v", state.Domains["leaf-0"], want)
+    }
+    changes, err := log.Diff(2, 3)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(changes) != 1 || changes[0].Kind != DomainUpdated || !reflect.DeepEqual(changes[0].Taints, taints) {
+        t.Errorf("Diff(2, 3) = %+v, want the taint added to leaf-0", changes)
+    }
+}
+
+func TestChangeLogRetention(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "topology.db")
+    log, err := OpenChangeLog(path, 10)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := log.Record(TopologyChange{Kind: DomainAdded, Domain: "leaf-0", Level: "leaf"}); err != nil {
+        t.Fatal(err)
+    }
+    nodes := []string{"gpu-001", "gpu-002", "gpu-003", "gpu-004", "gpu-005", "gpu-006"}
+    for i := 0; i < 3; i++ {
+        for _, node := range nodes {
+            if err := log.Record(TopologyChange{Kind: NodeAdded, Node: node, Domain: "leaf-0"}); err != nil {
+                t.Fatal(err)
+            }
+            if err := log.Record(TopologyChange{Kind: NodeRemoved, Node: node, Domain: "leaf-0"}); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+    if err := log.Record(TopologyChange{Kind: NodeAdded, Node: "gpu-001", Domain: "leaf-0"}); err != nil {
+        t.Fatal(err)
+    }
+    full := log.Version()
+    want, err := log.State(full)
+    if err != nil {
+        t.Fatal(err)
+    }
+    log.Close()
+
+    log, err = OpenChangeLog(path, 10)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer log.Close()
+    if full != 38 || log.Version() != full {
+        t.Fatalf("version = %d after reopening, %d before, want 38", log.Version(), full)
+    }
+    oldest := log.Oldest()
+    if kept := int(full - oldest); kept < 10 || kept > 11 {
+        t.Errorf("%d changes kept, want the latest 10 or 11", kept)
+    }
+    if _, err := log.State(oldest - 1); err == nil {
+        t.Errorf("State(%d) of a compacted version succeeded", oldest-1)
+    }
+    got, err := log.State(full)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("state after compaction = %+v, want %+v", got, want)
+    }
+
+    // Replaying the history from the start rebuilds the current state
+    replayed := NewTopologyState()
+    for _, change := range log.Changes(0) {
+        replayed.Apply(change)
+    }
+    if !reflect.DeepEqual(replayed, want) {
+        t.Errorf("replayed state = %+v, want %+v", replayed, want)
+    }
+}
@@ -50,6 +50,9 @@ func (tp *TopologySchedulerPlugin) Filter(
         return framework.NewStatus(framework.Unschedulable, 
             fmt.Sprintf("failed to get GPU requirements: %v", err))
     }
+    if err := tp.scheduler.annotateRequirements(pod, gpuReq); err != nil {
+        return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+    }
 
     domain, err := tp.scheduler.cache.GetDomainForNode(nodeInfo.Node().Name)
     if err != nil {
@@ -57,6 +60,11 @@ func (tp *TopologySchedulerPlugin) Filter(
             fmt.Sprintf("failed to get domain: %v", err))
     }
 
+    // Report a topology constraint by name before the generic check
+    if err := tp.scheduler.checkTopologyConstraints(domain, gpuReq, []string{nodeInfo.Node().Name}); err != nil {
+        return framework.NewStatus(framework.Unschedulable, err.Error())
+    }
+
     if !tp.scheduler.isDomainEligible(domain, gpuReq) {
         return framework.NewStatus(framework.Unschedulable,
             "node's domain does not meet GPU requirements")
@@ -25,13 +25,35 @@ type MetricsCollector struct {
     // Node metrics
     nodeGPUAllocation *prometheus.GaugeVec
     nodeHealthStatus *prometheus.GaugeVec
+    nodeStraggler *prometheus.GaugeVec
+
+    // Tuner metrics
+    tunerWeights *prometheus.GaugeVec
+    tunerFrozen prometheus.Gauge
+    tunerBounds *prometheus.GaugeVec
+    tunerUpdates prometheus.Counter
 
     // Placement metrics
     placementDecisions *prometheus.CounterVec
     placementScores *prometheus.HistogramVec
 }
 
+var (
+    sharedCollector     *MetricsCollector
+    sharedCollectorOnce sync.Once
+)
+
+// NewMetricsCollector returns the process-wide collector. The metrics are
+// registered with the default Prometheus registry, so building more than
+// one scheduler in a process (e.g. in the simulator) must share them.
 func NewMetricsCollector() *MetricsCollector {
+    sharedCollectorOnce.Do(func() {
+        sharedCollector = newMetricsCollector()
+    })
+    return sharedCollector
+}
+
+func newMetricsCollector() *MetricsCollector {
     return &MetricsCollector{
         schedulingLatency: promauto.NewHistogramVec(
             prometheus.HistogramOpts{
@@ -106,6 +128,44 @@ func NewMetricsCollector() *MetricsCollector {
             []string{"node", "domain"},
         ),
 
+        nodeStraggler: promauto.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "topology_node_straggler_quarantined",
+                Help: "Whether a node is quarantined as a straggler (1 for quarantined, 0 otherwise)",
+            },
+            []string{"node", "domain"},
+        ),
+
+        tunerWeights: promauto.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "topology_tuner_weight",
+                Help: "Current scoring weight chosen by the tuner",
+            },
+            []string{"factor"},
+        ),
+
+        tunerFrozen: promauto.NewGauge(
+            prometheus.GaugeOpts{
+                Name: "topology_tuner_frozen",
+                Help: "Whether the weight tuner is frozen (1 for frozen, 0 otherwise)",
+            },
+        ),
+
+        tunerBounds: promauto.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "topology_tuner_guard_rail",
+                Help: "Guard rails of the weight tuner",
+            },
+            []string{"limit"},
+        ),
+
+        tunerUpdates: promauto.NewCounter(
+            prometheus.CounterOpts{
+                Name: "topology_tuner_updates_total",
+                Help: "Number of weight updates applied by the tuner",
+            },
+        ),
+
         placementDecisions: promauto.NewCounterVec(
             prometheus.CounterOpts{
                 Name: "topology_placement_decisions_total",
@@ -166,6 +226,35 @@ func (mc *MetricsCollector) UpdateNodeMetrics(node string, domain string, gpuCou
     mc.nodeHealthStatus.WithLabelValues(node, domain).Set(healthStatus)
 }
 
+func (mc *MetricsCollector) UpdateStragglerStatus(node string, domain string, quarantined bool) {
+    value := 0.0
+    if quarantined {
+        value = 1.0
+    }
+    mc.nodeStraggler.WithLabelValues(node, domain).Set(value)
+}
+
+func (mc *MetricsCollector) UpdateTunerState(weights TopologyScore, frozen bool, minWeight, maxWeight, maxStep float64) {
+    mc.tunerWeights.WithLabelValues("resourceAvailability").Set(weights.ResourceAvailability)
+    mc.tunerWeights.WithLabelValues("topologyAlignment").Set(weights.TopologyAlignment)
+    mc.tunerWeights.WithLabelValues("domainUtilization").Set(weights.DomainUtilization)
+    mc.tunerWeights.WithLabelValues("historicalPerformance").Set(weights.HistoricalPerf)
+
+    frozenValue := 0.0
+    if frozen {
+        frozenValue = 1.0
+    }
+    mc.tunerFrozen.Set(frozenValue)
+
+    mc.tunerBounds.WithLabelValues("min_weight").Set(minWeight)
+    mc.tunerBounds.WithLabelValues("max_weight").Set(maxWeight)
+    mc.tunerBounds.WithLabelValues("max_step").Set(maxStep)
+}
+
+func (mc *MetricsCollector) IncTunerUpdates() {
+    mc.tunerUpdates.Inc()
+}
+
 func calculateFragmentation(domain *Domain) float64 {
     if domain.TotalGPUs == 0 {
         return 0.0
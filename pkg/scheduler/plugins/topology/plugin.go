@@ -4,7 +4,11 @@ import (
     "context"
     "fmt"
     v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/apimachinery/pkg/labels"
     "k8s.io/apimachinery/pkg/runtime"
+    toolscache "k8s.io/client-go/tools/cache"
+    "k8s.io/klog/v2"
     "k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
@@ -17,23 +21,154 @@ const (
     Name = "topology-aware-scheduler"
 )
 
+var _ framework.PreFilterPlugin = &TopologySchedulerPlugin{}
 var _ framework.FilterPlugin = &TopologySchedulerPlugin{}
 var _ framework.ScorePlugin = &TopologySchedulerPlugin{}
+var _ framework.ReservePlugin = &TopologySchedulerPlugin{}
+var _ framework.PreScorePlugin = &TopologySchedulerPlugin{}
+
+// externalScoreKey stores the blended scores computed in PreScore
+const externalScoreKey framework.StateKey = Name + "/external-scores"
+
+type externalScoreState struct {
+    scores map[string]float64
+}
+
+func (s *externalScoreState) Clone() framework.StateData {
+    return s
+}
+
+// affinityKey stores the pod's domain affinity parsed in PreFilter
+const affinityKey framework.StateKey = Name + "/domain-affinity"
+
+type affinityState struct {
+    affinity *podDomainAffinity
+}
+
+func (s *affinityState) Clone() framework.StateData {
+    return s
+}
 
 func New(obj runtime.Object, h framework.Handle) (framework.Plugin, error) {
     cache := NewTopologyCache(NewNodeCache())
+    cache.SetLabelSchema(DefaultNodeLabelSchema())
     scheduler := NewTopologyScheduler(cache)
-    
+
+    // Place nodes by their topology labels and move them when the labels
+    // change, taking the jobs of their pods along
+    podLister := h.SharedInformerFactory().Core().V1().Pods().Lister()
+    cache.SetPodSource(func(nodeName string) ([]*v1.Pod, error) {
+        pods, err := podLister.List(labels.Everything())
+        if err != nil {
+            return nil, err
+        }
+        var onNode []*v1.Pod
+        for _, pod := range pods {
+            if pod.Spec.NodeName == nodeName {
+                onNode = append(onNode, pod)
+            }
+        }
+        return onNode, nil
+    })
+    h.SharedInformerFactory().Core().V1().Nodes().Informer().AddEventHandler(cache.NodeEventHandler())
+
+    // Feed step time and throughput annotations of running pods to the
+    // straggler detector and the history store, and release the GPUs and
+    // jobs of pods that finish or are deleted
+    h.SharedInformerFactory().Core().V1().Pods().Informer().AddEventHandler(
+        toolscache.ResourceEventHandlerFuncs{
+            UpdateFunc: func(oldObj, newObj interface{}) {
+                oldPod, ok := oldObj.(*v1.Pod)
+                if !ok {
+                    return
+                }
+                pod, ok := newObj.(*v1.Pod)
+                if !ok {
+                    return
+                }
+                if !podFinished(oldPod) && podFinished(pod) {
+                    releasePod(scheduler, pod)
+                    return
+                }
+                if err := scheduler.StragglerDetector().ObservePod(pod); err != nil {
+                    klog.V(4).Infof("Ignoring step time report: %v", err)
+                }
+                if err := scheduler.ObservePodThroughput(pod); err != nil {
+                    klog.V(4).Infof("Ignoring throughput report: %v", err)
+                }
+            },
+            DeleteFunc: func(obj interface{}) {
+                if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+                    obj = tombstone.Obj
+                }
+                pod, ok := obj.(*v1.Pod)
+                if !ok {
+                    return
+                }
+                // Finished pods were released when their phase changed
+                if !podFinished(pod) {
+                    releasePod(scheduler, pod)
+                }
+            },
+        },
+    )
+
+    return NewWithScheduler(h, scheduler), nil
+}
+
+// releasePod returns the GPUs and job placement of a bound pod to the
+// domain of its node
+func releasePod(scheduler *TopologyScheduler, pod *v1.Pod) {
+    if pod.Spec.NodeName == "" {
+        return
+    }
+    node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: pod.Spec.NodeName}}
+    if err := scheduler.Release(pod, node); err != nil {
+        klog.V(4).Infof("Not releasing pod %s/%s: %v", pod.Namespace, pod.Name, err)
+    }
+}
+
+// NewWithScheduler builds the plugin around an existing scheduler, e.g. one
+// with a prepared topology in benchmarks. Unlike New it does not watch pods.
+func NewWithScheduler(h framework.Handle, scheduler *TopologyScheduler) *TopologySchedulerPlugin {
     return &TopologySchedulerPlugin{
         handle:    h,
         scheduler: scheduler,
-    }, nil
+    }
 }
 
 func (tp *TopologySchedulerPlugin) Name() string {
     return Name
 }
 
+// PreFilter parses the pod's domain affinity annotations once for the
+// cycle; Filter and Score read them from the cycle state
+func (tp *TopologySchedulerPlugin) PreFilter(
+    ctx context.Context,
+    state *framework.CycleState,
+    pod *v1.Pod,
+) (*framework.PreFilterResult, *framework.Status) {
+    affinity, err := parsePodDomainAffinity(pod)
+    if err != nil {
+        return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+    }
+    state.Write(affinityKey, &affinityState{affinity: affinity})
+    return nil, nil
+}
+
+func (tp *TopologySchedulerPlugin) PreFilterExtensions() framework.PreFilterExtensions {
+    return nil
+}
+
+// podAffinity returns the affinity stored by PreFilter, parsing it when
+// the plugin runs without PreFilter
+func podAffinity(state *framework.CycleState, pod *v1.Pod) (*podDomainAffinity, error) {
+    if data, err := state.Read(affinityKey); err == nil {
+        return data.(*affinityState).affinity, nil
+    }
+    return parsePodDomainAffinity(pod)
+}
+
 func (tp *TopologySchedulerPlugin) Filter(
     ctx context.Context,
     state *framework.CycleState,
@@ -49,6 +184,9 @@ func (tp *TopologySchedulerPlugin) Filter(
         return framework.NewStatus(framework.Unschedulable, 
             fmt.Sprintf("failed to get GPU requirements: %v", err))
     }
+    if err := tp.scheduler.annotateRequirements(pod, gpuReq); err != nil {
+        return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+    }
 
     domain, err := tp.scheduler.cache.GetDomainForNode(nodeInfo.Node().Name)
     if err != nil {
@@ -56,11 +194,33 @@ func (tp *TopologySchedulerPlugin) Filter(
             fmt.Sprintf("failed to get domain: %v", err))
     }
 
+    // Report a topology constraint by name before the generic check
+    if err := tp.scheduler.checkTopologyConstraints(domain, gpuReq, []string{nodeInfo.Node().Name}); err != nil {
+        return framework.NewStatus(framework.Unschedulable, err.Error())
+    }
+
     if !tp.scheduler.isDomainEligible(domain, gpuReq) {
         return framework.NewStatus(framework.Unschedulable,
             "node's domain does not meet GPU requirements")
     }
 
+    if err := tp.scheduler.checkCommProfile(domain, gpuReq); err != nil {
+        return framework.NewStatus(framework.Unschedulable, err.Error())
+    }
+
+    if err := tp.scheduler.checkDomainTaints(pod, domain); err != nil {
+        return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+    }
+
+    affinity, err := podAffinity(state, pod)
+    if err != nil {
+        return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+    }
+    if err := tp.scheduler.checkDomainAffinity(pod, affinity, domain); err != nil {
+        return framework.NewStatus(framework.UnschedulableAndUnresolvable,
+            fmt.Sprintf("domain affinity not satisfied: %v", err))
+    }
+
     return framework.NewStatus(framework.Success, "")
 }
 
@@ -81,12 +241,103 @@ func (tp *TopologySchedulerPlugin) Score(
         return 0, framework.NewStatus(framework.Error,
             fmt.Sprintf("failed to get GPU requirements: %v", err))
     }
+    if err := tp.scheduler.annotateRequirements(pod, gpuReq); err != nil {
+        return 0, framework.NewStatus(framework.Error, err.Error())
+    }
 
+    if data, err := state.Read(externalScoreKey); err == nil {
+        if score, ok := data.(*externalScoreState).scores[nodeName]; ok {
+            return int64(score * 100), framework.NewStatus(framework.Success, "")
+        }
+    }
+
+    score, status := tp.builtinScore(state, pod, gpuReq, nodeName)
+    if !status.IsSuccess() {
+        return 0, status
+    }
+    return int64(score * 100), framework.NewStatus(framework.Success, "")
+}
+
+// PreScore asks the external scorer, if configured, about all candidates
+// at once and stores the blended scores for Score to pick up.
+func (tp *TopologySchedulerPlugin) PreScore(
+    ctx context.Context,
+    state *framework.CycleState,
+    pod *v1.Pod,
+    nodes []*v1.Node,
+) *framework.Status {
+    if tp.scheduler.externalScorer() == nil {
+        return nil
+    }
+
+    gpuReq, err := tp.scheduler.getGPURequirements(pod)
+    if err != nil {
+        return framework.NewStatus(framework.Error,
+            fmt.Sprintf("failed to get GPU requirements: %v", err))
+    }
+    if err := tp.scheduler.annotateRequirements(pod, gpuReq); err != nil {
+        return framework.NewStatus(framework.Error, err.Error())
+    }
+
+    builtin := make(map[string]float64, len(nodes))
+    for _, node := range nodes {
+        score, status := tp.builtinScore(state, pod, gpuReq, node.Name)
+        if !status.IsSuccess() {
+            return status
+        }
+        builtin[node.Name] = score
+    }
+
+    state.Write(externalScoreKey, &externalScoreState{
+        scores: tp.scheduler.externalScores(ctx, pod, gpuReq, builtin),
+    })
+    return nil
+}
+
+func (tp *TopologySchedulerPlugin) builtinScore(
+    state *framework.CycleState,
+    pod *v1.Pod,
+    gpuReq *GPURequirements,
+    nodeName string,
+) (float64, *framework.Status) {
     domain, err := tp.scheduler.cache.GetDomainForNode(nodeName)
     if err != nil {
         return 0, framework.NewStatus(framework.Error,
             fmt.Sprintf("failed to get domain: %v", err))
     }
 
+    affinity, err := podAffinity(state, pod)
+    if err != nil {
+        return 0, framework.NewStatus(framework.Error, err.Error())
+    }
+
     score := tp.scheduler.calculateDomainScore(domain, gpuReq)
-    return int64(score * 100), framework.NewStatus(framework.Success,
+    score = 0.8*score + 0.2*tp.scheduler.scoreDomainAffinity(pod, affinity, domain)
+    score *= tp.scheduler.scoreDomainTaints(pod, domain)
+    score *= tp.scheduler.stragglers.NodeFactor(nodeName)
+    return score, nil
+}
+
+func (tp *TopologySchedulerPlugin) ScoreExtensions() framework.ScoreExtensions {
+    return nil
+}
+
+func (tp *TopologySchedulerPlugin) Reserve(
+    ctx context.Context,
+    state *framework.CycleState,
+    pod *v1.Pod,
+    nodeName string,
+) *framework.Status {
+    tp.scheduler.recordJobPlacement(pod, []*v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}},
+        getGPURequirements(pod))
+    return nil
+}
+
+func (tp *TopologySchedulerPlugin) Unreserve(
+    ctx context.Context,
+    state *framework.CycleState,
+    pod *v1.Pod,
+    nodeName string,
+) {
+    tp.scheduler.releaseJobPlacement(pod, nodeName)
+}
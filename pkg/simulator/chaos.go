@@ -0,0 +1,469 @@
+package simulator
+
+import (
+    "fmt"
+    "os"
+    "sort"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/klog/v2"
+    "sigs.k8s.io/yaml"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/utils/topology"
+)
+
+// ChaosEventType is the kind of failure to inject
+type ChaosEventType string
+
+const (
+    // ChaosNodeNotReady takes a single node down
+    ChaosNodeNotReady ChaosEventType = "node-not-ready"
+    // ChaosLeafDown takes every node of a leaf down
+    ChaosLeafDown ChaosEventType = "leaf-down"
+    // ChaosSpineDegraded slows down jobs whose traffic crosses the spine
+    ChaosSpineDegraded ChaosEventType = "spine-degraded"
+    // ChaosGPUXID reports an XID error on some GPUs of a node
+    ChaosGPUXID ChaosEventType = "gpu-xid"
+)
+
+// ChaosEvent is one scripted failure
+type ChaosEvent struct {
+    At   metav1.Duration `json:"at"`
+    Type ChaosEventType  `json:"type"`
+    Node string          `json:"node,omitempty"`
+    Leaf string          `json:"leaf,omitempty"`
+    // Spine is the degraded spine and Factor multiplies its penalty
+    Spine  string  `json:"spine,omitempty"`
+    Factor float64 `json:"factor,omitempty"`
+    // GPUs is the number of devices hit by the XID
+    GPUs int `json:"gpus,omitempty"`
+    XID  int `json:"xid,omitempty"`
+    // Duration until the failure is repaired, permanent when zero
+    Duration metav1.Duration `json:"duration,omitempty"`
+}
+
+// ChaosScript is a list of failures injected during a replay
+type ChaosScript struct {
+    Events []ChaosEvent `json:"events"`
+}
+
+// LoadChaosScript reads a failure script in YAML or JSON
+func LoadChaosScript(path string) (*ChaosScript, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read chaos script %s: %v", path, err)
+    }
+
+    script := &ChaosScript{}
+    if err := yaml.Unmarshal(data, script); err != nil {
+        return nil, fmt.Errorf("failed to parse chaos script %s: %v", path, err)
+    }
+    return script, nil
+}
+
+// target names what the event hits
+func (e *ChaosEvent) target() string {
+    switch e.Type {
+    case ChaosLeafDown:
+        return e.Leaf
+    case ChaosSpineDegraded:
+        return e.Spine
+    default:
+        return e.Node
+    }
+}
+
+// RecoveryResult describes the impact of one injected failure
+type RecoveryResult struct {
+    Time         float64 `json:"time"`
+    Event        string  `json:"event"`
+    Target       string  `json:"target"`
+    PodsAffected int     `json:"podsAffected"`
+    PodsMoved    int     `json:"podsMoved"`
+    // JobsRequeued could not be recovered in place and waited for capacity
+    JobsRequeued int `json:"jobsRequeued"`
+    Errors       int `json:"errors"`
+    // DecisionTime is the wall clock time spent in the recovery manager
+    DecisionTime float64 `json:"decisionTime"`
+    // TimeToRestore is the simulated time until requeued jobs ran again
+    TimeToRestore float64 `json:"timeToRestore"`
+    RecoveryTime  float64 `json:"recoveryTime"`
+    // Quality is the mean 1/slowdown of the affected jobs that kept running
+    QualityBefore float64 `json:"qualityBefore"`
+    QualityAfter  float64 `json:"qualityAfter"`
+    QualityLost   float64 `json:"qualityLost"`
+
+    at       time.Duration
+    decision time.Duration
+    restore  time.Duration
+}
+
+// Inject schedules failures to happen during the next Run
+func (s *Simulator) Inject(events []ChaosEvent) error {
+    for i := range events {
+        ev := &events[i]
+        if err := s.validateChaos(ev); err != nil {
+            return err
+        }
+        s.push(ev.At.Duration, &event{kind: eventFailure, chaos: ev})
+        if ev.Duration.Duration > 0 {
+            s.push(ev.At.Duration+ev.Duration.Duration, &event{kind: eventRepair, chaos: ev})
+        }
+    }
+    return nil
+}
+
+func (s *Simulator) validateChaos(ev *ChaosEvent) error {
+    switch ev.Type {
+    case ChaosNodeNotReady, ChaosGPUXID:
+        if _, err := s.nodeCache.GetNode(ev.Node); err != nil {
+            return fmt.Errorf("%s event: %v", ev.Type, err)
+        }
+        if ev.Type == ChaosGPUXID && ev.GPUs <= 0 {
+            return fmt.Errorf("%s event on %s must hit at least one GPU", ev.Type, ev.Node)
+        }
+    case ChaosLeafDown:
+        if len(s.leafNodes(ev.Leaf)) == 0 {
+            return fmt.Errorf("%s event: unknown leaf %q", ev.Type, ev.Leaf)
+        }
+    case ChaosSpineDegraded:
+        if ev.Factor < 1 {
+            return fmt.Errorf("%s event on %s: factor %v must be at least 1", ev.Type, ev.Spine, ev.Factor)
+        }
+        known := false
+        for _, spine := range s.spec.Spines {
+            known = known || spine.Name == ev.Spine
+        }
+        if !known {
+            return fmt.Errorf("%s event: unknown spine %q", ev.Type, ev.Spine)
+        }
+    default:
+        return fmt.Errorf("unknown chaos event type %q", ev.Type)
+    }
+    return nil
+}
+
+func (s *Simulator) inject(ev *ChaosEvent) {
+    klog.V(2).Infof("Injecting %s on %s at %v", ev.Type, ev.target(), s.now)
+
+    switch ev.Type {
+    case ChaosNodeNotReady:
+        s.failNodes(ev, []string{ev.Node})
+    case ChaosLeafDown:
+        s.failNodes(ev, s.leafNodes(ev.Leaf))
+    case ChaosSpineDegraded:
+        s.degraded[ev.Spine] = ev.Factor
+        s.rescheduleSpine(ev)
+    case ChaosGPUXID:
+        s.failGPUs(ev)
+    }
+}
+
+func (s *Simulator) repair(ev *ChaosEvent) {
+    klog.V(2).Infof("Repairing %s on %s at %v", ev.Type, ev.target(), s.now)
+
+    switch ev.Type {
+    case ChaosNodeNotReady:
+        s.restoreNodes([]string{ev.Node})
+    case ChaosLeafDown:
+        s.restoreNodes(s.leafNodes(ev.Leaf))
+    case ChaosSpineDegraded:
+        delete(s.degraded, ev.Spine)
+        for _, state := range s.jobsOnSpine(ev.Spine) {
+            s.reschedule(state)
+        }
+    case ChaosGPUXID:
+        s.blocked[ev.Node] -= ev.GPUs
+        if s.blocked[ev.Node] < 0 {
+            s.blocked[ev.Node] = 0
+        }
+        s.syncAllocation(ev.Node)
+        if node, err := s.nodeCache.GetNode(ev.Node); err == nil {
+            s.nodeCache.UpdateGPUHealth(ev.Node, deviceHealth(getNodeGPUs(node), s.xidGPUs(node), ev.XID))
+        }
+        s.rejoin(ev.Node)
+    }
+}
+
+// failNodes marks the nodes NotReady, blocks their GPUs and hands their
+// pods to the recovery manager
+func (s *Simulator) failNodes(ev *ChaosEvent, names []string) {
+    for _, name := range names {
+        node, err := s.nodeCache.GetNode(name)
+        if err != nil {
+            continue
+        }
+        setNodeReady(node, false)
+        s.down[name]++
+        s.syncAllocation(name)
+    }
+    s.recoverNodes(ev, names)
+}
+
+// restoreNodes undoes one node failure on each node. GPUs hit by XID
+// events that are still active stay blocked, and a node that is down for
+// another reason stays NotReady.
+func (s *Simulator) restoreNodes(names []string) {
+    for _, name := range names {
+        node, err := s.nodeCache.GetNode(name)
+        if err != nil {
+            continue
+        }
+        if s.down[name] > 0 {
+            s.down[name]--
+        }
+        if s.down[name] == 0 {
+            delete(s.down, name)
+            setNodeReady(node, true)
+        }
+        s.syncAllocation(name)
+        s.rejoin(name)
+    }
+}
+
+// rejoin puts a repaired node back into the domain the recovery manager
+// took it out of, once no failure holds any of its GPUs
+func (s *Simulator) rejoin(name string) {
+    domain, ok := s.removed[name]
+    if !ok || s.down[name] > 0 || s.blocked[name] > 0 {
+        return
+    }
+    node, err := s.nodeCache.GetNode(name)
+    if err != nil {
+        return
+    }
+    if err := s.domains.AssignNode(node, domain); err != nil {
+        klog.Warningf("Failed to return repaired node %s to domain %s: %v", name, domain, err)
+        return
+    }
+    delete(s.removed, name)
+}
+
+// failGPUs marks devices unhealthy. A fatal XID drains the node since the
+// pods on the failed devices cannot be told apart from the others.
+func (s *Simulator) failGPUs(ev *ChaosEvent) {
+    node, err := s.nodeCache.GetNode(ev.Node)
+    if err != nil {
+        return
+    }
+    capacity := getNodeGPUs(node)
+
+    s.blocked[ev.Node] += ev.GPUs
+    s.nodeCache.UpdateGPUHealth(ev.Node, deviceHealth(capacity, s.xidGPUs(node), ev.XID))
+    s.syncAllocation(ev.Node)
+
+    if topology.IsFatalXID(ev.XID) || s.used[ev.Node]+s.blockedGPUs(node) > capacity {
+        s.recoverNodes(ev, []string{ev.Node})
+        return
+    }
+    s.recordRecovery(&RecoveryResult{
+        Time:   s.now.Seconds(),
+        Event:  string(ev.Type),
+        Target: ev.target(),
+        at:     s.now,
+    })
+}
+
+// recoverNodes runs RecoveryManager.HandleNodeFailure for every node and
+// then settles the affected jobs: those with all pods on working nodes
+// continue with their new placement, the rest go back to the queue.
+func (s *Simulator) recoverNodes(ev *ChaosEvent, names []string) {
+    result := &RecoveryResult{
+        Time:   s.now.Seconds(),
+        Event:  string(ev.Type),
+        Target: ev.target(),
+        at:     s.now,
+    }
+    movedBefore := s.moved
+
+    affected := make(map[*jobState]float64)
+    for _, name := range names {
+        node, err := s.nodeCache.GetNode(name)
+        if err != nil {
+            continue
+        }
+
+        if _, ok := s.removed[name]; !ok {
+            if domain, err := s.domains.GetDomainByNode(name); err == nil {
+                s.removed[name] = domain.Name
+            }
+        }
+
+        pods := s.podsOn(name)
+        for _, pod := range pods {
+            state := s.pods[pod.Name].state
+            if _, ok := affected[state]; !ok {
+                affected[state] = 1 / state.slowdown
+            }
+        }
+        result.PodsAffected += len(pods)
+
+        start := time.Now()
+        if err := s.recovery.HandleNodeFailure(node, pods); err != nil {
+            klog.V(2).Infof("Recovery of node %s incomplete: %v", name, err)
+            result.Errors++
+        }
+        result.decision += time.Since(start)
+    }
+    result.PodsMoved = s.moved - movedBefore
+
+    var before, after float64
+    var kept int
+    for _, state := range sortedJobs(affected) {
+        quality := affected[state]
+        if !s.intact(state) {
+            state.recovery = result
+            s.requeue(state)
+            result.JobsRequeued++
+            continue
+        }
+        s.reschedule(state)
+        before += quality
+        after += 1 / state.slowdown
+        kept++
+    }
+    if kept > 0 {
+        result.QualityBefore = before / float64(kept)
+        result.QualityAfter = after / float64(kept)
+        result.QualityLost = result.QualityBefore - result.QualityAfter
+    }
+    s.recordRecovery(result)
+}
+
+// rescheduleSpine slows down the running jobs that cross a degraded spine
+func (s *Simulator) rescheduleSpine(ev *ChaosEvent) {
+    result := &RecoveryResult{
+        Time:   s.now.Seconds(),
+        Event:  string(ev.Type),
+        Target: ev.target(),
+        at:     s.now,
+    }
+
+    jobs := s.jobsOnSpine(ev.Spine)
+    var before, after float64
+    for _, state := range jobs {
+        before += 1 / state.slowdown
+        s.reschedule(state)
+        after += 1 / state.slowdown
+        result.PodsAffected += len(state.pods)
+    }
+    if len(jobs) > 0 {
+        result.QualityBefore = before / float64(len(jobs))
+        result.QualityAfter = after / float64(len(jobs))
+        result.QualityLost = result.QualityBefore - result.QualityAfter
+    }
+    s.recordRecovery(result)
+}
+
+func (s *Simulator) recordRecovery(result *RecoveryResult) {
+    s.recoveries = append(s.recoveries, result)
+}
+
+// intact reports whether every pod of the job runs on a working node
+func (s *Simulator) intact(state *jobState) bool {
+    if len(state.nodes) != state.job.GangSize {
+        return false
+    }
+    for _, node := range state.nodes {
+        if node == nil || s.blockedGPUs(node) >= getNodeGPUs(node) {
+            return false
+        }
+    }
+    return true
+}
+
+func (s *Simulator) podsOn(nodeName string) []*v1.Pod {
+    var pods []*v1.Pod
+    for _, state := range s.runningJobs() {
+        for i, node := range state.nodes {
+            if node != nil && node.Name == nodeName {
+                pods = append(pods, state.pods[i])
+            }
+        }
+    }
+    return pods
+}
+
+// jobsOnSpine returns the running jobs that span the spine and at least
+// one other
+func (s *Simulator) jobsOnSpine(spine string) []*jobState {
+    var jobs []*jobState
+    for _, state := range s.runningJobs() {
+        _, spines := s.span(state.nodes)
+        if spines[spine] && len(spines) > 1 {
+            jobs = append(jobs, state)
+        }
+    }
+    return jobs
+}
+
+// runningJobs returns the running jobs in a stable order
+func (s *Simulator) runningJobs() []*jobState {
+    jobs := make([]*jobState, 0, len(s.running))
+    for _, state := range s.running {
+        jobs = append(jobs, state)
+    }
+    sort.Slice(jobs, func(i, j int) bool { return jobs[i].job.ID < jobs[j].job.ID })
+    return jobs
+}
+
+func sortedJobs(jobs map[*jobState]float64) []*jobState {
+    sorted := make([]*jobState, 0, len(jobs))
+    for state := range jobs {
+        sorted = append(sorted, state)
+    }
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i].job.ID < sorted[j].job.ID })
+    return sorted
+}
+
+func (s *Simulator) leafNodes(leaf string) []string {
+    var names []string
+    for _, domain := range s.cache.GetAllDomains() {
+        if domain.Name != leaf {
+            continue
+        }
+        for _, node := range domain.Nodes {
+            names = append(names, node.Name)
+        }
+    }
+    return names
+}
+
+func setNodeReady(node *v1.Node, ready bool) {
+    status := v1.ConditionTrue
+    if !ready {
+        status = v1.ConditionFalse
+    }
+    for i := range node.Status.Conditions {
+        if node.Status.Conditions[i].Type == v1.NodeReady {
+            node.Status.Conditions[i].Status = status
+            return
+        }
+    }
+    node.Status.Conditions = append(node.Status.Conditions, v1.NodeCondition{Type: v1.NodeReady, Status: status})
+}
+
+// xidGPUs returns the GPUs of the node hit by active XID events
+func (s *Simulator) xidGPUs(node *v1.Node) int {
+    if capacity := getNodeGPUs(node); s.blocked[node.Name] > capacity {
+        return capacity
+    }
+    return s.blocked[node.Name]
+}
+
+// deviceHealth builds the health of a node's GPUs with the first failed
+// devices unhealthy
+func deviceHealth(capacity, failed, xid int) []topology.GPUDeviceHealth {
+    devices := make([]topology.GPUDeviceHealth, capacity)
+    for i := range devices {
+        devices[i] = topology.GPUDeviceHealth{Index: i, Healthy: i >= failed}
+        if i < failed {
+            devices[i].XIDErrors = 1
+            devices[i].LastXID = xid
+            devices[i].Reason = fmt.Sprintf("XID %d", xid)
+        }
+    }
+    return devices
+}
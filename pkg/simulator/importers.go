@@ -0,0 +1,322 @@
+package simulator
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "k8s.io/klog/v2"
+)
+
+// TraceFormat names a supported job trace format
+type TraceFormat string
+
+const (
+    // TraceFormatCSV is the simulator's own format, see ReadTrace
+    TraceFormatCSV TraceFormat = "csv"
+    // TraceFormatPhilly is cluster_job_log from the Microsoft Philly traces
+    TraceFormatPhilly TraceFormat = "philly"
+    // TraceFormatPAI is pai_task_table.csv from Alibaba cluster-trace-gpu-v2020
+    TraceFormatPAI TraceFormat = "pai"
+    // TraceFormatHelios is cluster_log.csv from the SenseTime Helios traces
+    TraceFormatHelios TraceFormat = "helios"
+)
+
+// traceTimeLayout is the timestamp layout used by the Philly and Helios traces
+const traceTimeLayout = "2006-01-02 15:04:05"
+
+// paiJobTable is the PAI table with job submit times, read from the
+// directory of the task table
+const paiJobTable = "pai_job_table.csv"
+
+// rawJob is a trace entry with absolute times, before normalization
+type rawJob struct {
+    id       string
+    submit   time.Time
+    duration time.Duration
+    gpus     int
+    gang     int
+}
+
+// ImportTrace reads a job trace in the given format and converts it to
+// simulator jobs, with arrivals relative to the first submission.
+func ImportTrace(format TraceFormat, path string) ([]Job, error) {
+    if format == TraceFormatCSV {
+        return LoadTrace(path)
+    }
+
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open trace %s: %v", path, err)
+    }
+    defer f.Close()
+
+    var raw []rawJob
+    switch format {
+    case TraceFormatPhilly:
+        raw, err = parsePhillyTrace(f)
+    case TraceFormatPAI:
+        var jobTable *os.File
+        jobTable, err = os.Open(filepath.Join(filepath.Dir(path), paiJobTable))
+        if err != nil {
+            return nil, fmt.Errorf("failed to open the PAI job table next to %s: %v", path, err)
+        }
+        defer jobTable.Close()
+        raw, err = parsePAITrace(f, jobTable)
+    case TraceFormatHelios:
+        raw, err = parseHeliosTrace(f)
+    default:
+        return nil, fmt.Errorf("unknown trace format %q", format)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse %s trace %s: %v", format, path, err)
+    }
+    return normalizeTrace(raw), nil
+}
+
+// normalizeTrace drops jobs without GPUs or run time, makes arrivals
+// relative and keeps gang sizes only where they split the GPUs evenly.
+func normalizeTrace(raw []rawJob) []Job {
+    var kept []rawJob
+    for _, job := range raw {
+        if job.gpus > 0 && job.duration > 0 && !job.submit.IsZero() {
+            kept = append(kept, job)
+        }
+    }
+    if skipped := len(raw) - len(kept); skipped > 0 {
+        klog.Infof("Skipped %d of %d trace jobs without GPUs, run time or submit time", skipped, len(raw))
+    }
+    if len(kept) == 0 {
+        return nil
+    }
+
+    sort.SliceStable(kept, func(i, j int) bool {
+        return kept[i].submit.Before(kept[j].submit)
+    })
+    origin := kept[0].submit
+
+    jobs := make([]Job, 0, len(kept))
+    for _, job := range kept {
+        gang := job.gang
+        if gang <= 0 || job.gpus%gang != 0 {
+            gang = 1
+        }
+        jobs = append(jobs, Job{
+            ID:       job.id,
+            Arrival:  job.submit.Sub(origin),
+            Duration: job.duration,
+            GPUs:     job.gpus,
+            GangSize: gang,
+        })
+    }
+    return jobs
+}
+
+type phillyJob struct {
+    JobID     string          `json:"jobid"`
+    Status    string          `json:"status"`
+    Submitted string          `json:"submitted_time"`
+    Attempts  []phillyAttempt `json:"attempts"`
+}
+
+type phillyAttempt struct {
+    Start  string `json:"start_time"`
+    End    string `json:"end_time"`
+    Detail []struct {
+        IP   string   `json:"ip"`
+        GPUs []string `json:"gpus"`
+    } `json:"detail"`
+}
+
+// parsePhillyTrace reads the cluster_job_log JSON array. The last attempt
+// gives the run time, its machines the gang size and their GPUs the total.
+func parsePhillyTrace(r io.Reader) ([]rawJob, error) {
+    var entries []phillyJob
+    if err := json.NewDecoder(r).Decode(&entries); err != nil {
+        return nil, err
+    }
+
+    jobs := make([]rawJob, 0, len(entries))
+    for _, entry := range entries {
+        job := rawJob{id: entry.JobID}
+        job.submit, _ = time.Parse(traceTimeLayout, entry.Submitted)
+
+        if len(entry.Attempts) > 0 {
+            last := entry.Attempts[len(entry.Attempts)-1]
+            start, errStart := time.Parse(traceTimeLayout, last.Start)
+            end, errEnd := time.Parse(traceTimeLayout, last.End)
+            if errStart == nil && errEnd == nil {
+                job.duration = end.Sub(start)
+            }
+            for _, machine := range last.Detail {
+                job.gpus += len(machine.GPUs)
+            }
+            job.gang = len(last.Detail)
+        }
+        jobs = append(jobs, job)
+    }
+    return jobs, nil
+}
+
+// parsePAITrace reads pai_task_table.csv, which has no header:
+// job_name,task_name,inst_num,status,start_time,end_time,plan_cpu,plan_mem,plan_gpu,gpu_type.
+// plan_gpu is in percent of a GPU per instance; fractional GPUs are
+// rounded up. Tasks are merged into their job, which runs from the first
+// task start to the last task end. The submit time is the start_time of
+// the job in pai_job_table.csv, read from jobTable:
+// job_name,inst_id,user,status,start_time,end_time.
+func parsePAITrace(tasks, jobTable io.Reader) ([]rawJob, error) {
+    submits, err := parsePAIJobTable(jobTable)
+    if err != nil {
+        return nil, fmt.Errorf("job table: %v", err)
+    }
+
+    reader := csv.NewReader(tasks)
+    reader.FieldsPerRecord = -1
+
+    byName := make(map[string]*rawJob)
+    var order []string
+    starts := make(map[string]time.Time)
+    ends := make(map[string]time.Time)
+
+    for line := 1; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+        if len(record) < 9 || record[0] == "job_name" {
+            continue
+        }
+
+        instances, errInst := strconv.ParseFloat(record[2], 64)
+        start, errStart := parseUnixTime(record[4])
+        end, errEnd := parseUnixTime(record[5])
+        planGPU, errGPU := strconv.ParseFloat(record[8], 64)
+        if errInst != nil || errStart != nil || errEnd != nil {
+            klog.V(4).Infof("Skipping PAI task on line %d: incomplete record", line)
+            continue
+        }
+        if errGPU != nil {
+            planGPU = 0
+        }
+
+        job, ok := byName[record[0]]
+        if !ok {
+            job = &rawJob{id: record[0], submit: submits[record[0]]}
+            byName[record[0]] = job
+            order = append(order, record[0])
+            starts[job.id] = start
+        }
+        if start.Before(starts[job.id]) {
+            starts[job.id] = start
+        }
+        if end.After(ends[job.id]) {
+            ends[job.id] = end
+        }
+        if planGPU > 0 {
+            job.gpus += int(instances) * int(math.Ceil(planGPU/100))
+            job.gang += int(instances)
+        }
+    }
+
+    jobs := make([]rawJob, 0, len(order))
+    for _, name := range order {
+        job := byName[name]
+        job.duration = ends[name].Sub(starts[name])
+        jobs = append(jobs, *job)
+    }
+    return jobs, nil
+}
+
+// parsePAIJobTable returns the submit time of every job in
+// pai_job_table.csv. Jobs missing from it keep no submit time and are
+// skipped.
+func parsePAIJobTable(r io.Reader) (map[string]time.Time, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+
+    submits := make(map[string]time.Time)
+    for {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+        if len(record) < 5 || record[0] == "job_name" {
+            continue
+        }
+        if submit, err := parseUnixTime(record[4]); err == nil {
+            submits[record[0]] = submit
+        }
+    }
+    return submits, nil
+}
+
+// parseHeliosTrace reads cluster_log.csv with the header
+// job_id,user,gpu_num,cpu_num,node_num,state,submit_time,start_time,end_time,duration,...
+func parseHeliosTrace(r io.Reader) ([]rawJob, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+
+    header, err := reader.Read()
+    if err != nil {
+        return nil, err
+    }
+    columns := make(map[string]int, len(header))
+    for i, name := range header {
+        columns[strings.TrimSpace(name)] = i
+    }
+    for _, name := range []string{"job_id", "gpu_num", "node_num", "submit_time", "duration"} {
+        if _, ok := columns[name]; !ok {
+            return nil, fmt.Errorf("missing column %s", name)
+        }
+    }
+
+    var jobs []rawJob
+    for {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+        field := func(name string) string {
+            if i := columns[name]; i < len(record) {
+                return record[i]
+            }
+            return ""
+        }
+
+        job := rawJob{id: field("job_id")}
+        job.submit, _ = time.Parse(traceTimeLayout, field("submit_time"))
+        job.gpus, _ = strconv.Atoi(field("gpu_num"))
+        job.gang, _ = strconv.Atoi(field("node_num"))
+        if seconds, err := strconv.ParseFloat(field("duration"), 64); err == nil {
+            job.duration = time.Duration(seconds * float64(time.Second))
+        }
+        jobs = append(jobs, job)
+    }
+    return jobs, nil
+}
+
+func parseUnixTime(s string) (time.Time, error) {
+    seconds, err := strconv.ParseFloat(s, 64)
+    if err != nil {
+        return time.Time{}, err
+    }
+    return time.Unix(0, int64(seconds*float64(time.Second))), nil
+}
@@ -0,0 +1,126 @@
+package simulator
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+    "time"
+)
+
+// phillySample has a job whose second attempt ran on two machines, an
+// earlier single-machine job and a job that never started
+const phillySample = `[
+  {"jobid": "application_1", "status": "Pass", "submitted_time": "2017-10-03 10:00:00",
+   "attempts": [
+     {"start_time": "2017-10-03 10:00:30", "end_time": "2017-10-03 10:05:00",
+      "detail": [{"ip": "m1", "gpus": ["gpu0"]}]},
+     {"start_time": "2017-10-03 10:10:00", "end_time": "2017-10-03 11:10:00",
+      "detail": [{"ip": "m1", "gpus": ["gpu0", "gpu1"]}, {"ip": "m2", "gpus": ["gpu0", "gpu1"]}]}
+   ]},
+  {"jobid": "application_2", "status": "Pass", "submitted_time": "2017-10-03 09:59:00",
+   "attempts": [
+     {"start_time": "2017-10-03 10:02:00", "end_time": "2017-10-03 10:32:00",
+      "detail": [{"ip": "m3", "gpus": ["gpu0", "gpu1", "gpu2", "gpu3", "gpu4", "gpu5", "gpu6", "gpu7"]}]}
+   ]},
+  {"jobid": "application_3", "status": "Killed", "submitted_time": "2017-10-03 10:01:00", "attempts": []}
+]`
+
+// paiTaskSample is pai_task_table.csv. job-a has a worker and a parameter
+// server task without GPUs, job-b half a GPU, job-c no times yet and job-d
+// no entry in the job table.
+const paiTaskSample = `job-a,worker,2,Terminated,1582000100,1582003700,600,29.3,100,V100
+job-a,ps,1,Terminated,1582000050,1582003800,600,29.3,,
+job-b,worker,1,Terminated,1582000500,1582001100,600,29.3,50,T4
+job-c,worker,1,Running,,,600,29.3,100,V100
+job-d,worker,1,Terminated,1582000600,1582000700,600,29.3,100,V100
+`
+
+// paiJobSample is pai_job_table.csv. Jobs are submitted before their
+// first task starts.
+const paiJobSample = `job-a,inst-1,user1,Terminated,1582000000,1582003800
+job-b,inst-2,user2,Terminated,1582000400,1582001100
+job-c,inst-3,user2,Running,1582000450,
+`
+
+// heliosSample is cluster_log.csv with a CPU-only job and a job whose
+// GPUs do not split evenly over its nodes
+const heliosSample = `job_id,user,gpu_num,cpu_num,node_num,state,submit_time,start_time,end_time,duration,queue
+j1,u1,16,64,2,COMPLETED,2020-04-01 00:00:10,2020-04-01 00:01:00,2020-04-01 01:01:00,3600,50
+j2,u1,0,4,1,COMPLETED,2020-04-01 00:00:00,2020-04-01 00:00:00,2020-04-01 00:02:00,120,0
+j3,u2,3,8,2,FAILED,2020-04-01 00:00:05,2020-04-01 00:00:05,2020-04-01 00:01:35,90.5,0
+`
+
+func TestImportTrace(t *testing.T) {
+    tests := []struct {
+        name    string
+        format  TraceFormat
+        files   map[string]string
+        trace   string
+        want    []Job
+        wantErr bool
+    }{
+        {
+            name:   "philly",
+            format: TraceFormatPhilly,
+            files:  map[string]string{"cluster_job_log": phillySample},
+            trace:  "cluster_job_log",
+            want: []Job{
+                {ID: "application_2", Arrival: 0, Duration: 30 * time.Minute, GPUs: 8, GangSize: 1},
+                {ID: "application_1", Arrival: time.Minute, Duration: time.Hour, GPUs: 4, GangSize: 2},
+            },
+        },
+        {
+            name:   "pai arrivals come from the job submit time",
+            format: TraceFormatPAI,
+            files:  map[string]string{"pai_task_table.csv": paiTaskSample, paiJobTable: paiJobSample},
+            trace:  "pai_task_table.csv",
+            want: []Job{
+                {ID: "job-a", Arrival: 0, Duration: 3750 * time.Second, GPUs: 2, GangSize: 2},
+                {ID: "job-b", Arrival: 400 * time.Second, Duration: 600 * time.Second, GPUs: 1, GangSize: 1},
+            },
+        },
+        {
+            name:    "pai without the job table",
+            format:  TraceFormatPAI,
+            files:   map[string]string{"pai_task_table.csv": paiTaskSample},
+            trace:   "pai_task_table.csv",
+            wantErr: true,
+        },
+        {
+            name:   "helios",
+            format: TraceFormatHelios,
+            files:  map[string]string{"cluster_log.csv": heliosSample},
+            trace:  "cluster_log.csv",
+            want: []Job{
+                {ID: "j3", Arrival: 0, Duration: 90500 * time.Millisecond, GPUs: 3, GangSize: 1},
+                {ID: "j1", Arrival: 5 * time.Second, Duration: time.Hour, GPUs: 16, GangSize: 2},
+            },
+        },
+        {
+            name:    "helios without a duration column",
+            format:  TraceFormatHelios,
+            files:   map[string]string{"cluster_log.csv": "job_id,gpu_num,node_num,submit_time\nj1,8,1,2020-04-01 00:00:00\n"},
+            trace:   "cluster_log.csv",
+            wantErr: true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := t.TempDir()
+            for name, content := range tt.files {
+                if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            got, err := ImportTrace(tt.format, filepath.Join(dir, tt.trace))
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ImportTrace() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("ImportTrace() = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,201 @@
+package simulator
+
+import (
+    "encoding/csv"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "time"
+)
+
+// Report is the outcome of a simulation run. Times are in seconds of
+// simulated time.
+type Report struct {
+    Summary    Summary          `json:"summary"`
+    Jobs       []JobResult      `json:"jobs"`
+    Samples    []Sample         `json:"samples"`
+    Recoveries []RecoveryResult `json:"recoveries,omitempty"`
+}
+
+// Summary aggregates the per-job results and the time series
+type Summary struct {
+    JobsCompleted     int     `json:"jobsCompleted"`
+    JobsUnscheduled   int     `json:"jobsUnscheduled"`
+    Makespan          float64 `json:"makespan"`
+    MeanUtilization   float64 `json:"meanUtilization"`
+    MeanFragmentation float64 `json:"meanFragmentation"`
+    MeanQueueWait     float64 `json:"meanQueueWait"`
+    P50QueueWait      float64 `json:"p50QueueWait"`
+    P99QueueWait      float64 `json:"p99QueueWait"`
+    MeanJCT           float64 `json:"meanJCT"`
+    P50JCT            float64 `json:"p50JCT"`
+    P99JCT            float64 `json:"p99JCT"`
+    JobsCrossingSpine int     `json:"jobsCrossingSpine"`
+    SpineCrossings    int     `json:"spineCrossings"`
+    Failures          int     `json:"failures"`
+    PodsMoved         int     `json:"podsMoved"`
+    JobsRequeued      int     `json:"jobsRequeued"`
+    MaxRecoveryTime   float64 `json:"maxRecoveryTime"`
+    MeanQualityLost   float64 `json:"meanQualityLost"`
+    // Unscheduled lists jobs still queued when the run ended
+    Unscheduled []string `json:"unscheduled,omitempty"`
+}
+
+// JobResult is the outcome of one completed job
+type JobResult struct {
+    ID             string  `json:"id"`
+    GPUs           int     `json:"gpus"`
+    Arrival        float64 `json:"arrival"`
+    QueueWait      float64 `json:"queueWait"`
+    CompletionTime float64 `json:"completionTime"`
+    Leaves         int     `json:"leaves"`
+    SpineCrossings int     `json:"spineCrossings"`
+    Restarts       int     `json:"restarts"`
+}
+
+// Sample is the cluster state at one point of simulated time
+type Sample struct {
+    Time          float64 `json:"time"`
+    Utilization   float64 `json:"utilization"`
+    Fragmentation float64 `json:"fragmentation"`
+    QueueLength   int     `json:"queueLength"`
+    RunningJobs   int     `json:"runningJobs"`
+    // UnavailableGPUs are taken out by injected failures
+    UnavailableGPUs int `json:"unavailableGPUs"`
+}
+
+func (r *Report) finish(end time.Duration, pending []*jobState, recoveries []*RecoveryResult) {
+    sum := &r.Summary
+    sum.Makespan = end.Seconds()
+    sum.JobsCompleted = len(r.Jobs)
+    sum.JobsUnscheduled = len(pending)
+    for _, state := range pending {
+        sum.Unscheduled = append(sum.Unscheduled, state.job.ID)
+    }
+
+    waits := make([]float64, 0, len(r.Jobs))
+    jcts := make([]float64, 0, len(r.Jobs))
+    for _, job := range r.Jobs {
+        waits = append(waits, job.QueueWait)
+        jcts = append(jcts, job.CompletionTime)
+        if job.SpineCrossings > 0 {
+            sum.JobsCrossingSpine++
+            sum.SpineCrossings += job.SpineCrossings
+        }
+    }
+    sum.MeanQueueWait = mean(waits)
+    sum.P50QueueWait = percentile(waits, 0.50)
+    sum.P99QueueWait = percentile(waits, 0.99)
+    sum.MeanJCT = mean(jcts)
+    sum.P50JCT = percentile(jcts, 0.50)
+    sum.P99JCT = percentile(jcts, 0.99)
+
+    utilization := make([]float64, 0, len(r.Samples))
+    fragmentation := make([]float64, 0, len(r.Samples))
+    for _, sample := range r.Samples {
+        utilization = append(utilization, sample.Utilization)
+        fragmentation = append(fragmentation, sample.Fragmentation)
+    }
+    sum.MeanUtilization = mean(utilization)
+    sum.MeanFragmentation = mean(fragmentation)
+
+    var lost []float64
+    for _, result := range recoveries {
+        result.DecisionTime = result.decision.Seconds()
+        result.TimeToRestore = result.restore.Seconds()
+        result.RecoveryTime = result.DecisionTime + result.TimeToRestore
+        r.Recoveries = append(r.Recoveries, *result)
+
+        sum.Failures++
+        sum.PodsMoved += result.PodsMoved
+        sum.JobsRequeued += result.JobsRequeued
+        if result.RecoveryTime > sum.MaxRecoveryTime {
+            sum.MaxRecoveryTime = result.RecoveryTime
+        }
+        if result.PodsAffected > 0 {
+            lost = append(lost, result.QualityLost)
+        }
+    }
+    sum.MeanQualityLost = mean(lost)
+}
+
+// WriteSummary prints a human readable summary
+func (r *Report) WriteSummary(w io.Writer) {
+    s := r.Summary
+    fmt.Fprintf(w, "Jobs completed:      %d (%d unscheduled)\n", s.JobsCompleted, s.JobsUnscheduled)
+    fmt.Fprintf(w, "Makespan:            %.0fs\n", s.Makespan)
+    fmt.Fprintf(w, "GPU utilization:     %.1f%%\n", s.MeanUtilization*100)
+    fmt.Fprintf(w, "Fragmentation:       %.1f%%\n", s.MeanFragmentation*100)
+    fmt.Fprintf(w, "Queue wait:          mean %.0fs, p50 %.0fs, p99 %.0fs\n", s.MeanQueueWait, s.P50QueueWait, s.P99QueueWait)
+    fmt.Fprintf(w, "Job completion time: mean %.0fs, p50 %.0fs, p99 %.0fs\n", s.MeanJCT, s.P50JCT, s.P99JCT)
+    fmt.Fprintf(w, "Spine crossings:     %d jobs, %d total\n", s.JobsCrossingSpine, s.SpineCrossings)
+    if s.Failures > 0 {
+        fmt.Fprintf(w, "Failures injected:   %d, %d pods moved, %d jobs requeued\n", s.Failures, s.PodsMoved, s.JobsRequeued)
+        fmt.Fprintf(w, "Recovery time:       max %.1fs, mean quality lost %.3f\n", s.MaxRecoveryTime, s.MeanQualityLost)
+    }
+}
+
+// CheckRecovery returns the failures that took longer than target to
+// recover from
+func (r *Report) CheckRecovery(target time.Duration) []string {
+    var violations []string
+    for _, result := range r.Recoveries {
+        if result.RecoveryTime > target.Seconds() {
+            violations = append(violations, fmt.Sprintf("%s on %s at %.0fs took %.1fs to recover",
+                result.Event, result.Target, result.Time, result.RecoveryTime))
+        }
+    }
+    return violations
+}
+
+// WriteSamplesCSV writes the time series as CSV
+func (r *Report) WriteSamplesCSV(w io.Writer) error {
+    writer := csv.NewWriter(w)
+    if err := writer.Write([]string{"time_s", "utilization", "fragmentation", "queue_length", "running_jobs", "unavailable_gpus"}); err != nil {
+        return err
+    }
+    for _, sample := range r.Samples {
+        record := []string{
+            strconv.FormatFloat(sample.Time, 'f', -1, 64),
+            strconv.FormatFloat(sample.Utilization, 'f', 4, 64),
+            strconv.FormatFloat(sample.Fragmentation, 'f', 4, 64),
+            strconv.Itoa(sample.QueueLength),
+            strconv.Itoa(sample.RunningJobs),
+            strconv.Itoa(sample.UnavailableGPUs),
+        }
+        if err := writer.Write(record); err != nil {
+            return err
+        }
+    }
+    writer.Flush()
+    return writer.Error()
+}
+
+func mean(values []float64) float64 {
+    if len(values) == 0 {
+        return 0
+    }
+    total := 0.0
+    for _, v := range values {
+        total += v
+    }
+    return total / float64(len(values))
+}
+
+// percentile uses the nearest-rank method
+func percentile(values []float64, p float64) float64 {
+    if len(values) == 0 {
+        return 0
+    }
+    sorted := append([]float64(nil), values...)
+    sort.Float64s(sorted)
+    rank := int(p*float64(len(sorted))+0.5) - 1
+    if rank < 0 {
+        rank = 0
+    }
+    if rank >= len(sorted) {
+        rank = len(sorted) - 1
+    }
+    return sorted[rank]
+}
@@ -0,0 +1,560 @@
+package simulator
+
+import (
+    "container/heap"
+    "context"
+    "fmt"
+    "strconv"
+    "time"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/apimachinery/pkg/api/resource"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "k8s.io/klog/v2"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/discovery"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// simNamespace is the namespace of the pods created for trace jobs
+const simNamespace = "simulation"
+
+// Config controls the simulation
+type Config struct {
+    // SampleInterval is the simulated time between time series samples
+    SampleInterval time.Duration
+    // LeafPenalty slows a job down for every leaf it spans beyond the first
+    LeafPenalty float64
+    // SpinePenalty slows a job down for every spine it spans beyond the first
+    SpinePenalty float64
+}
+
+func DefaultConfig() Config {
+    return Config{
+        SampleInterval: time.Minute,
+        LeafPenalty:    0.02,
+        SpinePenalty:   0.1,
+    }
+}
+
+type jobState struct {
+    job   Job
+    pods  []*v1.Pod
+    nodes []*v1.Node
+    // scheduled marks pods placed through Schedule, which Release undoes
+    scheduled []bool
+
+    started  bool
+    start    time.Duration
+    end      time.Duration
+    restarts int
+
+    // remaining is the work left, in ideal placement time, as of updated
+    remaining time.Duration
+    updated   time.Duration
+    slowdown  float64
+    // version invalidates completion events when the end time moves
+    version int
+
+    // recovery is the failure that forced the job back into the queue
+    recovery *RecoveryResult
+}
+
+// podRef locates a pod within its job
+type podRef struct {
+    state *jobState
+    index int
+}
+
+// Simulator replays a job trace against the real TopologyScheduler on a
+// synthetic cluster, advancing a simulated clock from event to event.
+type Simulator struct {
+    config    Config
+    spec      *TopologySpec
+    cache     *algorithm.TopologyCache
+    nodeCache *algorithm.NodeCache
+    scheduler *algorithm.TopologyScheduler
+    recovery  *algorithm.RecoveryManager
+
+    now     time.Duration
+    events  eventQueue
+    seq     int
+    pending []*jobState
+    running map[string]*jobState
+    pods    map[string]podRef
+
+    // used and blocked are GPUs per node taken by pods and by XID events;
+    // down counts the failures that took the whole node out
+    used     map[string]int
+    blocked  map[string]int
+    down     map[string]int
+    degraded map[string]float64
+    moved    int
+
+    // removed is the domain the recovery manager took each failed node
+    // out of, which it rejoins once repaired
+    domains *algorithm.DomainManager
+    removed map[string]string
+
+    recoveries []*RecoveryResult
+    report     *Report
+}
+
+func New(spec *TopologySpec, config Config) (*Simulator, error) {
+    if config.SampleInterval <= 0 {
+        return nil, fmt.Errorf("sample interval must be positive")
+    }
+
+    cache, nodeCache, err := BuildCache(spec)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build topology: %v", err)
+    }
+
+    s := &Simulator{
+        config:    config,
+        spec:      spec,
+        cache:     cache,
+        nodeCache: nodeCache,
+        scheduler: algorithm.NewTopologyScheduler(cache),
+        running:   make(map[string]*jobState),
+        pods:      make(map[string]podRef),
+        used:      make(map[string]int),
+        blocked:   make(map[string]int),
+        down:      make(map[string]int),
+        degraded:  make(map[string]float64),
+        domains:   algorithm.NewDomainManager(),
+        removed:   make(map[string]string),
+        report:    &Report{},
+    }
+
+    if err := discovery.RegisterDomains(cache, s.domains); err != nil {
+        return nil, err
+    }
+    s.recovery = algorithm.NewRecoveryManager(s.domains, s.scheduler)
+    s.recovery.SetPodMover(&podMover{sim: s})
+    return s, nil
+}
+
+// Scheduler returns the scheduler under test so callers can configure it,
+// e.g. enable the weight tuner, before Run
+func (s *Simulator) Scheduler() *algorithm.TopologyScheduler {
+    return s.scheduler
+}
+
+// Run replays the trace until every job has finished or can no longer be
+// placed, and returns the report.
+func (s *Simulator) Run(ctx context.Context, jobs []Job) (*Report, error) {
+    for i := range jobs {
+        if err := jobs[i].Validate(); err != nil {
+            return nil, err
+        }
+        s.push(jobs[i].Arrival, &event{kind: eventArrival, job: &jobs[i]})
+    }
+    s.push(0, &event{kind: eventSample})
+
+    for s.events.Len() > 0 {
+        if err := ctx.Err(); err != nil {
+            return nil, err
+        }
+
+        ev := heap.Pop(&s.events).(*event)
+        s.now = ev.at
+
+        switch ev.kind {
+        case eventArrival:
+            s.pending = append(s.pending, &jobState{job: *ev.job})
+            s.schedulePending(ctx)
+        case eventCompletion:
+            // Skip completions superseded by a migration or slowdown
+            if ev.version != ev.state.version {
+                continue
+            }
+            s.complete(ev.state)
+            s.schedulePending(ctx)
+        case eventFailure:
+            s.inject(ev.chaos)
+            s.schedulePending(ctx)
+        case eventRepair:
+            s.repair(ev.chaos)
+            s.schedulePending(ctx)
+        case eventSample:
+            s.sample()
+            // Stop sampling once nothing else can happen
+            if s.events.Len() > 0 {
+                s.push(s.now+s.config.SampleInterval, &event{kind: eventSample})
+            }
+        }
+    }
+
+    s.report.finish(s.now, s.pending, s.recoveries)
+    return s.report, nil
+}
+
+// schedulePending tries every queued job in arrival order. Jobs that do
+// not fit are skipped so smaller ones behind them can backfill.
+func (s *Simulator) schedulePending(ctx context.Context) {
+    remaining := s.pending[:0]
+    for _, state := range s.pending {
+        if !s.place(ctx, state) {
+            remaining = append(remaining, state)
+        }
+    }
+    s.pending = remaining
+}
+
+// place schedules every pod of the job through the scheduler, or none of
+// them: a partially placed gang is rolled back.
+func (s *Simulator) place(ctx context.Context, state *jobState) bool {
+    job := state.job
+    gpus := job.GPUs / job.GangSize
+
+    for i := 0; i < job.GangSize; i++ {
+        pod := newPod(job, i, gpus)
+        node, err := s.scheduler.Schedule(ctx, pod)
+        if err != nil {
+            klog.V(4).Infof("Job %s pod %d not placed at %v: %v", job.ID, i, s.now, err)
+            s.release(state)
+            return false
+        }
+        if err := s.allocate(node.Name, gpus); err != nil {
+            klog.Warningf("Job %s pod %d placed on unknown node %s: %v", job.ID, i, node.Name, err)
+            s.scheduler.Release(pod, node)
+            s.release(state)
+            return false
+        }
+        s.pods[pod.Name] = podRef{state: state, index: i}
+        state.pods = append(state.pods, pod)
+        state.nodes = append(state.nodes, node)
+        state.scheduled = append(state.scheduled, true)
+    }
+
+    if !state.started {
+        state.started = true
+        state.start = s.now
+        state.remaining = job.Duration
+    }
+    if r := state.recovery; r != nil {
+        if restore := s.now - r.at; restore > r.restore {
+            r.restore = restore
+        }
+        state.recovery = nil
+    }
+
+    s.running[job.ID] = state
+    s.reschedule(state)
+    return true
+}
+
+// progress accounts for the work done since the last update at the
+// current slowdown
+func (s *Simulator) progress(state *jobState) {
+    if state.slowdown > 0 {
+        state.remaining -= time.Duration(float64(s.now-state.updated) / state.slowdown)
+        if state.remaining < 0 {
+            state.remaining = 0
+        }
+    }
+    state.updated = s.now
+}
+
+// reschedule recomputes the slowdown of a running job from its current
+// placement and moves its completion accordingly
+func (s *Simulator) reschedule(state *jobState) {
+    s.progress(state)
+    state.slowdown = s.slowdown(state.nodes)
+    state.end = s.now + time.Duration(float64(state.remaining)*state.slowdown)
+    state.version++
+    s.push(state.end, &event{kind: eventCompletion, state: state, version: state.version})
+}
+
+// requeue stops a job whose pods could not all be recovered and puts it
+// back in the queue with the work it has left
+func (s *Simulator) requeue(state *jobState) {
+    s.progress(state)
+    delete(s.running, state.job.ID)
+    s.release(state)
+    state.slowdown = 0
+    state.version++
+    state.restarts++
+    s.pending = append(s.pending, state)
+}
+
+func (s *Simulator) complete(state *jobState) {
+    delete(s.running, state.job.ID)
+    leaves, spines := s.span(state.nodes)
+    s.release(state)
+
+    s.report.Jobs = append(s.report.Jobs, JobResult{
+        ID:             state.job.ID,
+        GPUs:           state.job.GPUs,
+        Arrival:        state.job.Arrival.Seconds(),
+        QueueWait:      (state.start - state.job.Arrival).Seconds(),
+        CompletionTime: (state.end - state.job.Arrival).Seconds(),
+        Leaves:         len(leaves),
+        SpineCrossings: len(spines) - 1,
+        Restarts:       state.restarts,
+    })
+}
+
+// release returns the GPUs of every placed pod of the job
+func (s *Simulator) release(state *jobState) {
+    for i, pod := range state.pods {
+        delete(s.pods, pod.Name)
+        node := state.nodes[i]
+        if node == nil {
+            continue
+        }
+        if err := s.allocate(node.Name, -getPodGPUs(pod)); err != nil {
+            klog.Warningf("Failed to release GPUs on node %s: %v", node.Name, err)
+        }
+        if !state.scheduled[i] {
+            continue
+        }
+        if err := s.scheduler.Release(pod, node); err != nil {
+            klog.Warningf("Failed to release pod %s: %v", pod.Name, err)
+        }
+    }
+    state.pods = state.pods[:0]
+    state.nodes = state.nodes[:0]
+    state.scheduled = state.scheduled[:0]
+}
+
+func (s *Simulator) allocate(nodeName string, gpus int) error {
+    if _, err := s.nodeCache.GetNode(nodeName); err != nil {
+        return err
+    }
+    s.used[nodeName] += gpus
+    return s.syncAllocation(nodeName)
+}
+
+// syncAllocation publishes the GPUs taken on a node, by pods or failures,
+// to the node cache the scheduler reads
+func (s *Simulator) syncAllocation(nodeName string) error {
+    node, err := s.nodeCache.GetNode(nodeName)
+    if err != nil {
+        return err
+    }
+    return s.nodeCache.UpdateGPUAllocation(nodeName, s.used[nodeName]+s.blockedGPUs(node))
+}
+
+// blockedGPUs returns the GPUs of the node taken out by failures: all of
+// them while the node is down, else those hit by XID events
+func (s *Simulator) blockedGPUs(node *v1.Node) int {
+    if s.down[node.Name] > 0 {
+        return getNodeGPUs(node)
+    }
+    return s.xidGPUs(node)
+}
+
+// span returns the distinct leaves and spines the nodes are in
+func (s *Simulator) span(nodes []*v1.Node) (map[string]bool, map[string]bool) {
+    leaves := make(map[string]bool)
+    spines := make(map[string]bool)
+    for _, node := range nodes {
+        if node == nil {
+            continue
+        }
+        domain, err := s.cache.GetDomainForNode(node.Name)
+        if err != nil {
+            continue
+        }
+        leaves[domain.Name] = true
+        spines[domain.SpineSwitch] = true
+    }
+    if len(leaves) == 0 {
+        leaves[""] = true
+        spines[""] = true
+    }
+    return leaves, spines
+}
+
+// slowdown is the run time multiplier of a placement. Degraded spines
+// scale the penalty of jobs that cross them.
+func (s *Simulator) slowdown(nodes []*v1.Node) float64 {
+    leaves, spines := s.span(nodes)
+    spinePenalty := s.config.SpinePenalty
+    if len(spines) > 1 {
+        factor := 1.0
+        for spine := range spines {
+            if f, ok := s.degraded[spine]; ok && f > factor {
+                factor = f
+            }
+        }
+        spinePenalty *= factor
+    }
+    return 1 + float64(len(leaves)-1)*s.config.LeafPenalty + float64(len(spines)-1)*spinePenalty
+}
+
+// sample records cluster utilization and fragmentation. Fragmentation is
+// the share of free GPUs stranded on partially used nodes.
+func (s *Simulator) sample() {
+    var total, used, stranded, unavailable int
+    for _, node := range s.nodeCache.GetAllNodes() {
+        blocked := s.blockedGPUs(node)
+        capacity := getNodeGPUs(node) - blocked
+        allocated := s.used[node.Name]
+        unavailable += blocked
+
+        total += capacity
+        used += allocated
+        if free := capacity - allocated; allocated > 0 && free > 0 {
+            stranded += free
+        }
+    }
+
+    sample := Sample{
+        Time:            s.now.Seconds(),
+        QueueLength:     len(s.pending),
+        RunningJobs:     len(s.running),
+        UnavailableGPUs: unavailable,
+    }
+    if total > 0 {
+        sample.Utilization = float64(used) / float64(total)
+    }
+    if free := total - used; free > 0 {
+        sample.Fragmentation = float64(stranded) / float64(free)
+    }
+    s.report.Samples = append(s.report.Samples, sample)
+}
+
+func (s *Simulator) push(at time.Duration, ev *event) {
+    ev.at = at
+    ev.seq = s.seq
+    s.seq++
+    heap.Push(&s.events, ev)
+}
+
+// podMover applies the recovery manager's migrations to the simulated
+// cluster instead of the API server
+type podMover struct {
+    sim *Simulator
+}
+
+func (m *podMover) DeletePod(ctx context.Context, pod *v1.Pod) error {
+    s := m.sim
+    ref, ok := s.pods[pod.Name]
+    if !ok {
+        return fmt.Errorf("pod %s is not running", pod.Name)
+    }
+
+    state := ref.state
+    node := state.nodes[ref.index]
+    if node == nil {
+        return nil
+    }
+    if err := s.allocate(node.Name, -getPodGPUs(pod)); err != nil {
+        return err
+    }
+    if state.scheduled[ref.index] {
+        s.scheduler.Release(pod, node)
+    }
+    state.nodes[ref.index] = nil
+    state.scheduled[ref.index] = false
+    return nil
+}
+
+func (m *podMover) CreatePod(ctx context.Context, pod *v1.Pod) (*v1.Pod, error) {
+    s := m.sim
+    ref, ok := s.pods[pod.Name]
+    if !ok {
+        return nil, fmt.Errorf("pod %s does not belong to a running job", pod.Name)
+    }
+    node, err := s.nodeCache.GetNode(pod.Spec.NodeName)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.allocate(node.Name, getPodGPUs(pod)); err != nil {
+        return nil, err
+    }
+
+    ref.state.pods[ref.index] = pod
+    ref.state.nodes[ref.index] = node
+    s.moved++
+    return pod, nil
+}
+
+func newPod(job Job, index, gpus int) *v1.Pod {
+    pod := &v1.Pod{
+        ObjectMeta: metav1.ObjectMeta{
+            Name:      fmt.Sprintf("%s-%d", job.ID, index),
+            Namespace: simNamespace,
+            Labels: map[string]string{
+                algorithm.JobLabel: job.ID,
+            },
+            Annotations: make(map[string]string),
+        },
+        Spec: v1.PodSpec{
+            Containers: []v1.Container{{
+                Name: "worker",
+                Resources: v1.ResourceRequirements{
+                    Limits: v1.ResourceList{
+                        "nvidia.com/gpu": *resource.NewQuantity(int64(gpus), resource.DecimalSI),
+                    },
+                },
+            }},
+        },
+    }
+    pod.Annotations[algorithm.JobGPUsAnnotation] = strconv.Itoa(job.GPUs)
+    if job.CommProfile != algorithm.CommProfileDefault {
+        pod.Annotations[algorithm.CommProfileAnnotation] = string(job.CommProfile)
+    }
+    return pod
+}
+
+func getPodGPUs(pod *v1.Pod) int {
+    total := 0
+    for _, container := range pod.Spec.Containers {
+        if gpus, ok := container.Resources.Limits["nvidia.com/gpu"]; ok {
+            total += int(gpus.Value())
+        }
+    }
+    return total
+}
+
+func getNodeGPUs(node *v1.Node) int {
+    gpus := node.Status.Capacity["nvidia.com/gpu"]
+    return int(gpus.Value())
+}
+
+type eventKind int
+
+const (
+    eventArrival eventKind = iota
+    eventCompletion
+    eventFailure
+    eventRepair
+    eventSample
+)
+
+type event struct {
+    at      time.Duration
+    seq     int
+    kind    eventKind
+    job     *Job
+    state   *jobState
+    version int
+    chaos   *ChaosEvent
+}
+
+// eventQueue orders events by time, then by insertion order
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+    if q[i].at != q[j].at {
+        return q[i].at < q[j].at
+    }
+    return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+    old := *q
+    ev := old[len(old)-1]
+    *q = old[:len(old)-1]
+    return ev
+}
@@ -0,0 +1,281 @@
+package simulator
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "strconv"
+
+    v1 "k8s.io/api/core/v1"
+    "k8s.io/apimachinery/pkg/api/resource"
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "sigs.k8s.io/yaml"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/discovery"
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// TopologySpec describes a synthetic leaf/spine cluster
+type TopologySpec struct {
+    Spines []SpineSpec `json:"spines"`
+    // Links lists the connections between leaves. When empty every leaf is
+    // connected to the other leaves of its spine.
+    Links []LinkSpec `json:"links,omitempty"`
+}
+
+// SpineSpec is a spine switch and the leaves below it
+type SpineSpec struct {
+    Name   string     `json:"name"`
+    Leaves []LeafSpec `json:"leaves"`
+}
+
+// LeafSpec is a leaf switch with identical GPU nodes, or with the nodes
+// listed one by one in NodeList
+type LeafSpec struct {
+    Name        string     `json:"name"`
+    Nodes       int        `json:"nodes,omitempty"`
+    GPUsPerNode int        `json:"gpusPerNode,omitempty"`
+    NodeList    []NodeSpec `json:"nodeList,omitempty"`
+}
+
+// NodeSpec is a single named node
+type NodeSpec struct {
+    Name string `json:"name"`
+    GPUs int    `json:"gpus"`
+}
+
+// LinkSpec connects the source leaf to the target leaf, with the
+// bandwidth and latency the scheduler scores when they are known
+type LinkSpec struct {
+    Source        string           `json:"source"`
+    Target        string           `json:"target"`
+    BandwidthGbps float64          `json:"bandwidthGbps,omitempty"`
+    Latency       *metav1.Duration `json:"latency,omitempty"`
+}
+
+// nodes returns the nodes of the leaf, generating names when not listed
+func (l *LeafSpec) nodes() []NodeSpec {
+    if len(l.NodeList) > 0 {
+        return l.NodeList
+    }
+    nodes := make([]NodeSpec, l.Nodes)
+    for i := range nodes {
+        nodes[i] = NodeSpec{Name: fmt.Sprintf("%s-node-%d", l.Name, i), GPUs: l.GPUsPerNode}
+    }
+    return nodes
+}
+
+// LoadTopologySpec reads a topology description in YAML or JSON. Versioned
+// topology documents (kind: Topology) are converted.
+func LoadTopologySpec(path string) (*TopologySpec, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read topology %s: %v", path, err)
+    }
+
+    var header struct {
+        Kind string `json:"kind"`
+    }
+    if err := yaml.Unmarshal(data, &header); err == nil && header.Kind == discovery.TopologyKind {
+        doc, err := discovery.ReadTopology(bytes.NewReader(data))
+        if err != nil {
+            return nil, fmt.Errorf("failed to load topology %s: %v", path, err)
+        }
+        return SpecFromDocument(doc)
+    }
+
+    spec := &TopologySpec{}
+    if err := yaml.Unmarshal(data, spec); err != nil {
+        return nil, fmt.Errorf("failed to parse topology %s: %v", path, err)
+    }
+    if err := spec.Validate(); err != nil {
+        return nil, err
+    }
+    return spec, nil
+}
+
+// SpecFromDocument converts a topology document. Every leaf needs a
+// parent spine.
+func SpecFromDocument(doc *discovery.TopologyDocument) (*TopologySpec, error) {
+    spec := &TopologySpec{}
+    spines := make(map[string]int)
+    for _, leaf := range doc.Leaves() {
+        if leaf.Parent == "" {
+            return nil, fmt.Errorf("leaf %s has no parent spine", leaf.Name)
+        }
+        idx, ok := spines[leaf.Parent]
+        if !ok {
+            idx = len(spec.Spines)
+            spines[leaf.Parent] = idx
+            spec.Spines = append(spec.Spines, SpineSpec{Name: leaf.Parent})
+        }
+
+        spine := &spec.Spines[idx]
+        leafSpec := LeafSpec{Name: leaf.Name}
+        for _, node := range leaf.Nodes {
+            leafSpec.NodeList = append(leafSpec.NodeList, NodeSpec{Name: node.Name, GPUs: node.GPUs})
+        }
+        spine.Leaves = append(spine.Leaves, leafSpec)
+    }
+    for _, link := range doc.Links {
+        spec.Links = append(spec.Links, LinkSpec{
+            Source:        link.Source,
+            Target:        link.Target,
+            BandwidthGbps: link.BandwidthGbps,
+            Latency:       link.Latency,
+        })
+    }
+
+    if err := spec.Validate(); err != nil {
+        return nil, err
+    }
+    return spec, nil
+}
+
+func (s *TopologySpec) Validate() error {
+    if len(s.Spines) == 0 {
+        return fmt.Errorf("topology has no spines")
+    }
+
+    seen := make(map[string]bool)
+    for _, spine := range s.Spines {
+        if spine.Name == "" || seen[spine.Name] {
+            return fmt.Errorf("spine name %q is empty or duplicated", spine.Name)
+        }
+        seen[spine.Name] = true
+        for _, leaf := range spine.Leaves {
+            if leaf.Name == "" || seen[leaf.Name] {
+                return fmt.Errorf("leaf name %q is empty or duplicated", leaf.Name)
+            }
+            seen[leaf.Name] = true
+            if len(leaf.NodeList) == 0 && (leaf.Nodes <= 0 || leaf.GPUsPerNode <= 0) {
+                return fmt.Errorf("leaf %s must have positive nodes and GPUs per node", leaf.Name)
+            }
+            for _, node := range leaf.nodes() {
+                if node.Name == "" || seen[node.Name] || node.GPUs < 0 {
+                    return fmt.Errorf("node %q in leaf %s is unnamed, duplicated or has negative GPUs", node.Name, leaf.Name)
+                }
+                seen[node.Name] = true
+            }
+        }
+    }
+    for _, link := range s.Links {
+        if !seen[link.Source] || !seen[link.Target] {
+            return fmt.Errorf("link %s -> %s refers to an unknown leaf", link.Source, link.Target)
+        }
+    }
+    return nil
+}
+
+// TotalGPUs returns the number of GPUs in the described cluster
+func (s *TopologySpec) TotalGPUs() int {
+    total := 0
+    for _, spine := range s.Spines {
+        for _, leaf := range spine.Leaves {
+            for _, node := range leaf.nodes() {
+                total += node.GPUs
+            }
+        }
+    }
+    return total
+}
+
+// BuildCache creates the node and topology caches for the description
+func BuildCache(spec *TopologySpec) (*algorithm.TopologyCache, *algorithm.NodeCache, error) {
+    nodeCache := algorithm.NewNodeCache()
+    topologyCache := algorithm.NewTopologyCache(nodeCache)
+
+    nodes := make(map[string][]*v1.Node)
+    for _, spine := range spec.Spines {
+        for _, leaf := range spine.Leaves {
+            for _, spec := range leaf.nodes() {
+                node := newNode(spec.Name, spec.GPUs)
+                if err := nodeCache.AddNode(node); err != nil {
+                    return nil, nil, err
+                }
+                nodes[leaf.Name] = append(nodes[leaf.Name], node)
+            }
+        }
+    }
+
+    _, err := topologyCache.Apply(func(tc *algorithm.TopologyCache) error {
+        for _, spine := range spec.Spines {
+            for _, leaf := range spine.Leaves {
+                domain := &algorithm.Domain{
+                    ID:          leaf.Name,
+                    Name:        leaf.Name,
+                    Level:       algorithm.LevelLeaf,
+                    Parent:      spine.Name,
+                    LeafSwitch:  leaf.Name,
+                    SpineSwitch: spine.Name,
+                    Nodes:       append([]*v1.Node(nil), nodes[leaf.Name]...),
+                    Jobs:        make(map[string]*algorithm.PlacedJob),
+                }
+                for _, node := range domain.Nodes {
+                    domain.TotalGPUs += getNodeGPUs(node)
+                }
+                if err := tc.AddDomain(domain); err != nil {
+                    return err
+                }
+            }
+        }
+        for _, link := range spec.links() {
+            if err := tc.AddSpineConnection(link.Source, link.Target); err != nil {
+                return err
+            }
+            if link.BandwidthGbps > 0 {
+                if err := tc.SetLinkBandwidth(link.Source, link.Target, link.BandwidthGbps); err != nil {
+                    return err
+                }
+            }
+            if link.Latency != nil {
+                if err := tc.SetLinkLatency(link.Source, link.Target, link.Latency.Duration); err != nil {
+                    return err
+                }
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+    return topologyCache, nodeCache, nil
+}
+
+// links returns the explicit links, or connects every leaf to the other
+// leaves of its spine
+func (s *TopologySpec) links() []LinkSpec {
+    if len(s.Links) > 0 {
+        return s.Links
+    }
+    var links []LinkSpec
+    for _, spine := range s.Spines {
+        for _, source := range spine.Leaves {
+            for _, target := range spine.Leaves {
+                if source.Name != target.Name {
+                    links = append(links, LinkSpec{Source: source.Name, Target: target.Name})
+                }
+            }
+        }
+    }
+    return links
+}
+
+func newNode(name string, gpus int) *v1.Node {
+    quantity := *resource.NewQuantity(int64(gpus), resource.DecimalSI)
+    return &v1.Node{
+        ObjectMeta: metav1.ObjectMeta{
+            Name: name,
+            Labels: map[string]string{
+                "nvidia.com/gpu.count": strconv.Itoa(gpus),
+            },
+        },
+        Status: v1.NodeStatus{
+            Capacity:    v1.ResourceList{"nvidia.com/gpu": quantity},
+            Allocatable: v1.ResourceList{"nvidia.com/gpu": quantity},
+            Conditions: []v1.NodeCondition{
+                {Type: v1.NodeReady, Status: v1.ConditionTrue},
+            },
+        },
+    }
+}
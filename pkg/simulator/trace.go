@@ -0,0 +1,139 @@
+package simulator
+
+import (
+    "encoding/csv"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// traceHeader is the column layout of the job trace CSV format
+var traceHeader = []string{"id", "arrival_s", "duration_s", "gpus", "gang_size", "comm_profile"}
+
+// Job is one entry of a job arrival trace
+type Job struct {
+    ID string
+    // Arrival is the offset from the start of the trace
+    Arrival time.Duration
+    // Duration is the run time with an ideal placement
+    Duration time.Duration
+    // GPUs is the total for the job, split evenly over GangSize pods
+    GPUs        int
+    GangSize    int
+    CommProfile algorithm.CommProfile
+}
+
+func (j *Job) Validate() error {
+    if j.ID == "" {
+        return fmt.Errorf("job has no id")
+    }
+    if j.GPUs <= 0 || j.Duration <= 0 || j.Arrival < 0 {
+        return fmt.Errorf("job %s has invalid gpus, duration or arrival", j.ID)
+    }
+    if j.GangSize <= 0 || j.GPUs%j.GangSize != 0 {
+        return fmt.Errorf("job %s: %d GPUs cannot be split over %d pods", j.ID, j.GPUs, j.GangSize)
+    }
+    return nil
+}
+
+// LoadTrace reads a job trace CSV file
+func LoadTrace(path string) ([]Job, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open trace %s: %v", path, err)
+    }
+    defer f.Close()
+    return ReadTrace(f)
+}
+
+// ReadTrace parses the job trace CSV format. Jobs are returned sorted by
+// arrival; gang_size and comm_profile may be left empty.
+func ReadTrace(r io.Reader) ([]Job, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+
+    records, err := reader.ReadAll()
+    if err != nil {
+        return nil, fmt.Errorf("failed to read trace: %v", err)
+    }
+    if len(records) > 0 && records[0][0] == traceHeader[0] {
+        records = records[1:]
+    }
+
+    jobs := make([]Job, 0, len(records))
+    for i, record := range records {
+        if len(record) < 4 {
+            return nil, fmt.Errorf("trace line %d: expected at least 4 fields, got %d", i+2, len(record))
+        }
+
+        arrival, err := strconv.ParseFloat(record[1], 64)
+        if err != nil {
+            return nil, fmt.Errorf("trace line %d: invalid arrival: %v", i+2, err)
+        }
+        duration, err := strconv.ParseFloat(record[2], 64)
+        if err != nil {
+            return nil, fmt.Errorf("trace line %d: invalid duration: %v", i+2, err)
+        }
+        gpus, err := strconv.Atoi(record[3])
+        if err != nil {
+            return nil, fmt.Errorf("trace line %d: invalid gpus: %v", i+2, err)
+        }
+
+        job := Job{
+            ID:       record[0],
+            Arrival:  seconds(arrival),
+            Duration: seconds(duration),
+            GPUs:     gpus,
+            GangSize: 1,
+        }
+        if len(record) > 4 && record[4] != "" {
+            if job.GangSize, err = strconv.Atoi(record[4]); err != nil {
+                return nil, fmt.Errorf("trace line %d: invalid gang size: %v", i+2, err)
+            }
+        }
+        if len(record) > 5 {
+            job.CommProfile = algorithm.CommProfile(record[5])
+        }
+        if err := job.Validate(); err != nil {
+            return nil, fmt.Errorf("trace line %d: %v", i+2, err)
+        }
+        jobs = append(jobs, job)
+    }
+
+    sort.SliceStable(jobs, func(i, j int) bool {
+        return jobs[i].Arrival < jobs[j].Arrival
+    })
+    return jobs, nil
+}
+
+// WriteTrace writes jobs in the job trace CSV format
+func WriteTrace(w io.Writer, jobs []Job) error {
+    writer := csv.NewWriter(w)
+    if err := writer.Write(traceHeader); err != nil {
+        return err
+    }
+    for _, job := range jobs {
+        record := []string{
+            job.ID,
+            strconv.FormatFloat(job.Arrival.Seconds(), 'f', -1, 64),
+            strconv.FormatFloat(job.Duration.Seconds(), 'f', -1, 64),
+            strconv.Itoa(job.GPUs),
+            strconv.Itoa(job.GangSize),
+            string(job.CommProfile),
+        }
+        if err := writer.Write(record); err != nil {
+            return err
+        }
+    }
+    writer.Flush()
+    return writer.Error()
+}
+
+func seconds(s float64) time.Duration {
+    return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,390 @@
+package simulator
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "sort"
+    "time"
+
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+    "sigs.k8s.io/yaml"
+
+    "github.com/yourusername/topology-aware-gpu-scheduler/pkg/scheduler/algorithm"
+)
+
+// TopologyDelta is a planned change to a cluster topology
+type TopologyDelta struct {
+    AddLeaves    []LeafAddition `json:"addLeaves,omitempty"`
+    RemoveLeaves []string       `json:"removeLeaves,omitempty"`
+    AddNodes     []NodeAddition `json:"addNodes,omitempty"`
+    RemoveNodes  []string       `json:"removeNodes,omitempty"`
+    AddLinks     []LinkSpec     `json:"addLinks,omitempty"`
+    RemoveLinks  []LinkSpec     `json:"removeLinks,omitempty"`
+}
+
+// LeafAddition is a new leaf switch under an existing or new spine
+type LeafAddition struct {
+    Spine string `json:"spine"`
+    LeafSpec
+}
+
+// NodeAddition adds identical nodes to an existing leaf
+type NodeAddition struct {
+    Leaf        string `json:"leaf"`
+    Count       int    `json:"count"`
+    GPUsPerNode int    `json:"gpusPerNode"`
+}
+
+// LoadTopologyDelta reads a topology change in YAML or JSON
+func LoadTopologyDelta(path string) (*TopologyDelta, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read topology delta %s: %v", path, err)
+    }
+
+    delta := &TopologyDelta{}
+    if err := yaml.Unmarshal(data, delta); err != nil {
+        return nil, fmt.Errorf("failed to parse topology delta %s: %v", path, err)
+    }
+    return delta, nil
+}
+
+// SnapshotSpec describes the leaves, nodes and links, with their bandwidth
+// and latency, currently held in the topology cache so the live cluster can
+// be replayed in the simulator
+func SnapshotSpec(cache *algorithm.TopologyCache) *TopologySpec {
+    domains := cache.GetAllDomains()
+    sort.Slice(domains, func(i, j int) bool {
+        return domains[i].Name < domains[j].Name
+    })
+
+    spec := &TopologySpec{}
+    spines := make(map[string]int)
+    for _, domain := range domains {
+        if domain.Level != "" && domain.Level != algorithm.LevelLeaf {
+            continue
+        }
+        spineName := domain.Parent
+        if spineName == "" {
+            spineName = domain.SpineSwitch
+        }
+        idx, exists := spines[spineName]
+        if !exists {
+            idx = len(spec.Spines)
+            spines[spineName] = idx
+            spec.Spines = append(spec.Spines, SpineSpec{Name: spineName})
+        }
+
+        leaf := LeafSpec{Name: domain.Name}
+        for _, node := range domain.Nodes {
+            leaf.NodeList = append(leaf.NodeList, NodeSpec{Name: node.Name, GPUs: getNodeGPUs(node)})
+        }
+        spec.Spines[idx].Leaves = append(spec.Spines[idx].Leaves, leaf)
+
+        connected, err := cache.GetConnectedDomains(domain.Name)
+        if err != nil {
+            continue
+        }
+        for _, target := range connected {
+            link := LinkSpec{Source: domain.Name, Target: target.Name}
+            link.BandwidthGbps, _ = cache.GetLinkBandwidth(domain.Name, target.Name)
+            if latency, ok := cache.GetLinkLatency(domain.Name, target.Name); ok {
+                link.Latency = &metav1.Duration{Duration: latency}
+            }
+            spec.Links = append(spec.Links, link)
+        }
+    }
+    return spec
+}
+
+// SnapshotHandler serves the live topology as a simulator description
+func SnapshotHandler(cache *algorithm.TopologyCache) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet {
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(SnapshotSpec(cache))
+    })
+}
+
+// FetchTopologySpec reads a topology snapshot from a running scheduler
+func FetchTopologySpec(url string) (*TopologySpec, error) {
+    client := &http.Client{Timeout: 10 * time.Second}
+    resp, err := client.Get(url)
+    if err != nil {
+        return nil, fmt.Errorf("failed to fetch topology from %s: %v", url, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("failed to fetch topology from %s: %s", url, resp.Status)
+    }
+
+    spec := &TopologySpec{}
+    if err := json.NewDecoder(resp.Body).Decode(spec); err != nil {
+        return nil, fmt.Errorf("failed to decode topology from %s: %v", url, err)
+    }
+    if err := spec.Validate(); err != nil {
+        return nil, err
+    }
+    return spec, nil
+}
+
+// Apply returns a copy of the description with the delta applied. Removals
+// are applied before additions. New leaves are linked to the other leaves
+// of their spine when the description lists links explicitly.
+func (s *TopologySpec) Apply(delta *TopologyDelta) (*TopologySpec, error) {
+    out := s.copy()
+    explicitLinks := len(out.Links) > 0
+
+    removedLeaves := make(map[string]bool)
+    for _, name := range delta.RemoveLeaves {
+        removedLeaves[name] = true
+    }
+    removedNodes := make(map[string]bool)
+    for _, name := range delta.RemoveNodes {
+        removedNodes[name] = true
+    }
+
+    for i := range out.Spines {
+        var leaves []LeafSpec
+        for _, leaf := range out.Spines[i].Leaves {
+            if removedLeaves[leaf.Name] {
+                delete(removedLeaves, leaf.Name)
+                continue
+            }
+            if len(removedNodes) > 0 {
+                var nodes []NodeSpec
+                for _, node := range leaf.nodes() {
+                    if removedNodes[node.Name] {
+                        delete(removedNodes, node.Name)
+                        continue
+                    }
+                    nodes = append(nodes, node)
+                }
+                if len(nodes) == 0 {
+                    continue
+                }
+                leaf = LeafSpec{Name: leaf.Name, NodeList: nodes}
+            }
+            leaves = append(leaves, leaf)
+        }
+        out.Spines[i].Leaves = leaves
+    }
+    for name := range removedLeaves {
+        return nil, fmt.Errorf("cannot remove unknown leaf %s", name)
+    }
+    for name := range removedNodes {
+        return nil, fmt.Errorf("cannot remove unknown node %s", name)
+    }
+
+    for _, addition := range delta.AddNodes {
+        leaf := out.findLeaf(addition.Leaf)
+        if leaf == nil {
+            return nil, fmt.Errorf("cannot add nodes to unknown leaf %s", addition.Leaf)
+        }
+        if addition.Count <= 0 || addition.GPUsPerNode <= 0 {
+            return nil, fmt.Errorf("nodes added to leaf %s must have positive count and GPUs", addition.Leaf)
+        }
+        nodes := leaf.nodes()
+        for i := 0; i < addition.Count; i++ {
+            name := out.freeNodeName(leaf.Name, len(nodes))
+            nodes = append(nodes, NodeSpec{Name: name, GPUs: addition.GPUsPerNode})
+            // Keep the name taken for the next ones
+            *leaf = LeafSpec{Name: leaf.Name, NodeList: nodes}
+        }
+    }
+
+    for _, addition := range delta.AddLeaves {
+        spine := out.findSpine(addition.Spine)
+        if spine == nil {
+            out.Spines = append(out.Spines, SpineSpec{Name: addition.Spine})
+            spine = &out.Spines[len(out.Spines)-1]
+        }
+        if explicitLinks {
+            for _, peer := range spine.Leaves {
+                out.Links = append(out.Links,
+                    LinkSpec{Source: addition.Name, Target: peer.Name},
+                    LinkSpec{Source: peer.Name, Target: addition.Name})
+            }
+        }
+        spine.Leaves = append(spine.Leaves, addition.LeafSpec)
+    }
+
+    // Drop links to removed leaves and the explicitly removed links. The
+    // implicit links have to be spelled out before any can be removed.
+    if len(delta.RemoveLinks) > 0 && !explicitLinks {
+        out.Links = out.links()
+    }
+    removedLinks := make(map[[2]string]bool)
+    for _, link := range delta.RemoveLinks {
+        removedLinks[[2]string{link.Source, link.Target}] = true
+    }
+    if len(out.Links) > 0 {
+        leaves := make(map[string]bool)
+        for _, spine := range out.Spines {
+            for _, leaf := range spine.Leaves {
+                leaves[leaf.Name] = true
+            }
+        }
+        var links []LinkSpec
+        for _, link := range out.Links {
+            if removedLinks[[2]string{link.Source, link.Target}] || !leaves[link.Source] || !leaves[link.Target] {
+                continue
+            }
+            links = append(links, link)
+        }
+        out.Links = links
+    }
+    if len(delta.AddLinks) > 0 && len(out.Links) == 0 {
+        out.Links = out.links()
+    }
+    out.Links = append(out.Links, delta.AddLinks...)
+
+    if err := out.Validate(); err != nil {
+        return nil, fmt.Errorf("topology after delta is invalid: %v", err)
+    }
+    return out, nil
+}
+
+// freeNodeName returns the first name leaf-node-N from N = next on that no
+// node of the description has, since live node names can follow the same
+// pattern
+func (s *TopologySpec) freeNodeName(leaf string, next int) string {
+    taken := make(map[string]bool)
+    for _, spine := range s.Spines {
+        for i := range spine.Leaves {
+            for _, node := range spine.Leaves[i].nodes() {
+                taken[node.Name] = true
+            }
+        }
+    }
+    for ; ; next++ {
+        if name := fmt.Sprintf("%s-node-%d", leaf, next); !taken[name] {
+            return name
+        }
+    }
+}
+
+func (s *TopologySpec) copy() *TopologySpec {
+    out := &TopologySpec{Links: append([]LinkSpec(nil), s.Links...)}
+    for _, spine := range s.Spines {
+        leaves := make([]LeafSpec, len(spine.Leaves))
+        for i, leaf := range spine.Leaves {
+            leaves[i] = leaf
+            leaves[i].NodeList = append([]NodeSpec(nil), leaf.NodeList...)
+        }
+        out.Spines = append(out.Spines, SpineSpec{Name: spine.Name, Leaves: leaves})
+    }
+    return out
+}
+
+func (s *TopologySpec) findSpine(name string) *SpineSpec {
+    for i := range s.Spines {
+        if s.Spines[i].Name == name {
+            return &s.Spines[i]
+        }
+    }
+    return nil
+}
+
+func (s *TopologySpec) findLeaf(name string) *LeafSpec {
+    for i := range s.Spines {
+        for j := range s.Spines[i].Leaves {
+            if s.Spines[i].Leaves[j].Name == name {
+                return &s.Spines[i].Leaves[j]
+            }
+        }
+    }
+    return nil
+}
+
+// RecentJobs keeps the trace jobs that arrived within the window before
+// the last arrival, shifted so the first of them arrives at zero
+func RecentJobs(jobs []Job, window time.Duration) []Job {
+    if window <= 0 || len(jobs) == 0 {
+        return jobs
+    }
+    cutoff := jobs[len(jobs)-1].Arrival - window
+    var recent []Job
+    for _, job := range jobs {
+        if job.Arrival >= cutoff {
+            recent = append(recent, job)
+        }
+    }
+    offset := recent[0].Arrival
+    for i := range recent {
+        recent[i].Arrival -= offset
+    }
+    return recent
+}
+
+// WhatIfReport compares replaying the same jobs on the current and the
+// modified topology
+type WhatIfReport struct {
+    BaselineGPUs int     `json:"baselineGPUs"`
+    ModifiedGPUs int     `json:"modifiedGPUs"`
+    Baseline     Summary `json:"baseline"`
+    Modified     Summary `json:"modified"`
+}
+
+// WhatIf replays the jobs on the baseline topology and on the baseline with
+// the delta applied
+func WhatIf(ctx context.Context, baseline *TopologySpec, delta *TopologyDelta, jobs []Job, config Config) (*WhatIfReport, error) {
+    modified, err := baseline.Apply(delta)
+    if err != nil {
+        return nil, err
+    }
+
+    report := &WhatIfReport{
+        BaselineGPUs: baseline.TotalGPUs(),
+        ModifiedGPUs: modified.TotalGPUs(),
+    }
+    for _, run := range []struct {
+        spec    *TopologySpec
+        summary *Summary
+    }{
+        {baseline, &report.Baseline},
+        {modified, &report.Modified},
+    } {
+        sim, err := New(run.spec, config)
+        if err != nil {
+            return nil, err
+        }
+        result, err := sim.Run(ctx, jobs)
+        if err != nil {
+            return nil, err
+        }
+        *run.summary = result.Summary
+    }
+    return report, nil
+}
+
+// WriteSummary prints the baseline and modified results side by side
+func (r *WhatIfReport) WriteSummary(w io.Writer) {
+    b, m := r.Baseline, r.Modified
+    fmt.Fprintf(w, "%-22s %12s %12s %12s\n", "", "baseline", "modified", "change")
+    row := func(name string, before, after float64) {
+        change := "-"
+        if before != 0 {
+            change = fmt.Sprintf("%+.1f%%", (after-before)/before*100)
+        }
+        fmt.Fprintf(w, "%-22s %12.2f %12.2f %12s\n", name, before, after, change)
+    }
+    row("GPUs", float64(r.BaselineGPUs), float64(r.ModifiedGPUs))
+    row("jobs completed", float64(b.JobsCompleted), float64(m.JobsCompleted))
+    row("jobs unscheduled", float64(b.JobsUnscheduled), float64(m.JobsUnscheduled))
+    row("mean queue wait (s)", b.MeanQueueWait, m.MeanQueueWait)
+    row("p99 queue wait (s)", b.P99QueueWait, m.P99QueueWait)
+    row("mean JCT (s)", b.MeanJCT, m.MeanJCT)
+    row("p99 JCT (s)", b.P99JCT, m.P99JCT)
+    row("makespan (s)", b.Makespan, m.Makespan)
+    row("mean utilization", b.MeanUtilization, m.MeanUtilization)
+    row("mean fragmentation", b.MeanFragmentation, m.MeanFragmentation)
+    row("jobs crossing spine", float64(b.JobsCrossingSpine), float64(m.JobsCrossingSpine))
+}
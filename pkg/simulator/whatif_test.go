@@ -0,0 +1,91 @@
+package simulator
+
+import (
+    "reflect"
+    "testing"
+    "time"
+
+    metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyAddNodesSkipsTakenNames(t *testing.T) {
+    // leaf-0-node-1 is a live node named like the generated ones
+    spec := &TopologySpec{Spines: []SpineSpec{{
+        Name: "spine-0",
+        Leaves: []LeafSpec{
+            {Name: "leaf-0", NodeList: []NodeSpec{{Name: "leaf-0-node-1", GPUs: 8}}},
+            {Name: "leaf-1", Nodes: 2, GPUsPerNode: 8},
+        },
+    }}}
+    delta := &TopologyDelta{AddNodes: []NodeAddition{
+        {Leaf: "leaf-0", Count: 2, GPUsPerNode: 8},
+        {Leaf: "leaf-1", Count: 1, GPUsPerNode: 4},
+    }}
+
+    got, err := spec.Apply(delta)
+    if err != nil {
+        t.Fatalf("Apply() error = %v", err)
+    }
+    want := map[string][]string{
+        "leaf-0": {"leaf-0-node-1", "leaf-0-node-2", "leaf-0-node-3"},
+        "leaf-1": {"leaf-1-node-0", "leaf-1-node-1", "leaf-1-node-2"},
+    }
+    for name, nodes := range want {
+        leaf := got.findLeaf(name)
+        var names []string
+        for _, node := range leaf.nodes() {
+            names = append(names, node.Name)
+        }
+        if !reflect.DeepEqual(names, nodes) {
+            t.Errorf("%s nodes = %v, want %v", name, names, nodes)
+        }
+    }
+}
+
+func TestValidateRejectsGeneratedNameClash(t *testing.T) {
+    spec := &TopologySpec{Spines: []SpineSpec{{
+        Name: "spine-0",
+        Leaves: []LeafSpec{
+            {Name: "leaf-0", Nodes: 2, GPUsPerNode: 8},
+            {Name: "leaf-1", NodeList: []NodeSpec{{Name: "leaf-0-node-1", GPUs: 8}}},
+        },
+    }}}
+    if err := spec.Validate(); err == nil {
+        t.Error("Validate() = nil, want an error for the duplicated node name")
+    }
+}
+
+func TestSnapshotSpecKeepsLinkAttributes(t *testing.T) {
+    spec := &TopologySpec{
+        Spines: []SpineSpec{{
+            Name: "spine-0",
+            Leaves: []LeafSpec{
+                {Name: "leaf-0", Nodes: 1, GPUsPerNode: 8},
+                {Name: "leaf-1", Nodes: 1, GPUsPerNode: 8},
+            },
+        }},
+        Links: []LinkSpec{
+            {Source: "leaf-0", Target: "leaf-1", BandwidthGbps: 400, Latency: &metav1.Duration{Duration: 2 * time.Microsecond}},
+            {Source: "leaf-1", Target: "leaf-0"},
+        },
+    }
+    cache, _, err := BuildCache(spec)
+    if err != nil {
+        t.Fatalf("BuildCache() error = %v", err)
+    }
+
+    links := make(map[[2]string]LinkSpec)
+    for _, link := range SnapshotSpec(cache).Links {
+        links[[2]string{link.Source, link.Target}] = link
+    }
+    for _, want := range spec.Links {
+        got, ok := links[[2]string{want.Source, want.Target}]
+        if !ok {
+            t.Errorf("snapshot misses link %s -> %s", want.Source, want.Target)
+            continue
+        }
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("snapshot link = %+v, want %+v", got, want)
+        }
+    }
+}
@@ -0,0 +1,193 @@
+package topology
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// DCGM exporter metric names used to derive device health
+const (
+    dcgmXIDMetric    = "DCGM_FI_DEV_XID_ERRORS"
+    dcgmECCDBEMetric = "DCGM_FI_DEV_ECC_DBE_VOL_TOTAL"
+    dcgmHealthMetric = "DCGM_EXP_GPU_HEALTH"
+)
+
+// DCGMScraper reads per-GPU health from a dcgm-exporter style endpoint
+// serving the Prometheus text format.
+type DCGMScraper struct {
+    endpoint string
+    client   *http.Client
+}
+
+func NewDCGMScraper(endpoint string, timeout time.Duration) *DCGMScraper {
+    return &DCGMScraper{
+        endpoint: endpoint,
+        client:   &http.Client{Timeout: timeout},
+    }
+}
+
+// Scrape returns the device health reported for every node, keyed by the
+// exporter's Hostname label.
+func (s *DCGMScraper) Scrape(ctx context.Context) (map[string][]GPUDeviceHealth, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("failed to scrape %s: %v", s.endpoint, err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("failed to scrape %s: status %d", s.endpoint, resp.StatusCode)
+    }
+    return ParseDCGMMetrics(resp.Body)
+}
+
+// ParseDCGMMetrics parses the subset of the exporter output needed for
+// health tracking. A device is unhealthy when it has reported a fatal XID,
+// any uncorrectable ECC error, or an explicit health value of zero. The
+// devices of each node are sorted by index.
+func ParseDCGMMetrics(r io.Reader) (map[string][]GPUDeviceHealth, error) {
+    devices := make(map[string]map[int]*GPUDeviceHealth)
+
+    scanner := bufio.NewScanner(r)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+
+        name, labels, value, err := parseMetricLine(line)
+        if err != nil {
+            return nil, err
+        }
+        if name != dcgmXIDMetric && name != dcgmECCDBEMetric && name != dcgmHealthMetric {
+            continue
+        }
+
+        host := labels["Hostname"]
+        index, err := strconv.Atoi(labels["gpu"])
+        if host == "" || err != nil {
+            continue
+        }
+
+        if devices[host] == nil {
+            devices[host] = make(map[int]*GPUDeviceHealth)
+        }
+        device, ok := devices[host][index]
+        if !ok {
+            device = &GPUDeviceHealth{Index: index, UUID: labels["UUID"], Healthy: true}
+            devices[host][index] = device
+        }
+
+        switch name {
+        case dcgmXIDMetric:
+            // The exporter reports the last XID seen as the sample value
+            if xid := int(value); xid != 0 {
+                device.XIDErrors++
+                device.LastXID = xid
+                if IsFatalXID(xid) {
+                    device.Healthy = false
+                    device.Reason = fmt.Sprintf("XID %d", xid)
+                }
+            }
+        case dcgmECCDBEMetric:
+            device.ECCErrors = int(value)
+            if value > 0 {
+                device.Healthy = false
+                device.Reason = "uncorrectable ECC errors"
+            }
+        case dcgmHealthMetric:
+            if value == 0 {
+                device.Healthy = false
+                if device.Reason == "" {
+                    device.Reason = "DCGM health check failed"
+                }
+            }
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+
+    result := make(map[string][]GPUDeviceHealth, len(devices))
+    for host, byIndex := range devices {
+        for _, device := range byIndex {
+            result[host] = append(result[host], *device)
+        }
+        sort.Slice(result[host], func(i, j int) bool {
+            return result[host][i].Index < result[host][j].Index
+        })
+    }
+    return result, nil
+}
+
+// parseMetricLine splits `name{k="v",...} value [timestamp]`
+func parseMetricLine(line string) (string, map[string]string, float64, error) {
+    labels := make(map[string]string)
+    name := line
+    rest := ""
+
+    if i := strings.Index(line, "{"); i >= 0 {
+        j := strings.LastIndex(line, "}")
+        if j < i {
+            return "", nil, 0, fmt.Errorf("malformed metric line: %q", line)
+        }
+        name = line[:i]
+        for _, pair := range splitLabels(line[i+1 : j]) {
+            kv := strings.SplitN(pair, "=", 2)
+            if len(kv) != 2 {
+                continue
+            }
+            labels[strings.TrimSpace(kv[0])] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+        }
+        rest = line[j+1:]
+    } else if i := strings.IndexAny(line, " \t"); i >= 0 {
+        name = line[:i]
+        rest = line[i:]
+    }
+
+    fields := strings.Fields(rest)
+    if len(fields) == 0 {
+        return "", nil, 0, fmt.Errorf("missing value in metric line: %q", line)
+    }
+    value, err := strconv.ParseFloat(fields[0], 64)
+    if err != nil {
+        return "", nil, 0, fmt.Errorf("invalid value in metric line %q: %v", line, err)
+    }
+    return name, labels, value, nil
+}
+
+// splitLabels splits on commas that are not inside quoted values
+func splitLabels(s string) []string {
+    var parts []string
+    inQuotes := false
+    start := 0
+    for i := 0; i < len(s); i++ {
+        switch s[i] {
+        case '\\':
+            i++
+        case '"':
+            inQuotes = !inQuotes
+        case ',':
+            if !inQuotes {
+                parts = append(parts, s[start:i])
+                start = i + 1
+            }
+        }
+    }
+    if start < len(s) {
+        parts = append(parts, s[start:])
+    }
+    return parts
+}
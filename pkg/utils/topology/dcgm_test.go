@@ -0,0 +1,121 @@
+package topology
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// dcgmSample is dcgm-exporter output for two nodes, trimmed to the metrics
+// the parser reads and a few it skips
+const dcgmSample = `# HELP DCGM_FI_DEV_XID_ERRORS Value of the last XID error encountered.
+# TYPE DCGM_FI_DEV_XID_ERRORS gauge
+DCGM_FI_DEV_XID_ERRORS{gpu="0",UUID="GPU-5c1a6f2e",device="nvidia0",modelName="NVIDIA H100 80GB HBM3",Hostname="gpu-001",DCGM_FI_DRIVER_VERSION="535.129.03"} 0
+DCGM_FI_DEV_XID_ERRORS{gpu="1",UUID="GPU-8d02b7c4",device="nvidia1",modelName="NVIDIA H100 80GB HBM3",Hostname="gpu-001",DCGM_FI_DRIVER_VERSION="535.129.03"} 79
+DCGM_FI_DEV_XID_ERRORS{gpu="2",UUID="GPU-1e9f03aa",device="nvidia2",modelName="NVIDIA H100 80GB HBM3",Hostname="gpu-001",DCGM_FI_DRIVER_VERSION="535.129.03"} 92
+DCGM_FI_DEV_XID_ERRORS{gpu="0",UUID="GPU-77ab21d0",device="nvidia0",modelName="NVIDIA H100 80GB HBM3",Hostname="gpu-002",DCGM_FI_DRIVER_VERSION="535.129.03"} 13 1697712000000
+# HELP DCGM_FI_DEV_ECC_DBE_VOL_TOTAL Total number of double-bit volatile ECC errors.
+# TYPE DCGM_FI_DEV_ECC_DBE_VOL_TOTAL counter
+DCGM_FI_DEV_ECC_DBE_VOL_TOTAL{gpu="0",UUID="GPU-5c1a6f2e",device="nvidia0",modelName="NVIDIA H100 80GB HBM3",Hostname="gpu-001"} 0
+DCGM_FI_DEV_ECC_DBE_VOL_TOTAL{gpu="1",UUID="GPU-77ab21d1",device="nvidia1",modelName="NVIDIA H100 80GB HBM3",Hostname="gpu-002"} 2
+# HELP DCGM_EXP_GPU_HEALTH GPU health as reported by the DCGM health checks.
+# TYPE DCGM_EXP_GPU_HEALTH gauge
+DCGM_EXP_GPU_HEALTH{gpu="2",UUID="GPU-77ab21d2",device="nvidia2",modelName="NVIDIA H100 80GB HBM3",Hostname="gpu-002"} 0
+# HELP DCGM_FI_DEV_GPU_TEMP GPU temperature (in C).
+# TYPE DCGM_FI_DEV_GPU_TEMP gauge
+DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="GPU-5c1a6f2e",device="nvidia0",modelName="NVIDIA H100 80GB HBM3",Hostname="gpu-001"} 41
+`
+
+func TestParseDCGMMetrics(t *testing.T) {
+    tests := []struct {
+        name    string
+        input   string
+        want    map[string][]GPUDeviceHealth
+        wantErr bool
+    }{
+        {
+            name:  "exporter output",
+            input: dcgmSample,
+            want: map[string][]GPUDeviceHealth{
+                "gpu-001": {
+                    {Index: 0, UUID: "GPU-5c1a6f2e", Healthy: true},
+                    {Index: 1, UUID: "GPU-8d02b7c4", Healthy: false, XIDErrors: 1, LastXID: 79, Reason: "XID 79"},
+                    {Index: 2, UUID: "GPU-1e9f03aa", Healthy: true, XIDErrors: 1, LastXID: 92},
+                },
+                "gpu-002": {
+                    {Index: 0, UUID: "GPU-77ab21d0", Healthy: true, XIDErrors: 1, LastXID: 13},
+                    {Index: 1, UUID: "GPU-77ab21d1", Healthy: false, ECCErrors: 2, Reason: "uncorrectable ECC errors"},
+                    {Index: 2, UUID: "GPU-77ab21d2", Healthy: false, Reason: "DCGM health check failed"},
+                },
+            },
+        },
+        {
+            name: "fatal XID reason kept over failed health check",
+            input: `DCGM_FI_DEV_XID_ERRORS{gpu="3",UUID="GPU-0",Hostname="gpu-003"} 48
+DCGM_EXP_GPU_HEALTH{gpu="3",UUID="GPU-0",Hostname="gpu-003"} 0
+`,
+            want: map[string][]GPUDeviceHealth{
+                "gpu-003": {{Index: 3, UUID: "GPU-0", Healthy: false, XIDErrors: 1, LastXID: 48, Reason: "XID 48"}},
+            },
+        },
+        {
+            name: "quoted commas and escaped quotes in labels",
+            input: `DCGM_FI_DEV_XID_ERRORS{gpu="0",modelName="A100, \"SXM4\"",UUID="GPU-1",Hostname="gpu-004"} 0
+`,
+            want: map[string][]GPUDeviceHealth{
+                "gpu-004": {{Index: 0, UUID: "GPU-1", Healthy: true}},
+            },
+        },
+        {
+            name: "samples without host or device are skipped",
+            input: `DCGM_FI_DEV_XID_ERRORS{gpu="0",UUID="GPU-1"} 79
+DCGM_FI_DEV_XID_ERRORS{UUID="GPU-2",Hostname="gpu-005"} 79
+`,
+            want: map[string][]GPUDeviceHealth{},
+        },
+        {
+            name:    "missing value",
+            input:   `DCGM_FI_DEV_XID_ERRORS{gpu="0",Hostname="gpu-006"}` + "\n",
+            wantErr: true,
+        },
+        {
+            name:    "invalid value",
+            input:   `DCGM_FI_DEV_XID_ERRORS{gpu="0",Hostname="gpu-006"} high` + "\n",
+            wantErr: true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := ParseDCGMMetrics(strings.NewReader(tt.input))
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ParseDCGMMetrics() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if tt.wantErr {
+                return
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("ParseDCGMMetrics() = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestIsFatalXID(t *testing.T) {
+    tests := []struct {
+        xid  int
+        want bool
+    }{
+        {13, false}, // graphics engine exception, an application fault
+        {31, false}, // MMU fault, an application fault
+        {48, true},
+        {79, true},
+        {92, false},
+        {95, true},
+    }
+    for _, tt := range tests {
+        if got := IsFatalXID(tt.xid); got != tt.want {
+            t.Errorf("IsFatalXID(%d) = %v, want %v", tt.xid, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,106 @@
+package topology
+
+import (
+    "encoding/json"
+    "fmt"
+
+    v1 "k8s.io/api/core/v1"
+)
+
+const (
+    // GPUHealthAnnotation carries a JSON list of GPUDeviceHealth written by
+    // the node's health exporter.
+    GPUHealthAnnotation = "topology.scheduler/gpu-health"
+
+    // GPUHealthyCondition is set to False by the exporter when the node has
+    // a GPU fault it could not attribute to a single device.
+    GPUHealthyCondition v1.NodeConditionType = "GPUHealthy"
+)
+
+// GPUDeviceHealth is the health of a single GPU as reported by DCGM
+type GPUDeviceHealth struct {
+    Index     int    `json:"index"`
+    UUID      string `json:"uuid,omitempty"`
+    Healthy   bool   `json:"healthy"`
+    XIDErrors int    `json:"xidErrors,omitempty"`
+    LastXID   int    `json:"lastXid,omitempty"`
+    ECCErrors int    `json:"eccErrors,omitempty"`
+    Reason    string `json:"reason,omitempty"`
+}
+
+// fatalXIDs are XID codes after which the device needs a reset before it
+// can be trusted with new work. XID 92, a high rate of corrected single
+// bit ECC errors, is left out: the device keeps working and a double bit
+// error, if it follows, is caught by the ECC counters.
+var fatalXIDs = map[int]bool{
+    48: true, // double bit ECC error
+    63: true, // ECC page retirement failure
+    64: true, // ECC page retirement recording failure
+    74: true, // NVLink error
+    79: true, // GPU has fallen off the bus
+    94: true, // contained ECC error
+    95: true, // uncontained ECC error
+}
+
+// IsFatalXID reports whether an XID code makes the device unusable
+func IsFatalXID(xid int) bool {
+    return fatalXIDs[xid]
+}
+
+// ParseGPUHealth reads per-device health from the node's annotation. When
+// the GPUHealthy condition is False every device is reported unhealthy.
+func ParseGPUHealth(node *v1.Node) ([]GPUDeviceHealth, error) {
+    var devices []GPUDeviceHealth
+    if val, ok := node.Annotations[GPUHealthAnnotation]; ok && val != "" {
+        if err := json.Unmarshal([]byte(val), &devices); err != nil {
+            return nil, fmt.Errorf("invalid GPU health annotation: %v", err)
+        }
+    }
+
+    for _, condition := range node.Status.Conditions {
+        if condition.Type != GPUHealthyCondition || condition.Status != v1.ConditionFalse {
+            continue
+        }
+        if len(devices) == 0 {
+            info, err := ExtractNodeGPUInfo(node)
+            if err != nil {
+                return nil, err
+            }
+            devices = make([]GPUDeviceHealth, info.TotalGPUs)
+            for i := range devices {
+                devices[i].Index = i
+            }
+        }
+        for i := range devices {
+            devices[i].Healthy = false
+            devices[i].Reason = condition.Reason
+        }
+    }
+    return devices, nil
+}
+
+// CountHealthyGPUs returns the number of usable GPUs out of total. Devices
+// without a health report are assumed to be healthy.
+func CountHealthyGPUs(total int, devices []GPUDeviceHealth) int {
+    unhealthy := 0
+    for _, device := range devices {
+        if !device.Healthy && device.Index < total {
+            unhealthy++
+        }
+    }
+    return total - unhealthy
+}
+
+// nodeGPUHealthRatio is 0 for a node that is not Ready, otherwise the
+// fraction of its GPUs that are healthy.
+func nodeGPUHealthRatio(node *v1.Node, devices []GPUDeviceHealth) float64 {
+    if !IsNodeHealthy(node) {
+        return 0.0
+    }
+
+    info, err := ExtractNodeGPUInfo(node)
+    if err != nil || info.TotalGPUs == 0 {
+        return 1.0
+    }
+    return float64(CountHealthyGPUs(info.TotalGPUs, devices)) / float64(info.TotalGPUs)
+}
@@ -72,27 +72,43 @@ func CalculateDomainDistance(source, target *Domain, connections map[string][]st
     return math.MaxInt32
 }
 
-func CalculateDomainHealth(domain *Domain) float64 {
+// CalculateDomainHealth returns the average GPU health of the domain's
+// nodes. lookup supplies per-device health tracked elsewhere, such as the
+// node cache; when it is nil or has no data the node annotations are used.
+func CalculateDomainHealth(domain *Domain, lookup func(nodeName string) []GPUDeviceHealth) float64 {
     if len(domain.Nodes) == 0 {
         return 0.0
     }
 
-    healthyNodes := 0
+    var total float64
     for _, node := range domain.Nodes {
-        if IsNodeHealthy(node) {
-            healthyNodes++
+        var devices []GPUDeviceHealth
+        if lookup != nil {
+            devices = lookup(node.Name)
         }
+        if devices == nil {
+            devices, _ = ParseGPUHealth(node)
+        }
+        total += nodeGPUHealthRatio(node, devices)
     }
-    return float64(healthyNodes) / float64(len(domain.Nodes))
+    return total / float64(len(domain.Nodes))
 }
 
+// IsNodeHealthy requires the node to be Ready and not to have reported a
+// node-wide GPU fault.
 func IsNodeHealthy(node *v1.Node) bool {
+    ready := false
     for _, condition := range node.Status.Conditions {
-        if condition.Type == v1.NodeReady {
-            return condition.Status == v1.ConditionTrue
+        switch condition.Type {
+        case v1.NodeReady:
+            ready = condition.Status == v1.ConditionTrue
+        case GPUHealthyCondition:
+            if condition.Status == v1.ConditionFalse {
+                return false
+            }
         }
     }
-    return false
+    return ready
 }
 
 func CalculateGPUFragmentation(domain *Domain) float64 {